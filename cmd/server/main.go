@@ -1,21 +1,52 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
+	"errors"
 	"log"
 	"net"
+	"net/http"
 	"sync"
 
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	reflectionv1alpha "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
 
+	"library-api-book/internal/auth"
+	"library-api-book/internal/cache"
 	"library-api-book/internal/config"
 	"library-api-book/internal/factory"
 	"library-api-book/internal/grpc/client"
 	"library-api-book/internal/grpc/handlers"
+	"library-api-book/internal/grpc/interceptors"
+	"library-api-book/internal/healthcheck"
+	"library-api-book/internal/permission"
 	"library-api-book/internal/routes"
 	"library-api-book/pkg/database"
+	"library-api-book/pkg/grpcweb"
+	"library-api-book/pkg/tlsutil"
+	"library-api-book/pkg/token"
 	"library-api-book/proto/book"
 )
 
+const (
+	serveModeSplit  = "split"
+	serveModeSingle = "single"
+)
+
+// cacheHealthService is SERVING while the book cache reaches Redis and its
+// circuit breaker is closed.
+const cacheHealthService = "cache"
+
 func main() {
 	config.LoadConfig()
 
@@ -24,39 +55,115 @@ func main() {
 		log.Fatal("Could not connect to PqSQL:", err)
 	}
 
-	redis := database.NewRedisClient()
-	if redis == nil {
-		log.Fatal("Failed connect to redis")
+	redis, err := database.NewRedisClient(context.Background())
+	if err != nil {
+		log.Fatal("Could not configure Redis:", err)
+	}
+	pingCtx, cancelPing := context.WithTimeout(context.Background(), config.ENV.RedisDialTimeout)
+	if err := redis.Ping(pingCtx).Err(); err != nil {
+		log.Printf("Redis at %s is unreachable, serving without it until it is back: %v", config.ENV.RedisAddr, err)
 	}
+	cancelPing()
 
 	provider := factory.InitFactory(psqlDB, redis)
 
-	var wg sync.WaitGroup
-	wg.Add(2)
+	healthServer := health.NewServer()
+	checker := healthcheck.NewChecker(healthServer, config.ENV.GRPCHealthCheckTimeout, provider.Logger,
+		healthcheck.Check{Name: "postgres", Ping: psqlDB.PingContext},
+		healthcheck.Check{Name: "redis", Ping: func(ctx context.Context) error { return redis.Ping(ctx).Err() }},
+		healthcheck.Check{Name: "cache", Ping: provider.Cache.Ping},
+	)
+	// Books are served from Postgres; Redis only backs caches and stock
+	// events, which degrade gracefully, so it only affects the overall status.
+	checker.AddService(book.BookService_ServiceDesc.ServiceName, "postgres")
+	checker.AddService(cacheHealthService, "cache")
+	go checker.Run(context.Background(), config.ENV.GRPCHealthCheckInterval)
+
+	validator, closeAuth := newTokenValidator(provider.Cache)
+	defer closeAuth()
+
+	go provider.StockBroker.Run(context.Background())
+
+	router := routes.RegisterRoutes(provider, validator, provider.Policy)
+
+	switch config.ENV.ServeMode {
+	case serveModeSplit:
+		creds, err := serverCredentials()
+		if err != nil {
+			log.Fatalf("Failed to load gRPC server certificates: %v", err)
+		}
+		grpcServer := newGRPCServer(provider, validator, provider.Policy, healthServer, grpc.Creds(creds))
+
+		var wg sync.WaitGroup
+		wg.Add(2)
 
-	go func() {
-		defer wg.Done()
-		runGRPCServer(provider)
-	}()
+		go func() {
+			defer wg.Done()
+			runGRPCServer(grpcServer)
+		}()
 
-	go func() {
-		defer wg.Done()
-		runHTTPServer(provider)
-	}()
+		go func() {
+			defer wg.Done()
+			runHTTPServer(router)
+		}()
 
-	wg.Wait()
+		wg.Wait()
+	case serveModeSingle:
+		runSinglePortServer(newGRPCServer(provider, validator, provider.Policy, healthServer), router)
+	default:
+		log.Fatalf("Unknown SERVE_MODE %q, expected %q or %q", config.ENV.ServeMode, serveModeSplit, serveModeSingle)
+	}
 }
 
-func runGRPCServer(provider *factory.Provider) {
-	listener, err := net.Listen("tcp", ":"+config.ENV.GRPCPort)
-	if err != nil {
-		log.Fatalf("Failed to listen on port %s: %v", config.ENV.GRPCPort, err)
+func newGRPCServer(provider *factory.Provider, validator auth.TokenValidator, policy *permission.Policy, healthServer *health.Server, options ...grpc.ServerOption) *grpc.Server {
+	var identities []interceptors.ServiceIdentity
+	if config.ENV.GRPCServiceIdentitiesFile != "" {
+		var err error
+		identities, err = interceptors.LoadServiceIdentities(config.ENV.GRPCServiceIdentitiesFile)
+		if err != nil {
+			log.Fatalf("Failed to load gRPC service identities: %v", err)
+		}
+	}
+	publicServices := []string{healthpb.Health_ServiceDesc.ServiceName}
+	if config.ENV.GRPCReflection {
+		publicServices = append(publicServices, reflectionpb.ServerReflection_ServiceDesc.ServiceName, reflectionv1alpha.ServerReflection_ServiceDesc.ServiceName)
 	}
+	authorizer := interceptors.NewAuthorizer(identities, validator, policy, handlers.MethodPermissions, publicServices...)
+	rateLimiter := interceptors.NewRateLimiter(provider.RateLimiter, provider.RateLimits, handlers.MethodRateLimitGroups, provider.Logger)
+	auditor := interceptors.NewAuditor(provider.AuditService, handlers.AuditedMethods)
 
-	grpcServer := grpc.NewServer()
+	options = append(options,
+		grpc.ChainUnaryInterceptor(
+			interceptors.UnaryLogging(provider.Logger),
+			auditor.Unary(),
+			interceptors.UnaryRecovery(provider.Logger),
+			authorizer.Unary(),
+			rateLimiter.Unary(),
+		),
+		grpc.ChainStreamInterceptor(
+			interceptors.StreamLogging(provider.Logger),
+			auditor.Stream(),
+			interceptors.StreamRecovery(provider.Logger),
+			authorizer.Stream(),
+			rateLimiter.Stream(),
+		),
+	)
+	grpcServer := grpc.NewServer(options...)
 
-	bookHandler := handlers.NewBookHandler(provider.BookService)
+	bookHandler := handlers.NewBookHandler(provider.BookService, provider.StockBroker)
 	book.RegisterBookServiceServer(grpcServer, bookHandler)
+	healthpb.RegisterHealthServer(grpcServer, healthServer)
+	if config.ENV.GRPCReflection {
+		reflection.Register(grpcServer)
+	}
+	return grpcServer
+}
+
+func runGRPCServer(grpcServer *grpc.Server) {
+	listener, err := net.Listen("tcp", ":"+config.ENV.GRPCPort)
+	if err != nil {
+		log.Fatalf("Failed to listen on port %s: %v", config.ENV.GRPCPort, err)
+	}
 
 	log.Printf("gRPC server running on port %s\n", config.ENV.GRPCPort)
 	if err := grpcServer.Serve(listener); err != nil {
@@ -64,14 +171,179 @@ func runGRPCServer(provider *factory.Provider) {
 	}
 }
 
-func runHTTPServer(provider *factory.Provider) {
-	authClient, err := client.NewAuthClient(config.ENV.UserGRPC)
+func runHTTPServer(router *gin.Engine) {
+	log.Printf("REST API server running on port %s\n", config.ENV.ServerPort)
+	log.Fatal(router.Run(":" + config.ENV.ServerPort))
+}
+
+// runSinglePortServer serves REST, gRPC and gRPC-Web on PORT. gRPC needs
+// HTTP/2, which is negotiated over TLS when a certificate is configured and
+// spoken in cleartext (h2c) otherwise.
+func runSinglePortServer(grpcServer *grpc.Server, router http.Handler) {
+	server := &http.Server{
+		Addr:    ":" + config.ENV.ServerPort,
+		Handler: grpcweb.NewMux(grpcServer, router),
+	}
+
+	tlsConfig, err := serverTLSConfig("h2", "http/1.1")
 	if err != nil {
-		log.Fatalf("Failed to initialize auth client: %v", err)
+		log.Fatalf("Failed to load server certificates: %v", err)
+	}
+	if tlsConfig == nil {
+		server.Handler = h2c.NewHandler(server.Handler, &http2.Server{})
+		log.Printf("REST, gRPC and gRPC-Web server running on port %s\n", config.ENV.ServerPort)
+		log.Fatal(server.ListenAndServe())
 	}
-	defer authClient.Close()
 
-	router := routes.RegisterRoutes(provider, authClient)
-	log.Printf("REST API server running on port %s\n", config.ENV.ServerPort)
-	log.Fatal(router.Run(":" + config.ENV.ServerPort))
+	server.TLSConfig = tlsConfig
+	log.Printf("REST, gRPC and gRPC-Web server running on port %s with TLS\n", config.ENV.ServerPort)
+	log.Fatal(server.ListenAndServeTLS("", ""))
+}
+
+// newTokenValidator builds the validator for AUTH_MODE. The returned function
+// closes the user service connection, if one was opened.
+func newTokenValidator(sharedCache *cache.Cache) (auth.TokenValidator, func()) {
+	closeAuth := func() {}
+
+	var remote auth.TokenValidator
+	if config.ENV.AuthMode != auth.ModeLocal {
+		authClient, err := newAuthClient(sharedCache)
+		if err != nil {
+			log.Fatalf("Failed to initialize auth client: %v", err)
+		}
+		closeAuth = authClient.Close
+		remote = authClient
+	}
+
+	var keys *token.KeySet
+	if config.ENV.AuthMode != auth.ModeRemote {
+		var err error
+		keys, err = loadKeySet()
+		if err != nil {
+			log.Fatalf("Failed to load token keys: %v", err)
+		}
+	}
+
+	validator, err := auth.NewTokenValidator(config.ENV.AuthMode, keys, remote)
+	if err != nil {
+		log.Fatalf("Failed to initialize token validation: %v", err)
+	}
+	return validator, closeAuth
+}
+
+func newAuthClient(sharedCache *cache.Cache) (*client.AuthClient, error) {
+	var tokenCache *client.TokenCache
+	if config.ENV.AuthCacheTTL > 0 {
+		var shared *cache.Cache
+		if config.ENV.AuthCacheRedis {
+			shared = sharedCache
+		}
+		tokenCache = client.NewTokenCache(config.ENV.AuthCacheTTL, shared)
+	}
+	clientFactory, err := newClientFactory()
+	if err != nil {
+		return nil, err
+	}
+	return client.NewAuthClient(clientFactory, config.ENV.UserGRPC, tokenCache)
+}
+
+func newClientFactory() (*client.ClientFactory, error) {
+	creds, err := clientCredentials()
+	if err != nil {
+		return nil, err
+	}
+
+	options := client.DefaultClientOptions()
+	options.Timeout = config.ENV.GRPCClientTimeout
+	options.MaxAttempts = config.ENV.GRPCClientMaxAttempts
+	options.BreakerThreshold = config.ENV.GRPCClientBreakerThreshold
+	options.BreakerCooldown = config.ENV.GRPCClientBreakerCooldown
+	options.TransportCredentials = creds
+	return client.NewClientFactory(options), nil
+}
+
+// serverCredentials enables TLS when a certificate is configured, and mutual
+// TLS when a client CA bundle is configured as well.
+func serverCredentials() (credentials.TransportCredentials, error) {
+	tlsConfig, err := serverTLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	if tlsConfig == nil {
+		return insecure.NewCredentials(), nil
+	}
+	return credentials.NewTLS(tlsConfig), nil
+}
+
+// serverTLSConfig returns nil when no certificate is configured.
+func serverTLSConfig(protocols ...string) (*tls.Config, error) {
+	if config.ENV.GRPCTLSCertFile == "" {
+		return nil, nil
+	}
+	if config.ENV.GRPCTLSRequireClient && config.ENV.GRPCTLSClientCAFile == "" {
+		return nil, errors.New("GRPC_TLS_REQUIRE_CLIENT_CERT needs GRPC_TLS_CLIENT_CA_FILE")
+	}
+
+	reloader, err := newCertReloader("server", tlsutil.Files{
+		CertFile: config.ENV.GRPCTLSCertFile,
+		KeyFile:  config.ENV.GRPCTLSKeyFile,
+		CAFile:   config.ENV.GRPCTLSClientCAFile,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return reloader.ServerConfig(config.ENV.GRPCTLSRequireClient, protocols...), nil
+}
+
+// clientCredentials returns nil for plaintext connections.
+func clientCredentials() (credentials.TransportCredentials, error) {
+	if !config.ENV.GRPCClientTLS {
+		return nil, nil
+	}
+
+	reloader, err := newCertReloader("client", tlsutil.Files{
+		CertFile: config.ENV.GRPCClientCertFile,
+		KeyFile:  config.ENV.GRPCClientKeyFile,
+		CAFile:   config.ENV.GRPCClientCAFile,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return credentials.NewTLS(reloader.ClientConfig(config.ENV.GRPCClientServerName)), nil
+}
+
+func newCertReloader(name string, files tlsutil.Files) (*tlsutil.Reloader, error) {
+	reloader, err := tlsutil.NewReloader(files)
+	if err != nil {
+		return nil, err
+	}
+
+	go reloader.Run(context.Background(), config.ENV.GRPCTLSReloadInterval, func(err error) {
+		log.Printf("Failed to reload gRPC %s certificates: %v", name, err)
+	})
+	return reloader, nil
+}
+
+// loadKeySet combines the key file with AUTH_JWT_SECRET. The secret has no kid
+// and becomes the default key unless the file names one.
+func loadKeySet() (*token.KeySet, error) {
+	var (
+		defaultKID string
+		keys       []*token.Key
+	)
+	if config.ENV.AuthJWTKeyFile != "" {
+		var err error
+		defaultKID, keys, err = token.LoadKeys(config.ENV.AuthJWTKeyFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if config.ENV.AuthJWTSecret != "" {
+		key, err := token.NewHMACKey("", []byte(config.ENV.AuthJWTSecret))
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return token.NewKeySet(defaultKID, keys...)
 }
@@ -0,0 +1,81 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	tkn "library-api-book/pkg/token"
+)
+
+const (
+	// ModeRemote asks the user service to validate every token.
+	ModeRemote = "remote"
+	// ModeLocal verifies token signatures in-process and never calls the
+	// user service.
+	ModeLocal = "local"
+	// ModeLocalFallback verifies locally and only asks the user service about
+	// tokens signed with a key this service does not know yet.
+	ModeLocalFallback = "local_fallback"
+)
+
+// TokenValidator checks a bearer token and returns its payload.
+// *client.AuthClient validates remotely.
+type TokenValidator interface {
+	ValidateToken(ctx context.Context, token string) (bool, *tkn.Token)
+}
+
+// NewTokenValidator returns the validator for mode. keys is required for the
+// local modes and remote for the remote ones.
+func NewTokenValidator(mode string, keys *tkn.KeySet, remote TokenValidator) (TokenValidator, error) {
+	switch mode {
+	case ModeRemote:
+		if remote == nil {
+			return nil, errors.New("auth mode remote requires the user service client")
+		}
+		return remote, nil
+	case ModeLocal, ModeLocalFallback:
+		if keys == nil {
+			return nil, fmt.Errorf("auth mode %s requires signing keys", mode)
+		}
+		if mode == ModeLocal {
+			return &LocalValidator{Keys: keys}, nil
+		}
+		if remote == nil {
+			return nil, fmt.Errorf("auth mode %s requires the user service client", mode)
+		}
+		return &FallbackValidator{Keys: keys, Remote: remote}, nil
+	default:
+		return nil, fmt.Errorf("unknown auth mode %q", mode)
+	}
+}
+
+type LocalValidator struct {
+	Keys *tkn.KeySet
+}
+
+func (validator *LocalValidator) ValidateToken(ctx context.Context, token string) (bool, *tkn.Token) {
+	payload, err := validator.Keys.Validate(token)
+	if err != nil {
+		return false, nil
+	}
+	return true, payload
+}
+
+// FallbackValidator trusts the local verdict whenever it knows the signing
+// key, so an expired or forged token is never sent on to the user service.
+type FallbackValidator struct {
+	Keys   *tkn.KeySet
+	Remote TokenValidator
+}
+
+func (validator *FallbackValidator) ValidateToken(ctx context.Context, token string) (bool, *tkn.Token) {
+	payload, err := validator.Keys.Validate(token)
+	if err == nil {
+		return true, payload
+	}
+	if errors.Is(err, tkn.ErrUnknownKey) {
+		return validator.Remote.ValidateToken(ctx, token)
+	}
+	return false, nil
+}
@@ -0,0 +1,86 @@
+package auth
+
+import (
+	"context"
+	"testing"
+
+	tkn "library-api-book/pkg/token"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var secret = []byte("0123456789abcdef0123456789abcdef")
+
+type stubValidator struct {
+	calls int
+}
+
+func (v *stubValidator) ValidateToken(ctx context.Context, token string) (bool, *tkn.Token) {
+	v.calls++
+	return true, &tkn.Token{AuthId: 99, Role: "admin"}
+}
+
+func newKeySet(t *testing.T) *tkn.KeySet {
+	key, err := tkn.NewHMACKey("current", secret)
+	require.NoError(t, err)
+	keys, err := tkn.NewKeySet("current", key)
+	require.NoError(t, err)
+	return keys
+}
+
+func TestFallbackValidator_LocalKey(t *testing.T) {
+	remote := &stubValidator{}
+	validator, err := NewTokenValidator(ModeLocalFallback, newKeySet(t), remote)
+	require.NoError(t, err)
+
+	token, err := tkn.GenerateToken("current", secret, 7, "author")
+	require.NoError(t, err)
+
+	valid, payload := validator.ValidateToken(context.Background(), token)
+	assert.True(t, valid)
+	assert.Equal(t, 7, payload.AuthId)
+	assert.Equal(t, 0, remote.calls)
+}
+
+func TestFallbackValidator_UnknownKeyAsksRemote(t *testing.T) {
+	remote := &stubValidator{}
+	validator, err := NewTokenValidator(ModeLocalFallback, newKeySet(t), remote)
+	require.NoError(t, err)
+
+	token, err := tkn.GenerateToken("next", []byte("fedcba9876543210fedcba9876543210"), 7, "author")
+	require.NoError(t, err)
+
+	valid, payload := validator.ValidateToken(context.Background(), token)
+	assert.True(t, valid)
+	assert.Equal(t, 99, payload.AuthId)
+	assert.Equal(t, 1, remote.calls)
+}
+
+func TestFallbackValidator_BadSignatureNotForwarded(t *testing.T) {
+	remote := &stubValidator{}
+	validator, err := NewTokenValidator(ModeLocalFallback, newKeySet(t), remote)
+	require.NoError(t, err)
+
+	token, err := tkn.GenerateToken("current", []byte("fedcba9876543210fedcba9876543210"), 7, "author")
+	require.NoError(t, err)
+
+	valid, _ := validator.ValidateToken(context.Background(), token)
+	assert.False(t, valid)
+	assert.Equal(t, 0, remote.calls)
+}
+
+func TestNewTokenValidator_Modes(t *testing.T) {
+	validator, err := NewTokenValidator(ModeLocal, newKeySet(t), nil)
+	require.NoError(t, err)
+	assert.IsType(t, &LocalValidator{}, validator)
+
+	_, err = NewTokenValidator(ModeLocal, nil, nil)
+	assert.Error(t, err)
+
+	_, err = NewTokenValidator(ModeRemote, nil, nil)
+	assert.Error(t, err)
+
+	_, err = NewTokenValidator("ldap", nil, &stubValidator{})
+	assert.Error(t, err)
+}
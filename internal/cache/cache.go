@@ -0,0 +1,100 @@
+// Package cache stores JSON values in Redis. Keys can be grouped in versioned
+// namespaces that are invalidated as a whole.
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"library-api-book/pkg/breaker"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Cache limits every Redis call to timeout. After repeated failures the
+// breaker skips Redis for its cooldown and calls fail at once with
+// breaker.ErrOpen, so a slow or down Redis costs callers little more than
+// the cache misses.
+type Cache struct {
+	client  *redis.Client
+	timeout time.Duration
+	breaker *breaker.Breaker
+}
+
+func New(client *redis.Client, timeout time.Duration, breaker *breaker.Breaker) *Cache {
+	return &Cache{client: client, timeout: timeout, breaker: breaker}
+}
+
+// Ping checks Redis through the breaker, for health checks. While the breaker
+// is open it fails without calling Redis; once the cooldown has passed, the
+// ping may be the probe that closes it again.
+func (cache *Cache) Ping(ctx context.Context) error {
+	return cache.do(ctx, func(ctx context.Context) error {
+		return cache.client.Ping(ctx).Err()
+	})
+}
+
+// do runs fn within the timeout unless the breaker is open. A miss is not a
+// failure, and neither is a call the caller cancelled or let run past its own
+// deadline, which says nothing about Redis.
+func (cache *Cache) do(ctx context.Context, fn func(ctx context.Context) error) error {
+	if err := cache.breaker.Allow(); err != nil {
+		return err
+	}
+	err := cache.run(ctx, fn)
+	switch {
+	case err != nil && ctx.Err() != nil:
+		cache.breaker.Release()
+	case err != nil && !errors.Is(err, redis.Nil):
+		cache.breaker.Failure()
+	default:
+		cache.breaker.Success()
+	}
+	return err
+}
+
+func (cache *Cache) run(ctx context.Context, fn func(ctx context.Context) error) error {
+	ctx, cancel := context.WithTimeout(ctx, cache.timeout)
+	defer cancel()
+	return fn(ctx)
+}
+
+// Get decodes the value cached under key into value and reports whether
+// there was one.
+func (cache *Cache) Get(ctx context.Context, key string, value interface{}) (bool, error) {
+	var data []byte
+	err := cache.do(ctx, func(ctx context.Context) (err error) {
+		data, err = cache.client.Get(ctx, key).Bytes()
+		return err
+	})
+	if errors.Is(err, redis.Nil) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if err := json.Unmarshal(data, value); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (cache *Cache) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return cache.do(ctx, func(ctx context.Context) error {
+		return cache.client.Set(ctx, key, data, ttl).Err()
+	})
+}
+
+func (cache *Cache) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	return cache.do(ctx, func(ctx context.Context) error {
+		return cache.client.Del(ctx, keys...).Err()
+	})
+}
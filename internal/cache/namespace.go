@@ -0,0 +1,99 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// bumpVersion increments the version in KEYS[1] and, when ARGV[2] is not 0,
+// expires it after ARGV[2] milliseconds. A missing version, say after a Redis
+// restart, starts over from ARGV[1] rather than 1 so it cannot resurrect keys
+// of an earlier version that are still cached.
+var bumpVersion = redis.NewScript(`
+local version
+if redis.call('EXISTS', KEYS[1]) == 1 then
+	version = redis.call('INCR', KEYS[1])
+else
+	redis.call('SET', KEYS[1], ARGV[1])
+	version = ARGV[1]
+end
+if tonumber(ARGV[2]) > 0 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return version
+`)
+
+// Namespace prefixes keys with a version number. Invalidate moves to a new
+// version, which makes every key built before unreachable; the old entries
+// are left to expire.
+type Namespace struct {
+	cache      *Cache
+	name       string
+	versionTTL time.Duration
+}
+
+// Namespace returns the namespace name. A versionTTL other than 0 lets the
+// version itself expire, for namespaces too many to keep forever; it must
+// outlive the keys cached in the namespace, and an expired version starts
+// over as after a Redis restart.
+func (cache *Cache) Namespace(name string, versionTTL time.Duration) *Namespace {
+	return &Namespace{cache: cache, name: name, versionTTL: versionTTL}
+}
+
+func (namespace *Namespace) versionKey() string {
+	return namespace.name + ":version"
+}
+
+// Key returns the key for parts in the current version. Build it before
+// loading the value to cache: if a write invalidates the namespace while the
+// value is loaded, the possibly stale value lands in the old version and is
+// never read.
+func (namespace *Namespace) Key(ctx context.Context, parts ...interface{}) (string, error) {
+	version, err := namespace.version(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	var key strings.Builder
+	key.WriteString(namespace.name)
+	key.WriteString(":v")
+	key.WriteString(strconv.FormatInt(version, 10))
+	for _, part := range parts {
+		key.WriteByte(':')
+		fmt.Fprint(&key, part)
+	}
+	return key.String(), nil
+}
+
+func (namespace *Namespace) version(ctx context.Context) (version int64, err error) {
+	err = namespace.cache.do(ctx, func(ctx context.Context) error {
+		client := namespace.cache.client
+		version, err = client.Get(ctx, namespace.versionKey()).Int64()
+		if !errors.Is(err, redis.Nil) {
+			return err
+		}
+
+		// First use: start from a value no earlier version can have used.
+		if err := client.SetNX(ctx, namespace.versionKey(), time.Now().UnixNano(), namespace.versionTTL).Err(); err != nil {
+			return err
+		}
+		version, err = client.Get(ctx, namespace.versionKey()).Int64()
+		return err
+	})
+	return version, err
+}
+
+// Invalidate moves the namespace to a new version. It skips the breaker: an
+// invalidation dropped while the breaker is open would leave entries stale
+// once Redis is back.
+func (namespace *Namespace) Invalidate(ctx context.Context) error {
+	return namespace.cache.run(ctx, func(ctx context.Context) error {
+		return bumpVersion.Run(ctx, namespace.cache.client, []string{namespace.versionKey()}, time.Now().UnixNano(), namespace.versionTTL.Milliseconds()).Err()
+	})
+}
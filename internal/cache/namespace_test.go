@@ -0,0 +1,216 @@
+package cache
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"library-api-book/pkg/breaker"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestCache(t *testing.T) (*Cache, *miniredis.Miniredis) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return New(client, time.Second, breaker.New(3, time.Minute)), server
+}
+
+func TestNamespace_InvalidateHidesOldKeys(t *testing.T) {
+	cache, _ := newTestCache(t)
+	namespace := cache.Namespace("books:list", 0)
+	ctx := context.Background()
+
+	key, err := namespace.Key(ctx, 1, 10, "go")
+	require.NoError(t, err)
+	require.NoError(t, cache.Set(ctx, key, []string{"cached"}, 0))
+
+	again, err := namespace.Key(ctx, 1, 10, "go")
+	require.NoError(t, err)
+	assert.Equal(t, key, again)
+
+	require.NoError(t, namespace.Invalidate(ctx))
+
+	fresh, err := namespace.Key(ctx, 1, 10, "go")
+	require.NoError(t, err)
+	assert.NotEqual(t, key, fresh)
+
+	var value []string
+	found, err := cache.Get(ctx, fresh, &value)
+	require.NoError(t, err)
+	assert.False(t, found)
+}
+
+func TestNamespace_LoadRacingInvalidation(t *testing.T) {
+	cache, _ := newTestCache(t)
+	namespace := cache.Namespace("books:list", 0)
+	ctx := context.Background()
+
+	// A reader resolves its key, a writer commits and invalidates, then the
+	// reader stores what it loaded before the commit.
+	key, err := namespace.Key(ctx, 1)
+	require.NoError(t, err)
+	require.NoError(t, namespace.Invalidate(ctx))
+	require.NoError(t, cache.Set(ctx, key, "stale", 0))
+
+	next, err := namespace.Key(ctx, 1)
+	require.NoError(t, err)
+	var value string
+	found, err := cache.Get(ctx, next, &value)
+	require.NoError(t, err)
+	assert.False(t, found)
+}
+
+func TestNamespace_LostVersionDoesNotResurrectKeys(t *testing.T) {
+	cache, server := newTestCache(t)
+	namespace := cache.Namespace("books:list", 0)
+	ctx := context.Background()
+
+	key, err := namespace.Key(ctx, 1)
+	require.NoError(t, err)
+	require.NoError(t, cache.Set(ctx, key, "old", 0))
+
+	server.Del("books:list:version")
+	require.NoError(t, namespace.Invalidate(ctx))
+
+	next, err := namespace.Key(ctx, 1)
+	require.NoError(t, err)
+	assert.NotEqual(t, key, next)
+}
+
+func TestNamespace_VersionTTL(t *testing.T) {
+	cache, server := newTestCache(t)
+	namespace := cache.Namespace("books:detail:1", time.Hour)
+	ctx := context.Background()
+
+	key, err := namespace.Key(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, time.Hour, server.TTL("books:detail:1:version"))
+
+	server.FastForward(time.Hour)
+	require.NoError(t, namespace.Invalidate(ctx))
+	assert.Equal(t, time.Hour, server.TTL("books:detail:1:version"))
+
+	next, err := namespace.Key(ctx)
+	require.NoError(t, err)
+	assert.NotEqual(t, key, next)
+}
+
+func TestCache_GetMissAndDelete(t *testing.T) {
+	cache, _ := newTestCache(t)
+	ctx := context.Background()
+
+	var value int
+	found, err := cache.Get(ctx, "missing", &value)
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	require.NoError(t, cache.Set(ctx, "answer", 42, 0))
+	found, err = cache.Get(ctx, "answer", &value)
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, 42, value)
+
+	require.NoError(t, cache.Delete(ctx, "answer"))
+	found, _ = cache.Get(ctx, "answer", &value)
+	assert.False(t, found)
+}
+
+func TestCache_BreakerSkipsFailingRedis(t *testing.T) {
+	cache, server := newTestCache(t)
+	ctx := context.Background()
+
+	server.SetError("LOADING Redis is loading the dataset in memory")
+	var value int
+	for i := 0; i < 3; i++ {
+		_, err := cache.Get(ctx, "answer", &value)
+		require.Error(t, err)
+		assert.NotErrorIs(t, err, breaker.ErrOpen)
+	}
+
+	server.SetError("")
+	_, err := cache.Get(ctx, "answer", &value)
+	assert.ErrorIs(t, err, breaker.ErrOpen)
+	assert.ErrorIs(t, cache.Ping(ctx), breaker.ErrOpen)
+}
+
+func TestCache_MissIsNotAFailure(t *testing.T) {
+	cache, _ := newTestCache(t)
+	ctx := context.Background()
+
+	var value int
+	for i := 0; i < 5; i++ {
+		found, err := cache.Get(ctx, "missing", &value)
+		require.NoError(t, err)
+		assert.False(t, found)
+	}
+	assert.NoError(t, cache.Ping(ctx))
+}
+
+func TestCache_CallerCancellationIsNotAFailure(t *testing.T) {
+	cache, _ := newTestCache(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var value int
+	for i := 0; i < 5; i++ {
+		_, err := cache.Get(ctx, "answer", &value)
+		require.ErrorIs(t, err, context.Canceled)
+	}
+	assert.Equal(t, breaker.StateClosed, cache.breaker.State())
+	assert.NoError(t, cache.Ping(context.Background()))
+}
+
+func TestCache_TimesOutSlowRedis(t *testing.T) {
+	// Accepts connections and never answers.
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			t.Cleanup(func() { conn.Close() })
+		}
+	}()
+
+	client := redis.NewClient(&redis.Options{Addr: listener.Addr().String(), MaxRetries: -1, ContextTimeoutEnabled: true})
+	t.Cleanup(func() { client.Close() })
+	cache := New(client, 20*time.Millisecond, breaker.New(3, time.Minute))
+
+	start := time.Now()
+	var value int
+	_, err = cache.Get(context.Background(), "answer", &value)
+
+	assert.Error(t, err)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestNamespace_InvalidateSkipsOpenBreaker(t *testing.T) {
+	cache, server := newTestCache(t)
+	namespace := cache.Namespace("books:list", 0)
+	ctx := context.Background()
+
+	key, err := namespace.Key(ctx)
+	require.NoError(t, err)
+
+	server.SetError("LOADING Redis is loading the dataset in memory")
+	for i := 0; i < 3; i++ {
+		_ = cache.Ping(ctx)
+	}
+	server.SetError("")
+	_, err = namespace.Key(ctx)
+	require.ErrorIs(t, err, breaker.ErrOpen)
+
+	require.NoError(t, namespace.Invalidate(ctx))
+	version, err := server.Get("books:list:version")
+	require.NoError(t, err)
+	assert.NotEqual(t, key, "books:list:v"+version)
+}
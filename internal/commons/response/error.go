@@ -43,6 +43,48 @@ var (
 		Status:     false,
 		Message:    "BAD REQUEST ERROR",
 	}
+	conflictError = CustomError{
+		Code:       "ERR0006",
+		StatusCode: http.StatusConflict,
+		Status:     false,
+		Message:    "CONFLICT",
+	}
+	preconditionFailedError = CustomError{
+		Code:       "ERR0007",
+		StatusCode: http.StatusPreconditionFailed,
+		Status:     false,
+		Message:    "PRECONDITION FAILED",
+	}
+	preconditionRequiredError = CustomError{
+		Code:       "ERR0008",
+		StatusCode: http.StatusPreconditionRequired,
+		Status:     false,
+		Message:    "PRECONDITION REQUIRED",
+	}
+	forbiddenError = CustomError{
+		Code:       "ERR0009",
+		StatusCode: http.StatusForbidden,
+		Status:     false,
+		Message:    "FORBIDDEN",
+	}
+	serviceUnavailableError = CustomError{
+		Code:       "ERR0010",
+		StatusCode: http.StatusServiceUnavailable,
+		Status:     false,
+		Message:    "SERVICE UNAVAILABLE",
+	}
+	tooManyRequestsError = CustomError{
+		Code:       "ERR0011",
+		StatusCode: http.StatusTooManyRequests,
+		Status:     false,
+		Message:    "TOO MANY REQUESTS",
+	}
+	unprocessableEntityError = CustomError{
+		Code:       "ERR0012",
+		StatusCode: http.StatusUnprocessableEntity,
+		Status:     false,
+		Message:    "UNPROCESSABLE ENTITY",
+	}
 )
 
 func GeneralError(message ...string) *CustomError {
@@ -127,3 +169,69 @@ func BadRequestErrorWithAdditionalInfo(info interface{}, message ...string) *Cus
 	}
 	return &err
 }
+
+func ConflictError(message ...string) *CustomError {
+	err := conflictError
+	if len(message) != 0 {
+		err.Message = message[0]
+	}
+	return &err
+}
+
+func ConflictErrorWithAdditionalInfo(info interface{}, message ...string) *CustomError {
+	err := conflictError
+	err.AdditionalInfo = info
+	if len(message) != 0 {
+		err.Message = message[0]
+	}
+	return &err
+}
+
+func PreconditionFailedError(message ...string) *CustomError {
+	err := preconditionFailedError
+	if len(message) != 0 {
+		err.Message = message[0]
+	}
+	return &err
+}
+
+func PreconditionRequiredError(message ...string) *CustomError {
+	err := preconditionRequiredError
+	if len(message) != 0 {
+		err.Message = message[0]
+	}
+	return &err
+}
+
+func ForbiddenError(message ...string) *CustomError {
+	err := forbiddenError
+	if len(message) != 0 {
+		err.Message = message[0]
+	}
+	return &err
+}
+
+func ServiceUnavailableError(message ...string) *CustomError {
+	err := serviceUnavailableError
+	if len(message) != 0 {
+		err.Message = message[0]
+	}
+	return &err
+}
+
+func TooManyRequestsError(message ...string) *CustomError {
+	err := tooManyRequestsError
+	if len(message) != 0 {
+		err.Message = message[0]
+	}
+	return &err
+}
+
+func UnprocessableEntityErrorWithAdditionalInfo(info interface{}, message ...string) *CustomError {
+	err := unprocessableEntityError
+	err.AdditionalInfo = info
+	if len(message) != 0 {
+		err.Message = message[0]
+	}
+	return &err
+}
@@ -1,6 +1,8 @@
 package config
 
 import (
+	"time"
+
 	"github.com/spf13/viper"
 )
 
@@ -14,6 +16,68 @@ type Config struct {
 	GRPCPort       string `mapstructure:"GRPC_PORT"`
 	UserGRPC       string `mapstructure:"USER_GRCP"`
 	Environtment   string `mapstructure:"ENVIRONTMENT"`
+
+	OAIRepositoryName       string `mapstructure:"OAI_REPOSITORY_NAME"`
+	OAIRepositoryIdentifier string `mapstructure:"OAI_REPOSITORY_IDENTIFIER"`
+	OAIAdminEmail           string `mapstructure:"OAI_ADMIN_EMAIL"`
+	OAITrustedProxies       string `mapstructure:"OAI_TRUSTED_PROXIES"`
+
+	StorageDriver    string `mapstructure:"STORAGE_DRIVER"`
+	StorageLocalPath string `mapstructure:"STORAGE_LOCAL_PATH"`
+	CoverMaxSize     int64  `mapstructure:"COVER_MAX_SIZE"`
+
+	PermissionPolicyPath string `mapstructure:"PERMISSION_POLICY_PATH"`
+
+	AuthMode       string        `mapstructure:"AUTH_MODE"`
+	AuthJWTSecret  string        `mapstructure:"AUTH_JWT_SECRET"`
+	AuthJWTKeyFile string        `mapstructure:"AUTH_JWT_KEY_FILE"`
+	AuthCacheTTL   time.Duration `mapstructure:"AUTH_CACHE_TTL"`
+	AuthCacheRedis bool          `mapstructure:"AUTH_CACHE_REDIS"`
+
+	GRPCClientTimeout          time.Duration `mapstructure:"GRPC_CLIENT_TIMEOUT"`
+	GRPCClientMaxAttempts      int           `mapstructure:"GRPC_CLIENT_MAX_ATTEMPTS"`
+	GRPCClientBreakerThreshold int           `mapstructure:"GRPC_CLIENT_BREAKER_THRESHOLD"`
+	GRPCClientBreakerCooldown  time.Duration `mapstructure:"GRPC_CLIENT_BREAKER_COOLDOWN"`
+
+	GRPCTLSCertFile       string        `mapstructure:"GRPC_TLS_CERT_FILE"`
+	GRPCTLSKeyFile        string        `mapstructure:"GRPC_TLS_KEY_FILE"`
+	GRPCTLSClientCAFile   string        `mapstructure:"GRPC_TLS_CLIENT_CA_FILE"`
+	GRPCTLSRequireClient  bool          `mapstructure:"GRPC_TLS_REQUIRE_CLIENT_CERT"`
+	GRPCClientTLS         bool          `mapstructure:"GRPC_CLIENT_TLS"`
+	GRPCClientCAFile      string        `mapstructure:"GRPC_CLIENT_CA_FILE"`
+	GRPCClientCertFile    string        `mapstructure:"GRPC_CLIENT_CERT_FILE"`
+	GRPCClientKeyFile     string        `mapstructure:"GRPC_CLIENT_KEY_FILE"`
+	GRPCClientServerName  string        `mapstructure:"GRPC_CLIENT_SERVER_NAME"`
+	GRPCTLSReloadInterval time.Duration `mapstructure:"GRPC_TLS_RELOAD_INTERVAL"`
+
+	GRPCServiceIdentitiesFile string `mapstructure:"GRPC_SERVICE_IDENTITIES_FILE"`
+
+	GRPCHealthCheckInterval time.Duration `mapstructure:"GRPC_HEALTH_CHECK_INTERVAL"`
+	GRPCHealthCheckTimeout  time.Duration `mapstructure:"GRPC_HEALTH_CHECK_TIMEOUT"`
+	GRPCReflection          bool          `mapstructure:"GRPC_REFLECTION"`
+
+	ServeMode string `mapstructure:"SERVE_MODE"`
+
+	RateLimitEnabled    bool          `mapstructure:"RATE_LIMIT_ENABLED"`
+	RateLimitPolicyPath string        `mapstructure:"RATE_LIMIT_POLICY_PATH"`
+	RateLimitTimeout    time.Duration `mapstructure:"RATE_LIMIT_TIMEOUT"`
+
+	RedisAddr          string        `mapstructure:"REDIS_ADDR"`
+	RedisUsername      string        `mapstructure:"REDIS_USERNAME"`
+	RedisPassword      string        `mapstructure:"REDIS_PASSWORD"`
+	RedisDB            int           `mapstructure:"REDIS_DB"`
+	RedisTLS           bool          `mapstructure:"REDIS_TLS"`
+	RedisTLSCAFile     string        `mapstructure:"REDIS_TLS_CA_FILE"`
+	RedisTLSCertFile   string        `mapstructure:"REDIS_TLS_CERT_FILE"`
+	RedisTLSKeyFile    string        `mapstructure:"REDIS_TLS_KEY_FILE"`
+	RedisTLSServerName string        `mapstructure:"REDIS_TLS_SERVER_NAME"`
+	RedisDialTimeout   time.Duration `mapstructure:"REDIS_DIAL_TIMEOUT"`
+	RedisReadTimeout   time.Duration `mapstructure:"REDIS_READ_TIMEOUT"`
+	RedisWriteTimeout  time.Duration `mapstructure:"REDIS_WRITE_TIMEOUT"`
+
+	CacheTimeout          time.Duration `mapstructure:"CACHE_TIMEOUT"`
+	CacheBreakerThreshold int           `mapstructure:"CACHE_BREAKER_THRESHOLD"`
+	CacheBreakerCooldown  time.Duration `mapstructure:"CACHE_BREAKER_COOLDOWN"`
 }
 
 var ENV *Config
@@ -25,6 +89,57 @@ func LoadConfig() {
 	fang.SetConfigName(".env")
 	fang.SetConfigType("env")
 
+	fang.SetDefault("OAI_REPOSITORY_NAME", "Library API Book")
+	fang.SetDefault("OAI_REPOSITORY_IDENTIFIER", "library-api-book")
+	fang.SetDefault("OAI_ADMIN_EMAIL", "admin@library.local")
+	fang.SetDefault("OAI_TRUSTED_PROXIES", "")
+	fang.SetDefault("STORAGE_DRIVER", "local")
+	fang.SetDefault("STORAGE_LOCAL_PATH", "./var/storage")
+	fang.SetDefault("COVER_MAX_SIZE", 5<<20)
+	fang.SetDefault("PERMISSION_POLICY_PATH", "./policy.yaml")
+	fang.SetDefault("AUTH_MODE", "remote")
+	fang.SetDefault("AUTH_JWT_SECRET", "")
+	fang.SetDefault("AUTH_JWT_KEY_FILE", "")
+	fang.SetDefault("AUTH_CACHE_TTL", 30*time.Second)
+	fang.SetDefault("AUTH_CACHE_REDIS", false)
+	fang.SetDefault("GRPC_CLIENT_TIMEOUT", 2*time.Second)
+	fang.SetDefault("GRPC_CLIENT_MAX_ATTEMPTS", 3)
+	fang.SetDefault("GRPC_CLIENT_BREAKER_THRESHOLD", 5)
+	fang.SetDefault("GRPC_CLIENT_BREAKER_COOLDOWN", 10*time.Second)
+	fang.SetDefault("GRPC_TLS_CERT_FILE", "")
+	fang.SetDefault("GRPC_TLS_KEY_FILE", "")
+	fang.SetDefault("GRPC_TLS_CLIENT_CA_FILE", "")
+	fang.SetDefault("GRPC_TLS_REQUIRE_CLIENT_CERT", false)
+	fang.SetDefault("GRPC_CLIENT_TLS", false)
+	fang.SetDefault("GRPC_CLIENT_CA_FILE", "")
+	fang.SetDefault("GRPC_CLIENT_CERT_FILE", "")
+	fang.SetDefault("GRPC_CLIENT_KEY_FILE", "")
+	fang.SetDefault("GRPC_CLIENT_SERVER_NAME", "")
+	fang.SetDefault("GRPC_TLS_RELOAD_INTERVAL", time.Minute)
+	fang.SetDefault("GRPC_SERVICE_IDENTITIES_FILE", "")
+	fang.SetDefault("GRPC_HEALTH_CHECK_INTERVAL", 10*time.Second)
+	fang.SetDefault("GRPC_HEALTH_CHECK_TIMEOUT", 2*time.Second)
+	fang.SetDefault("GRPC_REFLECTION", false)
+	fang.SetDefault("SERVE_MODE", "split")
+	fang.SetDefault("RATE_LIMIT_ENABLED", true)
+	fang.SetDefault("RATE_LIMIT_POLICY_PATH", "./ratelimit.yaml")
+	fang.SetDefault("RATE_LIMIT_TIMEOUT", 50*time.Millisecond)
+	fang.SetDefault("REDIS_ADDR", "localhost:6379")
+	fang.SetDefault("REDIS_USERNAME", "")
+	fang.SetDefault("REDIS_PASSWORD", "")
+	fang.SetDefault("REDIS_DB", 0)
+	fang.SetDefault("REDIS_TLS", false)
+	fang.SetDefault("REDIS_TLS_CA_FILE", "")
+	fang.SetDefault("REDIS_TLS_CERT_FILE", "")
+	fang.SetDefault("REDIS_TLS_KEY_FILE", "")
+	fang.SetDefault("REDIS_TLS_SERVER_NAME", "")
+	fang.SetDefault("REDIS_DIAL_TIMEOUT", 2*time.Second)
+	fang.SetDefault("REDIS_READ_TIMEOUT", time.Second)
+	fang.SetDefault("REDIS_WRITE_TIMEOUT", time.Second)
+	fang.SetDefault("CACHE_TIMEOUT", 100*time.Millisecond)
+	fang.SetDefault("CACHE_BREAKER_THRESHOLD", 5)
+	fang.SetDefault("CACHE_BREAKER_COOLDOWN", 10*time.Second)
+
 	err := fang.ReadInConfig()
 	if err != nil {
 		panic(err)
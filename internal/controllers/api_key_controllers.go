@@ -0,0 +1,95 @@
+package controllers
+
+import (
+	"library-api-book/internal/commons/response"
+	"library-api-book/internal/params"
+	"library-api-book/internal/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type APIKeyController interface {
+	CreateAPIKey(ctx *gin.Context)
+	GetAPIKeys(ctx *gin.Context)
+	RevokeAPIKey(ctx *gin.Context)
+}
+
+type APIKeyControllerImpl struct {
+	APIKeyService services.APIKeyService
+}
+
+func NewAPIKeyController(apiKeyService services.APIKeyService) APIKeyController {
+	return &APIKeyControllerImpl{
+		APIKeyService: apiKeyService,
+	}
+}
+
+func (controller *APIKeyControllerImpl) CreateAPIKey(ctx *gin.Context) {
+	var req = new(params.APIKeyRequest)
+
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status":  false,
+			"message": err,
+		})
+		return
+	}
+
+	var createdBy *uint64
+	if authID := ctx.GetInt("authId"); authID > 0 {
+		id := uint64(authID)
+		createdBy = &id
+	}
+
+	result, custErr := controller.APIKeyService.CreateAPIKey(ctx, createdBy, req)
+	if custErr != nil {
+		ctx.AbortWithStatusJSON(custErr.StatusCode, custErr)
+		return
+	}
+
+	resp := response.CreatedSuccessWithPayload(result)
+	ctx.JSON(resp.StatusCode, resp)
+}
+
+func (controller *APIKeyControllerImpl) GetAPIKeys(ctx *gin.Context) {
+	pagination := parsePagination(ctx)
+
+	result, custErr := controller.APIKeyService.GetAPIKeys(ctx, &pagination)
+	if custErr != nil {
+		ctx.AbortWithStatusJSON(custErr.StatusCode, custErr)
+		return
+	}
+
+	type Response struct {
+		APIKeys    interface{} `json:"api_keys"`
+		Pagination interface{} `json:"pagination"`
+	}
+
+	var responses Response
+	responses.APIKeys = result
+	responses.Pagination = pagination
+
+	resp := response.GeneralSuccessCustomMessageAndPayload("Success get data api keys", responses)
+	ctx.JSON(resp.StatusCode, resp)
+}
+
+func (controller *APIKeyControllerImpl) RevokeAPIKey(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		resp := response.BadRequestError("id must be a number")
+		ctx.AbortWithStatusJSON(resp.StatusCode, resp)
+		return
+	}
+
+	result, custErr := controller.APIKeyService.RevokeAPIKey(ctx, id)
+	if custErr != nil {
+		ctx.AbortWithStatusJSON(custErr.StatusCode, custErr)
+		return
+	}
+
+	resp := response.GeneralSuccessCustomMessageAndPayload("Success revoke api key", result)
+	ctx.JSON(resp.StatusCode, resp)
+}
@@ -0,0 +1,136 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"library-api-book/internal/commons/response"
+	"library-api-book/internal/models"
+	"library-api-book/internal/params"
+	"library-api-book/internal/services"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AuditController interface {
+	GetAuditLogs(ctx *gin.Context)
+	ExportAuditLogs(ctx *gin.Context)
+}
+
+type AuditControllerImpl struct {
+	AuditService services.AuditService
+}
+
+func NewAuditController(auditService services.AuditService) AuditController {
+	return &AuditControllerImpl{
+		AuditService: auditService,
+	}
+}
+
+func (controller *AuditControllerImpl) GetAuditLogs(ctx *gin.Context) {
+	filter, err := parseAuditLogFilter(ctx)
+	if err != nil {
+		resp := response.BadRequestError(err.Error())
+		ctx.AbortWithStatusJSON(resp.StatusCode, resp)
+		return
+	}
+
+	pagination := parsePagination(ctx)
+
+	result, custErr := controller.AuditService.GetAuditLogs(ctx, filter, &pagination)
+	if custErr != nil {
+		ctx.AbortWithStatusJSON(custErr.StatusCode, custErr)
+		return
+	}
+
+	type Response struct {
+		AuditLogs  interface{} `json:"audit_logs"`
+		Pagination interface{} `json:"pagination"`
+	}
+
+	var responses Response
+	responses.AuditLogs = result
+	responses.Pagination = pagination
+
+	resp := response.GeneralSuccessCustomMessageAndPayload("Success get data audit logs", responses)
+	ctx.JSON(resp.StatusCode, resp)
+}
+
+// ExportAuditLogs streams the matching entries as JSON Lines, one object per
+// line, oldest first.
+func (controller *AuditControllerImpl) ExportAuditLogs(ctx *gin.Context) {
+	filter, err := parseAuditLogFilter(ctx)
+	if err != nil {
+		resp := response.BadRequestError(err.Error())
+		ctx.AbortWithStatusJSON(resp.StatusCode, resp)
+		return
+	}
+
+	filename := "audit-logs-" + time.Now().UTC().Format("20060102T150405Z") + ".jsonl"
+	start := func() {
+		if ctx.Writer.Written() {
+			return
+		}
+		ctx.Header("Content-Type", "application/x-ndjson")
+		ctx.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+		ctx.Writer.WriteHeaderNow()
+	}
+
+	encoder := json.NewEncoder(ctx.Writer)
+	custErr := controller.AuditService.ExportAuditLogs(ctx, filter, func(log *params.AuditLogResponse) error {
+		start()
+		return encoder.Encode(log)
+	})
+	if custErr != nil {
+		// Once lines have been sent the status can no longer change; the
+		// truncated body is all the client will see.
+		if !ctx.Writer.Written() {
+			ctx.AbortWithStatusJSON(custErr.StatusCode, custErr)
+		}
+		return
+	}
+
+	start()
+}
+
+func parseAuditLogFilter(ctx *gin.Context) (*models.AuditLogFilter, error) {
+	filter := &models.AuditLogFilter{
+		Action:  ctx.Query("action"),
+		Outcome: ctx.Query("outcome"),
+	}
+
+	if actorID := ctx.Query("actor_id"); actorID != "" {
+		id, err := strconv.ParseUint(actorID, 10, 64)
+		if err != nil {
+			return nil, errors.New("actor_id must be a number")
+		}
+		filter.ActorID = &id
+	}
+
+	switch filter.Outcome {
+	case "", models.AuditOutcomeSuccess, models.AuditOutcomeFailure, models.AuditOutcomeDenied:
+	default:
+		return nil, errors.New("outcome must be one of success, failure or denied")
+	}
+
+	for _, bound := range []struct {
+		name   string
+		target **time.Time
+	}{
+		{name: "from", target: &filter.From},
+		{name: "until", target: &filter.Until},
+	} {
+		value := ctx.Query(bound.name)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return nil, errors.New(bound.name + " must be an RFC 3339 timestamp")
+		}
+		*bound.target = &t
+	}
+
+	return filter, nil
+}
@@ -1,23 +1,33 @@
 package controllers
 
 import (
+	"errors"
+	"io"
 	"library-api-book/internal/commons/response"
 	"library-api-book/internal/models"
 	"library-api-book/internal/params"
 	"library-api-book/internal/services"
+	"library-api-book/pkg/mergepatch"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
 
+const maxPatchSize = 64 << 10
+
 type BookController interface {
 	CreateBook(ctx *gin.Context)
 	GetDetailBook(ctx *gin.Context)
 	UpdateBook(ctx *gin.Context)
+	PatchBook(ctx *gin.Context)
 	DeleteBook(ctx *gin.Context)
 	GetAllBooks(ctx *gin.Context)
 	GetRecommendationBook(ctx *gin.Context)
+	RestoreBook(ctx *gin.Context)
+	GetDeletedBooks(ctx *gin.Context)
+	PurgeBook(ctx *gin.Context)
 }
 
 type BookControllerImpl struct {
@@ -42,7 +52,7 @@ func (controller *BookControllerImpl) CreateBook(ctx *gin.Context) {
 		return
 	}
 
-	custErr := controller.BookService.CreateBook(ctx, req)
+	_, custErr := controller.BookService.CreateBook(ctx, req)
 	if custErr != nil {
 		ctx.AbortWithStatusJSON(custErr.StatusCode, custErr)
 		return
@@ -69,6 +79,7 @@ func (controller *BookControllerImpl) GetDetailBook(ctx *gin.Context) {
 		return
 	}
 
+	ctx.Header("ETag", bookETag(result.Version))
 	resp := response.GeneralSuccessCustomMessageAndPayload("Success get detail book", result)
 	ctx.JSON(resp.StatusCode, resp)
 }
@@ -87,17 +98,31 @@ func (controller *BookControllerImpl) UpdateBook(ctx *gin.Context) {
 
 	id, _ := strconv.Atoi(ctx.Param("id"))
 
-	custErr := controller.BookService.UpdateBook(ctx, uint64(id), req)
+	ifMatch := ctx.GetHeader("If-Match")
+	if ifMatch == "" {
+		resp := response.PreconditionRequiredError("If-Match header with the book ETag is required")
+		ctx.AbortWithStatusJSON(resp.StatusCode, resp)
+		return
+	}
+	versions, ok := parseIfMatch(ifMatch)
+	if !ok {
+		resp := response.PreconditionFailedError("If-Match does not match the current book version")
+		ctx.AbortWithStatusJSON(resp.StatusCode, resp)
+		return
+	}
+
+	result, custErr := controller.BookService.UpdateBook(ctx, uint64(id), versions, req)
 	if custErr != nil {
 		ctx.AbortWithStatusJSON(custErr.StatusCode, custErr)
 		return
 	}
 
-	resp := response.GeneralSuccessCustomMessageAndPayload("Success update data book", nil)
+	ctx.Header("ETag", bookETag(result.Version))
+	resp := response.GeneralSuccessCustomMessageAndPayload("Success update data book", result)
 	ctx.JSON(resp.StatusCode, resp)
 }
 
-func (controller *BookControllerImpl) DeleteBook(ctx *gin.Context) {
+func (controller *BookControllerImpl) PatchBook(ctx *gin.Context) {
 	id, err := strconv.Atoi(ctx.Param("id"))
 	if err != nil {
 		ctx.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
@@ -107,45 +132,75 @@ func (controller *BookControllerImpl) DeleteBook(ctx *gin.Context) {
 		return
 	}
 
-	custErr := controller.BookService.DeleteBook(ctx, uint64(id))
+	if contentType := ctx.ContentType(); contentType != mergepatch.ContentType && contentType != "application/json" {
+		ctx.AbortWithStatusJSON(http.StatusUnsupportedMediaType, gin.H{
+			"status":  false,
+			"message": "Content-Type must be " + mergepatch.ContentType,
+		})
+		return
+	}
+
+	// If-Match is optional for PATCH since only the fields present are written,
+	// but a stale ETag is still rejected.
+	var versions []int32
+	if ifMatch := ctx.GetHeader("If-Match"); ifMatch != "" {
+		var ok bool
+		versions, ok = parseIfMatch(ifMatch)
+		if !ok {
+			resp := response.PreconditionFailedError("If-Match does not match the current book version")
+			ctx.AbortWithStatusJSON(resp.StatusCode, resp)
+			return
+		}
+	}
+
+	patch, err := io.ReadAll(http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxPatchSize))
+	if err != nil {
+		resp := response.BadRequestError("Failed to read merge patch: " + err.Error())
+		ctx.AbortWithStatusJSON(resp.StatusCode, resp)
+		return
+	}
+
+	result, custErr := controller.BookService.PatchBook(ctx, uint64(id), versions, patch)
 	if custErr != nil {
 		ctx.AbortWithStatusJSON(custErr.StatusCode, custErr)
 		return
 	}
 
-	resp := response.GeneralSuccessCustomMessageAndPayload("Success delete data book", nil)
+	ctx.Header("ETag", bookETag(result.Version))
+	resp := response.GeneralSuccessCustomMessageAndPayload("Success patch data book", result)
 	ctx.JSON(resp.StatusCode, resp)
 }
 
-func (controller *BookControllerImpl) GetAllBooks(ctx *gin.Context) {
-	page := ctx.Query("page")
-	limit := ctx.Query("limit")
-	search := ctx.Query("search")
-
-	pageNum := 1
-	limitSize := 5
-
-	if page != "" {
-		parsedPage, err := strconv.Atoi(page)
-		if err == nil && parsedPage > 0 {
-			pageNum = parsedPage
-		}
+func (controller *BookControllerImpl) DeleteBook(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status":  false,
+			"message": err,
+		})
+		return
 	}
 
-	if limit != "" {
-		parsedLimit, err := strconv.Atoi(limit)
-		if err == nil && parsedLimit > 0 {
-			limitSize = parsedLimit
-		}
+	custErr := controller.BookService.DeleteBook(ctx, uint64(id))
+	if custErr != nil {
+		ctx.AbortWithStatusJSON(custErr.StatusCode, custErr)
+		return
 	}
 
-	pagination := models.Pagination{
-		Page:     pageNum,
-		Offset:   (pageNum - 1) * limitSize,
-		PageSize: limitSize,
+	resp := response.GeneralSuccessCustomMessageAndPayload("Success delete data book", nil)
+	ctx.JSON(resp.StatusCode, resp)
+}
+
+func (controller *BookControllerImpl) GetAllBooks(ctx *gin.Context) {
+	filter, err := parseBookFilter(ctx)
+	if err != nil {
+		custErr := response.BadRequestError(err.Error())
+		ctx.AbortWithStatusJSON(custErr.StatusCode, custErr)
+		return
 	}
+	pagination := parsePagination(ctx)
 
-	result, custErr := controller.BookService.GetAllBooks(ctx, &pagination, search)
+	result, custErr := controller.BookService.GetAllBooks(ctx, &pagination, filter)
 
 	if custErr != nil {
 		ctx.AbortWithStatusJSON(custErr.StatusCode, custErr)
@@ -178,3 +233,142 @@ func (controller *BookControllerImpl) GetRecommendationBook(ctx *gin.Context) {
 	resp := response.GeneralSuccessCustomMessageAndPayload("Success get data recomendation books", result)
 	ctx.JSON(resp.StatusCode, resp)
 }
+
+func (controller *BookControllerImpl) RestoreBook(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status":  false,
+			"message": err,
+		})
+		return
+	}
+
+	custErr := controller.BookService.RestoreBook(ctx, uint64(id))
+	if custErr != nil {
+		ctx.AbortWithStatusJSON(custErr.StatusCode, custErr)
+		return
+	}
+
+	resp := response.GeneralSuccessCustomMessageAndPayload("Success restore data book", nil)
+	ctx.JSON(resp.StatusCode, resp)
+}
+
+func (controller *BookControllerImpl) GetDeletedBooks(ctx *gin.Context) {
+	pagination := parsePagination(ctx)
+
+	result, custErr := controller.BookService.GetDeletedBooks(ctx, &pagination)
+	if custErr != nil {
+		ctx.AbortWithStatusJSON(custErr.StatusCode, custErr)
+		return
+	}
+
+	type Response struct {
+		Books      interface{} `json:"books"`
+		Pagination interface{} `json:"pagination"`
+	}
+
+	var responses Response
+	responses.Books = result
+	responses.Pagination = pagination
+
+	resp := response.GeneralSuccessCustomMessageAndPayload("Success get data deleted books", responses)
+	ctx.JSON(resp.StatusCode, resp)
+}
+
+func (controller *BookControllerImpl) PurgeBook(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status":  false,
+			"message": err,
+		})
+		return
+	}
+
+	custErr := controller.BookService.PurgeBook(ctx, uint64(id))
+	if custErr != nil {
+		ctx.AbortWithStatusJSON(custErr.StatusCode, custErr)
+		return
+	}
+
+	resp := response.GeneralSuccessCustomMessageAndPayload("Success purge data book", nil)
+	ctx.JSON(resp.StatusCode, resp)
+}
+
+func parseBookFilter(ctx *gin.Context) (*models.BookFilter, error) {
+	filter := &models.BookFilter{Search: ctx.Query("search")}
+
+	if authorID := ctx.Query("author_id"); authorID != "" {
+		id, err := strconv.ParseUint(authorID, 10, 64)
+		if err != nil {
+			return nil, errors.New("author_id must be a number")
+		}
+		filter.AuthorID = id
+	}
+
+	if inStock := ctx.Query("in_stock"); inStock != "" {
+		value, err := strconv.ParseBool(inStock)
+		if err != nil {
+			return nil, errors.New("in_stock must be true or false")
+		}
+		filter.InStock = value
+	}
+
+	return filter, nil
+}
+
+func parsePagination(ctx *gin.Context) models.Pagination {
+	page := ctx.Query("page")
+	limit := ctx.Query("limit")
+
+	pageNum := 1
+	limitSize := 5
+
+	if page != "" {
+		parsedPage, err := strconv.Atoi(page)
+		if err == nil && parsedPage > 0 {
+			pageNum = parsedPage
+		}
+	}
+
+	if limit != "" {
+		parsedLimit, err := strconv.Atoi(limit)
+		if err == nil && parsedLimit > 0 {
+			limitSize = parsedLimit
+		}
+	}
+
+	return models.Pagination{
+		Page:     pageNum,
+		Offset:   (pageNum - 1) * limitSize,
+		PageSize: limitSize,
+	}
+}
+
+func bookETag(version int32) string {
+	return `"` + strconv.Itoa(int(version)) + `"`
+}
+
+// parseIfMatch returns every version listed in an If-Match header, or none
+// for "*". The header matches when the book is at any of them (RFC 9110).
+// Weak validators never match under If-Match, so they are ignored.
+func parseIfMatch(header string) ([]int32, bool) {
+	header = strings.TrimSpace(header)
+	if header == "*" {
+		return nil, true
+	}
+
+	var versions []int32
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if strings.HasPrefix(tag, "W/") || len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+			continue
+		}
+		version, err := strconv.ParseInt(tag[1:len(tag)-1], 10, 32)
+		if err == nil && version > 0 {
+			versions = append(versions, int32(version))
+		}
+	}
+	return versions, len(versions) > 0
+}
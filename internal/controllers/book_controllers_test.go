@@ -0,0 +1,30 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseIfMatch(t *testing.T) {
+	tests := []struct {
+		header   string
+		versions []int32
+		ok       bool
+	}{
+		{`"3"`, []int32{3}, true},
+		{`"3", "4"`, []int32{3, 4}, true},
+		{`W/"3", "4"`, []int32{4}, true},
+		{`"abc", "5"`, []int32{5}, true},
+		{` * `, nil, true},
+		{`W/"3"`, nil, false},
+		{`"0"`, nil, false},
+		{`3`, nil, false},
+	}
+
+	for _, tt := range tests {
+		versions, ok := parseIfMatch(tt.header)
+		assert.Equal(t, tt.ok, ok, tt.header)
+		assert.Equal(t, tt.versions, versions, tt.header)
+	}
+}
@@ -0,0 +1,115 @@
+package controllers
+
+import (
+	"library-api-book/internal/commons/response"
+	"library-api-book/internal/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type BookRevisionController interface {
+	GetRevisions(ctx *gin.Context)
+	DiffRevisions(ctx *gin.Context)
+	RevertBook(ctx *gin.Context)
+}
+
+type BookRevisionControllerImpl struct {
+	RevisionService services.BookRevisionService
+}
+
+func NewBookRevisionController(revisionService services.BookRevisionService) BookRevisionController {
+	return &BookRevisionControllerImpl{
+		RevisionService: revisionService,
+	}
+}
+
+func (controller *BookRevisionControllerImpl) GetRevisions(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status":  false,
+			"message": err,
+		})
+		return
+	}
+
+	pagination := parsePagination(ctx)
+
+	result, custErr := controller.RevisionService.GetRevisions(ctx, uint64(id), &pagination)
+	if custErr != nil {
+		ctx.AbortWithStatusJSON(custErr.StatusCode, custErr)
+		return
+	}
+
+	type Response struct {
+		Revisions  interface{} `json:"revisions"`
+		Pagination interface{} `json:"pagination"`
+	}
+
+	var responses Response
+	responses.Revisions = result
+	responses.Pagination = pagination
+
+	resp := response.GeneralSuccessCustomMessageAndPayload("Success get data book revisions", responses)
+	ctx.JSON(resp.StatusCode, resp)
+}
+
+func (controller *BookRevisionControllerImpl) DiffRevisions(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status":  false,
+			"message": err,
+		})
+		return
+	}
+
+	from, fromErr := strconv.ParseInt(ctx.Query("from"), 10, 32)
+	to, toErr := strconv.ParseInt(ctx.Query("to"), 10, 32)
+	if fromErr != nil || toErr != nil {
+		resp := response.BadRequestError("Query parameters from and to must be revision numbers")
+		ctx.AbortWithStatusJSON(resp.StatusCode, resp)
+		return
+	}
+
+	result, custErr := controller.RevisionService.DiffRevisions(ctx, uint64(id), int32(from), int32(to))
+	if custErr != nil {
+		ctx.AbortWithStatusJSON(custErr.StatusCode, custErr)
+		return
+	}
+
+	resp := response.GeneralSuccessCustomMessageAndPayload("Success get diff book revisions", result)
+	ctx.JSON(resp.StatusCode, resp)
+}
+
+func (controller *BookRevisionControllerImpl) RevertBook(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status":  false,
+			"message": err,
+		})
+		return
+	}
+
+	revision, err := strconv.ParseInt(ctx.Param("revision"), 10, 32)
+	if err != nil {
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status":  false,
+			"message": err,
+		})
+		return
+	}
+
+	result, custErr := controller.RevisionService.RevertBook(ctx, uint64(id), int32(revision))
+	if custErr != nil {
+		ctx.AbortWithStatusJSON(custErr.StatusCode, custErr)
+		return
+	}
+
+	ctx.Header("ETag", bookETag(result.Version))
+	resp := response.GeneralSuccessCustomMessageAndPayload("Success revert data book", result)
+	ctx.JSON(resp.StatusCode, resp)
+}
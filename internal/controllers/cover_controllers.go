@@ -0,0 +1,102 @@
+package controllers
+
+import (
+	"io"
+	"library-api-book/internal/commons/response"
+	"library-api-book/internal/services"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Cover objects are content addressed, so a URL never changes meaning and can
+// be cached indefinitely.
+const coverCacheControl = "public, max-age=31536000, immutable"
+
+type CoverController interface {
+	UploadCover(ctx *gin.Context)
+	GetCover(ctx *gin.Context)
+}
+
+type CoverControllerImpl struct {
+	CoverService services.CoverService
+	MaxSize      int64
+}
+
+func NewCoverController(coverService services.CoverService, maxSize int64) CoverController {
+	return &CoverControllerImpl{
+		CoverService: coverService,
+		MaxSize:      maxSize,
+	}
+}
+
+func (controller *CoverControllerImpl) UploadCover(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status":  false,
+			"message": err,
+		})
+		return
+	}
+
+	// Allow some room for multipart framing on top of the image itself.
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, controller.MaxSize+64<<10)
+
+	var body io.Reader = ctx.Request.Body
+	if strings.HasPrefix(ctx.ContentType(), "multipart/") {
+		file, _, err := ctx.Request.FormFile("cover")
+		if err != nil {
+			resp := response.BadRequestError("Missing cover file: " + err.Error())
+			ctx.AbortWithStatusJSON(resp.StatusCode, resp)
+			return
+		}
+		defer file.Close()
+		body = file
+	}
+
+	data, err := io.ReadAll(io.LimitReader(body, controller.MaxSize+1))
+	if err != nil {
+		resp := response.BadRequestError("Failed to read cover image: " + err.Error())
+		ctx.AbortWithStatusJSON(resp.StatusCode, resp)
+		return
+	}
+
+	result, custErr := controller.CoverService.UploadCover(ctx, uint64(id), data)
+	if custErr != nil {
+		ctx.AbortWithStatusJSON(custErr.StatusCode, custErr)
+		return
+	}
+
+	resp := response.GeneralSuccessCustomMessageAndPayload("Success upload book cover", result)
+	ctx.JSON(resp.StatusCode, resp)
+}
+
+func (controller *CoverControllerImpl) GetCover(ctx *gin.Context) {
+	key := strings.TrimPrefix(ctx.Param("key"), "/")
+
+	body, info, custErr := controller.CoverService.GetCover(ctx, key)
+	if custErr != nil {
+		ctx.AbortWithStatusJSON(http.StatusNotFound, custErr)
+		return
+	}
+	defer body.Close()
+
+	ctx.Header("Cache-Control", coverCacheControl)
+	ctx.Header("ETag", `"`+key+`"`)
+	ctx.Header("X-Content-Type-Options", "nosniff")
+	ctx.Header("Content-Type", info.ContentType)
+
+	if seeker, ok := body.(io.ReadSeeker); ok {
+		http.ServeContent(ctx.Writer, ctx.Request, "", info.LastModified, seeker)
+		return
+	}
+
+	if match := ctx.GetHeader("If-None-Match"); match == `"`+key+`"` {
+		ctx.Status(http.StatusNotModified)
+		return
+	}
+	ctx.DataFromReader(http.StatusOK, info.Size, info.ContentType, body, nil)
+}
@@ -0,0 +1,158 @@
+package controllers
+
+import (
+	"io"
+	"library-api-book/internal/commons/response"
+	"library-api-book/internal/models"
+	"library-api-book/internal/services"
+	"library-api-book/pkg/marc"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	marcContentType    = "application/marc"
+	marcXMLContentType = "application/marcxml+xml"
+	maxMarcImportSize  = 10 << 20
+	// maxMarcExportSize caps a page of the bulk export, which loads the
+	// author and categories of every book on it.
+	maxMarcExportSize = 100
+)
+
+type MarcController interface {
+	ImportBooks(ctx *gin.Context)
+	ExportBook(ctx *gin.Context)
+	ExportBooks(ctx *gin.Context)
+}
+
+type MarcControllerImpl struct {
+	MarcService services.MarcService
+}
+
+func NewMarcController(marcService services.MarcService) MarcController {
+	return &MarcControllerImpl{
+		MarcService: marcService,
+	}
+}
+
+func (controller *MarcControllerImpl) ImportBooks(ctx *gin.Context) {
+	body, err := io.ReadAll(http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxMarcImportSize))
+	if err != nil {
+		resp := response.BadRequestError("Failed to read request body: " + err.Error())
+		ctx.AbortWithStatusJSON(resp.StatusCode, resp)
+		return
+	}
+
+	var records []*marc.Record
+	if isMarcXML(ctx.Query("format"), ctx.ContentType()) {
+		records, err = marc.ParseXML(body)
+	} else {
+		records, err = marc.ParseBinary(body)
+	}
+	if err != nil {
+		resp := response.BadRequestError("Invalid MARC data: " + err.Error())
+		ctx.AbortWithStatusJSON(resp.StatusCode, resp)
+		return
+	}
+	if len(records) == 0 {
+		resp := response.BadRequestError("No MARC records found")
+		ctx.AbortWithStatusJSON(resp.StatusCode, resp)
+		return
+	}
+
+	result, custErr := controller.MarcService.ImportRecords(ctx, records)
+	if custErr != nil {
+		ctx.AbortWithStatusJSON(custErr.StatusCode, custErr)
+		return
+	}
+
+	if len(result.Imported) == 0 {
+		resp := response.UnprocessableEntityErrorWithAdditionalInfo(result, "No MARC record could be imported")
+		ctx.AbortWithStatusJSON(resp.StatusCode, resp)
+		return
+	}
+
+	resp := response.CreatedSuccessWithPayload(result)
+	ctx.JSON(resp.StatusCode, resp)
+}
+
+func (controller *MarcControllerImpl) ExportBook(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"status":  false,
+			"message": err,
+		})
+		return
+	}
+
+	record, custErr := controller.MarcService.ExportBook(ctx, uint64(id))
+	if custErr != nil {
+		ctx.AbortWithStatusJSON(custErr.StatusCode, custErr)
+		return
+	}
+
+	writeMarc(ctx, []*marc.Record{record})
+}
+
+func (controller *MarcControllerImpl) ExportBooks(ctx *gin.Context) {
+	pageNum := 1
+	limitSize := 50
+
+	if parsedPage, err := strconv.Atoi(ctx.Query("page")); err == nil && parsedPage > 0 {
+		pageNum = parsedPage
+	}
+	if parsedLimit, err := strconv.Atoi(ctx.Query("limit")); err == nil && parsedLimit > 0 {
+		limitSize = min(parsedLimit, maxMarcExportSize)
+	}
+
+	pagination := models.Pagination{
+		Page:     pageNum,
+		Offset:   (pageNum - 1) * limitSize,
+		PageSize: limitSize,
+	}
+
+	records, custErr := controller.MarcService.ExportBooks(ctx, &pagination, ctx.Query("search"))
+	if custErr != nil {
+		ctx.AbortWithStatusJSON(custErr.StatusCode, custErr)
+		return
+	}
+
+	writeMarc(ctx, records)
+}
+
+func writeMarc(ctx *gin.Context, records []*marc.Record) {
+	if strings.EqualFold(ctx.Query("format"), "marc") {
+		data, err := marc.EncodeBinary(records)
+		if err != nil {
+			resp := response.GeneralError("Failed to encode MARC: " + err.Error())
+			ctx.AbortWithStatusJSON(resp.StatusCode, resp)
+			return
+		}
+		ctx.Data(http.StatusOK, marcContentType, data)
+		return
+	}
+
+	data, err := marc.EncodeXML(records)
+	if err != nil {
+		resp := response.GeneralError("Failed to encode MARCXML: " + err.Error())
+		ctx.AbortWithStatusJSON(resp.StatusCode, resp)
+		return
+	}
+	ctx.Data(http.StatusOK, marcXMLContentType+"; charset=utf-8", data)
+}
+
+// isMarcXML decides the import format from the explicit format query parameter,
+// falling back to the request content type. Anything else is read as ISO 2709.
+func isMarcXML(format string, contentType string) bool {
+	switch strings.ToLower(format) {
+	case "marcxml", "xml":
+		return true
+	case "marc":
+		return false
+	}
+	return contentType == marcXMLContentType || strings.HasSuffix(contentType, "/xml")
+}
@@ -0,0 +1,404 @@
+package controllers
+
+import (
+	"fmt"
+	"library-api-book/internal/commons/response"
+	"library-api-book/internal/models"
+	"library-api-book/internal/services"
+	"library-api-book/pkg/oaipmh"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	oaiPageSize = 100
+
+	// oaiTokenLifetime is how long a resumption token stays valid. It is
+	// advertised as the token's expirationDate.
+	oaiTokenLifetime = 24 * time.Hour
+)
+
+// oaiArguments lists the arguments each verb accepts (true = required).
+// resumptionToken is handled separately because it is exclusive.
+var oaiArguments = map[string]map[string]bool{
+	"Identify":            {},
+	"ListMetadataFormats": {"identifier": false},
+	"ListIdentifiers":     {"metadataPrefix": true, "from": false, "until": false, "set": false},
+	"ListRecords":         {"metadataPrefix": true, "from": false, "until": false, "set": false},
+	"GetRecord":           {"identifier": true, "metadataPrefix": true},
+}
+
+type OAIController interface {
+	Handle(ctx *gin.Context)
+}
+
+type OAIControllerImpl struct {
+	OAIService           services.OAIService
+	RepositoryName       string
+	RepositoryIdentifier string
+	AdminEmail           string
+	// TrustedProxies are the networks whose X-Forwarded-Proto header is
+	// used for the baseURL. The header is ignored from anyone else.
+	TrustedProxies []*net.IPNet
+}
+
+func NewOAIController(oaiService services.OAIService, repositoryName string, repositoryIdentifier string, adminEmail string, trustedProxies []*net.IPNet) OAIController {
+	return &OAIControllerImpl{
+		OAIService:           oaiService,
+		RepositoryName:       repositoryName,
+		RepositoryIdentifier: repositoryIdentifier,
+		AdminEmail:           adminEmail,
+		TrustedProxies:       trustedProxies,
+	}
+}
+
+func (controller *OAIControllerImpl) Handle(ctx *gin.Context) {
+	if err := ctx.Request.ParseForm(); err != nil {
+		resp := response.BadRequestError("Invalid OAI-PMH request: " + err.Error())
+		ctx.AbortWithStatusJSON(resp.StatusCode, resp)
+		return
+	}
+	args := ctx.Request.Form
+	baseURL := controller.baseURL(ctx)
+	now := time.Now()
+
+	oaiResponse := oaipmh.NewResponse(oaipmh.Request{URL: baseURL}, now)
+
+	verb := args.Get("verb")
+	if _, ok := oaiArguments[verb]; !ok || len(args["verb"]) != 1 {
+		oaiResponse.AddError(oaipmh.ErrBadVerb, "Illegal OAI verb")
+		controller.write(ctx, oaiResponse)
+		return
+	}
+	if message := validateOAIArguments(verb, args); message != "" {
+		oaiResponse.AddError(oaipmh.ErrBadArgument, message)
+		controller.write(ctx, oaiResponse)
+		return
+	}
+
+	oaiResponse.Request = oaipmh.Request{
+		Verb:            verb,
+		Identifier:      args.Get("identifier"),
+		MetadataPrefix:  args.Get("metadataPrefix"),
+		From:            args.Get("from"),
+		Until:           args.Get("until"),
+		Set:             args.Get("set"),
+		ResumptionToken: args.Get("resumptionToken"),
+		URL:             baseURL,
+	}
+
+	var custErr *response.CustomError
+	switch verb {
+	case "Identify":
+		custErr = controller.identify(ctx, oaiResponse, baseURL)
+	case "ListMetadataFormats":
+		custErr = controller.listMetadataFormats(ctx, oaiResponse, args)
+	case "GetRecord":
+		custErr = controller.getRecord(ctx, oaiResponse, args)
+	case "ListIdentifiers", "ListRecords":
+		custErr = controller.list(ctx, oaiResponse, verb, args, now)
+	}
+	if custErr != nil {
+		ctx.AbortWithStatusJSON(custErr.StatusCode, custErr)
+		return
+	}
+
+	controller.write(ctx, oaiResponse)
+}
+
+func (controller *OAIControllerImpl) identify(ctx *gin.Context, oaiResponse *oaipmh.Response, baseURL string) *response.CustomError {
+	earliest, custErr := controller.OAIService.GetEarliestDatestamp(ctx)
+	if custErr != nil {
+		return custErr
+	}
+	if earliest.IsZero() {
+		earliest = time.Now()
+	}
+
+	oaiResponse.Identify = &oaipmh.Identify{
+		RepositoryName:    controller.RepositoryName,
+		BaseURL:           baseURL,
+		ProtocolVersion:   oaipmh.ProtocolVersion,
+		AdminEmail:        controller.AdminEmail,
+		EarliestDatestamp: oaipmh.FormatDatestamp(earliest),
+		DeletedRecord:     oaipmh.DeletedRecordPersistent,
+		Granularity:       oaipmh.Granularity,
+	}
+	return nil
+}
+
+func (controller *OAIControllerImpl) listMetadataFormats(ctx *gin.Context, oaiResponse *oaipmh.Response, args url.Values) *response.CustomError {
+	if identifier := args.Get("identifier"); identifier != "" {
+		id, ok := controller.parseIdentifier(identifier)
+		if !ok {
+			oaiResponse.AddError(oaipmh.ErrIDDoesNotExist, "Unknown identifier")
+			return nil
+		}
+		if _, custErr := controller.OAIService.GetItem(ctx, id); custErr != nil {
+			if custErr.Code == response.NotFoundError().Code {
+				oaiResponse.AddError(oaipmh.ErrIDDoesNotExist, "Unknown identifier")
+				return nil
+			}
+			return custErr
+		}
+	}
+
+	oaiResponse.ListMetadataFormats = &oaipmh.ListMetadataFormats{
+		MetadataFormats: []oaipmh.MetadataFormat{oaipmh.DublinCoreFormat},
+	}
+	return nil
+}
+
+func (controller *OAIControllerImpl) getRecord(ctx *gin.Context, oaiResponse *oaipmh.Response, args url.Values) *response.CustomError {
+	id, ok := controller.parseIdentifier(args.Get("identifier"))
+	if !ok {
+		oaiResponse.AddError(oaipmh.ErrIDDoesNotExist, "Unknown identifier")
+		return nil
+	}
+	if args.Get("metadataPrefix") != oaipmh.MetadataPrefixDC {
+		oaiResponse.AddError(oaipmh.ErrCannotDisseminateFormat, "Only oai_dc is supported")
+		return nil
+	}
+
+	item, custErr := controller.OAIService.GetItem(ctx, id)
+	if custErr != nil {
+		if custErr.Code == response.NotFoundError().Code {
+			oaiResponse.AddError(oaipmh.ErrIDDoesNotExist, "Unknown identifier")
+			return nil
+		}
+		return custErr
+	}
+
+	oaiResponse.GetRecord = &oaipmh.GetRecord{Record: controller.record(item)}
+	return nil
+}
+
+func (controller *OAIControllerImpl) list(ctx *gin.Context, oaiResponse *oaipmh.Response, verb string, args url.Values, now time.Time) *response.CustomError {
+	cursor, errCode, message := parseOAICursor(args, now)
+	if errCode != "" {
+		oaiResponse.AddError(errCode, message)
+		return nil
+	}
+
+	filter := models.HarvestFilter{
+		From:    cursor.From,
+		Until:   cursor.Until,
+		AfterID: cursor.AfterID,
+		Limit:   oaiPageSize,
+	}
+	if cursor.AfterID != 0 {
+		filter.AfterUpdatedAt = &cursor.AfterDatestamp
+	}
+
+	items, total, custErr := controller.OAIService.ListItems(ctx, &filter)
+	if custErr != nil {
+		return custErr
+	}
+	if len(items) == 0 {
+		oaiResponse.AddError(oaipmh.ErrNoRecordsMatch, "No records match the request")
+		return nil
+	}
+
+	var token *oaipmh.ResumptionToken
+	resumed := args.Get("resumptionToken") != ""
+	if cursor.Position+len(items) < total {
+		last := items[len(items)-1]
+		next := *cursor
+		next.AfterDatestamp = last.Datestamp
+		next.AfterID = last.ID
+		next.Position = cursor.Position + len(items)
+		next.Expires = now.Add(oaiTokenLifetime).UTC().Truncate(time.Second)
+		token = &oaipmh.ResumptionToken{
+			Value:            oaipmh.EncodeCursor(&next),
+			ExpirationDate:   oaipmh.FormatDatestamp(next.Expires),
+			CompleteListSize: total,
+			Cursor:           cursor.Position,
+		}
+	} else if resumed {
+		token = &oaipmh.ResumptionToken{
+			CompleteListSize: total,
+			Cursor:           cursor.Position,
+		}
+	}
+
+	if verb == "ListIdentifiers" {
+		headers := make([]oaipmh.Header, len(items))
+		for i, item := range items {
+			headers[i] = controller.header(item)
+		}
+		oaiResponse.ListIdentifiers = &oaipmh.ListIdentifiers{Headers: headers, ResumptionToken: token}
+		return nil
+	}
+
+	records := make([]oaipmh.Record, len(items))
+	for i, item := range items {
+		records[i] = controller.record(item)
+	}
+	oaiResponse.ListRecords = &oaipmh.ListRecords{Records: records, ResumptionToken: token}
+	return nil
+}
+
+func (controller *OAIControllerImpl) header(item *oaipmh.Item) oaipmh.Header {
+	header := oaipmh.Header{
+		Identifier: fmt.Sprintf("oai:%s:book/%d", controller.RepositoryIdentifier, item.ID),
+		Datestamp:  oaipmh.FormatDatestamp(item.Datestamp),
+	}
+	if item.Deleted {
+		header.Status = oaipmh.StatusDeleted
+	}
+	return header
+}
+
+// record disseminates item as oai_dc. Deleted records carry no metadata.
+func (controller *OAIControllerImpl) record(item *oaipmh.Item) oaipmh.Record {
+	if item.Deleted {
+		return oaipmh.Record{Header: controller.header(item)}
+	}
+	return oaipmh.Record{
+		Header:   controller.header(item),
+		Metadata: &oaipmh.Metadata{DublinCore: oaipmh.NewDublinCore(item)},
+	}
+}
+
+func (controller *OAIControllerImpl) parseIdentifier(identifier string) (uint64, bool) {
+	prefix := fmt.Sprintf("oai:%s:book/", controller.RepositoryIdentifier)
+	if !strings.HasPrefix(identifier, prefix) {
+		return 0, false
+	}
+	id, err := strconv.ParseUint(strings.TrimPrefix(identifier, prefix), 10, 64)
+	return id, err == nil && id > 0
+}
+
+func (controller *OAIControllerImpl) write(ctx *gin.Context, oaiResponse *oaipmh.Response) {
+	data, err := oaiResponse.Marshal()
+	if err != nil {
+		resp := response.GeneralError("Failed to encode OAI-PMH response: " + err.Error())
+		ctx.AbortWithStatusJSON(resp.StatusCode, resp)
+		return
+	}
+	ctx.Data(http.StatusOK, "text/xml; charset=utf-8", data)
+}
+
+// parseOAICursor builds the harvesting cursor either from a resumption token
+// or from metadataPrefix/from/until/set. It returns an OAI error code and
+// message when the arguments cannot be served.
+func parseOAICursor(args url.Values, now time.Time) (*oaipmh.Cursor, string, string) {
+	if token := args.Get("resumptionToken"); token != "" {
+		cursor, err := oaipmh.DecodeCursor(token)
+		if err != nil {
+			return nil, oaipmh.ErrBadResumptionToken, err.Error()
+		}
+		if cursor.Expired(now) {
+			return nil, oaipmh.ErrBadResumptionToken, "expired resumption token"
+		}
+		return cursor, "", ""
+	}
+
+	if args.Get("set") != "" {
+		return nil, oaipmh.ErrNoSetHierarchy, "This repository does not support sets"
+	}
+	if args.Get("metadataPrefix") != oaipmh.MetadataPrefixDC {
+		return nil, oaipmh.ErrCannotDisseminateFormat, "Only oai_dc is supported"
+	}
+
+	cursor := &oaipmh.Cursor{MetadataPrefix: oaipmh.MetadataPrefixDC}
+	fromDay, untilDay := false, false
+	if value := args.Get("from"); value != "" {
+		from, day, err := oaipmh.ParseDatestamp(value)
+		if err != nil {
+			return nil, oaipmh.ErrBadArgument, "Invalid from argument"
+		}
+		cursor.From, fromDay = &from, day
+	}
+	if value := args.Get("until"); value != "" {
+		until, day, err := oaipmh.ParseDatestamp(value)
+		if err != nil {
+			return nil, oaipmh.ErrBadArgument, "Invalid until argument"
+		}
+		// until is inclusive of the whole day or second it names.
+		if day {
+			until = until.Add(24*time.Hour - time.Microsecond)
+		} else {
+			until = until.Add(time.Second - time.Microsecond)
+		}
+		cursor.Until, untilDay = &until, day
+	}
+	if cursor.From != nil && cursor.Until != nil {
+		if fromDay != untilDay {
+			return nil, oaipmh.ErrBadArgument, "from and until must have the same granularity"
+		}
+		if cursor.From.After(*cursor.Until) {
+			return nil, oaipmh.ErrBadArgument, "from must not be later than until"
+		}
+	}
+
+	return cursor, "", ""
+}
+
+func validateOAIArguments(verb string, args url.Values) string {
+	allowed := oaiArguments[verb]
+	_, hasToken := args["resumptionToken"]
+	if hasToken && verb != "ListIdentifiers" && verb != "ListRecords" {
+		return "Illegal argument: resumptionToken"
+	}
+
+	for name, values := range args {
+		if name == "verb" {
+			continue
+		}
+		if len(values) != 1 {
+			return "Repeated argument: " + name
+		}
+		if name == "resumptionToken" {
+			continue
+		}
+		if _, ok := allowed[name]; !ok {
+			return "Illegal argument: " + name
+		}
+		if hasToken {
+			return "resumptionToken is an exclusive argument"
+		}
+	}
+
+	if !hasToken {
+		for name, required := range allowed {
+			if required && args.Get(name) == "" {
+				return "Missing required argument: " + name
+			}
+		}
+	}
+	return ""
+}
+
+// baseURL is the URL of the request as the harvester addressed it. The
+// scheme comes from X-Forwarded-Proto only when the request arrived through a
+// trusted proxy.
+func (controller *OAIControllerImpl) baseURL(ctx *gin.Context) string {
+	scheme := "http"
+	if ctx.Request.TLS != nil {
+		scheme = "https"
+	}
+	if forwarded := ctx.GetHeader("X-Forwarded-Proto"); (forwarded == "http" || forwarded == "https") && controller.fromTrustedProxy(ctx) {
+		scheme = forwarded
+	}
+	return scheme + "://" + ctx.Request.Host + ctx.Request.URL.Path
+}
+
+func (controller *OAIControllerImpl) fromTrustedProxy(ctx *gin.Context) bool {
+	ip := net.ParseIP(ctx.RemoteIP())
+	if ip == nil {
+		return false
+	}
+	for _, network := range controller.TrustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,295 @@
+package controllers
+
+import (
+	"context"
+	"encoding/xml"
+	"library-api-book/internal/commons/response"
+	"library-api-book/internal/models"
+	"library-api-book/pkg/oaipmh"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubOAIService serves items ordered by (Datestamp, ID) and keeps the last
+// filter it was asked for.
+type stubOAIService struct {
+	items  []*oaipmh.Item
+	filter models.HarvestFilter
+}
+
+func (s *stubOAIService) GetEarliestDatestamp(ctx context.Context) (time.Time, *response.CustomError) {
+	return s.items[0].Datestamp, nil
+}
+
+func (s *stubOAIService) GetItem(ctx context.Context, id uint64) (*oaipmh.Item, *response.CustomError) {
+	for _, item := range s.items {
+		if item.ID == id {
+			return item, nil
+		}
+	}
+	return nil, response.NotFoundError("Book not found")
+}
+
+func (s *stubOAIService) ListItems(ctx context.Context, filter *models.HarvestFilter) ([]*oaipmh.Item, int, *response.CustomError) {
+	s.filter = *filter
+	var matched []*oaipmh.Item
+	for _, item := range s.items {
+		if filter.From != nil && item.Datestamp.Before(*filter.From) {
+			continue
+		}
+		if filter.Until != nil && item.Datestamp.After(*filter.Until) {
+			continue
+		}
+		matched = append(matched, item)
+	}
+
+	page := []*oaipmh.Item{}
+	for _, item := range matched {
+		if filter.AfterUpdatedAt != nil && !item.Datestamp.After(*filter.AfterUpdatedAt) && (!item.Datestamp.Equal(*filter.AfterUpdatedAt) || item.ID <= filter.AfterID) {
+			continue
+		}
+		if len(page) == filter.Limit {
+			break
+		}
+		page = append(page, item)
+	}
+	return page, len(matched), nil
+}
+
+type oaiResult struct {
+	Request string `xml:"request"`
+	Errors  []struct {
+		Code string `xml:"code,attr"`
+	} `xml:"error"`
+	Identify struct {
+		DeletedRecord string `xml:"deletedRecord"`
+	} `xml:"Identify"`
+	ListIdentifiers struct {
+		Headers []struct {
+			Status     string `xml:"status,attr"`
+			Identifier string `xml:"identifier"`
+		} `xml:"header"`
+		ResumptionToken *struct {
+			Value            string `xml:",chardata"`
+			ExpirationDate   string `xml:"expirationDate,attr"`
+			CompleteListSize int    `xml:"completeListSize,attr"`
+			Cursor           int    `xml:"cursor,attr"`
+		} `xml:"resumptionToken"`
+	} `xml:"ListIdentifiers"`
+}
+
+func newOAITestController(count int, trustedProxies ...string) (*OAIControllerImpl, *stubOAIService) {
+	start := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+	service := &stubOAIService{}
+	for i := 1; i <= count; i++ {
+		service.items = append(service.items, &oaipmh.Item{ID: uint64(i), Datestamp: start.Add(time.Duration(i) * time.Minute), Title: "Book"})
+	}
+
+	var networks []*net.IPNet
+	for _, cidr := range trustedProxies {
+		_, network, _ := net.ParseCIDR(cidr)
+		networks = append(networks, network)
+	}
+	return NewOAIController(service, "Library", "library-api-book", "admin@library.local", networks).(*OAIControllerImpl), service
+}
+
+func harvest(t *testing.T, controller *OAIControllerImpl, query url.Values, configure func(request *http.Request)) *oaiResult {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/oai", controller.Handle)
+
+	request := httptest.NewRequest(http.MethodGet, "/oai?"+query.Encode(), nil)
+	if configure != nil {
+		configure(request)
+	}
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var result oaiResult
+	require.NoError(t, xml.Unmarshal(recorder.Body.Bytes(), &result))
+	return &result
+}
+
+func errorCodes(result *oaiResult) []string {
+	codes := []string{}
+	for _, e := range result.Errors {
+		codes = append(codes, e.Code)
+	}
+	return codes
+}
+
+func TestParseOAICursor_SelectiveHarvesting(t *testing.T) {
+	tests := []struct {
+		name      string
+		from      string
+		until     string
+		errCode   string
+		wantFrom  time.Time
+		wantUntil time.Time
+	}{
+		{name: "mixed granularity", from: "2024-03-01", until: "2024-03-02T00:00:00Z", errCode: oaipmh.ErrBadArgument},
+		{name: "from after until", from: "2024-03-02", until: "2024-03-01", errCode: oaipmh.ErrBadArgument},
+		{name: "invalid from", from: "2024-13-01", errCode: oaipmh.ErrBadArgument},
+		{name: "invalid until", until: "yesterday", errCode: oaipmh.ErrBadArgument},
+		{
+			name:      "until day is inclusive",
+			from:      "2024-03-01",
+			until:     "2024-03-01",
+			wantFrom:  time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC),
+			wantUntil: time.Date(2024, time.March, 1, 23, 59, 59, 999999000, time.UTC),
+		},
+		{
+			name:      "until second is inclusive",
+			from:      "2024-03-01T10:00:00Z",
+			until:     "2024-03-01T10:00:00Z",
+			wantFrom:  time.Date(2024, time.March, 1, 10, 0, 0, 0, time.UTC),
+			wantUntil: time.Date(2024, time.March, 1, 10, 0, 0, 999999000, time.UTC),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := url.Values{"metadataPrefix": {oaipmh.MetadataPrefixDC}}
+			if tt.from != "" {
+				args.Set("from", tt.from)
+			}
+			if tt.until != "" {
+				args.Set("until", tt.until)
+			}
+
+			cursor, errCode, _ := parseOAICursor(args, time.Now())
+
+			assert.Equal(t, tt.errCode, errCode)
+			if tt.errCode != "" {
+				assert.Nil(t, cursor)
+				return
+			}
+			assert.True(t, tt.wantFrom.Equal(*cursor.From))
+			assert.True(t, tt.wantUntil.Equal(*cursor.Until))
+		})
+	}
+}
+
+func TestParseOAICursor_ExpiredToken(t *testing.T) {
+	now := time.Date(2024, time.March, 2, 0, 0, 0, 0, time.UTC)
+	cursor := &oaipmh.Cursor{MetadataPrefix: oaipmh.MetadataPrefixDC, AfterID: 5, Position: 100, Expires: now.Add(-time.Second)}
+
+	_, errCode, message := parseOAICursor(url.Values{"resumptionToken": {oaipmh.EncodeCursor(cursor)}}, now)
+	assert.Equal(t, oaipmh.ErrBadResumptionToken, errCode)
+	assert.Equal(t, "expired resumption token", message)
+
+	cursor.Expires = now.Add(time.Second)
+	parsed, errCode, _ := parseOAICursor(url.Values{"resumptionToken": {oaipmh.EncodeCursor(cursor)}}, now)
+	assert.Empty(t, errCode)
+	assert.Equal(t, uint64(5), parsed.AfterID)
+}
+
+func TestHandle_ResumptionPaging(t *testing.T) {
+	controller, service := newOAITestController(150)
+	from := "2024-03-01T00:00:00Z"
+
+	first := harvest(t, controller, url.Values{"verb": {"ListIdentifiers"}, "metadataPrefix": {"oai_dc"}, "from": {from}}, nil)
+	require.Empty(t, first.Errors)
+	assert.Len(t, first.ListIdentifiers.Headers, 100)
+	token := first.ListIdentifiers.ResumptionToken
+	require.NotNil(t, token)
+	assert.NotEmpty(t, token.Value)
+	assert.NotEmpty(t, token.ExpirationDate)
+	assert.Equal(t, 150, token.CompleteListSize)
+	assert.Equal(t, 0, token.Cursor)
+
+	second := harvest(t, controller, url.Values{"verb": {"ListIdentifiers"}, "resumptionToken": {token.Value}}, nil)
+	require.Empty(t, second.Errors)
+	assert.Len(t, second.ListIdentifiers.Headers, 50)
+	assert.Equal(t, "oai:library-api-book:book/101", second.ListIdentifiers.Headers[0].Identifier)
+	require.NotNil(t, second.ListIdentifiers.ResumptionToken)
+	assert.Empty(t, second.ListIdentifiers.ResumptionToken.Value)
+	assert.Equal(t, 100, second.ListIdentifiers.ResumptionToken.Cursor)
+
+	// The token keeps the original from argument and continues after the
+	// last item of the first page.
+	assert.Equal(t, uint64(100), service.filter.AfterID)
+	require.NotNil(t, service.filter.From)
+	assert.Equal(t, from, oaipmh.FormatDatestamp(*service.filter.From))
+}
+
+func TestHandle_ResumptionTokenErrors(t *testing.T) {
+	controller, _ := newOAITestController(3)
+	valid := oaipmh.EncodeCursor(&oaipmh.Cursor{MetadataPrefix: oaipmh.MetadataPrefixDC, AfterID: 1, Expires: time.Now().Add(time.Hour)})
+	expired := oaipmh.EncodeCursor(&oaipmh.Cursor{MetadataPrefix: oaipmh.MetadataPrefixDC, AfterID: 1, Expires: time.Now().Add(-time.Hour)})
+
+	tests := []struct {
+		name  string
+		query url.Values
+		code  string
+	}{
+		{name: "malformed", query: url.Values{"verb": {"ListRecords"}, "resumptionToken": {"not-a-token"}}, code: oaipmh.ErrBadResumptionToken},
+		{name: "expired", query: url.Values{"verb": {"ListRecords"}, "resumptionToken": {expired}}, code: oaipmh.ErrBadResumptionToken},
+		{name: "malformed with metadataPrefix", query: url.Values{"verb": {"ListRecords"}, "resumptionToken": {"not-a-token"}, "metadataPrefix": {"oai_dc"}}, code: oaipmh.ErrBadArgument},
+		{name: "valid with from", query: url.Values{"verb": {"ListIdentifiers"}, "resumptionToken": {valid}, "from": {"2024-03-01"}}, code: oaipmh.ErrBadArgument},
+		{name: "repeated", query: url.Values{"verb": {"ListIdentifiers"}, "resumptionToken": {valid, valid}}, code: oaipmh.ErrBadArgument},
+		{name: "verb without tokens", query: url.Values{"verb": {"GetRecord"}, "resumptionToken": {valid}}, code: oaipmh.ErrBadArgument},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := harvest(t, controller, tt.query, nil)
+			assert.Equal(t, []string{tt.code}, errorCodes(result))
+		})
+	}
+}
+
+func TestHandle_UntilIsInclusive(t *testing.T) {
+	controller, _ := newOAITestController(3)
+
+	// Item 2 is stamped 2024-03-01T00:02:00Z.
+	result := harvest(t, controller, url.Values{"verb": {"ListIdentifiers"}, "metadataPrefix": {"oai_dc"}, "until": {"2024-03-01T00:02:00Z"}}, nil)
+
+	require.Empty(t, result.Errors)
+	assert.Len(t, result.ListIdentifiers.Headers, 2)
+}
+
+func TestHandle_DeletedRecords(t *testing.T) {
+	controller, service := newOAITestController(2)
+	service.items[1] = &oaipmh.Item{ID: 2, Datestamp: service.items[1].Datestamp, Deleted: true}
+
+	identify := harvest(t, controller, url.Values{"verb": {"Identify"}}, nil)
+	assert.Equal(t, oaipmh.DeletedRecordPersistent, identify.Identify.DeletedRecord)
+
+	result := harvest(t, controller, url.Values{"verb": {"ListIdentifiers"}, "metadataPrefix": {"oai_dc"}}, nil)
+	require.Len(t, result.ListIdentifiers.Headers, 2)
+	assert.Empty(t, result.ListIdentifiers.Headers[0].Status)
+	assert.Equal(t, oaipmh.StatusDeleted, result.ListIdentifiers.Headers[1].Status)
+}
+
+func TestHandle_ForwardedProto(t *testing.T) {
+	controller, _ := newOAITestController(1, "10.0.0.0/8")
+	identify := url.Values{"verb": {"Identify"}}
+
+	fromProxy := harvest(t, controller, identify, func(request *http.Request) {
+		request.RemoteAddr = "10.1.2.3:40000"
+		request.Header.Set("X-Forwarded-Proto", "https")
+	})
+	assert.Equal(t, "https://example.com/oai", fromProxy.Request)
+
+	fromClient := harvest(t, controller, identify, func(request *http.Request) {
+		request.RemoteAddr = "203.0.113.9:40000"
+		request.Header.Set("X-Forwarded-Proto", "https")
+	})
+	assert.Equal(t, "http://example.com/oai", fromClient.Request)
+
+	invalid := harvest(t, controller, identify, func(request *http.Request) {
+		request.RemoteAddr = "10.1.2.3:40000"
+		request.Header.Set("X-Forwarded-Proto", "javascript")
+	})
+	assert.Equal(t, "http://example.com/oai", invalid.Request)
+}
@@ -0,0 +1,223 @@
+// Package events fans out changes to book stock to subscribers in this and
+// other replicas of the service.
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"library-api-book/internal/logger"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// StockChannel is the Redis pub/sub channel stock events are published on.
+const StockChannel = "books:stock"
+
+var ErrSubscriptionClosed = errors.New("stock subscription is closed")
+
+// StockEvent reports the stock of a book after a committed change.
+type StockEvent struct {
+	BookID    uint64    `json:"book_id"`
+	Stock     int32     `json:"stock"`
+	Version   int32     `json:"version"`
+	ChangedAt time.Time `json:"changed_at"`
+}
+
+// StockPublisher is what the services need to announce stock changes.
+type StockPublisher interface {
+	Publish(ctx context.Context, event StockEvent)
+}
+
+// StockBroker delivers stock events to local subscriptions. With a Redis
+// client, events are published on StockChannel and delivered by Run, so every
+// replica sees every change; without one they are delivered in-process.
+type StockBroker struct {
+	redis   *redis.Client
+	timeout time.Duration
+	log     logger.Logger
+
+	mu          sync.Mutex
+	subscribers map[uint64]map[*Subscription]struct{}
+}
+
+// NewStockBroker waits at most timeout for Redis to take an event.
+func NewStockBroker(redisClient *redis.Client, timeout time.Duration, log logger.Logger) *StockBroker {
+	return &StockBroker{
+		redis:       redisClient,
+		timeout:     timeout,
+		log:         log,
+		subscribers: make(map[uint64]map[*Subscription]struct{}),
+	}
+}
+
+// Publish never fails the caller: if Redis is unreachable or slow the event
+// still reaches the subscribers of this replica.
+func (broker *StockBroker) Publish(ctx context.Context, event StockEvent) {
+	if broker.redis != nil {
+		data, err := json.Marshal(event)
+		if err == nil {
+			// The change is already committed, so the caller going away must
+			// not drop the event.
+			publishCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), broker.timeout)
+			err = broker.redis.Publish(publishCtx, StockChannel, data).Err()
+			cancel()
+		}
+		if err == nil {
+			return
+		}
+		broker.log.Warn("[StockBroker] Failed to publish stock event, delivering locally", map[string]interface{}{
+			"book_id": event.BookID,
+			"error":   err.Error(),
+		})
+	}
+	broker.dispatch(event)
+}
+
+// Run relays events from Redis to local subscriptions until ctx is done. It
+// returns at once without a Redis client.
+func (broker *StockBroker) Run(ctx context.Context) {
+	if broker.redis == nil {
+		return
+	}
+
+	pubsub := broker.redis.Subscribe(ctx, StockChannel)
+	defer pubsub.Close()
+
+	messages := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case message, ok := <-messages:
+			if !ok {
+				return
+			}
+			var event StockEvent
+			if err := json.Unmarshal([]byte(message.Payload), &event); err != nil {
+				broker.log.Warn("[StockBroker] Ignoring malformed stock event", map[string]interface{}{
+					"error": err.Error(),
+				})
+				continue
+			}
+			broker.dispatch(event)
+		}
+	}
+}
+
+func (broker *StockBroker) dispatch(event StockEvent) {
+	broker.mu.Lock()
+	subscriptions := make([]*Subscription, 0, len(broker.subscribers[event.BookID]))
+	for subscription := range broker.subscribers[event.BookID] {
+		subscriptions = append(subscriptions, subscription)
+	}
+	broker.mu.Unlock()
+
+	for _, subscription := range subscriptions {
+		subscription.offer(event)
+	}
+}
+
+// Subscribe starts collecting events for bookIDs. The subscription must be
+// closed when the caller is done with it.
+func (broker *StockBroker) Subscribe(bookIDs []uint64) *Subscription {
+	subscription := &Subscription{
+		broker:  broker,
+		bookIDs: bookIDs,
+		pending: make(map[uint64]StockEvent),
+		ready:   make(chan struct{}, 1),
+		done:    make(chan struct{}),
+	}
+
+	broker.mu.Lock()
+	defer broker.mu.Unlock()
+	for _, id := range bookIDs {
+		if broker.subscribers[id] == nil {
+			broker.subscribers[id] = make(map[*Subscription]struct{})
+		}
+		broker.subscribers[id][subscription] = struct{}{}
+	}
+	return subscription
+}
+
+func (broker *StockBroker) unsubscribe(subscription *Subscription) {
+	broker.mu.Lock()
+	defer broker.mu.Unlock()
+	for _, id := range subscription.bookIDs {
+		delete(broker.subscribers[id], subscription)
+		if len(broker.subscribers[id]) == 0 {
+			delete(broker.subscribers, id)
+		}
+	}
+}
+
+// Subscription buffers the events of one subscriber. Publishers never wait
+// for it: while the subscriber is busy, newer events replace older pending
+// ones of the same book, so a slow consumer skips intermediate values but
+// always ends up with the latest stock, and the buffer never holds more than
+// one event per book.
+type Subscription struct {
+	broker  *StockBroker
+	bookIDs []uint64
+
+	mu      sync.Mutex
+	pending map[uint64]StockEvent
+	ready   chan struct{}
+	done    chan struct{}
+	once    sync.Once
+}
+
+func (subscription *Subscription) offer(event StockEvent) {
+	subscription.mu.Lock()
+	if current, ok := subscription.pending[event.BookID]; ok && current.Version > event.Version {
+		subscription.mu.Unlock()
+		return
+	}
+	subscription.pending[event.BookID] = event
+	subscription.mu.Unlock()
+
+	select {
+	case subscription.ready <- struct{}{}:
+	default:
+	}
+}
+
+// Next waits for events and returns everything pending, oldest change first.
+func (subscription *Subscription) Next(ctx context.Context) ([]StockEvent, error) {
+	for {
+		subscription.mu.Lock()
+		if len(subscription.pending) > 0 {
+			events := make([]StockEvent, 0, len(subscription.pending))
+			for _, event := range subscription.pending {
+				events = append(events, event)
+			}
+			subscription.pending = make(map[uint64]StockEvent)
+			subscription.mu.Unlock()
+
+			sort.Slice(events, func(i, j int) bool {
+				return events[i].ChangedAt.Before(events[j].ChangedAt)
+			})
+			return events, nil
+		}
+		subscription.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-subscription.done:
+			return nil, ErrSubscriptionClosed
+		case <-subscription.ready:
+		}
+	}
+}
+
+func (subscription *Subscription) Close() {
+	subscription.once.Do(func() {
+		subscription.broker.unsubscribe(subscription)
+		close(subscription.done)
+	})
+}
@@ -0,0 +1,108 @@
+package events
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"library-api-book/internal/logger"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubscription_KeepsLatestEventPerBook(t *testing.T) {
+	broker := NewStockBroker(nil, time.Second, logger.NopLogger{})
+	subscription := broker.Subscribe([]uint64{1, 2})
+	defer subscription.Close()
+
+	for version := int32(1); version <= 100; version++ {
+		broker.Publish(context.Background(), StockEvent{BookID: 1, Stock: version, Version: version, ChangedAt: time.Unix(int64(version), 0)})
+	}
+	broker.Publish(context.Background(), StockEvent{BookID: 1, Stock: 50, Version: 50})
+	broker.Publish(context.Background(), StockEvent{BookID: 2, Stock: 7, Version: 3, ChangedAt: time.Unix(200, 0)})
+	broker.Publish(context.Background(), StockEvent{BookID: 3, Stock: 1, Version: 1})
+
+	events, err := subscription.Next(context.Background())
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, StockEvent{BookID: 1, Stock: 100, Version: 100, ChangedAt: time.Unix(100, 0)}, events[0])
+	assert.Equal(t, uint64(2), events[1].BookID)
+}
+
+func TestSubscription_NextWaits(t *testing.T) {
+	broker := NewStockBroker(nil, time.Second, logger.NopLogger{})
+	subscription := broker.Subscribe([]uint64{1})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := subscription.Next(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	go subscription.Close()
+	_, err = subscription.Next(context.Background())
+	assert.ErrorIs(t, err, ErrSubscriptionClosed)
+	assert.Empty(t, broker.subscribers)
+}
+
+func TestStockBroker_RelaysThroughRedis(t *testing.T) {
+	server := miniredis.RunT(t)
+	newClient := func() *redis.Client {
+		client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+		t.Cleanup(func() { client.Close() })
+		return client
+	}
+	publisher := NewStockBroker(newClient(), time.Second, logger.NopLogger{})
+	receiver := NewStockBroker(newClient(), time.Second, logger.NopLogger{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go receiver.Run(ctx)
+	require.Eventually(t, func() bool {
+		return server.PubSubNumSub(StockChannel)[StockChannel] == 1
+	}, time.Second, 5*time.Millisecond)
+
+	subscription := receiver.Subscribe([]uint64{1})
+	defer subscription.Close()
+
+	publisher.Publish(ctx, StockEvent{BookID: 1, Stock: 4, Version: 2, ChangedAt: time.Unix(10, 0).UTC()})
+
+	waitCtx, waitCancel := context.WithTimeout(ctx, time.Second)
+	defer waitCancel()
+	events, err := subscription.Next(waitCtx)
+	require.NoError(t, err)
+	assert.Equal(t, []StockEvent{{BookID: 1, Stock: 4, Version: 2, ChangedAt: time.Unix(10, 0).UTC()}}, events)
+}
+
+func TestStockBroker_DeliversLocallyWhenRedisHangs(t *testing.T) {
+	// Accepts connections and never answers.
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			t.Cleanup(func() { conn.Close() })
+		}
+	}()
+
+	client := redis.NewClient(&redis.Options{Addr: listener.Addr().String(), MaxRetries: -1, ContextTimeoutEnabled: true})
+	t.Cleanup(func() { client.Close() })
+	broker := NewStockBroker(client, 20*time.Millisecond, logger.NopLogger{})
+	subscription := broker.Subscribe([]uint64{1})
+	defer subscription.Close()
+
+	start := time.Now()
+	broker.Publish(context.Background(), StockEvent{BookID: 1, Stock: 4, Version: 2})
+	assert.Less(t, time.Since(start), time.Second)
+
+	events, err := subscription.Next(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int32(4), events[0].Stock)
+}
@@ -2,18 +2,42 @@ package factory
 
 import (
 	"database/sql"
+	"fmt"
+	"library-api-book/internal/cache"
+	"library-api-book/internal/config"
 	"library-api-book/internal/controllers"
+	"library-api-book/internal/events"
 	"library-api-book/internal/logger"
+	"library-api-book/internal/permission"
+	"library-api-book/internal/ratelimit"
 	"library-api-book/internal/repositories"
 	"library-api-book/internal/services"
+	"library-api-book/pkg/breaker"
+	"library-api-book/pkg/storage"
 	"log"
+	"net"
+	"strings"
 
 	"github.com/redis/go-redis/v9"
 )
 
 type Provider struct {
-	BookProvider controllers.BookController
-	BookService  services.BookService
+	BookProvider     controllers.BookController
+	RevisionProvider controllers.BookRevisionController
+	MarcProvider     controllers.MarcController
+	OAIProvider      controllers.OAIController
+	CoverProvider    controllers.CoverController
+	AuditProvider    controllers.AuditController
+	APIKeyProvider   controllers.APIKeyController
+	BookService      services.BookService
+	Cache            *cache.Cache
+	StockBroker      *events.StockBroker
+	RateLimiter      *ratelimit.Limiter
+	RateLimits       *ratelimit.Policy
+	Policy           *permission.Policy
+	AuditService     services.AuditService
+	APIKeyService    services.APIKeyService
+	Logger           logger.Logger
 }
 
 func InitFactory(db *sql.DB, redis *redis.Client) *Provider {
@@ -22,13 +46,118 @@ func InitFactory(db *sql.DB, redis *redis.Client) *Provider {
 		log.Fatalf("[Logger] Failed to initialize book service logger: %v", err)
 	}
 
+	policy, err := permission.LoadPolicy(config.ENV.PermissionPolicyPath)
+	if err != nil {
+		log.Fatalf("[Permission] Failed to load permission policy: %v", err)
+	}
+
 	bookRepo := repositories.NewBookRepository()
+	authorRepo := repositories.NewAuthorRepository()
+	categoryRepo := repositories.NewCategoryRepository()
+	revisionRepo := repositories.NewBookRevisionRepository()
+	auditLogRepo := repositories.NewAuditLogRepository()
+	apiKeyRepo := repositories.NewAPIKeyRepository()
+
+	bookStore := cache.New(redis, config.ENV.CacheTimeout, breaker.New(config.ENV.CacheBreakerThreshold, config.ENV.CacheBreakerCooldown))
+	bookCache := services.NewBookCache(bookStore, newLog)
+
+	coverStorage, err := newStorage()
+	if err != nil {
+		log.Fatalf("[Storage] Failed to initialize cover storage: %v", err)
+	}
 
-	bookService := services.NewBookService(db, redis, bookRepo, newLog)
+	stockBroker := events.NewStockBroker(redis, config.ENV.CacheTimeout, newLog)
+	bookService := services.NewBookService(db, bookCache, bookRepo, authorRepo, revisionRepo, stockBroker, coverStorage, policy, newLog)
 	bookController := controllers.NewBookController(bookService)
 
+	revisionService := services.NewBookRevisionService(db, bookCache, bookRepo, revisionRepo, newLog)
+	revisionController := controllers.NewBookRevisionController(revisionService)
+
+	marcService := services.NewMarcService(db, bookCache, bookRepo, authorRepo, categoryRepo, revisionRepo, policy, newLog)
+	marcController := controllers.NewMarcController(marcService)
+
+	oaiService := services.NewOAIService(db, bookRepo, authorRepo, categoryRepo, newLog)
+	oaiTrustedProxies, err := parseNetworks(config.ENV.OAITrustedProxies)
+	if err != nil {
+		log.Fatalf("[OAI] Invalid OAI_TRUSTED_PROXIES: %v", err)
+	}
+	oaiController := controllers.NewOAIController(oaiService, config.ENV.OAIRepositoryName, config.ENV.OAIRepositoryIdentifier, config.ENV.OAIAdminEmail, oaiTrustedProxies)
+
+	coverService := services.NewCoverService(db, bookCache, bookRepo, authorRepo, revisionRepo, coverStorage, policy, newLog, config.ENV.CoverMaxSize)
+	coverController := controllers.NewCoverController(coverService, config.ENV.CoverMaxSize)
+
+	auditService := services.NewAuditService(db, auditLogRepo, newLog)
+	auditController := controllers.NewAuditController(auditService)
+
+	apiKeyService := services.NewAPIKeyService(db, apiKeyRepo, newLog)
+	apiKeyController := controllers.NewAPIKeyController(apiKeyService)
+
+	// A nil limiter lets every request through.
+	var rateLimiter *ratelimit.Limiter
+	var rateLimits *ratelimit.Policy
+	if config.ENV.RateLimitEnabled {
+		rateLimits, err = ratelimit.LoadPolicy(config.ENV.RateLimitPolicyPath)
+		if err != nil {
+			log.Fatalf("[RateLimit] Failed to load rate limit policy: %v", err)
+		}
+		rateLimiter = ratelimit.NewLimiter(redis, config.ENV.RateLimitTimeout)
+	}
+
 	return &Provider{
-		BookProvider: bookController,
-		BookService:  bookService,
+		BookProvider:     bookController,
+		RevisionProvider: revisionController,
+		MarcProvider:     marcController,
+		OAIProvider:      oaiController,
+		CoverProvider:    coverController,
+		AuditProvider:    auditController,
+		APIKeyProvider:   apiKeyController,
+		BookService:      bookService,
+		Cache:            bookStore,
+		StockBroker:      stockBroker,
+		RateLimiter:      rateLimiter,
+		RateLimits:       rateLimits,
+		Policy:           policy,
+		AuditService:     auditService,
+		APIKeyService:    apiKeyService,
+		Logger:           newLog,
+	}
+}
+
+func newStorage() (storage.Storage, error) {
+	switch config.ENV.StorageDriver {
+	case "local":
+		return storage.NewLocalStorage(config.ENV.StorageLocalPath)
+	default:
+		return nil, fmt.Errorf("unsupported storage driver %q", config.ENV.StorageDriver)
+	}
+}
+
+// parseNetworks reads a comma separated list of IP addresses and CIDR ranges.
+// A plain address is taken as a network of that single address.
+func parseNetworks(value string) ([]*net.IPNet, error) {
+	var networks []*net.IPNet
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid address %q", entry)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, err
+		}
+		networks = append(networks, network)
 	}
+	return networks, nil
 }
@@ -7,16 +7,18 @@ import (
 	pb "library-api-book/proto/auth"
 
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
 )
 
 type AuthClient struct {
 	client pb.AuthServiceClient
 	conn   *grpc.ClientConn
+	cache  *TokenCache
 }
 
-func NewAuthClient(addr string) (*AuthClient, error) {
-	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+// NewAuthClient connects to the user service. cache may be nil to validate
+// every token remotely.
+func NewAuthClient(factory *ClientFactory, addr string, cache *TokenCache) (*AuthClient, error) {
+	conn, err := factory.Dial(addr, pb.AuthService_ValidateToken_FullMethodName)
 	if err != nil {
 		return nil, err
 	}
@@ -25,19 +27,30 @@ func NewAuthClient(addr string) (*AuthClient, error) {
 	return &AuthClient{
 		client: client,
 		conn:   conn,
+		cache:  cache,
 	}, nil
 }
 
 func (c *AuthClient) ValidateToken(ctx context.Context, token string) (bool, *tkn.Token) {
+	if c.cache != nil {
+		if payload, ok := c.cache.Get(ctx, token); ok {
+			return true, payload
+		}
+	}
+
 	resp, err := c.client.ValidateToken(ctx, &pb.ValidateRequest{Token: token})
 	if err != nil || !resp.Success {
 		return false, nil
 	}
 
-	return true, &tkn.Token{
+	payload := &tkn.Token{
 		AuthId: int(resp.AuthId),
 		Role:   resp.Role,
 	}
+	if c.cache != nil {
+		c.cache.Set(ctx, token, payload)
+	}
+	return true, payload
 }
 
 func (c *AuthClient) Close() {
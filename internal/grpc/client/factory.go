@@ -0,0 +1,198 @@
+package client
+
+import (
+	"context"
+	"math/rand"
+	"time"
+
+	"library-api-book/pkg/breaker"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	_ "google.golang.org/grpc/health" // registers the client-side health checker
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/status"
+)
+
+// Client-side health checking needs a load balancing policy that watches
+// subchannel health; pick_first would keep sending to an unhealthy backend.
+const healthServiceConfig = `{
+	"loadBalancingConfig": [{"round_robin": {}}],
+	"healthCheckConfig": {"serviceName": ""}
+}`
+
+type ClientOptions struct {
+	// Timeout bounds every attempt of a call. A shorter deadline on the
+	// caller's context still wins.
+	Timeout time.Duration
+	// MaxAttempts is the number of tries for idempotent methods, including
+	// the first one.
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// The breaker opens after BreakerThreshold consecutive failed calls and
+	// fails fast for BreakerCooldown.
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+	// TransportCredentials secures the connection. Nil means plaintext.
+	TransportCredentials credentials.TransportCredentials
+}
+
+func DefaultClientOptions() ClientOptions {
+	return ClientOptions{
+		Timeout:          2 * time.Second,
+		MaxAttempts:      3,
+		InitialBackoff:   100 * time.Millisecond,
+		MaxBackoff:       time.Second,
+		BreakerThreshold: 5,
+		BreakerCooldown:  10 * time.Second,
+	}
+}
+
+// ClientFactory dials downstream gRPC services with the same deadline, retry
+// and circuit breaking behaviour. Each connection gets its own breaker.
+type ClientFactory struct {
+	options     ClientOptions
+	dialOptions []grpc.DialOption
+}
+
+func NewClientFactory(options ClientOptions, dialOptions ...grpc.DialOption) *ClientFactory {
+	return &ClientFactory{
+		options:     options,
+		dialOptions: dialOptions,
+	}
+}
+
+// Dial connects to addr. Only the full method names listed in idempotent are
+// retried; everything else is attempted once.
+func (factory *ClientFactory) Dial(addr string, idempotent ...string) (*grpc.ClientConn, error) {
+	retryable := make(map[string]bool, len(idempotent))
+	for _, method := range idempotent {
+		retryable[method] = true
+	}
+
+	interceptor := &clientInterceptor{
+		options:   factory.options,
+		breaker:   breaker.New(factory.options.BreakerThreshold, factory.options.BreakerCooldown),
+		retryable: retryable,
+	}
+
+	transport := factory.options.TransportCredentials
+	if transport == nil {
+		transport = insecure.NewCredentials()
+	}
+
+	options := []grpc.DialOption{
+		grpc.WithTransportCredentials(transport),
+		grpc.WithDefaultServiceConfig(healthServiceConfig),
+		grpc.WithKeepaliveParams(keepalive.ClientParameters{
+			Time:    30 * time.Second,
+			Timeout: 10 * time.Second,
+		}),
+		grpc.WithChainUnaryInterceptor(interceptor.unary),
+	}
+	options = append(options, factory.dialOptions...)
+
+	return grpc.NewClient(addr, options...)
+}
+
+type clientInterceptor struct {
+	options   ClientOptions
+	breaker   *breaker.Breaker
+	retryable map[string]bool
+}
+
+func (interceptor *clientInterceptor) unary(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	attempts := 1
+	if interceptor.retryable[method] && interceptor.options.MaxAttempts > 1 {
+		attempts = interceptor.options.MaxAttempts
+	}
+
+	var err error
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			if waitErr := sleepContext(ctx, interceptor.backoff(attempt)); waitErr != nil {
+				return err
+			}
+		}
+
+		var rejected bool
+		rejected, err = interceptor.invoke(ctx, method, req, reply, cc, invoker, opts...)
+		if rejected || !retryableCode(status.Code(err)) || ctx.Err() != nil {
+			return err
+		}
+	}
+	return err
+}
+
+// invoke runs a single attempt through the breaker with its own deadline.
+// rejected is true when the open breaker refused the attempt, which is not
+// worth retrying. An attempt cut short by the caller's ctx says nothing about
+// the downstream and leaves the breaker unchanged.
+func (interceptor *clientInterceptor) invoke(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) (rejected bool, err error) {
+	if err := interceptor.breaker.Allow(); err != nil {
+		return true, status.Errorf(codes.Unavailable, "%s: %v", method, err)
+	}
+
+	attemptCtx := ctx
+	if interceptor.options.Timeout > 0 {
+		var cancel context.CancelFunc
+		attemptCtx, cancel = context.WithTimeout(ctx, interceptor.options.Timeout)
+		defer cancel()
+	}
+
+	err = invoker(attemptCtx, method, req, reply, cc, opts...)
+	if ctx.Err() != nil {
+		interceptor.breaker.Release()
+	} else if downstreamFailure(status.Code(err)) {
+		interceptor.breaker.Failure()
+	} else {
+		interceptor.breaker.Success()
+	}
+	return false, err
+}
+
+func (interceptor *clientInterceptor) backoff(attempt int) time.Duration {
+	backoff := interceptor.options.InitialBackoff << (attempt - 1)
+	if backoff <= 0 || backoff > interceptor.options.MaxBackoff {
+		backoff = interceptor.options.MaxBackoff
+	}
+	// Full jitter keeps retrying clients from synchronising.
+	return time.Duration(rand.Int63n(int64(backoff) + 1))
+}
+
+// retryableCode reports whether an attempt failed in a way another attempt
+// may fix.
+func retryableCode(code codes.Code) bool {
+	switch code {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted:
+		return true
+	default:
+		return false
+	}
+}
+
+// downstreamFailure reports whether an error counts against the breaker.
+// Application errors such as NotFound mean the service is up.
+func downstreamFailure(code codes.Code) bool {
+	switch code {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted, codes.Internal, codes.Unknown:
+		return true
+	default:
+		return false
+	}
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
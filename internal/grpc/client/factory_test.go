@@ -0,0 +1,174 @@
+package client
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	pb "library-api-book/proto/auth"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+type flakyAuthServer struct {
+	pb.UnimplementedAuthServiceServer
+	calls    atomic.Int32
+	failures int32
+	code     codes.Code
+	delay    time.Duration
+}
+
+func (s *flakyAuthServer) ValidateToken(ctx context.Context, req *pb.ValidateRequest) (*pb.ValidateResponse, error) {
+	call := s.calls.Add(1)
+	if s.delay > 0 {
+		select {
+		case <-time.After(s.delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	if call <= s.failures {
+		return nil, status.Error(s.code, "failing")
+	}
+	return &pb.ValidateResponse{Success: true, AuthId: 7, Role: "admin"}, nil
+}
+
+func startAuthServer(t *testing.T, server *flakyAuthServer, healthServer *health.Server) *grpc.ClientConn {
+	return startAuthServerWithOptions(t, server, healthServer, ClientOptions{
+		Timeout:          time.Second,
+		MaxAttempts:      3,
+		InitialBackoff:   time.Millisecond,
+		MaxBackoff:       5 * time.Millisecond,
+		BreakerThreshold: 100,
+		BreakerCooldown:  time.Minute,
+	})
+}
+
+func startAuthServerWithOptions(t *testing.T, server *flakyAuthServer, healthServer *health.Server, options ClientOptions) *grpc.ClientConn {
+	listener := bufconn.Listen(1 << 20)
+	grpcServer := grpc.NewServer()
+	pb.RegisterAuthServiceServer(grpcServer, server)
+	if healthServer != nil {
+		healthpb.RegisterHealthServer(grpcServer, healthServer)
+	}
+	go grpcServer.Serve(listener)
+	t.Cleanup(grpcServer.Stop)
+
+	factory := NewClientFactory(options, grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+		return listener.DialContext(ctx)
+	}))
+	conn, err := factory.Dial("passthrough:///bufnet", pb.AuthService_ValidateToken_FullMethodName)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestClientFactory_RetriesIdempotentCalls(t *testing.T) {
+	server := &flakyAuthServer{failures: 2, code: codes.Unavailable}
+	conn := startAuthServer(t, server, nil)
+
+	resp, err := pb.NewAuthServiceClient(conn).ValidateToken(context.Background(), &pb.ValidateRequest{Token: "t"})
+
+	require.NoError(t, err)
+	assert.True(t, resp.Success)
+	assert.Equal(t, int32(3), server.calls.Load())
+}
+
+func TestClientFactory_DoesNotRetryApplicationErrors(t *testing.T) {
+	server := &flakyAuthServer{failures: 1, code: codes.InvalidArgument}
+	conn := startAuthServer(t, server, nil)
+
+	_, err := pb.NewAuthServiceClient(conn).ValidateToken(context.Background(), &pb.ValidateRequest{Token: "t"})
+
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.Equal(t, int32(1), server.calls.Load())
+}
+
+func TestClientFactory_PerCallDeadline(t *testing.T) {
+	server := &flakyAuthServer{delay: time.Minute}
+	conn := startAuthServerWithOptions(t, server, nil, ClientOptions{
+		Timeout:          20 * time.Millisecond,
+		MaxAttempts:      2,
+		InitialBackoff:   time.Millisecond,
+		MaxBackoff:       time.Millisecond,
+		BreakerThreshold: 100,
+		BreakerCooldown:  time.Minute,
+	})
+
+	start := time.Now()
+	_, err := pb.NewAuthServiceClient(conn).ValidateToken(context.Background(), &pb.ValidateRequest{Token: "t"})
+
+	assert.Equal(t, codes.DeadlineExceeded, status.Code(err))
+	assert.Less(t, time.Since(start), 5*time.Second)
+	assert.Equal(t, int32(2), server.calls.Load())
+}
+
+func TestClientFactory_BreakerFailsFast(t *testing.T) {
+	server := &flakyAuthServer{failures: 100, code: codes.Unavailable}
+	conn := startAuthServerWithOptions(t, server, nil, ClientOptions{
+		Timeout:          time.Second,
+		MaxAttempts:      1,
+		BreakerThreshold: 2,
+		BreakerCooldown:  time.Minute,
+	})
+	authClient := pb.NewAuthServiceClient(conn)
+
+	for i := 0; i < 2; i++ {
+		_, err := authClient.ValidateToken(context.Background(), &pb.ValidateRequest{Token: "t"})
+		assert.Equal(t, codes.Unavailable, status.Code(err))
+	}
+
+	_, err := authClient.ValidateToken(context.Background(), &pb.ValidateRequest{Token: "t"})
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.Contains(t, err.Error(), "circuit breaker is open")
+	assert.Equal(t, int32(2), server.calls.Load())
+}
+
+func TestClientFactory_CallerCancellationKeepsBreakerClosed(t *testing.T) {
+	server := &flakyAuthServer{delay: time.Minute}
+	conn := startAuthServerWithOptions(t, server, nil, ClientOptions{
+		Timeout:          time.Second,
+		MaxAttempts:      3,
+		InitialBackoff:   time.Millisecond,
+		MaxBackoff:       time.Millisecond,
+		BreakerThreshold: 1,
+		BreakerCooldown:  time.Minute,
+	})
+	authClient := pb.NewAuthServiceClient(conn)
+
+	for i := 0; i < 3; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		_, err := authClient.ValidateToken(ctx, &pb.ValidateRequest{Token: "t"})
+		cancel()
+		assert.Equal(t, codes.DeadlineExceeded, status.Code(err))
+		assert.NotContains(t, err.Error(), "circuit breaker is open")
+	}
+	assert.Equal(t, int32(3), server.calls.Load())
+}
+
+func TestClientFactory_HealthCheck(t *testing.T) {
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+	server := &flakyAuthServer{}
+	conn := startAuthServer(t, server, healthServer)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	_, err := pb.NewAuthServiceClient(conn).ValidateToken(ctx, &pb.ValidateRequest{Token: "t"}, grpc.WaitForReady(true))
+	assert.Error(t, err)
+	assert.Equal(t, int32(0), server.calls.Load())
+
+	healthServer.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
+	resp, err := pb.NewAuthServiceClient(conn).ValidateToken(context.Background(), &pb.ValidateRequest{Token: "t"}, grpc.WaitForReady(true))
+	require.NoError(t, err)
+	assert.True(t, resp.Success)
+}
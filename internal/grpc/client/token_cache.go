@@ -0,0 +1,160 @@
+package client
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"library-api-book/internal/cache"
+	tkn "library-api-book/pkg/token"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	tokenCacheKeyPrefix  = "auth:token:"
+	tokenCacheMaxEntries = 10000
+)
+
+// TokenCache keeps successful token validations for a short time so that
+// repeated requests with the same token skip the user service. Entries are
+// keyed by a SHA-256 of the token, never by the token itself, and never
+// outlive the token's own expiry. A revoked token stays valid for at most the
+// configured TTL.
+type TokenCache struct {
+	ttl    time.Duration
+	shared *cache.Cache
+	now    func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]cachedToken
+}
+
+type cachedToken struct {
+	AuthId    int       `json:"auth_id"`
+	Role      string    `json:"role"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// NewTokenCache returns an in-memory cache. When shared is not nil,
+// validations are also shared through Redis between instances, within the
+// timeout and circuit breaker of shared.
+func NewTokenCache(ttl time.Duration, shared *cache.Cache) *TokenCache {
+	return &TokenCache{
+		ttl:     ttl,
+		shared:  shared,
+		now:     time.Now,
+		entries: make(map[string]cachedToken),
+	}
+}
+
+func (cache *TokenCache) Get(ctx context.Context, token string) (*tkn.Token, bool) {
+	key := tokenCacheKey(token)
+	now := cache.now()
+
+	cache.mu.Lock()
+	entry, ok := cache.entries[key]
+	if ok && !now.Before(entry.ExpiresAt) {
+		delete(cache.entries, key)
+		ok = false
+	}
+	cache.mu.Unlock()
+
+	if !ok && cache.shared != nil {
+		entry, ok = cache.getShared(ctx, key, now)
+		if ok {
+			cache.store(key, entry)
+		}
+	}
+	if !ok {
+		return nil, false
+	}
+
+	return &tkn.Token{
+		AuthId:  entry.AuthId,
+		Role:    entry.Role,
+		Expired: entry.ExpiresAt,
+	}, true
+}
+
+func (cache *TokenCache) Set(ctx context.Context, token string, payload *tkn.Token) {
+	now := cache.now()
+	expiresAt := now.Add(cache.ttl)
+	if expiry, ok := tokenExpiry(token); ok && expiry.Before(expiresAt) {
+		expiresAt = expiry
+	}
+	if !now.Before(expiresAt) {
+		return
+	}
+
+	key := tokenCacheKey(token)
+	entry := cachedToken{
+		AuthId:    payload.AuthId,
+		Role:      payload.Role,
+		ExpiresAt: expiresAt,
+	}
+	cache.store(key, entry)
+
+	// Redis only shares work between instances; losing it costs a lookup.
+	if cache.shared != nil {
+		cache.shared.Set(ctx, tokenCacheKeyPrefix+key, entry, expiresAt.Sub(now))
+	}
+}
+
+func (cache *TokenCache) getShared(ctx context.Context, key string, now time.Time) (cachedToken, bool) {
+	var entry cachedToken
+
+	found, err := cache.shared.Get(ctx, tokenCacheKeyPrefix+key, &entry)
+	if err != nil || !found || !now.Before(entry.ExpiresAt) {
+		return entry, false
+	}
+	return entry, true
+}
+
+func (cache *TokenCache) store(key string, entry cachedToken) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	if len(cache.entries) >= tokenCacheMaxEntries {
+		now := cache.now()
+		for k, e := range cache.entries {
+			if !now.Before(e.ExpiresAt) {
+				delete(cache.entries, k)
+			}
+		}
+		if len(cache.entries) >= tokenCacheMaxEntries {
+			return
+		}
+	}
+	cache.entries[key] = entry
+}
+
+func tokenCacheKey(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// tokenExpiry reads the expiry of a JWT without verifying it. The result only
+// shortens the cache TTL; whether the token is valid is decided by the user
+// service.
+func tokenExpiry(token string) (time.Time, bool) {
+	claims := jwt.MapClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(token, claims); err != nil {
+		return time.Time{}, false
+	}
+
+	if exp, err := claims.GetExpirationTime(); err == nil && exp != nil {
+		return exp.Time, true
+	}
+
+	// Tokens issued with pkg/token keep the expiry inside the payload claim.
+	var payload tkn.Token
+	data, err := json.Marshal(claims["payload"])
+	if err != nil || json.Unmarshal(data, &payload) != nil || payload.Expired.IsZero() {
+		return time.Time{}, false
+	}
+	return payload.Expired, true
+}
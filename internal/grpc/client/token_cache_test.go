@@ -0,0 +1,147 @@
+package client
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"library-api-book/internal/cache"
+	"library-api-book/pkg/breaker"
+	tkn "library-api-book/pkg/token"
+	pb "library-api-book/proto/auth"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+type countingAuthService struct {
+	calls int
+	resp  *pb.ValidateResponse
+}
+
+func (s *countingAuthService) ValidateToken(ctx context.Context, in *pb.ValidateRequest, opts ...grpc.CallOption) (*pb.ValidateResponse, error) {
+	s.calls++
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return s.resp, nil
+}
+
+func signedToken(t *testing.T, exp time.Time) string {
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"exp": exp.Unix()}).SignedString([]byte("secret"))
+	require.NoError(t, err)
+	return token
+}
+
+func TestValidateToken_CachesSuccess(t *testing.T) {
+	service := &countingAuthService{resp: &pb.ValidateResponse{Success: true, AuthId: 7, Role: "author"}}
+	authClient := &AuthClient{client: service, cache: NewTokenCache(time.Minute, nil)}
+	token := signedToken(t, time.Now().Add(time.Hour))
+
+	for i := 0; i < 3; i++ {
+		valid, payload := authClient.ValidateToken(context.Background(), token)
+		assert.True(t, valid)
+		assert.Equal(t, 7, payload.AuthId)
+		assert.Equal(t, "author", payload.Role)
+	}
+	assert.Equal(t, 1, service.calls)
+}
+
+func TestValidateToken_DoesNotCacheFailure(t *testing.T) {
+	service := &countingAuthService{resp: &pb.ValidateResponse{Success: false}}
+	authClient := &AuthClient{client: service, cache: NewTokenCache(time.Minute, nil)}
+
+	authClient.ValidateToken(context.Background(), "bad-token")
+	valid, _ := authClient.ValidateToken(context.Background(), "bad-token")
+
+	assert.False(t, valid)
+	assert.Equal(t, 2, service.calls)
+}
+
+func TestValidateToken_CancelledContext(t *testing.T) {
+	service := &countingAuthService{resp: &pb.ValidateResponse{Success: true, AuthId: 7}}
+	authClient := &AuthClient{client: service, cache: NewTokenCache(time.Minute, nil)}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	valid, _ := authClient.ValidateToken(ctx, "token")
+
+	assert.False(t, valid)
+	valid, _ = authClient.ValidateToken(context.Background(), "token")
+	assert.True(t, valid)
+}
+
+func TestTokenCache_BoundedByExpiry(t *testing.T) {
+	now := time.Now()
+	cache := NewTokenCache(time.Minute, nil)
+	cache.now = func() time.Time { return now }
+
+	token := signedToken(t, now.Add(10*time.Second))
+	cache.Set(context.Background(), token, &tkn.Token{AuthId: 7})
+
+	_, ok := cache.Get(context.Background(), token)
+	assert.True(t, ok)
+
+	now = now.Add(11 * time.Second)
+	_, ok = cache.Get(context.Background(), token)
+	assert.False(t, ok)
+}
+
+func TestTokenCache_PayloadExpiry(t *testing.T) {
+	expiry := time.Now().Add(5 * time.Second).Truncate(time.Second)
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"payload": tkn.Token{AuthId: 7, Expired: expiry},
+	}).SignedString([]byte("secret"))
+	require.NoError(t, err)
+
+	got, ok := tokenExpiry(token)
+	assert.True(t, ok)
+	assert.True(t, expiry.Equal(got))
+}
+
+func TestTokenCache_SkipsExpiredToken(t *testing.T) {
+	cache := NewTokenCache(time.Minute, nil)
+	token := signedToken(t, time.Now().Add(-time.Second))
+
+	cache.Set(context.Background(), token, &tkn.Token{AuthId: 7})
+
+	_, ok := cache.Get(context.Background(), token)
+	assert.False(t, ok)
+}
+
+func newSharedCache(t *testing.T) (*cache.Cache, *miniredis.Miniredis) {
+	server := miniredis.RunT(t)
+	redisClient := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { redisClient.Close() })
+	return cache.New(redisClient, time.Second, breaker.New(3, time.Minute)), server
+}
+
+func TestTokenCache_SharedThroughRedis(t *testing.T) {
+	shared, server := newSharedCache(t)
+
+	token := signedToken(t, time.Now().Add(time.Hour))
+	NewTokenCache(time.Minute, shared).Set(context.Background(), token, &tkn.Token{AuthId: 7, Role: "admin"})
+
+	assert.True(t, server.Exists(tokenCacheKeyPrefix+tokenCacheKey(token)))
+	assert.NotContains(t, server.Keys()[0], token)
+
+	payload, ok := NewTokenCache(time.Minute, shared).Get(context.Background(), token)
+	assert.True(t, ok)
+	assert.Equal(t, 7, payload.AuthId)
+	assert.Equal(t, "admin", payload.Role)
+}
+
+func TestTokenCache_SharedRedisFailing(t *testing.T) {
+	shared, server := newSharedCache(t)
+	server.SetError("LOADING Redis is loading the dataset in memory")
+
+	token := signedToken(t, time.Now().Add(time.Hour))
+	NewTokenCache(time.Minute, shared).Set(context.Background(), token, &tkn.Token{AuthId: 7})
+
+	_, ok := NewTokenCache(time.Minute, shared).Get(context.Background(), token)
+	assert.False(t, ok)
+}
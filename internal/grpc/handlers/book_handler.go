@@ -2,30 +2,196 @@ package handlers
 
 import (
 	"context"
+	"library-api-book/internal/commons/response"
+	"library-api-book/internal/events"
+	"library-api-book/internal/models"
+	"library-api-book/internal/params"
 	"library-api-book/internal/services"
 	pb "library-api-book/proto/book"
+
+	"github.com/go-playground/validator/v10"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
+var validate = validator.New()
+
 type BookHandler struct {
 	service services.BookService
+	stock   *events.StockBroker
 	pb.UnimplementedBookServiceServer
 }
 
-func NewBookHandler(service services.BookService) *BookHandler {
-	return &BookHandler{service: service}
+func NewBookHandler(service services.BookService, stock *events.StockBroker) *BookHandler {
+	return &BookHandler{service: service, stock: stock}
 }
 
 func (handler *BookHandler) DecreaseStock(ctx context.Context, req *pb.DecreaseStockRequest) (*pb.DecreaseStockResponse, error) {
 	err := handler.service.DecreaseStock(ctx, req.BookId)
 	if err != nil {
-		return &pb.DecreaseStockResponse{Success: false, Message: err.Message}, nil
+		return nil, statusError(err)
 	}
 	return &pb.DecreaseStockResponse{Success: true, Message: "Book stock decrease successfully"}, nil
 }
 func (handler *BookHandler) IncreaseStock(ctx context.Context, req *pb.IncreaseStockRequest) (*pb.IncreaseStockResponse, error) {
 	err := handler.service.IncreaseStock(ctx, req.BookId)
 	if err != nil {
-		return &pb.IncreaseStockResponse{Success: false, Message: err.Message}, nil
+		return nil, statusError(err)
 	}
 	return &pb.IncreaseStockResponse{Success: true, Message: "Book stock increase successfully"}, nil
 }
+
+func (handler *BookHandler) GetBook(ctx context.Context, req *pb.GetBookRequest) (*pb.Book, error) {
+	book, err := handler.service.GetDetailBook(ctx, req.Id)
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return toPbBook(book), nil
+}
+
+func (handler *BookHandler) BatchGetBooks(ctx context.Context, req *pb.BatchGetBooksRequest) (*pb.BatchGetBooksResponse, error) {
+	books, err := handler.service.GetBooksByIDs(ctx, req.Ids)
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return &pb.BatchGetBooksResponse{Books: toPbBooks(books)}, nil
+}
+
+func (handler *BookHandler) ListBooks(ctx context.Context, req *pb.ListBooksRequest) (*pb.ListBooksResponse, error) {
+	filter := &models.BookFilter{AuthorID: req.AuthorId, InStock: req.InStock}
+	return handler.listBooks(ctx, req.PageSize, req.PageToken, filter)
+}
+
+func (handler *BookHandler) SearchBooks(ctx context.Context, req *pb.SearchBooksRequest) (*pb.ListBooksResponse, error) {
+	if req.Query == "" {
+		return nil, statusError(response.BadRequestError("query is required"))
+	}
+	filter := &models.BookFilter{Search: req.Query, AuthorID: req.AuthorId, InStock: req.InStock}
+	return handler.listBooks(ctx, req.PageSize, req.PageToken, filter)
+}
+
+func (handler *BookHandler) listBooks(ctx context.Context, pageSize int32, token string, filter *models.BookFilter) (*pb.ListBooksResponse, error) {
+	page, query, err := pagination(pageSize, token, filter)
+	if err != nil {
+		return nil, statusError(response.BadRequestError(err.Error()))
+	}
+
+	books, custErr := handler.service.GetAllBooks(ctx, page, filter)
+	if custErr != nil {
+		return nil, statusError(custErr)
+	}
+
+	resp := &pb.ListBooksResponse{Books: toPbBooks(books)}
+	// A full page may be followed by more books; the next page is empty at
+	// worst.
+	if len(books) == page.PageSize {
+		resp.NextPageToken = encodePageToken(page.Page+1, query)
+	}
+	return resp, nil
+}
+
+func (handler *BookHandler) CreateBook(ctx context.Context, req *pb.CreateBookRequest) (*pb.Book, error) {
+	bookRequest, custErr := validBookRequest(req.Book)
+	if custErr != nil {
+		return nil, statusError(custErr)
+	}
+
+	book, custErr := handler.service.CreateBook(ctx, bookRequest)
+	if custErr != nil {
+		return nil, statusError(custErr)
+	}
+	return toPbBook(book), nil
+}
+
+func (handler *BookHandler) UpdateBook(ctx context.Context, req *pb.UpdateBookRequest) (*pb.Book, error) {
+	// Like the If-Match header of the REST API, the version is required so an
+	// update never silently overwrites a change the caller has not seen.
+	if req.Version == 0 {
+		return nil, statusError(response.PreconditionRequiredError("version of the book is required"))
+	}
+	// The update replaces the whole book, so a field left out must not be
+	// cleared to its zero value.
+	if req.Book != nil && (req.Book.Isbn == nil || req.Book.Publisher == nil || req.Book.Stock == nil) {
+		return nil, statusError(response.BadRequestError("isbn, publisher and stock are required to update a book"))
+	}
+	bookRequest, custErr := validBookRequest(req.Book)
+	if custErr != nil {
+		return nil, statusError(custErr)
+	}
+
+	book, custErr := handler.service.UpdateBook(ctx, req.Id, []int32{req.Version}, bookRequest)
+	if custErr != nil {
+		return nil, statusError(custErr)
+	}
+	return toPbBook(book), nil
+}
+
+func (handler *BookHandler) DeleteBook(ctx context.Context, req *pb.DeleteBookRequest) (*pb.DeleteBookResponse, error) {
+	if err := handler.service.DeleteBook(ctx, req.Id); err != nil {
+		return nil, statusError(err)
+	}
+	return &pb.DeleteBookResponse{}, nil
+}
+
+func (handler *BookHandler) WatchStock(req *pb.WatchStockRequest, stream pb.BookService_WatchStockServer) error {
+	ctx := stream.Context()
+
+	// Subscribe before reading the current stock so that no change made in
+	// between is lost; events older than what was sent are skipped below.
+	subscription := handler.stock.Subscribe(req.BookIds)
+	defer subscription.Close()
+
+	books, custErr := handler.service.GetBooksByIDs(ctx, req.BookIds)
+	if custErr != nil {
+		return statusError(custErr)
+	}
+
+	versions := make(map[uint64]int32, len(books))
+	for _, book := range books {
+		if _, sent := versions[book.ID]; sent {
+			continue
+		}
+		versions[book.ID] = book.Version
+		if err := stream.Send(&pb.StockEvent{
+			BookId:    book.ID,
+			Stock:     book.Stock,
+			Version:   book.Version,
+			ChangedAt: timestamppb.New(book.UpdatedAt),
+		}); err != nil {
+			return err
+		}
+	}
+
+	for {
+		stockEvents, err := subscription.Next(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return status.FromContextError(ctx.Err()).Err()
+			}
+			return status.Error(codes.Unavailable, err.Error())
+		}
+
+		for _, event := range stockEvents {
+			if event.Version <= versions[event.BookID] {
+				continue
+			}
+			versions[event.BookID] = event.Version
+			if err := stream.Send(toPbStockEvent(event)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// validBookRequest converts and validates the book of a write request.
+func validBookRequest(input *pb.BookInput) (*params.BookRequest, *response.CustomError) {
+	req := toBookRequest(input)
+	if req == nil {
+		return nil, response.BadRequestError("book is required")
+	}
+	if err := validate.Struct(req); err != nil {
+		return nil, response.BadRequestError("Invalid book: " + err.Error())
+	}
+	return req, nil
+}
@@ -0,0 +1,374 @@
+package handlers
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"library-api-book/internal/commons/response"
+	"library-api-book/internal/events"
+	"library-api-book/internal/logger"
+	"library-api-book/internal/models"
+	"library-api-book/internal/params"
+	"library-api-book/internal/services"
+	pb "library-api-book/proto/book"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+)
+
+type stubBookService struct {
+	services.BookService
+	err   *response.CustomError
+	books []*params.BookResponse
+
+	pagination *models.Pagination
+	filter     *models.BookFilter
+	ids        []uint64
+	request    *params.BookRequest
+	versions   []int32
+}
+
+func (s *stubBookService) GetDetailBook(ctx context.Context, id uint64) (*params.BookResponse, *response.CustomError) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return s.books[0], nil
+}
+
+func (s *stubBookService) GetBooksByIDs(ctx context.Context, ids []uint64) ([]*params.BookResponse, *response.CustomError) {
+	s.ids = ids
+	return s.books, s.err
+}
+
+func (s *stubBookService) GetAllBooks(ctx context.Context, pagination *models.Pagination, filter *models.BookFilter) ([]*params.BookResponse, *response.CustomError) {
+	s.pagination, s.filter = pagination, filter
+	return s.books, s.err
+}
+
+func (s *stubBookService) CreateBook(ctx context.Context, req *params.BookRequest) (*params.BookResponse, *response.CustomError) {
+	s.request = req
+	if s.err != nil {
+		return nil, s.err
+	}
+	return s.books[0], nil
+}
+
+func (s *stubBookService) UpdateBook(ctx context.Context, id uint64, versions []int32, req *params.BookRequest) (*params.BookResponse, *response.CustomError) {
+	s.request, s.versions = req, versions
+	if s.err != nil {
+		return nil, s.err
+	}
+	return s.books[0], nil
+}
+
+func (s *stubBookService) DeleteBook(ctx context.Context, id uint64) *response.CustomError {
+	return s.err
+}
+
+func (s *stubBookService) DecreaseStock(ctx context.Context, bookID uint64) *response.CustomError {
+	return s.err
+}
+
+func (s *stubBookService) IncreaseStock(ctx context.Context, bookID uint64) *response.CustomError {
+	return s.err
+}
+
+func TestDecreaseStock_Success(t *testing.T) {
+	handler := NewBookHandler(&stubBookService{}, nil)
+
+	resp, err := handler.DecreaseStock(context.Background(), &pb.DecreaseStockRequest{BookId: 1})
+
+	require.NoError(t, err)
+	assert.True(t, resp.Success)
+	assert.NotEmpty(t, resp.Message)
+}
+
+func TestDecreaseStock_StatusCodes(t *testing.T) {
+	for _, tc := range []struct {
+		err  *response.CustomError
+		code codes.Code
+	}{
+		{err: response.NotFoundError("Book not found"), code: codes.NotFound},
+		{err: response.ConflictError("Book is out of stock"), code: codes.FailedPrecondition},
+		{err: response.PreconditionFailedError("Book was modified concurrently"), code: codes.Aborted},
+		{err: response.GeneralError("Failed to update book stock"), code: codes.Internal},
+		{err: response.ServiceUnavailableError("Failed to connect to the database"), code: codes.Unavailable},
+	} {
+		handler := NewBookHandler(&stubBookService{err: tc.err}, nil)
+
+		resp, err := handler.DecreaseStock(context.Background(), &pb.DecreaseStockRequest{BookId: 1})
+
+		assert.Nil(t, resp)
+		st := status.Convert(err)
+		assert.Equal(t, tc.code, st.Code(), tc.err.Message)
+		assert.Equal(t, tc.err.Message, st.Message())
+
+		require.Len(t, st.Details(), 1)
+		info, ok := st.Details()[0].(*errdetails.ErrorInfo)
+		require.True(t, ok)
+		assert.Equal(t, tc.err.Code, info.Reason)
+		assert.Equal(t, errorDomain, info.Domain)
+	}
+}
+
+func TestIncreaseStock_NotFound(t *testing.T) {
+	handler := NewBookHandler(&stubBookService{err: response.NotFoundError("Book not found")}, nil)
+
+	_, err := handler.IncreaseStock(context.Background(), &pb.IncreaseStockRequest{BookId: 1})
+
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func startHandlerServer(t *testing.T, service services.BookService) pb.BookServiceClient {
+	return serveBookHandler(t, NewBookHandler(service, nil))
+}
+
+func serveBookHandler(t *testing.T, handler *BookHandler) pb.BookServiceClient {
+	server := grpc.NewServer()
+	pb.RegisterBookServiceServer(server, handler)
+
+	listener := bufconn.Listen(1 << 20)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return pb.NewBookServiceClient(conn)
+}
+
+func sampleBooks(n int) []*params.BookResponse {
+	books := make([]*params.BookResponse, n)
+	for i := range books {
+		books[i] = &params.BookResponse{
+			ID:        uint64(i + 1),
+			AuthorID:  2,
+			Title:     "Book",
+			Stock:     3,
+			PublishAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+			Version:   4,
+			Cover:     &params.CoverResponse{Small: "/api/v1/covers/books/1/ab/small.png"},
+		}
+	}
+	return books
+}
+
+func TestGetBook(t *testing.T) {
+	client := startHandlerServer(t, &stubBookService{books: sampleBooks(1)})
+
+	book, err := client.GetBook(context.Background(), &pb.GetBookRequest{Id: 1})
+
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), book.Id)
+	assert.Equal(t, int32(4), book.Version)
+	assert.Equal(t, "/api/v1/covers/books/1/ab/small.png", book.Cover.Small)
+	assert.True(t, book.PublishAt.AsTime().Equal(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)))
+}
+
+func TestGetBook_NotFound(t *testing.T) {
+	client := startHandlerServer(t, &stubBookService{err: response.NotFoundError("Book not found")})
+
+	_, err := client.GetBook(context.Background(), &pb.GetBookRequest{Id: 1})
+
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestBatchGetBooks(t *testing.T) {
+	service := &stubBookService{books: sampleBooks(2)}
+	client := startHandlerServer(t, service)
+
+	resp, err := client.BatchGetBooks(context.Background(), &pb.BatchGetBooksRequest{Ids: []uint64{2, 1}})
+
+	require.NoError(t, err)
+	assert.Len(t, resp.Books, 2)
+	assert.Equal(t, []uint64{2, 1}, service.ids)
+}
+
+func TestListBooks_PageTokens(t *testing.T) {
+	service := &stubBookService{books: sampleBooks(2)}
+	client := startHandlerServer(t, service)
+
+	first, err := client.ListBooks(context.Background(), &pb.ListBooksRequest{PageSize: 2, AuthorId: 2, InStock: true})
+	require.NoError(t, err)
+	assert.Len(t, first.Books, 2)
+	assert.Equal(t, &models.BookFilter{AuthorID: 2, InStock: true}, service.filter)
+	assert.Equal(t, 1, service.pagination.Page)
+	require.NotEmpty(t, first.NextPageToken)
+
+	service.books = sampleBooks(1)
+	second, err := client.ListBooks(context.Background(), &pb.ListBooksRequest{PageSize: 2, AuthorId: 2, InStock: true, PageToken: first.NextPageToken})
+	require.NoError(t, err)
+	assert.Equal(t, 2, service.pagination.Page)
+	assert.Empty(t, second.NextPageToken)
+}
+
+func TestListBooks_TokenForOtherFilter(t *testing.T) {
+	client := startHandlerServer(t, &stubBookService{books: sampleBooks(2)})
+
+	first, err := client.ListBooks(context.Background(), &pb.ListBooksRequest{PageSize: 2})
+	require.NoError(t, err)
+
+	_, err = client.ListBooks(context.Background(), &pb.ListBooksRequest{PageSize: 2, AuthorId: 9, PageToken: first.NextPageToken})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = client.ListBooks(context.Background(), &pb.ListBooksRequest{PageToken: "not-a-token"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestListBooks_DefaultPageSize(t *testing.T) {
+	service := &stubBookService{}
+	client := startHandlerServer(t, service)
+
+	resp, err := client.ListBooks(context.Background(), &pb.ListBooksRequest{})
+
+	require.NoError(t, err)
+	assert.Equal(t, defaultPageSize, service.pagination.PageSize)
+	assert.Empty(t, resp.NextPageToken)
+}
+
+func TestSearchBooks(t *testing.T) {
+	service := &stubBookService{books: sampleBooks(1)}
+	client := startHandlerServer(t, service)
+
+	_, err := client.SearchBooks(context.Background(), &pb.SearchBooksRequest{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	resp, err := client.SearchBooks(context.Background(), &pb.SearchBooksRequest{Query: "go"})
+	require.NoError(t, err)
+	assert.Len(t, resp.Books, 1)
+	assert.Equal(t, "go", service.filter.Search)
+}
+
+func TestCreateBook(t *testing.T) {
+	service := &stubBookService{books: sampleBooks(1)}
+	client := startHandlerServer(t, service)
+
+	book, err := client.CreateBook(context.Background(), &pb.CreateBookRequest{Book: &pb.BookInput{AuthorId: 2, Title: "Book", Stock: proto.Int32(3)}})
+
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), book.Id)
+	assert.Equal(t, &params.BookRequest{AuthorID: 2, Title: "Book", Stock: 3}, service.request)
+}
+
+func TestCreateBook_Invalid(t *testing.T) {
+	service := &stubBookService{books: sampleBooks(1)}
+	client := startHandlerServer(t, service)
+
+	_, err := client.CreateBook(context.Background(), &pb.CreateBookRequest{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = client.CreateBook(context.Background(), &pb.CreateBookRequest{Book: &pb.BookInput{AuthorId: 2, Stock: proto.Int32(-1)}})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.Nil(t, service.request)
+}
+
+func TestUpdateBook(t *testing.T) {
+	service := &stubBookService{books: sampleBooks(1)}
+	client := startHandlerServer(t, service)
+
+	_, err := client.UpdateBook(context.Background(), &pb.UpdateBookRequest{Id: 1, Version: 4, Book: fullBookInput()})
+
+	require.NoError(t, err)
+	assert.Equal(t, []int32{4}, service.versions)
+	assert.Equal(t, &params.BookRequest{AuthorID: 2, Title: "Book"}, service.request)
+}
+
+func TestUpdateBook_VersionRequired(t *testing.T) {
+	service := &stubBookService{books: sampleBooks(1)}
+	client := startHandlerServer(t, service)
+
+	_, err := client.UpdateBook(context.Background(), &pb.UpdateBookRequest{Id: 1, Book: fullBookInput()})
+
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	assert.Nil(t, service.request)
+}
+
+func TestUpdateBook_MissingFields(t *testing.T) {
+	service := &stubBookService{books: sampleBooks(1)}
+	client := startHandlerServer(t, service)
+
+	_, err := client.UpdateBook(context.Background(), &pb.UpdateBookRequest{Id: 1, Version: 4, Book: &pb.BookInput{AuthorId: 2, Title: "Book"}})
+
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.Nil(t, service.request)
+}
+
+// fullBookInput sets every field, with stock explicitly 0, as a full update
+// requires.
+func fullBookInput() *pb.BookInput {
+	return &pb.BookInput{AuthorId: 2, Title: "Book", Isbn: proto.String(""), Publisher: proto.String(""), Stock: proto.Int32(0)}
+}
+
+func TestUpdateBook_VersionConflict(t *testing.T) {
+	client := startHandlerServer(t, &stubBookService{err: response.PreconditionFailedError("Book has been modified")})
+
+	_, err := client.UpdateBook(context.Background(), &pb.UpdateBookRequest{Id: 1, Version: 3, Book: fullBookInput()})
+
+	assert.Equal(t, codes.Aborted, status.Code(err))
+}
+
+func TestDeleteBook_Forbidden(t *testing.T) {
+	client := startHandlerServer(t, &stubBookService{err: response.ForbiddenError("Authors can only modify their own books")})
+
+	_, err := client.DeleteBook(context.Background(), &pb.DeleteBookRequest{Id: 1})
+
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
+
+func TestWatchStock(t *testing.T) {
+	broker := events.NewStockBroker(nil, time.Second, logger.NopLogger{})
+	client := serveBookHandler(t, NewBookHandler(&stubBookService{books: sampleBooks(1)}, broker))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream, err := client.WatchStock(ctx, &pb.WatchStockRequest{BookIds: []uint64{1}})
+	require.NoError(t, err)
+
+	initial, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), initial.BookId)
+	assert.Equal(t, int32(3), initial.Stock)
+	assert.Equal(t, int32(4), initial.Version)
+
+	now := time.Now()
+	broker.Publish(ctx, events.StockEvent{BookID: 1, Stock: 2, Version: 5, ChangedAt: now})
+	changed, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), changed.Stock)
+	assert.Equal(t, int32(5), changed.Version)
+
+	// Stale versions and other books are not delivered.
+	broker.Publish(ctx, events.StockEvent{BookID: 1, Stock: 9, Version: 4, ChangedAt: now})
+	broker.Publish(ctx, events.StockEvent{BookID: 2, Stock: 9, Version: 9, ChangedAt: now})
+	broker.Publish(ctx, events.StockEvent{BookID: 1, Stock: 0, Version: 6, ChangedAt: now})
+	changed, err = stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, int32(0), changed.Stock)
+	assert.Equal(t, int32(6), changed.Version)
+}
+
+func TestWatchStock_UnknownBook(t *testing.T) {
+	broker := events.NewStockBroker(nil, time.Second, logger.NopLogger{})
+	client := serveBookHandler(t, NewBookHandler(&stubBookService{err: response.NotFoundError("Books not found: 7")}, broker))
+
+	stream, err := client.WatchStock(context.Background(), &pb.WatchStockRequest{BookIds: []uint64{7}})
+	require.NoError(t, err)
+
+	_, err = stream.Recv()
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"library-api-book/internal/events"
+	"library-api-book/internal/params"
+	pb "library-api-book/proto/book"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func toPbBook(book *params.BookResponse) *pb.Book {
+	message := &pb.Book{
+		Id:        book.ID,
+		AuthorId:  book.AuthorID,
+		Title:     book.Title,
+		Isbn:      book.ISBN,
+		Publisher: book.Publisher,
+		Stock:     book.Stock,
+		PublishAt: timestamppb.New(book.PublishAt),
+		UpdatedAt: timestamppb.New(book.UpdatedAt),
+		Version:   book.Version,
+	}
+	if book.Cover != nil {
+		message.Cover = &pb.Cover{
+			Original: book.Cover.Original,
+			Small:    book.Cover.Small,
+			Medium:   book.Cover.Medium,
+			Large:    book.Cover.Large,
+		}
+	}
+	return message
+}
+
+func toPbBooks(books []*params.BookResponse) []*pb.Book {
+	messages := make([]*pb.Book, len(books))
+	for i, book := range books {
+		messages[i] = toPbBook(book)
+	}
+	return messages
+}
+
+func toBookRequest(input *pb.BookInput) *params.BookRequest {
+	if input == nil {
+		return nil
+	}
+	return &params.BookRequest{
+		AuthorID:  input.AuthorId,
+		Title:     input.Title,
+		ISBN:      input.GetIsbn(),
+		Publisher: input.GetPublisher(),
+		Stock:     input.GetStock(),
+	}
+}
+
+func toPbStockEvent(event events.StockEvent) *pb.StockEvent {
+	return &pb.StockEvent{
+		BookId:    event.BookID,
+		Stock:     event.Stock,
+		Version:   event.Version,
+		ChangedAt: timestamppb.New(event.ChangedAt),
+	}
+}
@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"strconv"
+
+	"library-api-book/internal/commons/response"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// errorDomain identifies this service in the ErrorInfo attached to errors.
+const errorDomain = "library-api-book"
+
+// errorCodes maps the codes of response.CustomError to gRPC status codes.
+var errorCodes = map[string]codes.Code{
+	"ERR0001": codes.Internal,
+	"ERR0002": codes.Internal,
+	"ERR0003": codes.NotFound,
+	"ERR0004": codes.Unauthenticated,
+	"ERR0005": codes.InvalidArgument,
+	"ERR0006": codes.FailedPrecondition,
+	"ERR0007": codes.Aborted,
+	"ERR0008": codes.FailedPrecondition,
+	"ERR0009": codes.PermissionDenied,
+	"ERR0010": codes.Unavailable,
+	"ERR0011": codes.ResourceExhausted,
+	"ERR0012": codes.InvalidArgument,
+}
+
+// statusError converts a service error to a gRPC status. The internal error
+// code and HTTP status travel in an ErrorInfo detail so callers need not
+// parse the message.
+func statusError(custErr *response.CustomError) error {
+	code, ok := errorCodes[custErr.Code]
+	if !ok {
+		code = codes.Unknown
+	}
+
+	st := status.New(code, custErr.Message)
+	detailed, err := st.WithDetails(&errdetails.ErrorInfo{
+		Reason: custErr.Code,
+		Domain: errorDomain,
+		Metadata: map[string]string{
+			"status_code": strconv.Itoa(custErr.StatusCode),
+		},
+	})
+	if err != nil {
+		return st.Err()
+	}
+	return detailed.Err()
+}
@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"library-api-book/internal/models"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+var errInvalidPageToken = errors.New("page_token is invalid or does not match the request")
+
+// pageToken is the opaque cursor returned as next_page_token. It records the
+// query it was issued for so a token cannot be replayed against a different
+// filter or page size.
+type pageToken struct {
+	Page  int    `json:"p"`
+	Query string `json:"q"`
+}
+
+// listQuery identifies a list request apart from its page.
+func listQuery(filter *models.BookFilter, pageSize int) string {
+	return fmt.Sprintf("%d|%d|%t|%s", pageSize, filter.AuthorID, filter.InStock, filter.Search)
+}
+
+func encodePageToken(page int, query string) string {
+	data, _ := json.Marshal(pageToken{Page: page, Query: query})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// pagination resolves page_size and page_token to the page to fetch.
+func pagination(pageSize int32, token string, filter *models.BookFilter) (*models.Pagination, string, error) {
+	size := int(pageSize)
+	switch {
+	case size < 0:
+		return nil, "", errors.New("page_size must not be negative")
+	case size == 0:
+		size = defaultPageSize
+	case size > maxPageSize:
+		size = maxPageSize
+	}
+	query := listQuery(filter, size)
+
+	page := 1
+	if token != "" {
+		data, err := base64.RawURLEncoding.DecodeString(token)
+		if err != nil {
+			return nil, "", errInvalidPageToken
+		}
+		var decoded pageToken
+		if err := json.Unmarshal(data, &decoded); err != nil || decoded.Page < 2 || decoded.Query != query {
+			return nil, "", errInvalidPageToken
+		}
+		page = decoded.Page
+	}
+
+	return &models.Pagination{Page: page, PageSize: size}, query, nil
+}
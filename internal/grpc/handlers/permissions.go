@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"library-api-book/internal/permission"
+	pb "library-api-book/proto/book"
+)
+
+// MethodPermissions lists the permission each BookService RPC requires.
+// Methods missing here are rejected by the auth interceptor.
+var MethodPermissions = map[string]permission.Permission{
+	pb.BookService_DecreaseStock_FullMethodName: permission.StockAdjust,
+	pb.BookService_IncreaseStock_FullMethodName: permission.StockAdjust,
+	pb.BookService_GetBook_FullMethodName:       permission.BooksRead,
+	pb.BookService_BatchGetBooks_FullMethodName: permission.BooksRead,
+	pb.BookService_ListBooks_FullMethodName:     permission.BooksRead,
+	pb.BookService_SearchBooks_FullMethodName:   permission.BooksRead,
+	pb.BookService_CreateBook_FullMethodName:    permission.BooksWrite,
+	pb.BookService_UpdateBook_FullMethodName:    permission.BooksWrite,
+	pb.BookService_DeleteBook_FullMethodName:    permission.BooksWrite,
+	pb.BookService_WatchStock_FullMethodName:    permission.BooksRead,
+}
+
+// MethodRateLimitGroups puts the listing and search RPCs in the "search"
+// rate limit group, like their REST routes. Other methods use the default
+// group.
+var MethodRateLimitGroups = map[string]string{
+	pb.BookService_ListBooks_FullMethodName:   "search",
+	pb.BookService_SearchBooks_FullMethodName: "search",
+}
+
+// AuditedMethods lists the RPCs that change books. Like REST writes, every
+// call to them is recorded in the audit log.
+var AuditedMethods = map[string]bool{
+	pb.BookService_DecreaseStock_FullMethodName: true,
+	pb.BookService_IncreaseStock_FullMethodName: true,
+	pb.BookService_CreateBook_FullMethodName:    true,
+	pb.BookService_UpdateBook_FullMethodName:    true,
+	pb.BookService_DeleteBook_FullMethodName:    true,
+}
@@ -0,0 +1,147 @@
+package interceptors
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"library-api-book/internal/models"
+	"library-api-book/internal/services"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Auditor records the methods that change books, and every call rejected by
+// authentication or authorization, in the audit log like the REST Audit
+// middleware. It must run before the Authorizer so it sees rejected calls;
+// the Authorizer reports the caller back through the principal slot.
+type Auditor struct {
+	service services.AuditService
+	methods map[string]bool
+}
+
+// NewAuditor records every call to methods, given by full method name.
+// Other methods are only recorded when the caller is rejected.
+func NewAuditor(service services.AuditService, methods map[string]bool) *Auditor {
+	return &Auditor{service: service, methods: methods}
+}
+
+func (auditor *Auditor) Unary() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, principal := principalSlot(ctx)
+		resp, err := handler(ctx, req)
+		if auditor.methods[info.FullMethod] || isAuthFailure(status.Code(err)) {
+			auditor.record(ctx, info.FullMethod, auditTarget(req, resp), principal, err)
+		}
+		return resp, err
+	}
+}
+
+func (auditor *Auditor) Stream() grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, principal := principalSlot(stream.Context())
+		err := handler(srv, &contextStream{ServerStream: stream, ctx: ctx})
+		if auditor.methods[info.FullMethod] || isAuthFailure(status.Code(err)) {
+			auditor.record(ctx, info.FullMethod, "", principal, err)
+		}
+		return err
+	}
+}
+
+func (auditor *Auditor) record(ctx context.Context, method, target string, principal *Principal, err error) {
+	st := status.Convert(err)
+	log := &models.AuditLog{
+		OccurredAt: time.Now(),
+		Role:       principal.Role,
+		Action:     method,
+		Target:     target,
+		IP:         peerHost(ctx),
+		Outcome:    auditOutcome(st.Code()),
+		StatusCode: auditStatusCode(st),
+	}
+	if log.Outcome == models.AuditOutcomeDenied {
+		log.Detail = st.Message()
+	} else if principal.Method != "" && principal.Method != "user-token" {
+		log.Detail = principal.Method + " " + principal.Name
+	}
+	if principal.AuthID > 0 {
+		actorID := uint64(principal.AuthID)
+		log.ActorID = &actorID
+	}
+	log.Truncate()
+
+	// The call has already returned, so a client that hangs up must not
+	// cancel the insert.
+	auditor.service.Record(context.WithoutCancel(ctx), log)
+}
+
+// principalSlot returns the slot the Authorizer reports the caller to,
+// adding one when the logging interceptor has not.
+func principalSlot(ctx context.Context) (context.Context, *Principal) {
+	if slot, ok := ctx.Value(principalSlotKey{}).(*Principal); ok {
+		return ctx, slot
+	}
+	slot := &Principal{}
+	return withPrincipalSlot(ctx, slot), slot
+}
+
+// auditTarget names the book a call is about, taken from the request or, for
+// a created book, from the response.
+func auditTarget(req, resp interface{}) string {
+	for _, message := range []interface{}{req, resp} {
+		var id uint64
+		switch message := message.(type) {
+		case interface{ GetBookId() uint64 }:
+			id = message.GetBookId()
+		case interface{ GetId() uint64 }:
+			id = message.GetId()
+		}
+		if id != 0 {
+			return "books/" + strconv.FormatUint(id, 10)
+		}
+	}
+	return ""
+}
+
+func isAuthFailure(code codes.Code) bool {
+	return code == codes.Unauthenticated || code == codes.PermissionDenied
+}
+
+func auditOutcome(code codes.Code) string {
+	switch {
+	case isAuthFailure(code):
+		return models.AuditOutcomeDenied
+	case code != codes.OK:
+		return models.AuditOutcomeFailure
+	default:
+		return models.AuditOutcomeSuccess
+	}
+}
+
+// auditStatusCode gives the HTTP status the REST API would have answered
+// with, which service errors carry in their ErrorInfo.
+func auditStatusCode(st *status.Status) int {
+	for _, detail := range st.Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok {
+			if code, err := strconv.Atoi(info.Metadata["status_code"]); err == nil {
+				return code
+			}
+		}
+	}
+	switch st.Code() {
+	case codes.OK:
+		return http.StatusOK
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,101 @@
+package interceptors
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+
+	"library-api-book/internal/commons/response"
+	"library-api-book/internal/models"
+	"library-api-book/internal/params"
+	pb "library-api-book/proto/book"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type recordingAuditService struct {
+	mu   sync.Mutex
+	logs []*models.AuditLog
+}
+
+func (s *recordingAuditService) Record(ctx context.Context, log *models.AuditLog) *response.CustomError {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.logs = append(s.logs, log)
+	return nil
+}
+
+func (s *recordingAuditService) GetAuditLogs(ctx context.Context, filter *models.AuditLogFilter, pagination *models.Pagination) ([]*params.AuditLogResponse, *response.CustomError) {
+	return nil, nil
+}
+
+func (s *recordingAuditService) ExportAuditLogs(ctx context.Context, filter *models.AuditLogFilter, fn func(*params.AuditLogResponse) error) *response.CustomError {
+	return nil
+}
+
+func startAuditedBookServer(t *testing.T) (pb.BookServiceClient, *recordingAuditService) {
+	authorizer := newTestAuthorizer(t)
+	audit := &recordingAuditService{}
+	auditor := NewAuditor(audit, map[string]bool{pb.BookService_DecreaseStock_FullMethodName: true})
+
+	log := &recordingLogger{}
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(UnaryLogging(log), auditor.Unary(), UnaryRecovery(log), authorizer.Unary()),
+	)
+	return serveBookServer(t, server, &stubBookServer{}), audit
+}
+
+func TestAuditor_RecordsChanges(t *testing.T) {
+	client, audit := startAuditedBookServer(t)
+
+	_, err := client.DecreaseStock(withToken(serviceToken), &pb.DecreaseStockRequest{BookId: 5})
+	require.NoError(t, err)
+
+	require.Len(t, audit.logs, 1)
+	log := audit.logs[0]
+	assert.Nil(t, log.ActorID)
+	assert.Equal(t, "service", log.Role)
+	assert.Equal(t, pb.BookService_DecreaseStock_FullMethodName, log.Action)
+	assert.Equal(t, "books/5", log.Target)
+	assert.Equal(t, models.AuditOutcomeSuccess, log.Outcome)
+	assert.Equal(t, http.StatusOK, log.StatusCode)
+	assert.Equal(t, "service-token borrow-service", log.Detail)
+}
+
+func TestAuditor_RecordsDenials(t *testing.T) {
+	client, audit := startAuditedBookServer(t)
+
+	_, err := client.DecreaseStock(withToken("user-token"), &pb.DecreaseStockRequest{BookId: 5})
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+	_, err = client.IncreaseStock(context.Background(), &pb.IncreaseStockRequest{BookId: 5})
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	require.Len(t, audit.logs, 2)
+	denied := audit.logs[0]
+	assert.Equal(t, uint64(2), *denied.ActorID)
+	assert.Equal(t, "user", denied.Role)
+	assert.Equal(t, models.AuditOutcomeDenied, denied.Outcome)
+	assert.Equal(t, http.StatusForbidden, denied.StatusCode)
+	assert.Contains(t, denied.Detail, `does not have permission "stock:adjust"`)
+
+	unauthenticated := audit.logs[1]
+	assert.Nil(t, unauthenticated.ActorID)
+	assert.Equal(t, pb.BookService_IncreaseStock_FullMethodName, unauthenticated.Action)
+	assert.Equal(t, http.StatusUnauthorized, unauthenticated.StatusCode)
+	assert.Equal(t, "missing credentials", unauthenticated.Detail)
+}
+
+func TestAuditor_SkipsOtherMethods(t *testing.T) {
+	client, audit := startAuditedBookServer(t)
+
+	// IncreaseStock is not audited here and its handler panics.
+	_, err := client.IncreaseStock(withToken("admin-token"), &pb.IncreaseStockRequest{BookId: 5})
+	require.Equal(t, codes.Internal, status.Code(err))
+
+	assert.Empty(t, audit.logs)
+}
@@ -0,0 +1,158 @@
+package interceptors
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"library-api-book/internal/auth"
+	"library-api-book/internal/permission"
+	"library-api-book/internal/services"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// Authorizer authenticates RPC callers and checks the permission each method
+// requires. Methods missing from Methods are denied.
+type Authorizer struct {
+	identities *identityIndex
+	validator  auth.TokenValidator
+	policy     *permission.Policy
+	methods    map[string]permission.Permission
+	public     map[string]bool
+}
+
+// NewAuthorizer accepts, in order, a verified client certificate or a bearer
+// token in the "authorization" metadata that is either a service token or,
+// when validator is not nil, a user token. Every method of the public
+// services, given by full name such as "grpc.health.v1.Health", is open to
+// anyone.
+func NewAuthorizer(identities []ServiceIdentity, validator auth.TokenValidator, policy *permission.Policy, methods map[string]permission.Permission, publicServices ...string) *Authorizer {
+	public := make(map[string]bool, len(publicServices))
+	for _, service := range publicServices {
+		public[service] = true
+	}
+	return &Authorizer{
+		identities: newIdentityIndex(identities),
+		validator:  validator,
+		policy:     policy,
+		methods:    methods,
+		public:     public,
+	}
+}
+
+func (authorizer *Authorizer) Unary() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := authorizer.authorize(ctx, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+func (authorizer *Authorizer) Stream() grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := authorizer.authorize(stream.Context(), info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &contextStream{ServerStream: stream, ctx: ctx})
+	}
+}
+
+func (authorizer *Authorizer) authorize(ctx context.Context, method string) (context.Context, error) {
+	if authorizer.public[serviceName(method)] {
+		return ctx, nil
+	}
+
+	required, ok := authorizer.methods[method]
+	if !ok {
+		return nil, status.Errorf(codes.PermissionDenied, "method %s is not available", method)
+	}
+
+	principal, err := authorizer.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	reportPrincipal(ctx, principal)
+	if !authorizer.policy.Allows(principal.Role, required) {
+		return nil, status.Errorf(codes.PermissionDenied, "role %q does not have permission %q", principal.Role, required)
+	}
+	return withUser(ContextWithPrincipal(ctx, principal), principal), nil
+}
+
+// withUser stores a user caller under the same keys the REST auth middleware
+// sets, so the services apply the same per-user rules, such as authors only
+// writing their own books. Service callers are left without a user and are
+// marked as trusted instead.
+func withUser(ctx context.Context, principal *Principal) context.Context {
+	if principal.Method != "user-token" {
+		return services.WithTrustedCaller(ctx)
+	}
+	ctx = context.WithValue(ctx, "authId", principal.AuthID)
+	return context.WithValue(ctx, "role", principal.Role)
+}
+
+func (authorizer *Authorizer) authenticate(ctx context.Context) (*Principal, error) {
+	if p, ok := peer.FromContext(ctx); ok {
+		if tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo); ok && len(tlsInfo.State.VerifiedChains) > 0 {
+			if identity := authorizer.identities.certificate(tlsInfo.State.VerifiedChains[0][0]); identity != nil {
+				return &Principal{Name: identity.Name, Role: identity.Role, Method: "mtls"}, nil
+			}
+		}
+	}
+
+	token := bearerToken(ctx)
+	if token == "" {
+		return nil, status.Error(codes.Unauthenticated, "missing credentials")
+	}
+	if identity := authorizer.identities.token(token); identity != nil {
+		return &Principal{Name: identity.Name, Role: identity.Role, Method: "service-token"}, nil
+	}
+	if authorizer.validator != nil {
+		if valid, payload := authorizer.validator.ValidateToken(ctx, token); valid {
+			return &Principal{
+				Name:   strconv.Itoa(payload.AuthId),
+				Role:   payload.Role,
+				AuthID: payload.AuthId,
+				Method: "user-token",
+			}, nil
+		}
+	}
+	return nil, status.Error(codes.Unauthenticated, "invalid credentials")
+}
+
+// serviceName returns "pkg.Service" for a full method "/pkg.Service/Method".
+func serviceName(method string) string {
+	service, _, _ := strings.Cut(strings.TrimPrefix(method, "/"), "/")
+	return service
+}
+
+func bearerToken(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	for _, value := range md.Get("authorization") {
+		if token, ok := strings.CutPrefix(value, "Bearer "); ok {
+			return strings.TrimSpace(token)
+		}
+	}
+	return ""
+}
+
+// contextStream replaces the context of a server stream.
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (stream *contextStream) Context() context.Context {
+	return stream.ctx
+}
@@ -0,0 +1,121 @@
+package interceptors
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Principal is the authenticated caller of an RPC.
+type Principal struct {
+	// Name is the service name for service callers and the user ID for
+	// user tokens.
+	Name   string
+	Role   string
+	AuthID int
+	// Method is how the caller authenticated: "service-token", "mtls" or
+	// "user-token".
+	Method string
+}
+
+type principalKey struct{}
+
+func ContextWithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFromContext returns the caller stored by the auth interceptor.
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(*Principal)
+	return principal, ok
+}
+
+// ServiceIdentity is another service allowed to call this one. It can
+// authenticate with a static token, whose SHA-256 is stored here, or with a
+// client certificate whose common name or DNS/URI SAN is listed.
+type ServiceIdentity struct {
+	Name             string   `yaml:"name"`
+	Role             string   `yaml:"role"`
+	TokenSHA256      string   `yaml:"token_sha256"`
+	CertificateNames []string `yaml:"certificate_names"`
+}
+
+type identityFile struct {
+	Services []ServiceIdentity `yaml:"services"`
+}
+
+// LoadServiceIdentities reads a YAML file of the form
+//
+//	services:
+//	  - name: borrow-service
+//	    role: service
+//	    token_sha256: 9f86d081884c7d65...
+//	    certificate_names: [borrow-service.internal]
+func LoadServiceIdentities(path string) ([]ServiceIdentity, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read service identities: %w", err)
+	}
+
+	var file identityFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parse service identities: %w", err)
+	}
+	for _, identity := range file.Services {
+		if identity.Name == "" || identity.Role == "" {
+			return nil, errors.New("service identity needs a name and a role")
+		}
+		if identity.TokenSHA256 == "" && len(identity.CertificateNames) == 0 {
+			return nil, fmt.Errorf("service %q has neither a token nor certificate names", identity.Name)
+		}
+	}
+	return file.Services, nil
+}
+
+// identityIndex looks identities up by token hash and certificate name.
+type identityIndex struct {
+	byToken       map[string]*ServiceIdentity
+	byCertificate map[string]*ServiceIdentity
+}
+
+func newIdentityIndex(identities []ServiceIdentity) *identityIndex {
+	index := &identityIndex{
+		byToken:       make(map[string]*ServiceIdentity),
+		byCertificate: make(map[string]*ServiceIdentity),
+	}
+	for i := range identities {
+		identity := &identities[i]
+		if identity.TokenSHA256 != "" {
+			index.byToken[strings.ToLower(identity.TokenSHA256)] = identity
+		}
+		for _, name := range identity.CertificateNames {
+			index.byCertificate[name] = identity
+		}
+	}
+	return index
+}
+
+func (index *identityIndex) token(token string) *ServiceIdentity {
+	sum := sha256.Sum256([]byte(token))
+	return index.byToken[hex.EncodeToString(sum[:])]
+}
+
+func (index *identityIndex) certificate(cert *x509.Certificate) *ServiceIdentity {
+	names := append([]string{cert.Subject.CommonName}, cert.DNSNames...)
+	for _, uri := range cert.URIs {
+		names = append(names, uri.String())
+	}
+	for _, name := range names {
+		if identity, ok := index.byCertificate[name]; ok && name != "" {
+			return identity
+		}
+	}
+	return nil
+}
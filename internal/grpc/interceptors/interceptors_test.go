@@ -0,0 +1,219 @@
+package interceptors
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"net"
+	"net/url"
+	"sync"
+	"testing"
+
+	"library-api-book/internal/permission"
+	"library-api-book/internal/services"
+	tkn "library-api-book/pkg/token"
+	pb "library-api-book/proto/book"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const serviceToken = "borrow-service-secret"
+
+type recordingLogger struct {
+	mu      sync.Mutex
+	entries []map[string]interface{}
+}
+
+func (l *recordingLogger) record(message string, fields map[string]interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	fields["message"] = message
+	l.entries = append(l.entries, fields)
+}
+
+func (l *recordingLogger) Info(message string, fields map[string]interface{}) {
+	l.record(message, fields)
+}
+func (l *recordingLogger) Error(message string, fields map[string]interface{}) {
+	l.record(message, fields)
+}
+func (l *recordingLogger) Warn(message string, fields map[string]interface{}) {
+	l.record(message, fields)
+}
+func (l *recordingLogger) Debug(message string, fields map[string]interface{}) {
+	l.record(message, fields)
+}
+
+type stubBookServer struct {
+	pb.UnimplementedBookServiceServer
+	caller  *Principal
+	role    interface{}
+	trusted bool
+}
+
+func (s *stubBookServer) DecreaseStock(ctx context.Context, req *pb.DecreaseStockRequest) (*pb.DecreaseStockResponse, error) {
+	s.caller, _ = PrincipalFromContext(ctx)
+	s.role = ctx.Value("role")
+	s.trusted = services.TrustedCaller(ctx)
+	return &pb.DecreaseStockResponse{Success: true}, nil
+}
+
+func (s *stubBookServer) IncreaseStock(ctx context.Context, req *pb.IncreaseStockRequest) (*pb.IncreaseStockResponse, error) {
+	panic("boom")
+}
+
+type stubValidator map[string]*tkn.Token
+
+func (v stubValidator) ValidateToken(ctx context.Context, token string) (bool, *tkn.Token) {
+	payload, ok := v[token]
+	return ok, payload
+}
+
+func newTestAuthorizer(t *testing.T) *Authorizer {
+	policy, err := permission.NewPolicy(map[string][]permission.Permission{
+		"admin":   {permission.Wildcard},
+		"service": {permission.StockAdjust},
+		"user":    {permission.BooksRead},
+	})
+	require.NoError(t, err)
+
+	sum := sha256.Sum256([]byte(serviceToken))
+	identities := []ServiceIdentity{{Name: "borrow-service", Role: "service", TokenSHA256: hex.EncodeToString(sum[:])}}
+	validator := stubValidator{
+		"admin-token": {AuthId: 1, Role: "admin"},
+		"user-token":  {AuthId: 2, Role: "user"},
+	}
+	return NewAuthorizer(identities, validator, policy, map[string]permission.Permission{
+		pb.BookService_DecreaseStock_FullMethodName: permission.StockAdjust,
+		pb.BookService_IncreaseStock_FullMethodName: permission.StockAdjust,
+	})
+}
+
+func startBookServer(t *testing.T) (pb.BookServiceClient, *stubBookServer, *recordingLogger) {
+	authorizer := newTestAuthorizer(t)
+
+	log := &recordingLogger{}
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(UnaryLogging(log), UnaryRecovery(log), authorizer.Unary()),
+		grpc.ChainStreamInterceptor(StreamLogging(log), StreamRecovery(log), authorizer.Stream()),
+	)
+	books := &stubBookServer{}
+	return serveBookServer(t, server, books), books, log
+}
+
+// serveBookServer registers books on server and returns a client for it.
+func serveBookServer(t *testing.T, server *grpc.Server, books pb.BookServiceServer) pb.BookServiceClient {
+	pb.RegisterBookServiceServer(server, books)
+
+	listener := bufconn.Listen(1 << 20)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return pb.NewBookServiceClient(conn)
+}
+
+func withToken(token string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token)
+}
+
+func TestAuthorizer_MissingCredentials(t *testing.T) {
+	client, _, _ := startBookServer(t)
+
+	_, err := client.DecreaseStock(context.Background(), &pb.DecreaseStockRequest{BookId: 1})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestAuthorizer_InvalidToken(t *testing.T) {
+	client, _, _ := startBookServer(t)
+
+	_, err := client.DecreaseStock(withToken("forged"), &pb.DecreaseStockRequest{BookId: 1})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestAuthorizer_ServiceToken(t *testing.T) {
+	client, books, log := startBookServer(t)
+
+	_, err := client.DecreaseStock(withToken(serviceToken), &pb.DecreaseStockRequest{BookId: 1})
+	require.NoError(t, err)
+	assert.Equal(t, "borrow-service", books.caller.Name)
+	assert.Equal(t, "service-token", books.caller.Method)
+	assert.Nil(t, books.role)
+	assert.True(t, books.trusted)
+
+	require.Len(t, log.entries, 1)
+	assert.Equal(t, "borrow-service", log.entries[0]["principal"])
+	assert.Equal(t, "OK", log.entries[0]["code"])
+}
+
+func TestAuthorizer_UserTokens(t *testing.T) {
+	client, books, _ := startBookServer(t)
+
+	_, err := client.DecreaseStock(withToken("user-token"), &pb.DecreaseStockRequest{BookId: 1})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = client.DecreaseStock(withToken("admin-token"), &pb.DecreaseStockRequest{BookId: 1})
+	require.NoError(t, err)
+	assert.Equal(t, 1, books.caller.AuthID)
+	assert.Equal(t, "admin", books.role)
+	assert.False(t, books.trusted)
+}
+
+func TestAuthorizer_UnmappedMethod(t *testing.T) {
+	authorizer := NewAuthorizer(nil, nil, nil, map[string]permission.Permission{})
+
+	_, err := authorizer.authorize(withToken(serviceToken), "/book.BookService/DropAll")
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
+
+func TestAuthorizer_PublicService(t *testing.T) {
+	authorizer := NewAuthorizer(nil, nil, nil, map[string]permission.Permission{}, "grpc.health.v1.Health")
+
+	_, err := authorizer.authorize(context.Background(), "/grpc.health.v1.Health/Watch")
+	assert.NoError(t, err)
+
+	_, err = authorizer.authorize(context.Background(), "/grpc.health.v1.Healthy/Check")
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
+
+func TestIdentityIndex_Certificate(t *testing.T) {
+	spiffe, err := url.Parse("spiffe://library/borrow-service")
+	require.NoError(t, err)
+	index := newIdentityIndex([]ServiceIdentity{
+		{Name: "borrow-service", Role: "service", CertificateNames: []string{"spiffe://library/borrow-service"}},
+	})
+
+	identity := index.certificate(&x509.Certificate{Subject: pkix.Name{CommonName: "borrow"}, URIs: []*url.URL{spiffe}})
+	require.NotNil(t, identity)
+	assert.Equal(t, "borrow-service", identity.Name)
+
+	assert.Nil(t, index.certificate(&x509.Certificate{Subject: pkix.Name{CommonName: "other"}}))
+}
+
+func TestRecovery_ReturnsInternal(t *testing.T) {
+	client, _, log := startBookServer(t)
+
+	_, err := client.IncreaseStock(withToken(serviceToken), &pb.IncreaseStockRequest{BookId: 1})
+	assert.Equal(t, codes.Internal, status.Code(err))
+
+	require.Len(t, log.entries, 2)
+	assert.Equal(t, "[gRPC] Recovered from panic", log.entries[0]["message"])
+	assert.Equal(t, "Internal", log.entries[1]["code"])
+}
@@ -0,0 +1,79 @@
+package interceptors
+
+import (
+	"context"
+	"time"
+
+	"library-api-book/internal/logger"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryLogging logs every call with its status code and duration. It should
+// be first in the chain so it also sees calls rejected by later interceptors.
+func UnaryLogging(log logger.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		principal := &Principal{}
+		resp, err := handler(withPrincipalSlot(ctx, principal), req)
+		logCall(ctx, log, info.FullMethod, principal, start, err)
+		return resp, err
+	}
+}
+
+func StreamLogging(log logger.Logger) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		principal := &Principal{}
+		ctx := withPrincipalSlot(stream.Context(), principal)
+		err := handler(srv, &contextStream{ServerStream: stream, ctx: ctx})
+		logCall(stream.Context(), log, info.FullMethod, principal, start, err)
+		return err
+	}
+}
+
+type principalSlotKey struct{}
+
+// withPrincipalSlot lets the auth interceptor, which runs later in the chain,
+// report the caller back to the logging interceptor.
+func withPrincipalSlot(ctx context.Context, slot *Principal) context.Context {
+	return context.WithValue(ctx, principalSlotKey{}, slot)
+}
+
+func reportPrincipal(ctx context.Context, principal *Principal) {
+	if slot, ok := ctx.Value(principalSlotKey{}).(*Principal); ok {
+		*slot = *principal
+	}
+}
+
+func logCall(ctx context.Context, log logger.Logger, method string, principal *Principal, start time.Time, err error) {
+	code := status.Code(err)
+	fields := map[string]interface{}{
+		"method":      method,
+		"code":        code.String(),
+		"duration_ms": time.Since(start).Milliseconds(),
+	}
+	if p, ok := peer.FromContext(ctx); ok {
+		fields["peer"] = p.Addr.String()
+	}
+	if principal.Name != "" {
+		fields["principal"] = principal.Name
+		fields["role"] = principal.Role
+		fields["auth_method"] = principal.Method
+	}
+	if err != nil {
+		fields["error"] = status.Convert(err).Message()
+	}
+
+	switch code {
+	case codes.OK:
+		log.Info("[gRPC] Handled call", fields)
+	case codes.Internal, codes.Unknown, codes.DataLoss:
+		log.Error("[gRPC] Call failed", fields)
+	default:
+		log.Warn("[gRPC] Call failed", fields)
+	}
+}
@@ -0,0 +1,138 @@
+package interceptors
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"strings"
+
+	"library-api-book/internal/logger"
+	"library-api-book/internal/ratelimit"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// RateLimiter applies the rate limit policy to RPCs. Streams are limited when
+// they are opened. It must run after the Authorizer, whose principal
+// identifies the caller; calls without one are limited by peer address.
+type RateLimiter struct {
+	limiter *ratelimit.Limiter
+	policy  *ratelimit.Policy
+	groups  map[string]string
+	log     logger.Logger
+}
+
+// NewRateLimiter limits every method in the policy group given by groups,
+// keyed by full method name, and the rest in the default group. Calls are
+// let through when limiter is nil or Redis cannot be reached.
+func NewRateLimiter(limiter *ratelimit.Limiter, policy *ratelimit.Policy, groups map[string]string, log logger.Logger) *RateLimiter {
+	return &RateLimiter{
+		limiter: limiter,
+		policy:  policy,
+		groups:  groups,
+		log:     log,
+	}
+}
+
+func (rateLimiter *RateLimiter) Unary() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		header, err := rateLimiter.allow(ctx, info.FullMethod)
+		if header != nil {
+			grpc.SetHeader(ctx, header)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+func (rateLimiter *RateLimiter) Stream() grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		header, err := rateLimiter.allow(stream.Context(), info.FullMethod)
+		if header != nil {
+			stream.SetHeader(header)
+		}
+		if err != nil {
+			return err
+		}
+		return handler(srv, stream)
+	}
+}
+
+// allow returns the rate limit headers for the call and a ResourceExhausted
+// status carrying RetryInfo when the caller is over its limit.
+func (rateLimiter *RateLimiter) allow(ctx context.Context, method string) (metadata.MD, error) {
+	if rateLimiter.limiter == nil {
+		return nil, nil
+	}
+
+	group, ok := rateLimiter.groups[method]
+	if !ok {
+		group = ratelimit.Default
+	}
+	subject, class := rpcSubject(ctx)
+	limit, ok := rateLimiter.policy.Limit(group, class)
+	if !ok {
+		return nil, nil
+	}
+
+	result, err := rateLimiter.limiter.Allow(ctx, group+":"+subject, limit)
+	if err != nil {
+		rateLimiter.log.Warn("[gRPC] Rate limiter unavailable, call allowed", map[string]interface{}{
+			"method":  method,
+			"subject": subject,
+			"error":   err.Error(),
+		})
+		return nil, nil
+	}
+
+	header := metadata.MD{}
+	for name, value := range result.Headers() {
+		header.Set(strings.ToLower(name), value)
+	}
+	if result.Allowed {
+		return header, nil
+	}
+
+	st := status.New(codes.ResourceExhausted, "rate limit exceeded")
+	if detailed, err := st.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(result.RetryAfter)}); err == nil {
+		st = detailed
+	}
+	return header, st.Err()
+}
+
+// rpcSubject returns the bucket owner of the call and its class in the rate
+// limit policy.
+func rpcSubject(ctx context.Context) (subject, class string) {
+	if principal, ok := PrincipalFromContext(ctx); ok && principal != nil {
+		if principal.Method == "user-token" {
+			return "user:" + strconv.Itoa(principal.AuthID), principal.Role
+		}
+		return "service:" + principal.Name, principal.Role
+	}
+	if host := peerHost(ctx); host != "" {
+		return "ip:" + host, ratelimit.ClassAnonymous
+	}
+	return "ip:unknown", ratelimit.ClassAnonymous
+}
+
+// peerHost returns the address of the caller without its port, or "" when
+// it is not known.
+func peerHost(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
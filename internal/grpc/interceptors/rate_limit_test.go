@@ -0,0 +1,69 @@
+package interceptors
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"library-api-book/internal/logger"
+	"library-api-book/internal/ratelimit"
+	pb "library-api-book/proto/book"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func newTestRateLimiter(t *testing.T) (*RateLimiter, *miniredis.Miniredis) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr(), MaxRetries: -1})
+	t.Cleanup(func() { client.Close() })
+
+	policy, err := ratelimit.NewPolicy(map[string]map[string]ratelimit.Limit{
+		ratelimit.Default: {ratelimit.Default: {Requests: 100, Per: time.Minute}, "service": {}},
+		"search":          {ratelimit.Default: {Requests: 1, Per: time.Minute}},
+	})
+	require.NoError(t, err)
+
+	groups := map[string]string{pb.BookService_SearchBooks_FullMethodName: "search"}
+	return NewRateLimiter(ratelimit.NewLimiter(client, time.Second), policy, groups, logger.NopLogger{}), server
+}
+
+func TestRateLimiter_ResourceExhausted(t *testing.T) {
+	rateLimiter, _ := newTestRateLimiter(t)
+	ctx := ContextWithPrincipal(context.Background(), &Principal{Name: "2", Role: "user", AuthID: 2, Method: "user-token"})
+
+	header, err := rateLimiter.allow(ctx, pb.BookService_SearchBooks_FullMethodName)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"0"}, header.Get("ratelimit-remaining"))
+
+	// Methods outside the group use the default bucket.
+	_, err = rateLimiter.allow(ctx, pb.BookService_GetBook_FullMethodName)
+	require.NoError(t, err)
+
+	header, err = rateLimiter.allow(ctx, pb.BookService_SearchBooks_FullMethodName)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.Equal(t, []string{"60"}, header.Get("retry-after"))
+
+	details := status.Convert(err).Details()
+	require.Len(t, details, 1)
+	assert.InDelta(t, time.Minute.Seconds(), details[0].(*errdetails.RetryInfo).RetryDelay.AsDuration().Seconds(), 1)
+}
+
+func TestRateLimiter_ServiceUnlimitedAndFailOpen(t *testing.T) {
+	rateLimiter, server := newTestRateLimiter(t)
+	service := ContextWithPrincipal(context.Background(), &Principal{Name: "borrow-service", Role: "service", Method: "service-token"})
+
+	for i := 0; i < 3; i++ {
+		_, err := rateLimiter.allow(service, pb.BookService_SearchBooks_FullMethodName)
+		require.NoError(t, err)
+	}
+
+	server.Close()
+	_, err := rateLimiter.allow(context.Background(), pb.BookService_SearchBooks_FullMethodName)
+	assert.NoError(t, err)
+}
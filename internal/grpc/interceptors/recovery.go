@@ -0,0 +1,45 @@
+package interceptors
+
+import (
+	"context"
+	"runtime/debug"
+
+	"library-api-book/internal/logger"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryRecovery turns a panic in a handler into an Internal error instead of
+// crashing the server.
+func UnaryRecovery(log logger.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		defer func() {
+			if p := recover(); p != nil {
+				err = recovered(log, info.FullMethod, p)
+			}
+		}()
+		return handler(ctx, req)
+	}
+}
+
+func StreamRecovery(log logger.Logger) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		defer func() {
+			if p := recover(); p != nil {
+				err = recovered(log, info.FullMethod, p)
+			}
+		}()
+		return handler(srv, stream)
+	}
+}
+
+func recovered(log logger.Logger, method string, p interface{}) error {
+	log.Error("[gRPC] Recovered from panic", map[string]interface{}{
+		"method": method,
+		"error":  p,
+		"stack":  string(debug.Stack()),
+	})
+	return status.Error(codes.Internal, "internal server error")
+}
@@ -0,0 +1,132 @@
+// Package healthcheck keeps the statuses of the standard gRPC health service
+// in line with the dependencies of each served service.
+package healthcheck
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"library-api-book/internal/logger"
+
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// Check pings one dependency, such as the database.
+type Check struct {
+	Name string
+	Ping func(ctx context.Context) error
+}
+
+// Checker runs every check on an interval and marks each registered service
+// SERVING only while all the checks it depends on pass. The overall status,
+// the empty service name, depends on every check.
+type Checker struct {
+	server   *health.Server
+	checks   []Check
+	services map[string][]string
+	timeout  time.Duration
+	log      logger.Logger
+
+	mu      sync.Mutex
+	failing map[string]error
+}
+
+// NewChecker reports every service as NOT_SERVING until the first round of
+// checks has run.
+func NewChecker(server *health.Server, timeout time.Duration, log logger.Logger, checks ...Check) *Checker {
+	checker := &Checker{
+		server:   server,
+		checks:   checks,
+		services: make(map[string][]string),
+		timeout:  timeout,
+		log:      log,
+		failing:  make(map[string]error),
+	}
+	server.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+	return checker
+}
+
+// AddService registers service as depending on the named checks.
+func (checker *Checker) AddService(service string, checks ...string) {
+	checker.services[service] = checks
+	checker.server.SetServingStatus(service, healthpb.HealthCheckResponse_NOT_SERVING)
+}
+
+// Run checks immediately and then every interval until ctx is done. On return
+// every service is marked NOT_SERVING so callers drain before shutdown.
+func (checker *Checker) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		checker.CheckNow(ctx)
+		select {
+		case <-ctx.Done():
+			checker.server.Shutdown()
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// CheckNow runs every check concurrently and updates the statuses.
+func (checker *Checker) CheckNow(ctx context.Context) {
+	results := make([]error, len(checker.checks))
+	var wg sync.WaitGroup
+	for i, check := range checker.checks {
+		wg.Add(1)
+		go func(i int, check Check) {
+			defer wg.Done()
+			checkCtx, cancel := context.WithTimeout(ctx, checker.timeout)
+			defer cancel()
+			results[i] = check.Ping(checkCtx)
+		}(i, check)
+	}
+	wg.Wait()
+
+	checker.mu.Lock()
+	defer checker.mu.Unlock()
+
+	for i, check := range checker.checks {
+		err := results[i]
+		previous, wasFailing := checker.failing[check.Name]
+		switch {
+		case err != nil && !wasFailing:
+			checker.log.Error("[HealthCheck] Dependency check failed", map[string]interface{}{
+				"check": check.Name,
+				"error": err.Error(),
+			})
+		case err == nil && wasFailing:
+			checker.log.Info("[HealthCheck] Dependency check recovered", map[string]interface{}{
+				"check":          check.Name,
+				"previous_error": previous.Error(),
+			})
+		}
+		if err != nil {
+			checker.failing[check.Name] = err
+		} else {
+			delete(checker.failing, check.Name)
+		}
+	}
+
+	checker.server.SetServingStatus("", servingStatus(len(checker.failing) == 0))
+	for service, dependencies := range checker.services {
+		healthy := true
+		for _, name := range dependencies {
+			if _, failed := checker.failing[name]; failed {
+				healthy = false
+				break
+			}
+		}
+		checker.server.SetServingStatus(service, servingStatus(healthy))
+	}
+}
+
+func servingStatus(healthy bool) healthpb.HealthCheckResponse_ServingStatus {
+	if healthy {
+		return healthpb.HealthCheckResponse_SERVING
+	}
+	return healthpb.HealthCheckResponse_NOT_SERVING
+}
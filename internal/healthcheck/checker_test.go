@@ -0,0 +1,123 @@
+package healthcheck
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"library-api-book/internal/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+const bookService = "book.BookService"
+
+type fakeDependency struct {
+	err atomic.Value
+}
+
+func (d *fakeDependency) fail(err error) { d.err.Store(&err) }
+func (d *fakeDependency) heal()          { d.err.Store((*error)(nil)) }
+
+func (d *fakeDependency) Ping(ctx context.Context) error {
+	if err, _ := d.err.Load().(*error); err != nil {
+		return *err
+	}
+	return nil
+}
+
+func status(t *testing.T, server *health.Server, service string) healthpb.HealthCheckResponse_ServingStatus {
+	resp, err := server.Check(context.Background(), &healthpb.HealthCheckRequest{Service: service})
+	require.NoError(t, err)
+	return resp.Status
+}
+
+func newTestChecker() (*health.Server, *Checker, *fakeDependency, *fakeDependency) {
+	postgres, redis := &fakeDependency{}, &fakeDependency{}
+	server := health.NewServer()
+	checker := NewChecker(server, time.Second, logger.NopLogger{},
+		Check{Name: "postgres", Ping: postgres.Ping},
+		Check{Name: "redis", Ping: redis.Ping},
+	)
+	checker.AddService(bookService, "postgres")
+	return server, checker, postgres, redis
+}
+
+func TestChecker_NotServingBeforeFirstCheck(t *testing.T) {
+	server, _, _, _ := newTestChecker()
+
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, status(t, server, ""))
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, status(t, server, bookService))
+}
+
+func TestChecker_PerServiceStatus(t *testing.T) {
+	server, checker, postgres, redis := newTestChecker()
+
+	checker.CheckNow(context.Background())
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, status(t, server, ""))
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, status(t, server, bookService))
+
+	redis.fail(errors.New("connection refused"))
+	checker.CheckNow(context.Background())
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, status(t, server, ""))
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, status(t, server, bookService))
+
+	postgres.fail(errors.New("connection refused"))
+	checker.CheckNow(context.Background())
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, status(t, server, bookService))
+
+	postgres.heal()
+	redis.heal()
+	checker.CheckNow(context.Background())
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, status(t, server, ""))
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, status(t, server, bookService))
+}
+
+func TestChecker_TimesOutSlowChecks(t *testing.T) {
+	server := health.NewServer()
+	checker := NewChecker(server, 10*time.Millisecond, logger.NopLogger{},
+		Check{Name: "postgres", Ping: func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}},
+	)
+	checker.AddService(bookService, "postgres")
+
+	checker.CheckNow(context.Background())
+
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, status(t, server, bookService))
+}
+
+func TestChecker_RunUpdatesAndShutsDown(t *testing.T) {
+	server, checker, postgres, _ := newTestChecker()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		checker.Run(ctx, 5*time.Millisecond)
+		close(done)
+	}()
+
+	require.Eventually(t, func() bool {
+		return status(t, server, bookService) == healthpb.HealthCheckResponse_SERVING
+	}, time.Second, time.Millisecond)
+
+	postgres.fail(errors.New("connection refused"))
+	require.Eventually(t, func() bool {
+		return status(t, server, bookService) == healthpb.HealthCheckResponse_NOT_SERVING
+	}, time.Second, time.Millisecond)
+
+	postgres.heal()
+	require.Eventually(t, func() bool {
+		return status(t, server, bookService) == healthpb.HealthCheckResponse_SERVING
+	}, time.Second, time.Millisecond)
+
+	cancel()
+	<-done
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, status(t, server, bookService))
+}
@@ -0,0 +1,10 @@
+package logger
+
+// NopLogger discards every entry. It is used where a Logger is required but
+// the output is irrelevant, such as unit tests.
+type NopLogger struct{}
+
+func (NopLogger) Info(message string, fields map[string]interface{})  {}
+func (NopLogger) Error(message string, fields map[string]interface{}) {}
+func (NopLogger) Warn(message string, fields map[string]interface{})  {}
+func (NopLogger) Debug(message string, fields map[string]interface{}) {}
@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"context"
+	"library-api-book/internal/models"
+	"library-api-book/internal/services"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AuditDetailKey holds a short reason, such as why a token was rejected, that
+// is stored with the audit entry of the request.
+const AuditDetailKey = "auditDetail"
+
+// Audit records every write and every request rejected by authentication or
+// authorization once the rest of the handler chain has finished.
+func Audit(auditService services.AuditService) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.Next()
+
+		status := ctx.Writer.Status()
+		if !isWriteMethod(ctx.Request.Method) && !isAuthFailure(status) {
+			return
+		}
+
+		route := ctx.FullPath()
+		if route == "" {
+			route = ctx.Request.URL.Path
+		}
+
+		log := &models.AuditLog{
+			OccurredAt: time.Now(),
+			Role:       ctx.GetString("role"),
+			Action:     ctx.Request.Method + " " + route,
+			Target:     ctx.Request.URL.RequestURI(),
+			IP:         ctx.ClientIP(),
+			RequestID:  ctx.GetString(RequestIDKey),
+			Outcome:    auditOutcome(status),
+			StatusCode: status,
+			Detail:     ctx.GetString(AuditDetailKey),
+		}
+		if prefix := ctx.GetString(APIKeyPrefixKey); prefix != "" && log.Detail == "" {
+			log.Detail = "api key " + prefix
+		}
+		if authID := ctx.GetInt("authId"); authID > 0 {
+			actorID := uint64(authID)
+			log.ActorID = &actorID
+		}
+		log.Truncate()
+
+		// The response is already written, so a client that hangs up must not
+		// cancel the insert.
+		auditService.Record(context.WithoutCancel(ctx.Request.Context()), log)
+	}
+}
+
+func isWriteMethod(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+func isAuthFailure(status int) bool {
+	return status == http.StatusUnauthorized || status == http.StatusForbidden
+}
+
+func auditOutcome(status int) string {
+	switch {
+	case isAuthFailure(status):
+		return models.AuditOutcomeDenied
+	case status >= http.StatusBadRequest:
+		return models.AuditOutcomeFailure
+	default:
+		return models.AuditOutcomeSuccess
+	}
+}
@@ -0,0 +1,144 @@
+package middleware
+
+import (
+	"context"
+	"library-api-book/internal/commons/response"
+	"library-api-book/internal/models"
+	"library-api-book/internal/params"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type recordingAuditService struct {
+	logs []*models.AuditLog
+}
+
+func (s *recordingAuditService) Record(ctx context.Context, log *models.AuditLog) *response.CustomError {
+	s.logs = append(s.logs, log)
+	return nil
+}
+
+func (s *recordingAuditService) GetAuditLogs(ctx context.Context, filter *models.AuditLogFilter, pagination *models.Pagination) ([]*params.AuditLogResponse, *response.CustomError) {
+	return nil, nil
+}
+
+func (s *recordingAuditService) ExportAuditLogs(ctx context.Context, filter *models.AuditLogFilter, fn func(*params.AuditLogResponse) error) *response.CustomError {
+	return nil
+}
+
+func newAuditRouter(auditService *recordingAuditService) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(RequestID(), Audit(auditService))
+
+	router.GET("/books/:id", func(ctx *gin.Context) {
+		ctx.Status(http.StatusOK)
+	})
+	router.PUT("/books/:id", func(ctx *gin.Context) {
+		ctx.Set("authId", 7)
+		ctx.Set("role", "admin")
+		ctx.Status(http.StatusOK)
+	})
+	router.POST("/books", func(ctx *gin.Context) {
+		ctx.Set(APIKeyPrefixKey, "0123456789ab")
+		ctx.Status(http.StatusCreated)
+	})
+	router.PATCH("/books/:id", func(ctx *gin.Context) {
+		ctx.Set("role", strings.Repeat("é", 30))
+		ctx.Status(http.StatusOK)
+	})
+	router.DELETE("/books/:id", func(ctx *gin.Context) {
+		abortUnauthorized(ctx, "Invalid token")
+	})
+	return router
+}
+
+func TestAudit_RecordsWrites(t *testing.T) {
+	auditService := &recordingAuditService{}
+	router := newAuditRouter(auditService)
+
+	req := httptest.NewRequest(http.MethodPut, "/books/12", nil)
+	req.Header.Set(RequestIDHeader, "req-1")
+	req.RemoteAddr = "10.0.0.5:1234"
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	assert.Len(t, auditService.logs, 1)
+	log := auditService.logs[0]
+	assert.Equal(t, uint64(7), *log.ActorID)
+	assert.Equal(t, "admin", log.Role)
+	assert.Equal(t, "PUT /books/:id", log.Action)
+	assert.Equal(t, "/books/12", log.Target)
+	assert.Equal(t, "10.0.0.5", log.IP)
+	assert.Equal(t, "req-1", log.RequestID)
+	assert.Equal(t, models.AuditOutcomeSuccess, log.Outcome)
+}
+
+func TestAudit_TruncatesOversizedValues(t *testing.T) {
+	auditService := &recordingAuditService{}
+	router := newAuditRouter(auditService)
+
+	target := "/books/12?q=" + strings.Repeat("a", 5000)
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPatch, target, nil))
+
+	assert.Len(t, auditService.logs, 1)
+	log := auditService.logs[0]
+	assert.Equal(t, models.AuditTargetSize, len(log.Target))
+	assert.Equal(t, target[:models.AuditTargetSize], log.Target)
+	assert.Equal(t, models.AuditRoleSize, utf8.RuneCountInString(log.Role))
+	assert.True(t, utf8.ValidString(log.Role))
+}
+
+func TestAudit_RecordsAuthFailures(t *testing.T) {
+	auditService := &recordingAuditService{}
+	router := newAuditRouter(auditService)
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodDelete, "/books/12", nil))
+
+	assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+	assert.Len(t, auditService.logs, 1)
+	assert.Nil(t, auditService.logs[0].ActorID)
+	assert.Equal(t, models.AuditOutcomeDenied, auditService.logs[0].Outcome)
+	assert.Equal(t, "Invalid token", auditService.logs[0].Detail)
+	assert.Equal(t, recorder.Header().Get(RequestIDHeader), auditService.logs[0].RequestID)
+}
+
+func TestAudit_SkipsReads(t *testing.T) {
+	auditService := &recordingAuditService{}
+	router := newAuditRouter(auditService)
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/books/12", nil))
+
+	assert.Empty(t, auditService.logs)
+}
+
+func TestRequestID_RejectsMalformedHeader(t *testing.T) {
+	auditService := &recordingAuditService{}
+	router := newAuditRouter(auditService)
+
+	req := httptest.NewRequest(http.MethodPut, "/books/12", nil)
+	req.Header.Set(RequestIDHeader, "bad id\nwith newline")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	requestID := recorder.Header().Get(RequestIDHeader)
+	assert.Len(t, requestID, 32)
+	assert.Equal(t, requestID, auditService.logs[0].RequestID)
+}
+
+func TestAudit_RecordsAPIKey(t *testing.T) {
+	auditService := &recordingAuditService{}
+	router := newAuditRouter(auditService)
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/books", nil))
+
+	assert.Len(t, auditService.logs, 1)
+	assert.Nil(t, auditService.logs[0].ActorID)
+	assert.Equal(t, "api key 0123456789ab", auditService.logs[0].Detail)
+}
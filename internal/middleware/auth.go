@@ -1,29 +1,46 @@
 package middleware
 
 import (
-	"context"
+	"fmt"
+	"library-api-book/internal/auth"
 	"library-api-book/internal/commons/response"
-	"library-api-book/internal/grpc/client"
+	"library-api-book/internal/permission"
+	"library-api-book/internal/services"
+	"net/http"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 )
 
-func CheckAuth(authClient *client.AuthClient) gin.HandlerFunc {
+const (
+	// APIKeyHeader carries an API key issued through /api/v1/api-keys. It is
+	// accepted instead of a bearer token.
+	APIKeyHeader = "X-API-Key"
+
+	// APIKeyPrefixKey holds the prefix of the key a request was authenticated
+	// with. Its scopes are stored on the request context with
+	// services.WithAPIKeyScopes; API key callers have no role.
+	APIKeyPrefixKey = "apiKeyPrefix"
+)
+
+func CheckAuth(validator auth.TokenValidator, apiKeys services.APIKeyService) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
+		if rawKey := ctx.GetHeader(APIKeyHeader); rawKey != "" {
+			checkAPIKey(ctx, apiKeys, rawKey)
+			return
+		}
+
 		header := ctx.GetHeader("Authorization")
 		bearerToken := strings.Split(header, "Bearer ")
 
 		if len(bearerToken) != 2 {
-			resp := response.UnauthorizedErrorWithAdditionalInfo("len token must be 2")
-			ctx.AbortWithStatusJSON(resp.StatusCode, resp)
+			abortUnauthorized(ctx, "len token must be 2")
 			return
 		}
 
-		valid, payload := authClient.ValidateToken(context.Background(), bearerToken[1])
+		valid, payload := validator.ValidateToken(ctx.Request.Context(), bearerToken[1])
 		if !valid {
-			resp := response.UnauthorizedErrorWithAdditionalInfo("Invalid token")
-			ctx.AbortWithStatusJSON(resp.StatusCode, resp)
+			abortUnauthorized(ctx, "Invalid token")
 			return
 		}
 
@@ -33,61 +50,64 @@ func CheckAuth(authClient *client.AuthClient) gin.HandlerFunc {
 	}
 }
 
-func CheckAuthIsAdmin(authClient *client.AuthClient) gin.HandlerFunc {
-	return func(ctx *gin.Context) {
-		header := ctx.GetHeader("Authorization")
-		bearerToken := strings.Split(header, "Bearer ")
-
-		if len(bearerToken) != 2 {
-			resp := response.UnauthorizedErrorWithAdditionalInfo("len token must be 2")
-			ctx.AbortWithStatusJSON(resp.StatusCode, resp)
+func checkAPIKey(ctx *gin.Context, apiKeys services.APIKeyService, rawKey string) {
+	key, custErr := apiKeys.Authenticate(ctx.Request.Context(), rawKey)
+	if custErr != nil {
+		if custErr.StatusCode == http.StatusUnauthorized {
+			abortUnauthorized(ctx, custErr.Message)
 			return
 		}
+		ctx.AbortWithStatusJSON(custErr.StatusCode, custErr)
+		return
+	}
 
-		valid, payload := authClient.ValidateToken(context.Background(), bearerToken[1])
-		if !valid {
-			resp := response.UnauthorizedErrorWithAdditionalInfo("Invalid token")
-			ctx.AbortWithStatusJSON(resp.StatusCode, resp)
+	ctx.Set(APIKeyPrefixKey, key.Prefix)
+	ctx.Request = ctx.Request.WithContext(services.WithAPIKeyScopes(ctx.Request.Context(), key.Permissions))
+	ctx.Next()
+}
+
+// RequirePermission rejects callers whose role is not granted required by the
+// policy, or whose API key is not scoped to it. It must run after CheckAuth,
+// which stores the role or the key scopes.
+func RequirePermission(policy *permission.Policy, required permission.Permission) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if scopes, ok := services.APIKeyScopes(ctx.Request.Context()); ok {
+			if !hasScope(scopes, required) {
+				abortForbidden(ctx, fmt.Sprintf("api key %q does not have permission %q", ctx.GetString(APIKeyPrefixKey), required))
+				return
+			}
+			ctx.Next()
 			return
 		}
 
-		if payload.Role != "admin" {
-			resp := response.UnauthorizedErrorWithAdditionalInfo("user doesn't have permission to access")
-			ctx.AbortWithStatusJSON(resp.StatusCode, resp)
+		role := ctx.GetString("role")
+		if !policy.Allows(role, required) {
+			abortForbidden(ctx, fmt.Sprintf("role %q does not have permission %q", role, required))
 			return
 		}
-
-		ctx.Set("authId", payload.AuthId)
-		ctx.Set("role", payload.Role)
 		ctx.Next()
 	}
 }
 
-func CheckAuthIsAdminOrAuthor(authClient *client.AuthClient) gin.HandlerFunc {
-	return func(ctx *gin.Context) {
-		header := ctx.GetHeader("Authorization")
-		bearerToken := strings.Split(header, "Bearer ")
-
-		if len(bearerToken) != 2 {
-			resp := response.UnauthorizedErrorWithAdditionalInfo("len token must be 2")
-			ctx.AbortWithStatusJSON(resp.StatusCode, resp)
-			return
+func hasScope(scopes []string, required permission.Permission) bool {
+	for _, scope := range scopes {
+		if permission.Permission(scope) == required {
+			return true
 		}
+	}
+	return false
+}
 
-		valid, payload := authClient.ValidateToken(context.Background(), bearerToken[1])
-		if !valid {
-			resp := response.UnauthorizedErrorWithAdditionalInfo("Invalid token")
-			ctx.AbortWithStatusJSON(resp.StatusCode, resp)
-			return
-		}
+// abortUnauthorized rejects the request and keeps the reason for the audit log.
+func abortUnauthorized(ctx *gin.Context, reason string) {
+	ctx.Set(AuditDetailKey, reason)
+	resp := response.UnauthorizedErrorWithAdditionalInfo(reason)
+	ctx.AbortWithStatusJSON(resp.StatusCode, resp)
+}
 
-		if payload.Role == "user" {
-			resp := response.UnauthorizedErrorWithAdditionalInfo("user doesn't have permission to access")
-			ctx.AbortWithStatusJSON(resp.StatusCode, resp)
-			return
-		}
-		ctx.Set("authId", payload.AuthId)
-		ctx.Set("role", payload.Role)
-		ctx.Next()
-	}
+// abortForbidden rejects an authenticated caller the policy denies.
+func abortForbidden(ctx *gin.Context, reason string) {
+	ctx.Set(AuditDetailKey, reason)
+	resp := response.ForbiddenError(reason)
+	ctx.AbortWithStatusJSON(resp.StatusCode, resp)
 }
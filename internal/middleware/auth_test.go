@@ -0,0 +1,130 @@
+package middleware
+
+import (
+	"context"
+	"library-api-book/internal/commons/response"
+	"library-api-book/internal/models"
+	"library-api-book/internal/permission"
+	"library-api-book/internal/services"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newPermissionRouter(t *testing.T, role string) *gin.Engine {
+	policy, err := permission.NewPolicy(map[string][]permission.Permission{
+		"admin":  {permission.Wildcard},
+		"author": {permission.BooksRead, permission.BooksWrite},
+	})
+	require.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(ctx *gin.Context) {
+		ctx.Set("role", role)
+	})
+	router.DELETE("/books/:id", RequirePermission(policy, permission.BooksWrite), func(ctx *gin.Context) {
+		ctx.Status(http.StatusOK)
+	})
+	router.DELETE("/books/:id/purge", RequirePermission(policy, permission.BooksPurge), func(ctx *gin.Context) {
+		ctx.Status(http.StatusOK)
+	})
+	return router
+}
+
+func TestRequirePermission_Allowed(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	newPermissionRouter(t, "author").ServeHTTP(recorder, httptest.NewRequest(http.MethodDelete, "/books/1", nil))
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+}
+
+func TestRequirePermission_Wildcard(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	newPermissionRouter(t, "admin").ServeHTTP(recorder, httptest.NewRequest(http.MethodDelete, "/books/1/purge", nil))
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+}
+
+func TestRequirePermission_Denied(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	newPermissionRouter(t, "author").ServeHTTP(recorder, httptest.NewRequest(http.MethodDelete, "/books/1/purge", nil))
+
+	assert.Equal(t, http.StatusForbidden, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), `books:purge`)
+}
+
+func TestRequirePermission_UnknownRole(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	newPermissionRouter(t, "user").ServeHTTP(recorder, httptest.NewRequest(http.MethodDelete, "/books/1", nil))
+
+	assert.Equal(t, http.StatusForbidden, recorder.Code)
+}
+
+type stubAPIKeyService struct {
+	services.APIKeyService
+	keys map[string]*models.APIKey
+}
+
+func (s stubAPIKeyService) Authenticate(ctx context.Context, rawKey string) (*models.APIKey, *response.CustomError) {
+	key, ok := s.keys[rawKey]
+	if !ok {
+		return nil, response.UnauthorizedError("Invalid API key")
+	}
+	return key, nil
+}
+
+func newAPIKeyRouter(t *testing.T) *gin.Engine {
+	policy, err := permission.NewPolicy(map[string][]permission.Permission{
+		"admin": {permission.Wildcard},
+	})
+	require.NoError(t, err)
+
+	apiKeys := stubAPIKeyService{keys: map[string]*models.APIKey{
+		"lak_reader": {Prefix: "reader", Permissions: []string{string(permission.BooksRead)}},
+	}}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.ContextWithFallback = true
+	router.Use(CheckAuth(nil, apiKeys))
+	router.GET("/books", RequirePermission(policy, permission.BooksRead), func(ctx *gin.Context) {
+		scopes, _ := services.APIKeyScopes(ctx)
+		ctx.String(http.StatusOK, "role=%q scopes=%v", ctx.GetString("role"), scopes)
+	})
+	router.POST("/books", RequirePermission(policy, permission.BooksWrite), func(ctx *gin.Context) {
+		ctx.Status(http.StatusCreated)
+	})
+	return router
+}
+
+func serveWithAPIKey(router *gin.Engine, method, key string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(method, "/books", nil)
+	request.Header.Set(APIKeyHeader, key)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func TestCheckAuth_APIKeyScopes(t *testing.T) {
+	router := newAPIKeyRouter(t)
+
+	recorder := serveWithAPIKey(router, http.MethodGet, "lak_reader")
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, `role="" scopes=[books:read]`, recorder.Body.String())
+
+	recorder = serveWithAPIKey(router, http.MethodPost, "lak_reader")
+	assert.Equal(t, http.StatusForbidden, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), `books:write`)
+}
+
+func TestCheckAuth_InvalidAPIKey(t *testing.T) {
+	recorder := serveWithAPIKey(newAPIKeyRouter(t), http.MethodGet, "lak_forged")
+
+	assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "Invalid API key")
+}
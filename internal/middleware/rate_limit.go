@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"library-api-book/internal/commons/response"
+	"library-api-book/internal/logger"
+	"library-api-book/internal/ratelimit"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimit applies the policy limit of group to the caller, identified by
+// user, API key or, for anonymous requests, client IP. On authenticated
+// routes it must run after CheckAuth. Requests are let through when the
+// limiter is nil or Redis cannot be reached.
+func RateLimit(limiter *ratelimit.Limiter, policy *ratelimit.Policy, group string, log logger.Logger) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if limiter == nil {
+			ctx.Next()
+			return
+		}
+
+		subject, class := rateLimitSubject(ctx)
+		limit, ok := policy.Limit(group, class)
+		if !ok {
+			ctx.Next()
+			return
+		}
+
+		result, err := limiter.Allow(ctx.Request.Context(), group+":"+subject, limit)
+		if err != nil {
+			log.Warn("[RateLimit] Limiter unavailable, request allowed", map[string]interface{}{
+				"group":   group,
+				"subject": subject,
+				"error":   err.Error(),
+			})
+			ctx.Next()
+			return
+		}
+
+		for name, value := range result.Headers() {
+			ctx.Header(name, value)
+		}
+		if !result.Allowed {
+			resp := response.TooManyRequestsError("Rate limit exceeded")
+			ctx.AbortWithStatusJSON(resp.StatusCode, resp)
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// rateLimitSubject returns the bucket owner of the request and its class in
+// the rate limit policy.
+func rateLimitSubject(ctx *gin.Context) (subject, class string) {
+	if prefix := ctx.GetString(APIKeyPrefixKey); prefix != "" {
+		return "key:" + prefix, ratelimit.ClassAPIKey
+	}
+	if authID := ctx.GetInt("authId"); authID > 0 {
+		return "user:" + strconv.Itoa(authID), ctx.GetString("role")
+	}
+	return "ip:" + ctx.ClientIP(), ratelimit.ClassAnonymous
+}
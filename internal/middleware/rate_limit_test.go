@@ -0,0 +1,91 @@
+package middleware
+
+import (
+	"library-api-book/internal/logger"
+	"library-api-book/internal/ratelimit"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newRateLimitRouter(t *testing.T) (*gin.Engine, *miniredis.Miniredis) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr(), MaxRetries: -1})
+	t.Cleanup(func() { client.Close() })
+
+	policy, err := ratelimit.NewPolicy(map[string]map[string]ratelimit.Limit{
+		ratelimit.Default: {
+			ratelimit.Default: {Requests: 2, Per: time.Minute},
+			"admin":           {},
+		},
+	})
+	require.NoError(t, err)
+	limiter := ratelimit.NewLimiter(client, time.Second)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(ctx *gin.Context) {
+		if role := ctx.GetHeader("X-Test-Role"); role != "" {
+			ctx.Set("authId", 5)
+			ctx.Set("role", role)
+		}
+	})
+	router.GET("/books", RateLimit(limiter, policy, "search", logger.NopLogger{}), func(ctx *gin.Context) {
+		ctx.Status(http.StatusOK)
+	})
+	return router, server
+}
+
+func serveAs(router *gin.Engine, role string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(http.MethodGet, "/books", nil)
+	if role != "" {
+		request.Header.Set("X-Test-Role", role)
+	}
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func TestRateLimit_RejectsOverLimit(t *testing.T) {
+	router, _ := newRateLimitRouter(t)
+
+	recorder := serveAs(router, "user")
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "2", recorder.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "1", recorder.Header().Get("RateLimit-Remaining"))
+
+	serveAs(router, "user")
+	recorder = serveAs(router, "user")
+	assert.Equal(t, http.StatusTooManyRequests, recorder.Code)
+	assert.Equal(t, "30", recorder.Header().Get("Retry-After"))
+	assert.Contains(t, recorder.Body.String(), "ERR0011")
+
+	// Anonymous callers are counted by IP, separately from the user.
+	assert.Equal(t, http.StatusOK, serveAs(router, "").Code)
+}
+
+func TestRateLimit_UnlimitedClass(t *testing.T) {
+	router, _ := newRateLimitRouter(t)
+
+	for i := 0; i < 5; i++ {
+		recorder := serveAs(router, "admin")
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Empty(t, recorder.Header().Get("RateLimit-Limit"))
+	}
+}
+
+func TestRateLimit_FailsOpen(t *testing.T) {
+	router, server := newRateLimitRouter(t)
+	server.Close()
+
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusOK, serveAs(router, "user").Code)
+	}
+}
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// APIKey lets a batch job or partner system call the API without a user
+// token. Only a hash of the secret is stored; Prefix identifies the key.
+type APIKey struct {
+	ID          uint64
+	Name        string
+	Prefix      string
+	KeyHash     string
+	Permissions []string
+	CreatedBy   *uint64
+	CreatedAt   time.Time
+	ExpiresAt   *time.Time
+	LastUsedAt  *time.Time
+	RevokedAt   *time.Time
+}
@@ -0,0 +1,60 @@
+package models
+
+import "time"
+
+const (
+	AuditOutcomeSuccess = "success"
+	AuditOutcomeFailure = "failure"
+	AuditOutcomeDenied  = "denied"
+)
+
+// Sizes of the audit_logs columns that take request input.
+const (
+	AuditRoleSize   = 20
+	AuditActionSize = 255
+	AuditTargetSize = 2048
+)
+
+type AuditLog struct {
+	ID         uint64
+	OccurredAt time.Time
+	ActorID    *uint64
+	Role       string
+	Action     string
+	Target     string
+	IP         string
+	RequestID  string
+	Outcome    string
+	StatusCode int
+	Detail     string
+}
+
+// Truncate cuts the role, action and target to their column sizes so an
+// oversized URI or role cannot make the insert fail and lose the entry.
+func (log *AuditLog) Truncate() {
+	log.Role = truncate(log.Role, AuditRoleSize)
+	log.Action = truncate(log.Action, AuditActionSize)
+	log.Target = truncate(log.Target, AuditTargetSize)
+}
+
+// truncate cuts value to at most size characters, which is how Postgres
+// measures VARCHAR columns.
+func truncate(value string, size int) string {
+	count := 0
+	for i := range value {
+		if count == size {
+			return value[:i]
+		}
+		count++
+	}
+	return value
+}
+
+// AuditLogFilter narrows an audit log query. Zero values match everything.
+type AuditLogFilter struct {
+	ActorID *uint64
+	Action  string
+	Outcome string
+	From    *time.Time
+	Until   *time.Time
+}
@@ -0,0 +1,7 @@
+package models
+
+type Author struct {
+	ID     uint64
+	UserID uint64
+	Name   string
+}
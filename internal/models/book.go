@@ -6,7 +6,20 @@ type Book struct {
 	ID        uint64
 	AuthorID  uint64
 	Title     string
+	ISBN      string
+	Publisher string
+	CoverPath string
 	Stock     int32
 	PublishAt time.Time
 	UpdatedAt time.Time
+	DeletedAt *time.Time
+	Version   int32
+}
+
+// BookFilter narrows the books listed by GetAllBooks. Zero values match every
+// book.
+type BookFilter struct {
+	Search   string
+	AuthorID uint64
+	InStock  bool
 }
@@ -0,0 +1,51 @@
+package models
+
+import "time"
+
+const (
+	RevisionActionCreate  = "create"
+	RevisionActionUpdate  = "update"
+	RevisionActionDelete  = "delete"
+	RevisionActionRestore = "restore"
+	RevisionActionRevert  = "revert"
+	RevisionActionPurge   = "purge"
+)
+
+// BookRevision is an immutable copy of a book taken right after a change.
+// Revision equals the book version the change produced.
+type BookRevision struct {
+	ID             uint64
+	BookID         uint64
+	Revision       int32
+	Action         string
+	Snapshot       BookSnapshot
+	ActorID        *uint64
+	SourceRevision *int32
+	CreatedAt      time.Time
+}
+
+// BookSnapshot holds the stored fields of a book. It is persisted as JSON, so
+// the field names are part of the stored format.
+type BookSnapshot struct {
+	AuthorID  uint64     `json:"author_id"`
+	Title     string     `json:"title"`
+	ISBN      string     `json:"isbn"`
+	Publisher string     `json:"publisher"`
+	CoverPath string     `json:"cover_path"`
+	Stock     int32      `json:"stock"`
+	PublishAt time.Time  `json:"publish_at"`
+	DeletedAt *time.Time `json:"deleted_at"`
+}
+
+func NewBookSnapshot(book *Book) BookSnapshot {
+	return BookSnapshot{
+		AuthorID:  book.AuthorID,
+		Title:     book.Title,
+		ISBN:      book.ISBN,
+		Publisher: book.Publisher,
+		CoverPath: book.CoverPath,
+		Stock:     book.Stock,
+		PublishAt: book.PublishAt,
+		DeletedAt: book.DeletedAt,
+	}
+}
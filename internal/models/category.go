@@ -0,0 +1,6 @@
+package models
+
+type Category struct {
+	ID   uint64
+	Name string
+}
@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// HarvestFilter selects books by updated_at for incremental harvesting.
+// Results are ordered by (updated_at, id) and start after the After* pair.
+type HarvestFilter struct {
+	From           *time.Time
+	Until          *time.Time
+	AfterUpdatedAt *time.Time
+	AfterID        uint64
+	Limit          int
+}
@@ -0,0 +1,9 @@
+package params
+
+import "time"
+
+type APIKeyRequest struct {
+	Name        string     `json:"name"  validate:"required,max=100"`
+	Permissions []string   `json:"permissions"  validate:"required,min=1"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+}
@@ -0,0 +1,22 @@
+package params
+
+import "time"
+
+type APIKeyResponse struct {
+	ID          uint64     `json:"id"`
+	Name        string     `json:"name"`
+	Prefix      string     `json:"prefix"`
+	Permissions []string   `json:"permissions"`
+	CreatedBy   *uint64    `json:"created_by"`
+	CreatedAt   time.Time  `json:"created_at"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+	LastUsedAt  *time.Time `json:"last_used_at"`
+	RevokedAt   *time.Time `json:"revoked_at"`
+}
+
+// CreatedAPIKeyResponse is only returned when a key is created; Key is the
+// plaintext secret and cannot be retrieved again.
+type CreatedAPIKeyResponse struct {
+	APIKeyResponse
+	Key string `json:"key"`
+}
@@ -0,0 +1,17 @@
+package params
+
+import "time"
+
+type AuditLogResponse struct {
+	ID         uint64    `json:"id"`
+	OccurredAt time.Time `json:"occurred_at"`
+	ActorID    *uint64   `json:"actor_id"`
+	Role       string    `json:"role,omitempty"`
+	Action     string    `json:"action"`
+	Target     string    `json:"target"`
+	IP         string    `json:"ip"`
+	RequestID  string    `json:"request_id"`
+	Outcome    string    `json:"outcome"`
+	StatusCode int       `json:"status_code"`
+	Detail     string    `json:"detail,omitempty"`
+}
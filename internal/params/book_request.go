@@ -1,7 +1,9 @@
 package params
 
 type BookRequest struct {
-	AuthorID uint64 `json:"author_id"  validate:"required"`
-	Title    string `json:"title"  validate:"required"`
-	Stock    int32  `json:"stock"`
+	AuthorID  uint64 `json:"author_id"  validate:"required"`
+	Title     string `json:"title"  validate:"required"`
+	ISBN      string `json:"isbn"`
+	Publisher string `json:"publisher"`
+	Stock     int32  `json:"stock"  validate:"gte=0"`
 }
@@ -3,10 +3,15 @@ package params
 import "time"
 
 type BookResponse struct {
-	ID        uint64    `json:"id"`
-	AuthorID  uint64    `json:"author_id"`
-	Title     string    `json:"title"`
-	Stock     int32     `json:"stock"`
-	PublishAt time.Time `json:"publish_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID        uint64         `json:"id"`
+	AuthorID  uint64         `json:"author_id"`
+	Title     string         `json:"title"`
+	ISBN      string         `json:"isbn,omitempty"`
+	Publisher string         `json:"publisher,omitempty"`
+	Stock     int32          `json:"stock"`
+	PublishAt time.Time      `json:"publish_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt *time.Time     `json:"deleted_at,omitempty"`
+	Version   int32          `json:"version"`
+	Cover     *CoverResponse `json:"cover,omitempty"`
 }
@@ -0,0 +1,28 @@
+package params
+
+import (
+	"library-api-book/internal/models"
+	"time"
+)
+
+type BookRevisionResponse struct {
+	Revision       int32               `json:"revision"`
+	Action         string              `json:"action"`
+	ActorID        *uint64             `json:"actor_id"`
+	SourceRevision *int32              `json:"source_revision,omitempty"`
+	Snapshot       models.BookSnapshot `json:"snapshot"`
+	CreatedAt      time.Time           `json:"created_at"`
+}
+
+type BookRevisionDiffResponse struct {
+	BookID  uint64            `json:"book_id"`
+	From    int32             `json:"from"`
+	To      int32             `json:"to"`
+	Changes []BookFieldChange `json:"changes"`
+}
+
+type BookFieldChange struct {
+	Field string      `json:"field"`
+	From  interface{} `json:"from"`
+	To    interface{} `json:"to"`
+}
@@ -0,0 +1,8 @@
+package params
+
+type CoverResponse struct {
+	Original string `json:"original"`
+	Small    string `json:"small"`
+	Medium   string `json:"medium"`
+	Large    string `json:"large"`
+}
@@ -0,0 +1,12 @@
+package params
+
+type MarcImportResponse struct {
+	Imported []uint64            `json:"imported"`
+	Failed   []MarcImportFailure `json:"failed"`
+}
+
+type MarcImportFailure struct {
+	Record int    `json:"record"`
+	Title  string `json:"title"`
+	Reason string `json:"reason"`
+}
@@ -0,0 +1,107 @@
+package permission
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Permission names an action a route requires, e.g. "books:write".
+type Permission string
+
+const (
+	BooksRead       Permission = "books:read"
+	BooksWrite      Permission = "books:write"
+	BooksRestore    Permission = "books:restore"
+	BooksPurge      Permission = "books:purge"
+	RevisionsRead   Permission = "revisions:read"
+	RevisionsRevert Permission = "revisions:revert"
+	StockAdjust     Permission = "stock:adjust"
+	AuditRead       Permission = "audit:read"
+	APIKeysManage   Permission = "api_keys:manage"
+
+	// BooksWriteAny lifts the ownership rule of books:write, which limits a
+	// role to the books of the author it is.
+	BooksWriteAny Permission = "books:write_any"
+
+	// Wildcard grants every permission.
+	Wildcard Permission = "*"
+)
+
+var known = map[Permission]bool{
+	BooksRead:       true,
+	BooksWrite:      true,
+	BooksWriteAny:   true,
+	BooksRestore:    true,
+	BooksPurge:      true,
+	RevisionsRead:   true,
+	RevisionsRevert: true,
+	StockAdjust:     true,
+	AuditRead:       true,
+	APIKeysManage:   true,
+	Wildcard:        true,
+}
+
+// Known reports whether permission is one routes can require.
+func Known(permission Permission) bool {
+	return known[permission] && permission != Wildcard
+}
+
+// Policy maps roles to the permissions they are granted. Roles missing from
+// the policy are granted nothing.
+type Policy struct {
+	roles map[string]map[Permission]bool
+}
+
+type policyFile struct {
+	Roles map[string][]Permission `yaml:"roles"`
+}
+
+// LoadPolicy reads a YAML policy file of the form
+//
+//	roles:
+//	  admin: ["*"]
+//	  user: [books:read]
+func LoadPolicy(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read permission policy: %w", err)
+	}
+	return ParsePolicy(data)
+}
+
+// ParsePolicy parses the YAML policy format read by LoadPolicy. Unknown
+// permissions are rejected so a typo cannot silently lock a role out.
+func ParsePolicy(data []byte) (*Policy, error) {
+	var file policyFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parse permission policy: %w", err)
+	}
+	if len(file.Roles) == 0 {
+		return nil, errors.New("permission policy defines no roles")
+	}
+	return NewPolicy(file.Roles)
+}
+
+func NewPolicy(roles map[string][]Permission) (*Policy, error) {
+	policy := &Policy{roles: make(map[string]map[Permission]bool, len(roles))}
+	for role, permissions := range roles {
+		granted := make(map[Permission]bool, len(permissions))
+		for _, permission := range permissions {
+			if !known[permission] {
+				return nil, fmt.Errorf("role %q: unknown permission %q", role, permission)
+			}
+			granted[permission] = true
+		}
+		policy.roles[role] = granted
+	}
+	return policy, nil
+}
+
+// Allows reports whether role has been granted permission.
+func (policy *Policy) Allows(role string, permission Permission) bool {
+	granted := policy.roles[role]
+	return granted[Wildcard] || granted[permission]
+}
@@ -0,0 +1,53 @@
+package permission
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePolicy(t *testing.T) {
+	policy, err := ParsePolicy([]byte(`
+roles:
+  admin: ["*"]
+  author: [books:read, books:write]
+  user: [books:read]
+`))
+	require.NoError(t, err)
+
+	assert.True(t, policy.Allows("admin", BooksPurge))
+	assert.True(t, policy.Allows("author", BooksWrite))
+	assert.False(t, policy.Allows("author", BooksPurge))
+	assert.True(t, policy.Allows("user", BooksRead))
+	assert.False(t, policy.Allows("user", BooksWrite))
+	assert.False(t, policy.Allows("guest", BooksRead))
+	assert.False(t, policy.Allows("", BooksRead))
+}
+
+func TestParsePolicy_UnknownPermission(t *testing.T) {
+	_, err := ParsePolicy([]byte(`
+roles:
+  author: [books:wrtie]
+`))
+	assert.ErrorContains(t, err, `unknown permission "books:wrtie"`)
+}
+
+func TestParsePolicy_NoRoles(t *testing.T) {
+	_, err := ParsePolicy([]byte(`roles: {}`))
+	assert.Error(t, err)
+}
+
+func TestLoadPolicy_RepositoryDefault(t *testing.T) {
+	path := filepath.Join("..", "..", "policy.yaml")
+	if _, err := os.Stat(path); err != nil {
+		t.Skip("policy.yaml not found")
+	}
+
+	policy, err := LoadPolicy(path)
+	require.NoError(t, err)
+	assert.True(t, policy.Allows("admin", AuditRead))
+	assert.False(t, policy.Allows("user", BooksWrite))
+}
@@ -0,0 +1,126 @@
+// Package ratelimit implements token bucket rate limits shared by every
+// instance of the service through Redis.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// keyPrefix namespaces the buckets in Redis.
+const keyPrefix = "ratelimit:"
+
+// tokenBucket refills the bucket in KEYS[1] for the time passed since it was
+// last touched and takes one token if there is one. Times are in
+// milliseconds; the bucket expires once it would be full again.
+var tokenBucket = redis.NewScript(`
+local capacity = tonumber(ARGV[1])
+local interval = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1])
+local ts = tonumber(state[2])
+if tokens == nil or ts == nil then
+	tokens = capacity
+	ts = now
+end
+if now > ts then
+	tokens = math.min(capacity, tokens + (now - ts) / interval)
+	ts = now
+end
+
+local allowed = 0
+local retry = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+else
+	retry = math.ceil((1 - tokens) * interval)
+end
+
+local reset = math.ceil((capacity - tokens) * interval)
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', tostring(ts))
+redis.call('PEXPIRE', KEYS[1], reset + 1000)
+return {allowed, math.floor(tokens), retry, reset}
+`)
+
+// Result is the outcome of one Allow call.
+type Result struct {
+	Limit     Limit
+	Allowed   bool
+	Remaining int
+	// RetryAfter is how long to wait before the next request is allowed.
+	// It is zero when the request was allowed.
+	RetryAfter time.Duration
+	// Reset is how long until the bucket is full again.
+	Reset time.Duration
+}
+
+// Headers returns the RateLimit-* response headers of the IETF RateLimit
+// header fields draft, and Retry-After when the request was rejected.
+func (result *Result) Headers() map[string]string {
+	headers := map[string]string{
+		"RateLimit-Limit":     strconv.Itoa(result.Limit.Burst),
+		"RateLimit-Remaining": strconv.Itoa(result.Remaining),
+		"RateLimit-Reset":     strconv.Itoa(seconds(result.Reset)),
+		"RateLimit-Policy":    fmt.Sprintf("%d;w=%d;burst=%d", result.Limit.Requests, seconds(result.Limit.Per), result.Limit.Burst),
+	}
+	if !result.Allowed {
+		headers["Retry-After"] = strconv.Itoa(seconds(result.RetryAfter))
+	}
+	return headers
+}
+
+// seconds rounds up, so clients never retry too early.
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+type Limiter struct {
+	client  redis.Scripter
+	timeout time.Duration
+	now     func() time.Time
+}
+
+// NewLimiter keeps its buckets in client. Every Allow call gives up after
+// timeout so a slow Redis cannot hold up requests.
+func NewLimiter(client redis.Scripter, timeout time.Duration) *Limiter {
+	return &Limiter{
+		client:  client,
+		timeout: timeout,
+		now:     time.Now,
+	}
+}
+
+// Allow takes a token from the bucket named key, which holds limit.
+func (limiter *Limiter) Allow(ctx context.Context, key string, limit Limit) (*Result, error) {
+	ctx, cancel := context.WithTimeout(ctx, limiter.timeout)
+	defer cancel()
+
+	interval := float64(limit.Per) / float64(time.Millisecond) / float64(limit.Requests)
+	values, err := tokenBucket.Run(ctx, limiter.client, []string{keyPrefix + key},
+		limit.Burst,
+		strconv.FormatFloat(interval, 'f', -1, 64),
+		limiter.now().UnixMilli(),
+	).Int64Slice()
+	if err != nil {
+		return nil, fmt.Errorf("rate limit %s: %w", key, err)
+	}
+	if len(values) != 4 {
+		return nil, fmt.Errorf("rate limit %s: unexpected script result %v", key, values)
+	}
+
+	return &Result{
+		Limit:      limit,
+		Allowed:    values[0] == 1,
+		Remaining:  int(values[1]),
+		RetryAfter: time.Duration(values[2]) * time.Millisecond,
+		Reset:      time.Duration(values[3]) * time.Millisecond,
+	}, nil
+}
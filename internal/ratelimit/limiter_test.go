@@ -0,0 +1,98 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestLimiter(t *testing.T) (*Limiter, *miniredis.Miniredis, *time.Time) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	now := time.Unix(1_700_000_000, 0)
+	limiter := NewLimiter(client, time.Second)
+	limiter.now = func() time.Time { return now }
+	return limiter, server, &now
+}
+
+func TestLimiter_BurstThenRefill(t *testing.T) {
+	limiter, _, now := newTestLimiter(t)
+	limit := Limit{Requests: 60, Per: time.Minute, Burst: 3}
+	ctx := context.Background()
+
+	for remaining := 2; remaining >= 0; remaining-- {
+		result, err := limiter.Allow(ctx, "search:user:1", limit)
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+		assert.Equal(t, remaining, result.Remaining)
+	}
+
+	result, err := limiter.Allow(ctx, "search:user:1", limit)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, time.Second, result.RetryAfter)
+	assert.Equal(t, 3*time.Second, result.Reset)
+
+	// Other callers have their own bucket.
+	result, err = limiter.Allow(ctx, "search:user:2", limit)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+
+	// One token per second comes back.
+	*now = now.Add(1500 * time.Millisecond)
+	result, err = limiter.Allow(ctx, "search:user:1", limit)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 0, result.Remaining)
+
+	result, err = limiter.Allow(ctx, "search:user:1", limit)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, 500*time.Millisecond, result.RetryAfter)
+}
+
+func TestLimiter_BucketExpires(t *testing.T) {
+	limiter, server, _ := newTestLimiter(t)
+
+	_, err := limiter.Allow(context.Background(), "default:ip:10.0.0.1", Limit{Requests: 10, Per: time.Second, Burst: 10})
+	require.NoError(t, err)
+
+	ttl := server.TTL(keyPrefix + "default:ip:10.0.0.1")
+	assert.Greater(t, ttl, time.Duration(0))
+	assert.LessOrEqual(t, ttl, 2*time.Second)
+}
+
+func TestLimiter_RedisDown(t *testing.T) {
+	limiter, server, _ := newTestLimiter(t)
+	server.Close()
+
+	_, err := limiter.Allow(context.Background(), "default:ip:10.0.0.1", Limit{Requests: 1, Per: time.Second, Burst: 1})
+	assert.Error(t, err)
+}
+
+func TestResult_Headers(t *testing.T) {
+	result := &Result{
+		Limit:      Limit{Requests: 60, Per: time.Minute, Burst: 10},
+		Remaining:  0,
+		RetryAfter: 200 * time.Millisecond,
+		Reset:      9500 * time.Millisecond,
+	}
+
+	assert.Equal(t, map[string]string{
+		"RateLimit-Limit":     "10",
+		"RateLimit-Remaining": "0",
+		"RateLimit-Reset":     "10",
+		"RateLimit-Policy":    "60;w=60;burst=10",
+		"Retry-After":         "1",
+	}, result.Headers())
+
+	result.Allowed = true
+	assert.NotContains(t, result.Headers(), "Retry-After")
+}
@@ -0,0 +1,105 @@
+package ratelimit
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// Default names the fallback group and the fallback class of a group.
+	Default = "default"
+
+	// ClassAPIKey and ClassAnonymous are the classes of callers without a
+	// role: requests made with an API key and unauthenticated requests.
+	ClassAPIKey    = "api_key"
+	ClassAnonymous = "anonymous"
+)
+
+// Limit is a token bucket holding Burst requests that refills at Requests per
+// Per. A zero Requests means unlimited.
+type Limit struct {
+	Requests int           `yaml:"requests"`
+	Per      time.Duration `yaml:"per"`
+	Burst    int           `yaml:"burst"`
+}
+
+// Policy maps route groups and caller classes to limits. A caller class is
+// the role of a user or service, ClassAPIKey or ClassAnonymous.
+type Policy struct {
+	groups map[string]map[string]Limit
+}
+
+type policyFile struct {
+	Groups map[string]map[string]Limit `yaml:"groups"`
+}
+
+// LoadPolicy reads a YAML policy file of the form
+//
+//	groups:
+//	  default:
+//	    default: {requests: 300, per: 1m, burst: 60}
+//	    admin: {requests: 0}
+//	  search:
+//	    default: {requests: 60, per: 1m, burst: 10}
+func LoadPolicy(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read rate limit policy: %w", err)
+	}
+	return ParsePolicy(data)
+}
+
+// ParsePolicy parses the YAML policy format read by LoadPolicy.
+func ParsePolicy(data []byte) (*Policy, error) {
+	var file policyFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parse rate limit policy: %w", err)
+	}
+	if len(file.Groups) == 0 {
+		return nil, errors.New("rate limit policy defines no groups")
+	}
+	return NewPolicy(file.Groups)
+}
+
+// NewPolicy checks every limit and fills in a missing Burst with Requests.
+func NewPolicy(groups map[string]map[string]Limit) (*Policy, error) {
+	policy := &Policy{groups: make(map[string]map[string]Limit, len(groups))}
+	for group, classes := range groups {
+		limits := make(map[string]Limit, len(classes))
+		for class, limit := range classes {
+			if limit.Requests < 0 || limit.Burst < 0 {
+				return nil, fmt.Errorf("group %q, class %q: requests and burst must not be negative", group, class)
+			}
+			if limit.Requests > 0 && limit.Per <= 0 {
+				return nil, fmt.Errorf("group %q, class %q: per must be positive", group, class)
+			}
+			if limit.Burst == 0 {
+				limit.Burst = limit.Requests
+			}
+			limits[class] = limit
+		}
+		policy.groups[group] = limits
+	}
+	return policy, nil
+}
+
+// Limit returns the limit for class in group. A class set in the default
+// group applies to every group that does not list it, and takes precedence
+// over the group's own default. ok is false when the caller is unlimited.
+func (policy *Policy) Limit(group, class string) (limit Limit, ok bool) {
+	for _, candidate := range [][2]string{
+		{group, class},
+		{Default, class},
+		{group, Default},
+		{Default, Default},
+	} {
+		if limit, found := policy.groups[candidate[0]][candidate[1]]; found {
+			return limit, limit.Requests > 0
+		}
+	}
+	return Limit{}, false
+}
@@ -0,0 +1,53 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testPolicy = `
+groups:
+  default:
+    default: {requests: 300, per: 1m, burst: 60}
+    admin: {requests: 0}
+  search:
+    default: {requests: 60, per: 1m}
+    api_key: {requests: 600, per: 1m, burst: 100}
+`
+
+func TestPolicy_Limit(t *testing.T) {
+	policy, err := ParsePolicy([]byte(testPolicy))
+	require.NoError(t, err)
+
+	tests := []struct {
+		group, class string
+		want         Limit
+		limited      bool
+	}{
+		{group: "search", class: ClassAPIKey, want: Limit{Requests: 600, Per: time.Minute, Burst: 100}, limited: true},
+		{group: "search", class: "user", want: Limit{Requests: 60, Per: time.Minute, Burst: 60}, limited: true},
+		{group: "search", class: "admin", limited: false},
+		{group: "public", class: ClassAnonymous, want: Limit{Requests: 300, Per: time.Minute, Burst: 60}, limited: true},
+	}
+	for _, tt := range tests {
+		limit, ok := policy.Limit(tt.group, tt.class)
+		assert.Equal(t, tt.limited, ok, "%s/%s", tt.group, tt.class)
+		if tt.limited {
+			assert.Equal(t, tt.want, limit, "%s/%s", tt.group, tt.class)
+		}
+	}
+}
+
+func TestParsePolicy_Invalid(t *testing.T) {
+	for _, data := range []string{
+		``,
+		`groups: {default: {default: {requests: 10}}}`,
+		`groups: {default: {default: {requests: -1, per: 1s}}}`,
+	} {
+		_, err := ParsePolicy([]byte(data))
+		assert.Error(t, err, data)
+	}
+}
@@ -0,0 +1,48 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"library-api-book/internal/models"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MockAPIKeyRepository struct {
+	mock.Mock
+}
+
+func (m *MockAPIKeyRepository) CreateAPIKey(ctx context.Context, tx *sql.Tx, key *models.APIKey) error {
+	args := m.Called(ctx, tx, key)
+	return args.Error(0)
+}
+
+func (m *MockAPIKeyRepository) GetAPIKeys(ctx context.Context, tx *sql.Tx, pagination *models.Pagination) ([]*models.APIKey, error) {
+	args := m.Called(ctx, tx, pagination)
+	if args.Get(0) != nil {
+		return args.Get(0).([]*models.APIKey), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockAPIKeyRepository) FindAPIKeyByPrefix(ctx context.Context, tx *sql.Tx, prefix string) (*models.APIKey, error) {
+	args := m.Called(ctx, tx, prefix)
+	if args.Get(0) != nil {
+		return args.Get(0).(*models.APIKey), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockAPIKeyRepository) RevokeAPIKey(ctx context.Context, tx *sql.Tx, id uint64, revokedAt time.Time) (*models.APIKey, error) {
+	args := m.Called(ctx, tx, id, revokedAt)
+	if args.Get(0) != nil {
+		return args.Get(0).(*models.APIKey), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockAPIKeyRepository) TouchAPIKey(ctx context.Context, tx *sql.Tx, id uint64, usedAt time.Time, staleBefore time.Time) error {
+	args := m.Called(ctx, tx, id, usedAt, staleBefore)
+	return args.Error(0)
+}
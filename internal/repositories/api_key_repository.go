@@ -0,0 +1,139 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"library-api-book/internal/models"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+var ErrAPIKeyNotFound = errors.New("api key is not found")
+
+type APIKeyRepository interface {
+	CreateAPIKey(ctx context.Context, tx *sql.Tx, key *models.APIKey) error
+	GetAPIKeys(ctx context.Context, tx *sql.Tx, pagination *models.Pagination) ([]*models.APIKey, error)
+	FindAPIKeyByPrefix(ctx context.Context, tx *sql.Tx, prefix string) (*models.APIKey, error)
+	RevokeAPIKey(ctx context.Context, tx *sql.Tx, id uint64, revokedAt time.Time) (*models.APIKey, error)
+	TouchAPIKey(ctx context.Context, tx *sql.Tx, id uint64, usedAt time.Time, staleBefore time.Time) error
+}
+
+type APIKeyRepositoryImpl struct {
+}
+
+func NewAPIKeyRepository() APIKeyRepository {
+	return &APIKeyRepositoryImpl{}
+}
+
+const apiKeyColumns = `id, name, prefix, key_hash, permissions, created_by, created_at, expires_at, last_used_at, revoked_at`
+
+func (repository *APIKeyRepositoryImpl) CreateAPIKey(ctx context.Context, tx *sql.Tx, key *models.APIKey) error {
+	query := `INSERT INTO api_keys (name, prefix, key_hash, permissions, created_by, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id`
+	err := tx.QueryRowContext(ctx, query,
+		key.Name,
+		key.Prefix,
+		key.KeyHash,
+		pq.Array(key.Permissions),
+		key.CreatedBy,
+		key.CreatedAt,
+		nullTime(key.ExpiresAt),
+	).Scan(&key.ID)
+	if err != nil {
+		return errors.New("Failed to create an api key, transaction rolled back. Reason: " + err.Error())
+	}
+	return nil
+}
+
+func (repository *APIKeyRepositoryImpl) GetAPIKeys(ctx context.Context, tx *sql.Tx, pagination *models.Pagination) ([]*models.APIKey, error) {
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys ORDER BY id DESC LIMIT $1 OFFSET $2`
+
+	rows, err := tx.QueryContext(ctx, query, pagination.PageSize, pagination.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []*models.APIKey
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+func (repository *APIKeyRepositoryImpl) FindAPIKeyByPrefix(ctx context.Context, tx *sql.Tx, prefix string) (*models.APIKey, error) {
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys WHERE prefix = $1`
+
+	key, err := scanAPIKey(tx.QueryRowContext(ctx, query, prefix))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrAPIKeyNotFound
+	}
+	return key, err
+}
+
+// RevokeAPIKey marks the key revoked. Revoking it again keeps the original
+// revocation time.
+func (repository *APIKeyRepositoryImpl) RevokeAPIKey(ctx context.Context, tx *sql.Tx, id uint64, revokedAt time.Time) (*models.APIKey, error) {
+	query := `UPDATE api_keys SET revoked_at = COALESCE(revoked_at, $1)
+		WHERE id = $2
+		RETURNING ` + apiKeyColumns
+
+	key, err := scanAPIKey(tx.QueryRowContext(ctx, query, revokedAt, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return nil, errors.New("Failed to revoke an api key, transaction rolled back. Reason: " + err.Error())
+	}
+	return key, nil
+}
+
+// TouchAPIKey records usedAt as the last use of the key unless it was already
+// recorded after staleBefore, so busy keys do not write on every request.
+func (repository *APIKeyRepositoryImpl) TouchAPIKey(ctx context.Context, tx *sql.Tx, id uint64, usedAt time.Time, staleBefore time.Time) error {
+	query := `UPDATE api_keys SET last_used_at = $1
+		WHERE id = $2 AND (last_used_at IS NULL OR last_used_at < $3)`
+
+	_, err := tx.ExecContext(ctx, query, usedAt, id, staleBefore)
+	if err != nil {
+		return errors.New("Failed to update api key usage, transaction rolled back. Reason: " + err.Error())
+	}
+	return nil
+}
+
+type apiKeyScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanAPIKey(row apiKeyScanner) (*models.APIKey, error) {
+	var key models.APIKey
+	var createdBy sql.NullInt64
+	var expiresAt, lastUsedAt, revokedAt sql.NullTime
+
+	err := row.Scan(&key.ID, &key.Name, &key.Prefix, &key.KeyHash, pq.Array(&key.Permissions), &createdBy, &key.CreatedAt, &expiresAt, &lastUsedAt, &revokedAt)
+	if err != nil {
+		return nil, err
+	}
+	if createdBy.Valid {
+		id := uint64(createdBy.Int64)
+		key.CreatedBy = &id
+	}
+	key.ExpiresAt = timeOrNil(expiresAt)
+	key.LastUsedAt = timeOrNil(lastUsedAt)
+	key.RevokedAt = timeOrNil(revokedAt)
+	return &key, nil
+}
+
+func timeOrNil(value sql.NullTime) *time.Time {
+	if !value.Valid {
+		return nil
+	}
+	return &value.Time
+}
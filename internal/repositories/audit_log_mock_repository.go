@@ -0,0 +1,38 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"library-api-book/internal/models"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MockAuditLogRepository struct {
+	mock.Mock
+}
+
+func (m *MockAuditLogRepository) CreateAuditLog(ctx context.Context, tx *sql.Tx, log *models.AuditLog) error {
+	args := m.Called(ctx, tx, log)
+	return args.Error(0)
+}
+
+func (m *MockAuditLogRepository) GetAuditLogs(ctx context.Context, tx *sql.Tx, filter *models.AuditLogFilter, pagination *models.Pagination) ([]*models.AuditLog, error) {
+	args := m.Called(ctx, tx, filter, pagination)
+	if args.Get(0) != nil {
+		return args.Get(0).([]*models.AuditLog), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockAuditLogRepository) StreamAuditLogs(ctx context.Context, tx *sql.Tx, filter *models.AuditLogFilter, fn func(*models.AuditLog) error) error {
+	args := m.Called(ctx, tx, filter, fn)
+	if logs, ok := args.Get(0).([]*models.AuditLog); ok {
+		for _, log := range logs {
+			if err := fn(log); err != nil {
+				return err
+			}
+		}
+	}
+	return args.Error(1)
+}
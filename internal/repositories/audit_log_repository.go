@@ -0,0 +1,142 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"library-api-book/internal/models"
+	"strconv"
+	"strings"
+)
+
+type AuditLogRepository interface {
+	CreateAuditLog(ctx context.Context, tx *sql.Tx, log *models.AuditLog) error
+	GetAuditLogs(ctx context.Context, tx *sql.Tx, filter *models.AuditLogFilter, pagination *models.Pagination) ([]*models.AuditLog, error)
+	StreamAuditLogs(ctx context.Context, tx *sql.Tx, filter *models.AuditLogFilter, fn func(*models.AuditLog) error) error
+}
+
+type AuditLogRepositoryImpl struct {
+}
+
+func NewAuditLogRepository() AuditLogRepository {
+	return &AuditLogRepositoryImpl{}
+}
+
+func (repository *AuditLogRepositoryImpl) CreateAuditLog(ctx context.Context, tx *sql.Tx, log *models.AuditLog) error {
+	query := `INSERT INTO audit_logs (occurred_at, actor_id, role, action, target, ip, request_id, outcome, status_code, detail)
+		VALUES ($1, $2, NULLIF($3, ''), $4, $5, $6, $7, $8, $9, NULLIF($10, ''))
+		RETURNING id`
+	err := tx.QueryRowContext(ctx, query,
+		log.OccurredAt,
+		log.ActorID,
+		log.Role,
+		log.Action,
+		log.Target,
+		log.IP,
+		log.RequestID,
+		log.Outcome,
+		log.StatusCode,
+		log.Detail,
+	).Scan(&log.ID)
+	if err != nil {
+		return errors.New("Failed to create an audit log, transaction rolled back. Reason: " + err.Error())
+	}
+	return nil
+}
+
+func (repository *AuditLogRepositoryImpl) GetAuditLogs(ctx context.Context, tx *sql.Tx, filter *models.AuditLogFilter, pagination *models.Pagination) ([]*models.AuditLog, error) {
+	where, params := auditLogWhere(filter)
+	query := `SELECT id, occurred_at, actor_id, COALESCE(role, ''), action, target, ip, request_id, outcome, status_code, COALESCE(detail, '')
+		FROM audit_logs` + where + `
+		ORDER BY id DESC
+		LIMIT $` + strconv.Itoa(len(params)+1) + ` OFFSET $` + strconv.Itoa(len(params)+2)
+	params = append(params, pagination.PageSize, pagination.Offset)
+
+	rows, err := tx.QueryContext(ctx, query, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var logs []*models.AuditLog
+	for rows.Next() {
+		log, err := scanAuditLog(rows)
+		if err != nil {
+			return nil, err
+		}
+		logs = append(logs, log)
+	}
+	return logs, rows.Err()
+}
+
+// StreamAuditLogs calls fn for every matching entry in insertion order without
+// loading the whole result into memory.
+func (repository *AuditLogRepositoryImpl) StreamAuditLogs(ctx context.Context, tx *sql.Tx, filter *models.AuditLogFilter, fn func(*models.AuditLog) error) error {
+	where, params := auditLogWhere(filter)
+	query := `SELECT id, occurred_at, actor_id, COALESCE(role, ''), action, target, ip, request_id, outcome, status_code, COALESCE(detail, '')
+		FROM audit_logs` + where + `
+		ORDER BY id ASC`
+
+	rows, err := tx.QueryContext(ctx, query, params...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		log, err := scanAuditLog(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(log); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func auditLogWhere(filter *models.AuditLogFilter) (string, []interface{}) {
+	var conditions []string
+	var params []interface{}
+
+	add := func(condition string, value interface{}) {
+		params = append(params, value)
+		conditions = append(conditions, strings.Replace(condition, "?", "$"+strconv.Itoa(len(params)), 1))
+	}
+
+	if filter.ActorID != nil {
+		add("actor_id = ?", *filter.ActorID)
+	}
+	if filter.Action != "" {
+		add("action ILIKE ?", "%"+filter.Action+"%")
+	}
+	if filter.Outcome != "" {
+		add("outcome = ?", filter.Outcome)
+	}
+	if filter.From != nil {
+		add("occurred_at >= ?", *filter.From)
+	}
+	if filter.Until != nil {
+		add("occurred_at <= ?", *filter.Until)
+	}
+
+	if len(conditions) == 0 {
+		return "", params
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), params
+}
+
+func scanAuditLog(rows *sql.Rows) (*models.AuditLog, error) {
+	var log models.AuditLog
+	var actorID sql.NullInt64
+
+	err := rows.Scan(&log.ID, &log.OccurredAt, &actorID, &log.Role, &log.Action, &log.Target, &log.IP, &log.RequestID, &log.Outcome, &log.StatusCode, &log.Detail)
+	if err != nil {
+		return nil, err
+	}
+	if actorID.Valid {
+		id := uint64(actorID.Int64)
+		log.ActorID = &id
+	}
+	return &log, nil
+}
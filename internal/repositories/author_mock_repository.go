@@ -0,0 +1,37 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"library-api-book/internal/models"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MockAuthorRepository struct {
+	mock.Mock
+}
+
+func (m *MockAuthorRepository) FindAuthorByID(ctx context.Context, tx *sql.Tx, id uint64) (*models.Author, error) {
+	args := m.Called(ctx, tx, id)
+	if args.Get(0) != nil {
+		return args.Get(0).(*models.Author), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockAuthorRepository) FindAuthorByName(ctx context.Context, tx *sql.Tx, name string) (*models.Author, error) {
+	args := m.Called(ctx, tx, name)
+	if args.Get(0) != nil {
+		return args.Get(0).(*models.Author), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockAuthorRepository) FindAuthorByUserID(ctx context.Context, tx *sql.Tx, userID uint64) (*models.Author, error) {
+	args := m.Called(ctx, tx, userID)
+	if args.Get(0) != nil {
+		return args.Get(0).(*models.Author), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
@@ -0,0 +1,83 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"library-api-book/internal/models"
+)
+
+var ErrAuthorNotFound = errors.New("author is not found")
+
+type AuthorRepository interface {
+	FindAuthorByID(ctx context.Context, tx *sql.Tx, id uint64) (*models.Author, error)
+	FindAuthorByName(ctx context.Context, tx *sql.Tx, name string) (*models.Author, error)
+	FindAuthorByUserID(ctx context.Context, tx *sql.Tx, userID uint64) (*models.Author, error)
+}
+
+type AuthorRepositoryImpl struct {
+}
+
+func NewAuthorRepository() AuthorRepository {
+	return &AuthorRepositoryImpl{}
+}
+
+func (repository *AuthorRepositoryImpl) FindAuthorByID(ctx context.Context, tx *sql.Tx, id uint64) (*models.Author, error) {
+	query := "SELECT id, user_id, name FROM authors WHERE id = $1"
+	rows, err := tx.QueryContext(ctx, query, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var author = models.Author{}
+	if rows.Next() {
+		err := rows.Scan(&author.ID, &author.UserID, &author.Name)
+		if err != nil {
+			return nil, err
+		}
+		return &author, nil
+	} else {
+		return nil, ErrAuthorNotFound
+	}
+}
+
+func (repository *AuthorRepositoryImpl) FindAuthorByName(ctx context.Context, tx *sql.Tx, name string) (*models.Author, error) {
+	query := "SELECT id, user_id, name FROM authors WHERE LOWER(name) = LOWER($1) ORDER BY id LIMIT 1"
+	rows, err := tx.QueryContext(ctx, query, name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var author = models.Author{}
+	if rows.Next() {
+		err := rows.Scan(&author.ID, &author.UserID, &author.Name)
+		if err != nil {
+			return nil, err
+		}
+		return &author, nil
+	} else {
+		return nil, ErrAuthorNotFound
+	}
+}
+
+func (repository *AuthorRepositoryImpl) FindAuthorByUserID(ctx context.Context, tx *sql.Tx, userID uint64) (*models.Author, error) {
+	query := "SELECT id, user_id, name FROM authors WHERE user_id = $1"
+	rows, err := tx.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var author = models.Author{}
+	if rows.Next() {
+		err := rows.Scan(&author.ID, &author.UserID, &author.Name)
+		if err != nil {
+			return nil, err
+		}
+		return &author, nil
+	} else {
+		return nil, ErrAuthorNotFound
+	}
+}
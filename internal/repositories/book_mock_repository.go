@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"library-api-book/internal/models"
+	"time"
 
 	"github.com/stretchr/testify/mock"
 )
@@ -30,13 +31,29 @@ func (m *MockBookRepository) UpdateBook(ctx context.Context, tx *sql.Tx, book *m
 	return args.Error(0)
 }
 
-func (m *MockBookRepository) DeleteBook(ctx context.Context, tx *sql.Tx, id uint64) error {
-	args := m.Called(ctx, tx, id)
+func (m *MockBookRepository) PatchBook(ctx context.Context, tx *sql.Tx, book *models.Book, columns []string) error {
+	args := m.Called(ctx, tx, book, columns)
 	return args.Error(0)
 }
 
-func (m *MockBookRepository) GetAllBooks(ctx context.Context, tx *sql.Tx, pagination *models.Pagination, searchQuery string) ([]*models.Book, error) {
-	args := m.Called(ctx, tx, pagination, searchQuery)
+func (m *MockBookRepository) DeleteBook(ctx context.Context, tx *sql.Tx, id uint64) (*models.Book, error) {
+	args := m.Called(ctx, tx, id)
+	if args.Get(0) != nil {
+		return args.Get(0).(*models.Book), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockBookRepository) GetAllBooks(ctx context.Context, tx *sql.Tx, pagination *models.Pagination, filter *models.BookFilter) ([]*models.Book, error) {
+	args := m.Called(ctx, tx, pagination, filter)
+	if args.Get(0) != nil {
+		return args.Get(0).([]*models.Book), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockBookRepository) FindBooksByIDs(ctx context.Context, tx *sql.Tx, ids []uint64) ([]*models.Book, error) {
+	args := m.Called(ctx, tx, ids)
 	if args.Get(0) != nil {
 		return args.Get(0).([]*models.Book), args.Error(1)
 	}
@@ -50,3 +67,79 @@ func (m *MockBookRepository) GetRecommendationBooks(ctx context.Context, tx *sql
 	}
 	return nil, args.Error(1)
 }
+
+func (m *MockBookRepository) FindBookByISBN(ctx context.Context, tx *sql.Tx, isbn string) (*models.Book, error) {
+	args := m.Called(ctx, tx, isbn)
+	if args.Get(0) != nil {
+		return args.Get(0).(*models.Book), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockBookRepository) GetBooksForHarvest(ctx context.Context, tx *sql.Tx, filter *models.HarvestFilter) ([]*models.Book, error) {
+	args := m.Called(ctx, tx, filter)
+	if args.Get(0) != nil {
+		return args.Get(0).([]*models.Book), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockBookRepository) CountBooksForHarvest(ctx context.Context, tx *sql.Tx, filter *models.HarvestFilter) (int, error) {
+	args := m.Called(ctx, tx, filter)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockBookRepository) FindBookForHarvest(ctx context.Context, tx *sql.Tx, id uint64) (*models.Book, error) {
+	args := m.Called(ctx, tx, id)
+	if args.Get(0) != nil {
+		return args.Get(0).(*models.Book), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockBookRepository) FindEarliestUpdatedAt(ctx context.Context, tx *sql.Tx) (time.Time, error) {
+	args := m.Called(ctx, tx)
+	return args.Get(0).(time.Time), args.Error(1)
+}
+
+func (m *MockBookRepository) UpdateBookCover(ctx context.Context, tx *sql.Tx, id uint64, coverPath string) (int32, error) {
+	args := m.Called(ctx, tx, id, coverPath)
+	return args.Get(0).(int32), args.Error(1)
+}
+
+func (m *MockBookRepository) AdjustStock(ctx context.Context, tx *sql.Tx, id uint64, delta int32) (*models.Book, error) {
+	args := m.Called(ctx, tx, id, delta)
+	if args.Get(0) != nil {
+		return args.Get(0).(*models.Book), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockBookRepository) RestoreBook(ctx context.Context, tx *sql.Tx, id uint64) (*models.Book, error) {
+	args := m.Called(ctx, tx, id)
+	if args.Get(0) != nil {
+		return args.Get(0).(*models.Book), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockBookRepository) GetDeletedBooks(ctx context.Context, tx *sql.Tx, pagination *models.Pagination) ([]*models.Book, error) {
+	args := m.Called(ctx, tx, pagination)
+	if args.Get(0) != nil {
+		return args.Get(0).([]*models.Book), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockBookRepository) HasOpenBorrows(ctx context.Context, tx *sql.Tx, id uint64) (bool, error) {
+	args := m.Called(ctx, tx, id)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockBookRepository) PurgeBook(ctx context.Context, tx *sql.Tx, id uint64) (*models.Book, error) {
+	args := m.Called(ctx, tx, id)
+	if args.Get(0) != nil {
+		return args.Get(0).(*models.Book), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
@@ -5,15 +5,39 @@ import (
 	"database/sql"
 	"errors"
 	"library-api-book/internal/models"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+var (
+	ErrBookNotFound        = errors.New("book is not found")
+	ErrBookVersionConflict = errors.New("book version does not match")
+	ErrBookOutOfStock      = errors.New("book is out of stock")
 )
 
 type BookRepository interface {
 	CreateBook(ctx context.Context, tx *sql.Tx, book *models.Book) error
 	FindBookByID(ctx context.Context, tx *sql.Tx, id uint64) (*models.Book, error)
 	UpdateBook(ctx context.Context, tx *sql.Tx, book *models.Book) error
-	DeleteBook(ctx context.Context, tx *sql.Tx, id uint64) error
-	GetAllBooks(ctx context.Context, tx *sql.Tx, pagination *models.Pagination, searchQuery string) ([]*models.Book, error)
+	PatchBook(ctx context.Context, tx *sql.Tx, book *models.Book, columns []string) error
+	DeleteBook(ctx context.Context, tx *sql.Tx, id uint64) (*models.Book, error)
+	GetAllBooks(ctx context.Context, tx *sql.Tx, pagination *models.Pagination, filter *models.BookFilter) ([]*models.Book, error)
+	FindBooksByIDs(ctx context.Context, tx *sql.Tx, ids []uint64) ([]*models.Book, error)
 	GetRecommendationBooks(ctx context.Context, tx *sql.Tx, userID uint64) ([]*models.Book, error)
+	FindBookByISBN(ctx context.Context, tx *sql.Tx, isbn string) (*models.Book, error)
+	GetBooksForHarvest(ctx context.Context, tx *sql.Tx, filter *models.HarvestFilter) ([]*models.Book, error)
+	CountBooksForHarvest(ctx context.Context, tx *sql.Tx, filter *models.HarvestFilter) (int, error)
+	FindBookForHarvest(ctx context.Context, tx *sql.Tx, id uint64) (*models.Book, error)
+	FindEarliestUpdatedAt(ctx context.Context, tx *sql.Tx) (time.Time, error)
+	UpdateBookCover(ctx context.Context, tx *sql.Tx, id uint64, coverPath string) (int32, error)
+	AdjustStock(ctx context.Context, tx *sql.Tx, id uint64, delta int32) (*models.Book, error)
+	RestoreBook(ctx context.Context, tx *sql.Tx, id uint64) (*models.Book, error)
+	GetDeletedBooks(ctx context.Context, tx *sql.Tx, pagination *models.Pagination) ([]*models.Book, error)
+	HasOpenBorrows(ctx context.Context, tx *sql.Tx, id uint64) (bool, error)
+	PurgeBook(ctx context.Context, tx *sql.Tx, id uint64) (*models.Book, error)
 }
 
 type BookRepositoryImpl struct {
@@ -24,9 +48,9 @@ func NewBookRepository() BookRepository {
 }
 
 func (repository *BookRepositoryImpl) CreateBook(ctx context.Context, tx *sql.Tx, book *models.Book) error {
-	query := `INSERT INTO books (author_id, title, stock, publish_at, updated_at) VALUES ($1, $2, $3, $4, $5)`
-	response, err := tx.ExecContext(ctx, query, book.AuthorID, book.Title, book.Stock, book.PublishAt, book.UpdatedAt)
-	if err != nil || response == nil {
+	query := `INSERT INTO books (author_id, title, isbn, publisher, stock, publish_at, updated_at) VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, ''), $5, $6, $7) RETURNING id, version`
+	err := tx.QueryRowContext(ctx, query, book.AuthorID, book.Title, book.ISBN, book.Publisher, book.Stock, book.PublishAt, book.UpdatedAt).Scan(&book.ID, &book.Version)
+	if err != nil {
 		return errors.New("Failed to create a book, transaction rolled back. Reason: " + err.Error())
 	}
 
@@ -34,7 +58,7 @@ func (repository *BookRepositoryImpl) CreateBook(ctx context.Context, tx *sql.Tx
 }
 
 func (repository *BookRepositoryImpl) FindBookByID(ctx context.Context, tx *sql.Tx, id uint64) (*models.Book, error) {
-	query := "SELECT id, author_id, title, stock, publish_at, updated_at FROM books WHERE id = $1"
+	query := "SELECT id, author_id, title, COALESCE(isbn, ''), COALESCE(publisher, ''), COALESCE(cover_path, ''), stock, publish_at, updated_at, version FROM books WHERE id = $1 AND deleted_at IS NULL"
 	rows, err := tx.QueryContext(ctx, query, id)
 	if err != nil {
 		return nil, err
@@ -43,57 +67,249 @@ func (repository *BookRepositoryImpl) FindBookByID(ctx context.Context, tx *sql.
 
 	var book = models.Book{}
 	if rows.Next() {
-		err := rows.Scan(&book.ID, &book.AuthorID, &book.Title, &book.Stock, &book.PublishAt, &book.UpdatedAt)
+		err := rows.Scan(&book.ID, &book.AuthorID, &book.Title, &book.ISBN, &book.Publisher, &book.CoverPath, &book.Stock, &book.PublishAt, &book.UpdatedAt, &book.Version)
 		if err != nil {
 			return nil, err
 		}
 		return &book, nil
 	} else {
-		return nil, errors.New("book is not found")
+		return nil, ErrBookNotFound
 	}
 }
 
+// UpdateBook only writes the row when it is still at book.Version and bumps
+// the version in the same statement, so concurrent writers cannot silently
+// overwrite each other. On success book.Version holds the new version.
 func (repository *BookRepositoryImpl) UpdateBook(ctx context.Context, tx *sql.Tx, book *models.Book) error {
-	query := `UPDATE books SET author_id = $1, title = $2, stock = $3, updated_at = $4 WHERE id = $5`
+	query := `UPDATE books SET author_id = $1, title = $2, isbn = NULLIF($3, ''), publisher = NULLIF($4, ''), stock = $5, updated_at = $6, version = version + 1
+		WHERE id = $7 AND version = $8 AND deleted_at IS NULL
+		RETURNING version`
 
-	_, err := tx.ExecContext(ctx, query,
+	err := tx.QueryRowContext(ctx, query,
 		book.AuthorID,
 		book.Title,
+		book.ISBN,
+		book.Publisher,
 		book.Stock,
 		book.UpdatedAt,
 		book.ID,
-	)
+		book.Version,
+	).Scan(&book.Version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrBookVersionConflict
+	}
 	if err != nil {
 		return errors.New("Failed to update a book, transaction rolled back. Reason: " + err.Error())
 	}
 	return nil
 }
 
-func (repository *BookRepositoryImpl) DeleteBook(ctx context.Context, tx *sql.Tx, id uint64) error {
-	SQL := `DELETE FROM books WHERE id = $1`
+// PatchBook writes only the given columns of book. Like UpdateBook it is
+// guarded by book.Version and bumps it on success.
+func (repository *BookRepositoryImpl) PatchBook(ctx context.Context, tx *sql.Tx, book *models.Book, columns []string) error {
+	if len(columns) == 0 {
+		return nil
+	}
+
+	var assignments []string
+	var params []interface{}
+
+	for _, column := range columns {
+		var value interface{}
+		placeholder := "$" + strconv.Itoa(len(params)+1)
+
+		switch column {
+		case "author_id":
+			value = book.AuthorID
+		case "title":
+			value = book.Title
+		case "isbn":
+			value = book.ISBN
+			placeholder = "NULLIF(" + placeholder + ", '')"
+		case "publisher":
+			value = book.Publisher
+			placeholder = "NULLIF(" + placeholder + ", '')"
+		case "stock":
+			value = book.Stock
+		default:
+			return errors.New("Failed to patch a book, unknown column " + column)
+		}
+
+		assignments = append(assignments, column+" = "+placeholder)
+		params = append(params, value)
+	}
 
-	_, err := tx.ExecContext(ctx, SQL, id)
+	params = append(params, book.UpdatedAt, book.ID, book.Version)
+	n := len(params)
+	query := `UPDATE books SET ` + strings.Join(assignments, ", ") + `, updated_at = $` + strconv.Itoa(n-2) + `, version = version + 1
+		WHERE id = $` + strconv.Itoa(n-1) + ` AND version = $` + strconv.Itoa(n) + ` AND deleted_at IS NULL
+		RETURNING version`
+
+	err := tx.QueryRowContext(ctx, query, params...).Scan(&book.Version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrBookVersionConflict
+	}
 	if err != nil {
-		return errors.New("Failed to update a book, transaction rolled back. Reason: " + err.Error())
+		return errors.New("Failed to patch a book, transaction rolled back. Reason: " + err.Error())
 	}
 	return nil
 }
 
-func (repository *BookRepositoryImpl) GetAllBooks(ctx context.Context, tx *sql.Tx, pagination *models.Pagination, searchQuery string) ([]*models.Book, error) {
+// UpdateBookCover sets the cover and returns the new book version.
+func (repository *BookRepositoryImpl) UpdateBookCover(ctx context.Context, tx *sql.Tx, id uint64, coverPath string) (int32, error) {
+	query := `UPDATE books SET cover_path = $1, updated_at = $2, version = version + 1 WHERE id = $3 AND deleted_at IS NULL RETURNING version`
+
+	var version int32
+	err := tx.QueryRowContext(ctx, query, coverPath, time.Now(), id).Scan(&version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrBookNotFound
+	}
+	if err != nil {
+		return 0, errors.New("Failed to update a book cover, transaction rolled back. Reason: " + err.Error())
+	}
+	return version, nil
+}
+
+// AdjustStock changes the stock of a book by delta in a single statement and
+// bumps the version, so concurrent borrows and returns add up instead of
+// conflicting. The stock never drops below zero; ErrBookOutOfStock is returned
+// instead. The book is returned as it is stored after the change.
+func (repository *BookRepositoryImpl) AdjustStock(ctx context.Context, tx *sql.Tx, id uint64, delta int32) (*models.Book, error) {
+	SQL := `UPDATE books SET stock = stock + $1, updated_at = $2, version = version + 1
+		WHERE id = $3 AND deleted_at IS NULL AND stock + $1 >= 0
+		RETURNING id, author_id, title, COALESCE(isbn, ''), COALESCE(publisher, ''), COALESCE(cover_path, ''), stock, publish_at, updated_at, version, deleted_at`
+
+	book, err := scanReturnedBook(tx.QueryRowContext(ctx, SQL, delta, time.Now(), id))
+	if errors.Is(err, sql.ErrNoRows) {
+		var exists bool
+		err = tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM books WHERE id = $1 AND deleted_at IS NULL)`, id).Scan(&exists)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			return nil, ErrBookOutOfStock
+		}
+		return nil, ErrBookNotFound
+	}
+	if err != nil {
+		return nil, errors.New("Failed to update a book stock, transaction rolled back. Reason: " + err.Error())
+	}
+	return book, nil
+}
+
+// DeleteBook only marks the book as deleted. Borrows and user activities
+// reference books with ON DELETE CASCADE, so removing the row would also wipe
+// the circulation history; use PurgeBook for that. The deleted book is
+// returned as it is stored after the change.
+func (repository *BookRepositoryImpl) DeleteBook(ctx context.Context, tx *sql.Tx, id uint64) (*models.Book, error) {
+	SQL := `UPDATE books SET deleted_at = $1, updated_at = $1, version = version + 1 WHERE id = $2 AND deleted_at IS NULL
+		RETURNING id, author_id, title, COALESCE(isbn, ''), COALESCE(publisher, ''), COALESCE(cover_path, ''), stock, publish_at, updated_at, version, deleted_at`
+
+	book, err := scanReturnedBook(tx.QueryRowContext(ctx, SQL, time.Now(), id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrBookNotFound
+	}
+	if err != nil {
+		return nil, errors.New("Failed to delete a book, transaction rolled back. Reason: " + err.Error())
+	}
+	return book, nil
+}
+
+func (repository *BookRepositoryImpl) RestoreBook(ctx context.Context, tx *sql.Tx, id uint64) (*models.Book, error) {
+	SQL := `UPDATE books SET deleted_at = NULL, updated_at = $1, version = version + 1 WHERE id = $2 AND deleted_at IS NOT NULL
+		RETURNING id, author_id, title, COALESCE(isbn, ''), COALESCE(publisher, ''), COALESCE(cover_path, ''), stock, publish_at, updated_at, version, deleted_at`
+
+	book, err := scanReturnedBook(tx.QueryRowContext(ctx, SQL, time.Now(), id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrBookNotFound
+	}
+	if err != nil {
+		return nil, errors.New("Failed to restore a book, transaction rolled back. Reason: " + err.Error())
+	}
+	return book, nil
+}
+
+func (repository *BookRepositoryImpl) GetDeletedBooks(ctx context.Context, tx *sql.Tx, pagination *models.Pagination) ([]*models.Book, error) {
+	query := `SELECT id, author_id, title, COALESCE(isbn, ''), COALESCE(publisher, ''), COALESCE(cover_path, ''), stock, publish_at, updated_at, version, deleted_at
+		FROM books
+		WHERE deleted_at IS NOT NULL
+		ORDER BY deleted_at DESC, id DESC
+		LIMIT $1 OFFSET $2`
+
+	rows, err := tx.QueryContext(ctx, query, pagination.PageSize, pagination.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var books []*models.Book
+	for rows.Next() {
+		var book models.Book
+		var deletedAt sql.NullTime
+		err := rows.Scan(&book.ID, &book.AuthorID, &book.Title, &book.ISBN, &book.Publisher, &book.CoverPath, &book.Stock, &book.PublishAt, &book.UpdatedAt, &book.Version, &deletedAt)
+		if err != nil {
+			return nil, err
+		}
+		if deletedAt.Valid {
+			book.DeletedAt = &deletedAt.Time
+		}
+
+		books = append(books, &book)
+	}
+	return books, nil
+}
+
+func (repository *BookRepositoryImpl) HasOpenBorrows(ctx context.Context, tx *sql.Tx, id uint64) (bool, error) {
+	query := `SELECT EXISTS (SELECT 1 FROM borrows WHERE book_id = $1 AND returned_at IS NULL)`
+
+	var exists bool
+	err := tx.QueryRowContext(ctx, query, id).Scan(&exists)
+	if err != nil {
+		return false, err
+	}
+	return exists, nil
+}
+
+// PurgeBook permanently removes a book that has already been soft deleted,
+// together with its borrows and user activities. The removed row is returned
+// so the caller can keep a last revision of it.
+func (repository *BookRepositoryImpl) PurgeBook(ctx context.Context, tx *sql.Tx, id uint64) (*models.Book, error) {
+	SQL := `DELETE FROM books WHERE id = $1 AND deleted_at IS NOT NULL
+		RETURNING id, author_id, title, COALESCE(isbn, ''), COALESCE(publisher, ''), COALESCE(cover_path, ''), stock, publish_at, updated_at, version, deleted_at`
+
+	book, err := scanReturnedBook(tx.QueryRowContext(ctx, SQL, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrBookNotFound
+	}
+	if err != nil {
+		return nil, errors.New("Failed to purge a book, transaction rolled back. Reason: " + err.Error())
+	}
+	return book, nil
+}
+
+func (repository *BookRepositoryImpl) GetAllBooks(ctx context.Context, tx *sql.Tx, pagination *models.Pagination, filter *models.BookFilter) ([]*models.Book, error) {
 	query := `
-		SELECT id, author_id, title, stock, publish_at, updated_at 
-		FROM books 
+		SELECT id, author_id, title, COALESCE(isbn, ''), COALESCE(publisher, ''), COALESCE(cover_path, ''), stock, publish_at, updated_at, version
+		FROM books
+		WHERE deleted_at IS NULL
 	`
 
 	var params []interface{}
 	params = append(params, pagination.PageSize, pagination.Offset)
 
-	if searchQuery != "" {
-		query += ` WHERE title ILIKE $3`
-		params = append(params, "%"+searchQuery+"%")
+	if filter.Search != "" {
+		params = append(params, "%"+filter.Search+"%")
+		query += ` AND title ILIKE $` + strconv.Itoa(len(params))
+	}
+	if filter.AuthorID != 0 {
+		params = append(params, filter.AuthorID)
+		query += ` AND author_id = $` + strconv.Itoa(len(params))
+	}
+	if filter.InStock {
+		query += ` AND stock > 0`
 	}
 
-	query += ` ORDER BY title ASC, publish_at DESC LIMIT $1 OFFSET $2`
+	query += ` ORDER BY title ASC, publish_at DESC, id ASC LIMIT $1 OFFSET $2`
 
 	rows, err := tx.QueryContext(ctx, query, params...)
 	if err != nil {
@@ -104,7 +320,7 @@ func (repository *BookRepositoryImpl) GetAllBooks(ctx context.Context, tx *sql.T
 	var books []*models.Book
 	for rows.Next() {
 		var book models.Book
-		err := rows.Scan(&book.ID, &book.AuthorID, &book.Title, &book.Stock, &book.PublishAt, &book.UpdatedAt)
+		err := rows.Scan(&book.ID, &book.AuthorID, &book.Title, &book.ISBN, &book.Publisher, &book.CoverPath, &book.Stock, &book.PublishAt, &book.UpdatedAt, &book.Version)
 		if err != nil {
 			return nil, err
 		}
@@ -114,11 +330,43 @@ func (repository *BookRepositoryImpl) GetAllBooks(ctx context.Context, tx *sql.T
 	}
 	return books, nil
 }
+
+// FindBooksByIDs returns the books that exist among ids, in no particular
+// order.
+func (repository *BookRepositoryImpl) FindBooksByIDs(ctx context.Context, tx *sql.Tx, ids []uint64) ([]*models.Book, error) {
+	query := `SELECT id, author_id, title, COALESCE(isbn, ''), COALESCE(publisher, ''), COALESCE(cover_path, ''), stock, publish_at, updated_at, version
+		FROM books
+		WHERE id = ANY($1) AND deleted_at IS NULL`
+
+	ints := make([]int64, len(ids))
+	for i, id := range ids {
+		ints[i] = int64(id)
+	}
+
+	rows, err := tx.QueryContext(ctx, query, pq.Array(ints))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var books []*models.Book
+	for rows.Next() {
+		var book models.Book
+		err := rows.Scan(&book.ID, &book.AuthorID, &book.Title, &book.ISBN, &book.Publisher, &book.CoverPath, &book.Stock, &book.PublishAt, &book.UpdatedAt, &book.Version)
+		if err != nil {
+			return nil, err
+		}
+		books = append(books, &book)
+	}
+	return books, rows.Err()
+}
+
 func (repository *BookRepositoryImpl) GetRecommendationBooks(ctx context.Context, tx *sql.Tx, userID uint64) ([]*models.Book, error) {
-	query := `SELECT b.id, b.title, b.author_id, b.stock, b.publish_at, b.updated_at
+	query := `SELECT b.id, b.author_id, b.title, COALESCE(b.isbn, ''), COALESCE(b.publisher, ''), COALESCE(b.cover_path, ''), b.stock, b.publish_at, b.updated_at, b.version
 		FROM books b
 		JOIN book_categories bc ON b.id = bc.book_id
-		WHERE bc.category_id IN (
+		WHERE b.deleted_at IS NULL
+		AND bc.category_id IN (
 			SELECT bc.category_id
 			FROM user_activities ua
 			JOIN book_categories bc ON ua.book_id = bc.book_id
@@ -138,7 +386,7 @@ func (repository *BookRepositoryImpl) GetRecommendationBooks(ctx context.Context
 	var books []*models.Book
 	for rows.Next() {
 		var book models.Book
-		err := rows.Scan(&book.ID, &book.AuthorID, &book.Title, &book.Stock, &book.PublishAt, &book.UpdatedAt)
+		err := rows.Scan(&book.ID, &book.AuthorID, &book.Title, &book.ISBN, &book.Publisher, &book.CoverPath, &book.Stock, &book.PublishAt, &book.UpdatedAt, &book.Version)
 		if err != nil {
 			return nil, err
 		}
@@ -148,3 +396,134 @@ func (repository *BookRepositoryImpl) GetRecommendationBooks(ctx context.Context
 	}
 	return books, nil
 }
+
+func (repository *BookRepositoryImpl) FindBookByISBN(ctx context.Context, tx *sql.Tx, isbn string) (*models.Book, error) {
+	query := "SELECT id, author_id, title, COALESCE(isbn, ''), COALESCE(publisher, ''), COALESCE(cover_path, ''), stock, publish_at, updated_at, version FROM books WHERE isbn = $1 AND deleted_at IS NULL LIMIT 1"
+	rows, err := tx.QueryContext(ctx, query, isbn)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var book = models.Book{}
+	if rows.Next() {
+		err := rows.Scan(&book.ID, &book.AuthorID, &book.Title, &book.ISBN, &book.Publisher, &book.CoverPath, &book.Stock, &book.PublishAt, &book.UpdatedAt, &book.Version)
+		if err != nil {
+			return nil, err
+		}
+		return &book, nil
+	} else {
+		return nil, ErrBookNotFound
+	}
+}
+
+// harvestSource lists every book a harvester may have seen: live and soft
+// deleted books from books, and purged books from their last revision. Purged
+// rows only carry the id and the time of the purge, which is all a deleted
+// OAI-PMH header needs.
+const harvestSource = `(
+		SELECT id, author_id, title, isbn, publisher, cover_path, stock, publish_at, updated_at, version, deleted_at
+		FROM books
+		UNION ALL
+		SELECT book_id, 0, '', NULL, NULL, NULL, 0, created_at, created_at, revision, created_at
+		FROM book_revisions
+		WHERE action = 'purge'
+	) harvest`
+
+const harvestColumns = `id, author_id, title, COALESCE(isbn, ''), COALESCE(publisher, ''), COALESCE(cover_path, ''), stock, publish_at, updated_at, version, deleted_at`
+
+// GetBooksForHarvest includes deleted books; they come back with DeletedAt set
+// so the caller can report them as deleted records.
+func (repository *BookRepositoryImpl) GetBooksForHarvest(ctx context.Context, tx *sql.Tx, filter *models.HarvestFilter) ([]*models.Book, error) {
+	query := `SELECT ` + harvestColumns + `
+		FROM ` + harvestSource + `
+		WHERE ($1::timestamp IS NULL OR updated_at >= $1)
+		AND ($2::timestamp IS NULL OR updated_at <= $2)
+		AND ($3::timestamp IS NULL OR (updated_at, id) > ($3::timestamp, $4::int))
+		ORDER BY updated_at ASC, id ASC
+		LIMIT $5`
+
+	rows, err := tx.QueryContext(ctx, query, nullTime(filter.From), nullTime(filter.Until), nullTime(filter.AfterUpdatedAt), filter.AfterID, filter.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var books []*models.Book
+	for rows.Next() {
+		var book models.Book
+		var deletedAt sql.NullTime
+		err := rows.Scan(&book.ID, &book.AuthorID, &book.Title, &book.ISBN, &book.Publisher, &book.CoverPath, &book.Stock, &book.PublishAt, &book.UpdatedAt, &book.Version, &deletedAt)
+		if err != nil {
+			return nil, err
+		}
+		if deletedAt.Valid {
+			book.DeletedAt = &deletedAt.Time
+		}
+
+		books = append(books, &book)
+	}
+	return books, rows.Err()
+}
+
+func (repository *BookRepositoryImpl) CountBooksForHarvest(ctx context.Context, tx *sql.Tx, filter *models.HarvestFilter) (int, error) {
+	query := `SELECT COUNT(*)
+		FROM ` + harvestSource + `
+		WHERE ($1::timestamp IS NULL OR updated_at >= $1)
+		AND ($2::timestamp IS NULL OR updated_at <= $2)`
+
+	var count int
+	err := tx.QueryRowContext(ctx, query, nullTime(filter.From), nullTime(filter.Until)).Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// FindBookForHarvest finds a book by ID whether it is live, soft deleted or
+// purged.
+func (repository *BookRepositoryImpl) FindBookForHarvest(ctx context.Context, tx *sql.Tx, id uint64) (*models.Book, error) {
+	query := `SELECT ` + harvestColumns + `
+		FROM ` + harvestSource + `
+		WHERE id = $1`
+
+	book, err := scanReturnedBook(tx.QueryRowContext(ctx, query, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrBookNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return book, nil
+}
+
+func (repository *BookRepositoryImpl) FindEarliestUpdatedAt(ctx context.Context, tx *sql.Tx) (time.Time, error) {
+	query := `SELECT MIN(updated_at) FROM ` + harvestSource
+
+	var earliest sql.NullTime
+	err := tx.QueryRowContext(ctx, query).Scan(&earliest)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return earliest.Time, nil
+}
+
+func scanReturnedBook(row *sql.Row) (*models.Book, error) {
+	var book models.Book
+	var deletedAt sql.NullTime
+	err := row.Scan(&book.ID, &book.AuthorID, &book.Title, &book.ISBN, &book.Publisher, &book.CoverPath, &book.Stock, &book.PublishAt, &book.UpdatedAt, &book.Version, &deletedAt)
+	if err != nil {
+		return nil, err
+	}
+	if deletedAt.Valid {
+		book.DeletedAt = &deletedAt.Time
+	}
+	return &book, nil
+}
+
+func nullTime(t *time.Time) sql.NullTime {
+	if t == nil {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: *t, Valid: true}
+}
@@ -0,0 +1,34 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"library-api-book/internal/models"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MockBookRevisionRepository struct {
+	mock.Mock
+}
+
+func (m *MockBookRevisionRepository) CreateRevision(ctx context.Context, tx *sql.Tx, revision *models.BookRevision) error {
+	args := m.Called(ctx, tx, revision)
+	return args.Error(0)
+}
+
+func (m *MockBookRevisionRepository) GetRevisionsByBookID(ctx context.Context, tx *sql.Tx, bookID uint64, pagination *models.Pagination) ([]*models.BookRevision, error) {
+	args := m.Called(ctx, tx, bookID, pagination)
+	if args.Get(0) != nil {
+		return args.Get(0).([]*models.BookRevision), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockBookRevisionRepository) FindRevision(ctx context.Context, tx *sql.Tx, bookID uint64, revision int32) (*models.BookRevision, error) {
+	args := m.Called(ctx, tx, bookID, revision)
+	if args.Get(0) != nil {
+		return args.Get(0).(*models.BookRevision), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
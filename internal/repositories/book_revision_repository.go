@@ -0,0 +1,112 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"library-api-book/internal/models"
+)
+
+var ErrRevisionNotFound = errors.New("revision is not found")
+
+type BookRevisionRepository interface {
+	CreateRevision(ctx context.Context, tx *sql.Tx, revision *models.BookRevision) error
+	GetRevisionsByBookID(ctx context.Context, tx *sql.Tx, bookID uint64, pagination *models.Pagination) ([]*models.BookRevision, error)
+	FindRevision(ctx context.Context, tx *sql.Tx, bookID uint64, revision int32) (*models.BookRevision, error)
+}
+
+type BookRevisionRepositoryImpl struct {
+}
+
+func NewBookRevisionRepository() BookRevisionRepository {
+	return &BookRevisionRepositoryImpl{}
+}
+
+func (repository *BookRevisionRepositoryImpl) CreateRevision(ctx context.Context, tx *sql.Tx, revision *models.BookRevision) error {
+	snapshot, err := json.Marshal(revision.Snapshot)
+	if err != nil {
+		return err
+	}
+
+	query := `INSERT INTO book_revisions (book_id, revision, action, snapshot, actor_id, source_revision, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id`
+	err = tx.QueryRowContext(ctx, query,
+		revision.BookID,
+		revision.Revision,
+		revision.Action,
+		snapshot,
+		revision.ActorID,
+		revision.SourceRevision,
+		revision.CreatedAt,
+	).Scan(&revision.ID)
+	if err != nil {
+		return errors.New("Failed to create a book revision, transaction rolled back. Reason: " + err.Error())
+	}
+	return nil
+}
+
+func (repository *BookRevisionRepositoryImpl) GetRevisionsByBookID(ctx context.Context, tx *sql.Tx, bookID uint64, pagination *models.Pagination) ([]*models.BookRevision, error) {
+	query := `SELECT id, book_id, revision, action, snapshot, actor_id, source_revision, created_at
+		FROM book_revisions
+		WHERE book_id = $1
+		ORDER BY revision DESC
+		LIMIT $2 OFFSET $3`
+
+	rows, err := tx.QueryContext(ctx, query, bookID, pagination.PageSize, pagination.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var revisions []*models.BookRevision
+	for rows.Next() {
+		revision, err := scanRevision(rows)
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, revision)
+	}
+	return revisions, nil
+}
+
+func (repository *BookRevisionRepositoryImpl) FindRevision(ctx context.Context, tx *sql.Tx, bookID uint64, revision int32) (*models.BookRevision, error) {
+	query := `SELECT id, book_id, revision, action, snapshot, actor_id, source_revision, created_at
+		FROM book_revisions
+		WHERE book_id = $1 AND revision = $2`
+
+	rows, err := tx.QueryContext(ctx, query, bookID, revision)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return nil, ErrRevisionNotFound
+	}
+	return scanRevision(rows)
+}
+
+func scanRevision(rows *sql.Rows) (*models.BookRevision, error) {
+	var revision models.BookRevision
+	var snapshot []byte
+	var actorID sql.NullInt64
+	var sourceRevision sql.NullInt32
+
+	err := rows.Scan(&revision.ID, &revision.BookID, &revision.Revision, &revision.Action, &snapshot, &actorID, &sourceRevision, &revision.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(snapshot, &revision.Snapshot); err != nil {
+		return nil, err
+	}
+	if actorID.Valid {
+		id := uint64(actorID.Int64)
+		revision.ActorID = &id
+	}
+	if sourceRevision.Valid {
+		revision.SourceRevision = &sourceRevision.Int32
+	}
+	return &revision, nil
+}
@@ -0,0 +1,34 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"library-api-book/internal/models"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MockCategoryRepository struct {
+	mock.Mock
+}
+
+func (m *MockCategoryRepository) FindOrCreateCategory(ctx context.Context, tx *sql.Tx, name string) (*models.Category, error) {
+	args := m.Called(ctx, tx, name)
+	if args.Get(0) != nil {
+		return args.Get(0).(*models.Category), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockCategoryRepository) FindCategoriesByBookID(ctx context.Context, tx *sql.Tx, bookID uint64) ([]*models.Category, error) {
+	args := m.Called(ctx, tx, bookID)
+	if args.Get(0) != nil {
+		return args.Get(0).([]*models.Category), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockCategoryRepository) AttachCategoryToBook(ctx context.Context, tx *sql.Tx, bookID uint64, categoryID uint64) error {
+	args := m.Called(ctx, tx, bookID, categoryID)
+	return args.Error(0)
+}
@@ -0,0 +1,71 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"library-api-book/internal/models"
+)
+
+type CategoryRepository interface {
+	FindOrCreateCategory(ctx context.Context, tx *sql.Tx, name string) (*models.Category, error)
+	FindCategoriesByBookID(ctx context.Context, tx *sql.Tx, bookID uint64) ([]*models.Category, error)
+	AttachCategoryToBook(ctx context.Context, tx *sql.Tx, bookID uint64, categoryID uint64) error
+}
+
+type CategoryRepositoryImpl struct {
+}
+
+func NewCategoryRepository() CategoryRepository {
+	return &CategoryRepositoryImpl{}
+}
+
+func (repository *CategoryRepositoryImpl) FindOrCreateCategory(ctx context.Context, tx *sql.Tx, name string) (*models.Category, error) {
+	query := `INSERT INTO categories (name) VALUES ($1)
+		ON CONFLICT (name) DO UPDATE SET name = EXCLUDED.name
+		RETURNING id, name`
+
+	var category = models.Category{}
+	err := tx.QueryRowContext(ctx, query, name).Scan(&category.ID, &category.Name)
+	if err != nil {
+		return nil, errors.New("Failed to find or create a category. Reason: " + err.Error())
+	}
+	return &category, nil
+}
+
+func (repository *CategoryRepositoryImpl) FindCategoriesByBookID(ctx context.Context, tx *sql.Tx, bookID uint64) ([]*models.Category, error) {
+	query := `SELECT c.id, c.name
+		FROM categories c
+		JOIN book_categories bc ON c.id = bc.category_id
+		WHERE bc.book_id = $1
+		ORDER BY c.name ASC`
+	rows, err := tx.QueryContext(ctx, query, bookID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var categories []*models.Category
+	for rows.Next() {
+		var category models.Category
+		err := rows.Scan(&category.ID, &category.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		categories = append(categories, &category)
+	}
+	return categories, nil
+}
+
+func (repository *CategoryRepositoryImpl) AttachCategoryToBook(ctx context.Context, tx *sql.Tx, bookID uint64, categoryID uint64) error {
+	query := `INSERT INTO book_categories (category_id, book_id)
+		SELECT $1, $2
+		WHERE NOT EXISTS (SELECT 1 FROM book_categories WHERE category_id = $1 AND book_id = $2)`
+
+	_, err := tx.ExecContext(ctx, query, categoryID, bookID)
+	if err != nil {
+		return errors.New("Failed to attach a category to a book, transaction rolled back. Reason: " + err.Error())
+	}
+	return nil
+}
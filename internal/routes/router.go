@@ -2,19 +2,24 @@ package routes
 
 import (
 	"fmt"
+	"library-api-book/internal/auth"
 	"library-api-book/internal/factory"
-	"library-api-book/internal/grpc/client"
 	"library-api-book/internal/middleware"
+	"library-api-book/internal/permission"
+	"library-api-book/internal/ratelimit"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
-func RegisterRoutes(provider *factory.Provider, authClient *client.AuthClient) *gin.Engine {
+func RegisterRoutes(provider *factory.Provider, validator auth.TokenValidator, policy *permission.Policy) *gin.Engine {
 	router := gin.New()
+	// Services read values such as the API key scopes from the request
+	// context through the gin context they are handed.
+	router.ContextWithFallback = true
 
-	router.Use(gin.Logger(), CORS())
+	router.Use(gin.Logger(), CORS(), middleware.RequestID())
 
 	router.GET("/", func(ctx *gin.Context) {
 		currentYear := time.Now().Year()
@@ -23,19 +28,53 @@ func RegisterRoutes(provider *factory.Provider, authClient *client.AuthClient) *
 		ctx.JSON(http.StatusOK, message)
 	})
 
-	api := router.Group("/api")
+	limit := func(group string) gin.HandlerFunc {
+		return middleware.RateLimit(provider.RateLimiter, provider.RateLimits, group, provider.Logger)
+	}
+
+	router.GET("/oai", limit("public"), provider.OAIProvider.Handle)
+	router.POST("/oai", limit("public"), provider.OAIProvider.Handle)
+
+	api := router.Group("/api", middleware.Audit(provider.AuditService))
 	{
 		v1 := api.Group("v1")
 		{
-			auth := v1.Use(middleware.CheckAuth(authClient))
-			auth.GET("/books", provider.BookProvider.GetAllBooks)
-			auth.GET("/books/:id", provider.BookProvider.GetDetailBook)
-			auth.GET("/books/recommendation", provider.BookProvider.GetRecommendationBook)
-
-			admin := v1.Use(middleware.CheckAuthIsAdminOrAuthor(authClient))
-			admin.POST("/books", provider.BookProvider.CreateBook)
-			admin.PUT("/books/:id", provider.BookProvider.UpdateBook)
-			admin.DELETE("/books/id", provider.BookProvider.DeleteBook)
+			v1.GET("/covers/*key", limit("public"), provider.CoverProvider.GetCover)
+
+			auth := v1.Group("", middleware.CheckAuth(validator, provider.APIKeyService), limit(ratelimit.Default))
+			can := func(required permission.Permission) gin.HandlerFunc {
+				return middleware.RequirePermission(policy, required)
+			}
+
+			// Listing and search queries are the most expensive reads and
+			// are also limited by the stricter "search" group.
+			auth.GET("/books", can(permission.BooksRead), limit("search"), provider.BookProvider.GetAllBooks)
+			auth.GET("/books/:id", can(permission.BooksRead), provider.BookProvider.GetDetailBook)
+			auth.GET("/books/recommendation", can(permission.BooksRead), limit("search"), provider.BookProvider.GetRecommendationBook)
+			auth.GET("/books/marc", can(permission.BooksRead), limit("search"), provider.MarcProvider.ExportBooks)
+			auth.GET("/books/:id/marc", can(permission.BooksRead), provider.MarcProvider.ExportBook)
+
+			auth.POST("/books", can(permission.BooksWrite), provider.BookProvider.CreateBook)
+			auth.POST("/books/marc", can(permission.BooksWrite), provider.MarcProvider.ImportBooks)
+			auth.PUT("/books/:id", can(permission.BooksWrite), provider.BookProvider.UpdateBook)
+			auth.PATCH("/books/:id", can(permission.BooksWrite), provider.BookProvider.PatchBook)
+			auth.PUT("/books/:id/cover", can(permission.BooksWrite), provider.CoverProvider.UploadCover)
+			auth.DELETE("/books/:id", can(permission.BooksWrite), provider.BookProvider.DeleteBook)
+
+			auth.GET("/books/deleted", can(permission.BooksRestore), provider.BookProvider.GetDeletedBooks)
+			auth.POST("/books/:id/restore", can(permission.BooksRestore), provider.BookProvider.RestoreBook)
+			auth.DELETE("/books/:id/purge", can(permission.BooksPurge), provider.BookProvider.PurgeBook)
+
+			auth.GET("/books/:id/revisions", can(permission.RevisionsRead), provider.RevisionProvider.GetRevisions)
+			auth.GET("/books/:id/revisions/diff", can(permission.RevisionsRead), provider.RevisionProvider.DiffRevisions)
+			auth.POST("/books/:id/revisions/:revision/revert", can(permission.RevisionsRevert), provider.RevisionProvider.RevertBook)
+
+			auth.GET("/audit-logs", can(permission.AuditRead), provider.AuditProvider.GetAuditLogs)
+			auth.GET("/audit-logs/export", can(permission.AuditRead), provider.AuditProvider.ExportAuditLogs)
+
+			auth.POST("/api-keys", can(permission.APIKeysManage), provider.APIKeyProvider.CreateAPIKey)
+			auth.GET("/api-keys", can(permission.APIKeysManage), provider.APIKeyProvider.GetAPIKeys)
+			auth.DELETE("/api-keys/:id", can(permission.APIKeysManage), provider.APIKeyProvider.RevokeAPIKey)
 		}
 	}
 
@@ -46,8 +85,9 @@ func CORS() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		ctx.Writer.Header().Set("Access-Control-Allow-Origin", "*")
 		ctx.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-		ctx.Writer.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS, POST, PUT, DELETE")
-		ctx.Writer.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type, accept, access-control-allow-origin, access-control-allow-headers")
+		ctx.Writer.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS, POST, PUT, PATCH, DELETE")
+		ctx.Writer.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type, If-Match, X-API-Key, X-Request-ID, accept, access-control-allow-origin, access-control-allow-headers")
+		ctx.Writer.Header().Set("Access-Control-Expose-Headers", "ETag, RateLimit-Limit, RateLimit-Policy, RateLimit-Remaining, RateLimit-Reset, Retry-After, X-Request-ID")
 		if ctx.Request.Method == "OPTIONS" {
 			ctx.AbortWithStatus(http.StatusNoContent)
 		}
@@ -0,0 +1,324 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"library-api-book/internal/commons/response"
+	"library-api-book/internal/logger"
+	"library-api-book/internal/models"
+	"library-api-book/internal/params"
+	"library-api-book/internal/permission"
+	"library-api-book/internal/repositories"
+	"strings"
+	"time"
+)
+
+const (
+	// apiKeyScheme starts every key so leaked keys are easy to spot in logs
+	// and secret scanners. A key reads lak_<prefix>_<secret>.
+	apiKeyScheme = "lak"
+
+	// apiKeyTouchInterval limits how often a key's last use is written.
+	apiKeyTouchInterval = time.Minute
+)
+
+type APIKeyService interface {
+	CreateAPIKey(ctx context.Context, createdBy *uint64, req *params.APIKeyRequest) (*params.CreatedAPIKeyResponse, *response.CustomError)
+	GetAPIKeys(ctx context.Context, pagination *models.Pagination) ([]*params.APIKeyResponse, *response.CustomError)
+	RevokeAPIKey(ctx context.Context, id uint64) (*params.APIKeyResponse, *response.CustomError)
+	Authenticate(ctx context.Context, rawKey string) (*models.APIKey, *response.CustomError)
+}
+
+type APIKeyServiceImpl struct {
+	DB               *sql.DB
+	APIKeyRepository repositories.APIKeyRepository
+	Logger           logger.Logger
+}
+
+func NewAPIKeyService(db *sql.DB, apiKeyRepository repositories.APIKeyRepository, log logger.Logger) APIKeyService {
+	return &APIKeyServiceImpl{
+		DB:               db,
+		APIKeyRepository: apiKeyRepository,
+		Logger:           log,
+	}
+}
+
+// CreateAPIKey stores a new key and returns it with its plaintext secret,
+// which is not kept and cannot be shown again.
+func (service *APIKeyServiceImpl) CreateAPIKey(ctx context.Context, createdBy *uint64, req *params.APIKeyRequest) (*params.CreatedAPIKeyResponse, *response.CustomError) {
+	if err := validate.Struct(req); err != nil {
+		return nil, response.BadRequestError(err.Error())
+	}
+	permissions, custErr := apiKeyPermissions(req.Permissions)
+	if custErr != nil {
+		return nil, custErr
+	}
+	now := time.Now()
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(now) {
+		return nil, response.BadRequestError("expires_at must be in the future")
+	}
+
+	prefix, secret, err := generateAPIKey()
+	if err != nil {
+		service.Logger.Error("[APIKeyService] Failed to generate api key - CreateAPIKey", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return nil, response.GeneralError("Failed to generate api key: " + err.Error())
+	}
+	rawKey := apiKeyScheme + "_" + prefix + "_" + secret
+
+	tx, err := service.DB.Begin()
+	if err != nil {
+		service.Logger.Error("[APIKeyService] Failed to begin transaction - CreateAPIKey", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return nil, response.GeneralError("Failed to connect to the database: " + err.Error())
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			service.Logger.Error("[APIKeyService] Transaction rolled back due to panic - CreateAPIKey", map[string]interface{}{
+				"error": p,
+			})
+		} else if err != nil {
+			tx.Rollback()
+			service.Logger.Error("[APIKeyService] Transaction rolled back due to error - CreateAPIKey", map[string]interface{}{
+				"error": err.Error(),
+			})
+		} else {
+			tx.Commit()
+		}
+	}()
+
+	key := &models.APIKey{
+		Name:        req.Name,
+		Prefix:      prefix,
+		KeyHash:     hashAPIKey(rawKey),
+		Permissions: permissions,
+		CreatedBy:   createdBy,
+		CreatedAt:   now,
+		ExpiresAt:   req.ExpiresAt,
+	}
+	err = service.APIKeyRepository.CreateAPIKey(ctx, tx, key)
+	if err != nil {
+		service.Logger.Error("[APIKeyService] Failed to create api key - CreateAPIKey", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return nil, response.GeneralError("Failed to create api key: " + err.Error())
+	}
+
+	return &params.CreatedAPIKeyResponse{
+		APIKeyResponse: *newAPIKeyResponse(key),
+		Key:            rawKey,
+	}, nil
+}
+
+func (service *APIKeyServiceImpl) GetAPIKeys(ctx context.Context, pagination *models.Pagination) ([]*params.APIKeyResponse, *response.CustomError) {
+	tx, err := service.DB.Begin()
+	if err != nil {
+		service.Logger.Error("[APIKeyService] Failed to begin transaction - GetAPIKeys", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return nil, response.GeneralError("Failed to connect to the database: " + err.Error())
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			service.Logger.Error("[APIKeyService] Transaction rolled back due to panic - GetAPIKeys", map[string]interface{}{
+				"error": p,
+			})
+		} else if err != nil {
+			tx.Rollback()
+			service.Logger.Error("[APIKeyService] Transaction rolled back due to error - GetAPIKeys", map[string]interface{}{
+				"error": err.Error(),
+			})
+		} else {
+			tx.Commit()
+		}
+	}()
+
+	pagination.Offset = (pagination.Page - 1) * pagination.PageSize
+
+	keys, err := service.APIKeyRepository.GetAPIKeys(ctx, tx, pagination)
+	if err != nil {
+		service.Logger.Error("[APIKeyService] Failed to fetch api keys - GetAPIKeys", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return nil, response.GeneralError("Failed to fetch api keys: " + err.Error())
+	}
+
+	keyResponses := make([]*params.APIKeyResponse, len(keys))
+	for i, key := range keys {
+		keyResponses[i] = newAPIKeyResponse(key)
+	}
+
+	return keyResponses, nil
+}
+
+// RevokeAPIKey disables a key for good. Revoking a revoked key is a no-op.
+func (service *APIKeyServiceImpl) RevokeAPIKey(ctx context.Context, id uint64) (*params.APIKeyResponse, *response.CustomError) {
+	tx, err := service.DB.Begin()
+	if err != nil {
+		service.Logger.Error("[APIKeyService] Failed to begin transaction - RevokeAPIKey", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return nil, response.GeneralError("Failed to connect to the database: " + err.Error())
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			service.Logger.Error("[APIKeyService] Transaction rolled back due to panic - RevokeAPIKey", map[string]interface{}{
+				"error": p,
+			})
+		} else if err != nil {
+			tx.Rollback()
+			service.Logger.Error("[APIKeyService] Transaction rolled back due to error - RevokeAPIKey", map[string]interface{}{
+				"error": err.Error(),
+			})
+		} else {
+			tx.Commit()
+		}
+	}()
+
+	key, err := service.APIKeyRepository.RevokeAPIKey(ctx, tx, id, time.Now())
+	if errors.Is(err, repositories.ErrAPIKeyNotFound) {
+		return nil, response.NotFoundError("API key not found")
+	}
+	if err != nil {
+		service.Logger.Error("[APIKeyService] Failed to revoke api key - RevokeAPIKey", map[string]interface{}{
+			"id":    id,
+			"error": err.Error(),
+		})
+		return nil, response.GeneralError("Failed to revoke api key: " + err.Error())
+	}
+
+	return newAPIKeyResponse(key), nil
+}
+
+// Authenticate returns the key matching rawKey if it is neither revoked nor
+// expired, and records that it was used.
+func (service *APIKeyServiceImpl) Authenticate(ctx context.Context, rawKey string) (*models.APIKey, *response.CustomError) {
+	prefix, ok := apiKeyPrefix(rawKey)
+	if !ok {
+		return nil, response.UnauthorizedError("Invalid API key")
+	}
+
+	tx, err := service.DB.Begin()
+	if err != nil {
+		service.Logger.Error("[APIKeyService] Failed to begin transaction - Authenticate", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return nil, response.GeneralError("Failed to connect to the database: " + err.Error())
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			service.Logger.Error("[APIKeyService] Transaction rolled back due to panic - Authenticate", map[string]interface{}{
+				"error": p,
+			})
+		} else if err != nil {
+			tx.Rollback()
+		} else {
+			tx.Commit()
+		}
+	}()
+
+	key, err := service.APIKeyRepository.FindAPIKeyByPrefix(ctx, tx, prefix)
+	if errors.Is(err, repositories.ErrAPIKeyNotFound) {
+		return nil, response.UnauthorizedError("Invalid API key")
+	}
+	if err != nil {
+		service.Logger.Error("[APIKeyService] Failed to fetch api key - Authenticate", map[string]interface{}{
+			"prefix": prefix,
+			"error":  err.Error(),
+		})
+		return nil, response.GeneralError("Failed to fetch api key: " + err.Error())
+	}
+	if subtle.ConstantTimeCompare([]byte(hashAPIKey(rawKey)), []byte(key.KeyHash)) != 1 {
+		return nil, response.UnauthorizedError("Invalid API key")
+	}
+
+	now := time.Now()
+	if key.RevokedAt != nil {
+		return nil, response.UnauthorizedError("API key has been revoked")
+	}
+	if key.ExpiresAt != nil && !key.ExpiresAt.After(now) {
+		return nil, response.UnauthorizedError("API key has expired")
+	}
+
+	// A failed usage update must not lock the caller out.
+	if touchErr := service.APIKeyRepository.TouchAPIKey(ctx, tx, key.ID, now, now.Add(-apiKeyTouchInterval)); touchErr != nil {
+		service.Logger.Warn("[APIKeyService] Failed to record api key usage - Authenticate", map[string]interface{}{
+			"prefix": prefix,
+			"error":  touchErr.Error(),
+		})
+	}
+
+	return key, nil
+}
+
+// apiKeyPermissions checks the requested scopes and removes duplicates. Keys
+// may not manage other keys, and the wildcard is not accepted so every scope
+// is explicit.
+func apiKeyPermissions(requested []string) ([]string, *response.CustomError) {
+	seen := make(map[string]bool, len(requested))
+	permissions := make([]string, 0, len(requested))
+	for _, name := range requested {
+		if !permission.Known(permission.Permission(name)) {
+			return nil, response.BadRequestError("Unknown permission " + name)
+		}
+		if permission.Permission(name) == permission.APIKeysManage {
+			return nil, response.BadRequestError("API keys cannot be granted " + name)
+		}
+		if !seen[name] {
+			seen[name] = true
+			permissions = append(permissions, name)
+		}
+	}
+	return permissions, nil
+}
+
+func generateAPIKey() (prefix, secret string, err error) {
+	buf := make([]byte, 6+32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+	return hex.EncodeToString(buf[:6]), base64.RawURLEncoding.EncodeToString(buf[6:]), nil
+}
+
+// apiKeyPrefix extracts the lookup prefix from a key of the form
+// lak_<prefix>_<secret>. The secret may itself contain underscores.
+func apiKeyPrefix(rawKey string) (string, bool) {
+	parts := strings.SplitN(rawKey, "_", 3)
+	if len(parts) != 3 || parts[0] != apiKeyScheme || len(parts[1]) != 12 || parts[2] == "" {
+		return "", false
+	}
+	return parts[1], true
+}
+
+// hashAPIKey is a plain SHA-256; the secrets are random and long enough that
+// a slow password hash would add nothing but latency to every request.
+func hashAPIKey(rawKey string) string {
+	sum := sha256.Sum256([]byte(rawKey))
+	return hex.EncodeToString(sum[:])
+}
+
+func newAPIKeyResponse(key *models.APIKey) *params.APIKeyResponse {
+	return &params.APIKeyResponse{
+		ID:          key.ID,
+		Name:        key.Name,
+		Prefix:      key.Prefix,
+		Permissions: key.Permissions,
+		CreatedBy:   key.CreatedBy,
+		CreatedAt:   key.CreatedAt,
+		ExpiresAt:   key.ExpiresAt,
+		LastUsedAt:  key.LastUsedAt,
+		RevokedAt:   key.RevokedAt,
+	}
+}
@@ -0,0 +1,166 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"library-api-book/internal/logger"
+	"library-api-book/internal/models"
+	"library-api-book/internal/params"
+	"library-api-book/internal/repositories"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func setupAPIKeyTest(t *testing.T) (sqlmock.Sqlmock, *repositories.MockAPIKeyRepository, *APIKeyServiceImpl) {
+	db, mockDB, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	apiKeyRepo := new(repositories.MockAPIKeyRepository)
+	service := &APIKeyServiceImpl{
+		DB:               db,
+		APIKeyRepository: apiKeyRepo,
+		Logger:           logger.NopLogger{},
+	}
+
+	return mockDB, apiKeyRepo, service
+}
+
+func TestCreateAPIKey_Success(t *testing.T) {
+	mockDB, apiKeyRepo, service := setupAPIKeyTest(t)
+
+	var stored *models.APIKey
+	mockDB.ExpectBegin()
+	apiKeyRepo.On("CreateAPIKey", mock.Anything, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		stored = args.Get(2).(*models.APIKey)
+		stored.ID = 7
+	}).Return(nil)
+	mockDB.ExpectCommit()
+
+	createdBy := uint64(1)
+	result, errResponse := service.CreateAPIKey(context.Background(), &createdBy, &params.APIKeyRequest{
+		Name:        "nightly import",
+		Permissions: []string{"books:read", "books:write", "books:read"},
+	})
+
+	require.Nil(t, errResponse)
+	assert.Equal(t, uint64(7), result.ID)
+	assert.Equal(t, []string{"books:read", "books:write"}, result.Permissions)
+	assert.True(t, strings.HasPrefix(result.Key, "lak_"+result.Prefix+"_"))
+
+	// Only the hash is stored.
+	assert.Equal(t, hashAPIKey(result.Key), stored.KeyHash)
+	assert.NotContains(t, stored.KeyHash, result.Key)
+	assert.Equal(t, &createdBy, stored.CreatedBy)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestCreateAPIKey_InvalidPermissions(t *testing.T) {
+	_, _, service := setupAPIKeyTest(t)
+
+	for _, permissions := range [][]string{{"books:delete"}, {"*"}, {"api_keys:manage"}} {
+		_, errResponse := service.CreateAPIKey(context.Background(), nil, &params.APIKeyRequest{
+			Name:        "partner",
+			Permissions: permissions,
+		})
+		require.NotNil(t, errResponse, permissions)
+		assert.Equal(t, 400, errResponse.StatusCode)
+	}
+}
+
+func TestCreateAPIKey_ExpiryInThePast(t *testing.T) {
+	_, _, service := setupAPIKeyTest(t)
+
+	past := time.Now().Add(-time.Hour)
+	_, errResponse := service.CreateAPIKey(context.Background(), nil, &params.APIKeyRequest{
+		Name:        "partner",
+		Permissions: []string{"books:read"},
+		ExpiresAt:   &past,
+	})
+
+	require.NotNil(t, errResponse)
+	assert.Equal(t, "expires_at must be in the future", errResponse.Message)
+}
+
+func TestRevokeAPIKey_NotFound(t *testing.T) {
+	mockDB, apiKeyRepo, service := setupAPIKeyTest(t)
+
+	mockDB.ExpectBegin()
+	apiKeyRepo.On("RevokeAPIKey", mock.Anything, mock.Anything, uint64(9), mock.Anything).Return(nil, repositories.ErrAPIKeyNotFound)
+	mockDB.ExpectCommit()
+
+	_, errResponse := service.RevokeAPIKey(context.Background(), 9)
+
+	require.NotNil(t, errResponse)
+	assert.Equal(t, "API key not found", errResponse.Message)
+}
+
+func TestAuthenticate(t *testing.T) {
+	const rawKey = "lak_0123456789ab_c2VjcmV0_with_underscores"
+	past := time.Now().Add(-time.Minute)
+	future := time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name    string
+		rawKey  string
+		key     *models.APIKey
+		findErr error
+		message string
+	}{
+		{name: "valid", rawKey: rawKey, key: &models.APIKey{ID: 1, KeyHash: hashAPIKey(rawKey), ExpiresAt: &future}},
+		{name: "malformed", rawKey: "0123456789ab", message: "Invalid API key"},
+		{name: "unknown prefix", rawKey: rawKey, findErr: repositories.ErrAPIKeyNotFound, message: "Invalid API key"},
+		{name: "wrong secret", rawKey: rawKey, key: &models.APIKey{ID: 1, KeyHash: hashAPIKey(rawKey + "x")}, message: "Invalid API key"},
+		{name: "revoked", rawKey: rawKey, key: &models.APIKey{ID: 1, KeyHash: hashAPIKey(rawKey), RevokedAt: &past}, message: "API key has been revoked"},
+		{name: "expired", rawKey: rawKey, key: &models.APIKey{ID: 1, KeyHash: hashAPIKey(rawKey), ExpiresAt: &past}, message: "API key has expired"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDB, apiKeyRepo, service := setupAPIKeyTest(t)
+
+			if tt.key != nil || tt.findErr != nil {
+				mockDB.ExpectBegin()
+				apiKeyRepo.On("FindAPIKeyByPrefix", mock.Anything, mock.Anything, "0123456789ab").Return(tt.key, tt.findErr)
+				apiKeyRepo.On("TouchAPIKey", mock.Anything, mock.Anything, uint64(1), mock.Anything, mock.Anything).Return(nil)
+				mockDB.ExpectCommit()
+			}
+
+			key, errResponse := service.Authenticate(context.Background(), tt.rawKey)
+
+			if tt.message == "" {
+				require.Nil(t, errResponse)
+				assert.Equal(t, tt.key, key)
+				apiKeyRepo.AssertCalled(t, "TouchAPIKey", mock.Anything, mock.Anything, uint64(1), mock.Anything, mock.Anything)
+				return
+			}
+			require.NotNil(t, errResponse)
+			assert.Equal(t, 401, errResponse.StatusCode)
+			assert.Equal(t, tt.message, errResponse.Message)
+			apiKeyRepo.AssertNotCalled(t, "TouchAPIKey", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestAuthenticate_TouchFailureIsIgnored(t *testing.T) {
+	const rawKey = "lak_0123456789ab_secret"
+	mockDB, apiKeyRepo, service := setupAPIKeyTest(t)
+
+	mockDB.ExpectBegin()
+	apiKeyRepo.On("FindAPIKeyByPrefix", mock.Anything, mock.Anything, "0123456789ab").Return(&models.APIKey{ID: 1, KeyHash: hashAPIKey(rawKey)}, nil)
+	apiKeyRepo.On("TouchAPIKey", mock.Anything, mock.Anything, uint64(1), mock.Anything, mock.Anything).Return(errors.New("database is read-only"))
+	mockDB.ExpectCommit()
+
+	key, errResponse := service.Authenticate(context.Background(), rawKey)
+
+	require.Nil(t, errResponse)
+	assert.Equal(t, uint64(1), key.ID)
+}
@@ -0,0 +1,174 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"library-api-book/internal/commons/response"
+	"library-api-book/internal/logger"
+	"library-api-book/internal/models"
+	"library-api-book/internal/params"
+	"library-api-book/internal/repositories"
+)
+
+type AuditService interface {
+	Record(ctx context.Context, log *models.AuditLog) *response.CustomError
+	GetAuditLogs(ctx context.Context, filter *models.AuditLogFilter, pagination *models.Pagination) ([]*params.AuditLogResponse, *response.CustomError)
+	ExportAuditLogs(ctx context.Context, filter *models.AuditLogFilter, fn func(*params.AuditLogResponse) error) *response.CustomError
+}
+
+type AuditServiceImpl struct {
+	DB                 *sql.DB
+	AuditLogRepository repositories.AuditLogRepository
+	Logger             logger.Logger
+}
+
+func NewAuditService(db *sql.DB, auditLogRepository repositories.AuditLogRepository, log logger.Logger) AuditService {
+	return &AuditServiceImpl{
+		DB:                 db,
+		AuditLogRepository: auditLogRepository,
+		Logger:             log,
+	}
+}
+
+func (service *AuditServiceImpl) Record(ctx context.Context, log *models.AuditLog) *response.CustomError {
+	tx, err := service.DB.Begin()
+	if err != nil {
+		service.Logger.Error("[AuditService] Failed to begin transaction - Record", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return response.GeneralError("Failed to connect to the database: " + err.Error())
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			service.Logger.Error("[AuditService] Transaction rolled back due to panic - Record", map[string]interface{}{
+				"error": p,
+			})
+		} else if err != nil {
+			tx.Rollback()
+			service.Logger.Error("[AuditService] Transaction rolled back due to error - Record", map[string]interface{}{
+				"error": err.Error(),
+			})
+		} else {
+			tx.Commit()
+		}
+	}()
+
+	err = service.AuditLogRepository.CreateAuditLog(ctx, tx, log)
+	if err != nil {
+		// The entry is lost at this point, so keep everything it held in the
+		// application log instead.
+		service.Logger.Error("[AuditService] Failed to write audit log - Record", map[string]interface{}{
+			"actor_id":    log.ActorID,
+			"role":        log.Role,
+			"action":      log.Action,
+			"target":      log.Target,
+			"ip":          log.IP,
+			"request_id":  log.RequestID,
+			"outcome":     log.Outcome,
+			"status_code": log.StatusCode,
+			"detail":      log.Detail,
+			"error":       err.Error(),
+		})
+		return response.GeneralError("Failed to write audit log: " + err.Error())
+	}
+
+	return nil
+}
+
+func (service *AuditServiceImpl) GetAuditLogs(ctx context.Context, filter *models.AuditLogFilter, pagination *models.Pagination) ([]*params.AuditLogResponse, *response.CustomError) {
+	tx, err := service.DB.Begin()
+	if err != nil {
+		service.Logger.Error("[AuditService] Failed to begin transaction - GetAuditLogs", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return nil, response.GeneralError("Failed to connect to the database: " + err.Error())
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			service.Logger.Error("[AuditService] Transaction rolled back due to panic - GetAuditLogs", map[string]interface{}{
+				"error": p,
+			})
+		} else if err != nil {
+			tx.Rollback()
+			service.Logger.Error("[AuditService] Transaction rolled back due to error - GetAuditLogs", map[string]interface{}{
+				"error": err.Error(),
+			})
+		} else {
+			tx.Commit()
+		}
+	}()
+
+	pagination.Offset = (pagination.Page - 1) * pagination.PageSize
+
+	logs, err := service.AuditLogRepository.GetAuditLogs(ctx, tx, filter, pagination)
+	if err != nil {
+		service.Logger.Error("[AuditService] Failed to fetch audit logs - GetAuditLogs", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return nil, response.GeneralError("Failed to fetch audit logs: " + err.Error())
+	}
+
+	logResponses := make([]*params.AuditLogResponse, len(logs))
+	for i, log := range logs {
+		logResponses[i] = newAuditLogResponse(log)
+	}
+
+	return logResponses, nil
+}
+
+// ExportAuditLogs passes every matching entry to fn, oldest first. It stops at
+// the first error returned by fn.
+func (service *AuditServiceImpl) ExportAuditLogs(ctx context.Context, filter *models.AuditLogFilter, fn func(*params.AuditLogResponse) error) *response.CustomError {
+	tx, err := service.DB.Begin()
+	if err != nil {
+		service.Logger.Error("[AuditService] Failed to begin transaction - ExportAuditLogs", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return response.GeneralError("Failed to connect to the database: " + err.Error())
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			service.Logger.Error("[AuditService] Transaction rolled back due to panic - ExportAuditLogs", map[string]interface{}{
+				"error": p,
+			})
+		} else if err != nil {
+			tx.Rollback()
+			service.Logger.Error("[AuditService] Transaction rolled back due to error - ExportAuditLogs", map[string]interface{}{
+				"error": err.Error(),
+			})
+		} else {
+			tx.Commit()
+		}
+	}()
+
+	err = service.AuditLogRepository.StreamAuditLogs(ctx, tx, filter, func(log *models.AuditLog) error {
+		return fn(newAuditLogResponse(log))
+	})
+	if err != nil {
+		service.Logger.Error("[AuditService] Failed to export audit logs - ExportAuditLogs", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return response.GeneralError("Failed to export audit logs: " + err.Error())
+	}
+
+	return nil
+}
+
+func newAuditLogResponse(log *models.AuditLog) *params.AuditLogResponse {
+	return &params.AuditLogResponse{
+		ID:         log.ID,
+		OccurredAt: log.OccurredAt,
+		ActorID:    log.ActorID,
+		Role:       log.Role,
+		Action:     log.Action,
+		Target:     log.Target,
+		IP:         log.IP,
+		RequestID:  log.RequestID,
+		Outcome:    log.Outcome,
+		StatusCode: log.StatusCode,
+		Detail:     log.Detail,
+	}
+}
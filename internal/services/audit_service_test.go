@@ -0,0 +1,88 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"library-api-book/internal/logger"
+	"library-api-book/internal/models"
+	"library-api-book/internal/params"
+	"library-api-book/internal/repositories"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func setupAuditTest(t *testing.T) (sqlmock.Sqlmock, *repositories.MockAuditLogRepository, *AuditServiceImpl) {
+	db, mockDB, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	auditRepo := new(repositories.MockAuditLogRepository)
+	service := &AuditServiceImpl{
+		DB:                 db,
+		AuditLogRepository: auditRepo,
+		Logger:             logger.NopLogger{},
+	}
+
+	return mockDB, auditRepo, service
+}
+
+func TestRecordAuditLog_Failed(t *testing.T) {
+	mockDB, auditRepo, service := setupAuditTest(t)
+
+	mockDB.ExpectBegin()
+	auditRepo.On("CreateAuditLog", mock.Anything, mock.Anything, mock.Anything).Return(errors.New("repository error"))
+	mockDB.ExpectRollback()
+
+	errResponse := service.Record(context.Background(), &models.AuditLog{Action: "DELETE /api/v1/books/:id"})
+
+	assert.NotNil(t, errResponse)
+	assert.Equal(t, "Failed to write audit log: repository error", errResponse.Message)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestExportAuditLogs_Success(t *testing.T) {
+	mockDB, auditRepo, service := setupAuditTest(t)
+
+	logs := []*models.AuditLog{
+		{ID: 1, Action: "POST /api/v1/books", Outcome: models.AuditOutcomeSuccess},
+		{ID: 2, Action: "DELETE /api/v1/books/:id", Outcome: models.AuditOutcomeDenied},
+	}
+	filter := &models.AuditLogFilter{Outcome: models.AuditOutcomeDenied}
+
+	mockDB.ExpectBegin()
+	auditRepo.On("StreamAuditLogs", mock.Anything, mock.Anything, filter, mock.Anything).Return(logs, nil)
+	mockDB.ExpectCommit()
+
+	var exported []uint64
+	errResponse := service.ExportAuditLogs(context.Background(), filter, func(log *params.AuditLogResponse) error {
+		exported = append(exported, log.ID)
+		return nil
+	})
+
+	assert.Nil(t, errResponse)
+	assert.Equal(t, []uint64{1, 2}, exported)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestExportAuditLogs_WriterFailure(t *testing.T) {
+	mockDB, auditRepo, service := setupAuditTest(t)
+
+	mockDB.ExpectBegin()
+	auditRepo.On("StreamAuditLogs", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return([]*models.AuditLog{{ID: 1}, {ID: 2}}, nil)
+	mockDB.ExpectRollback()
+
+	calls := 0
+	errResponse := service.ExportAuditLogs(context.Background(), &models.AuditLogFilter{}, func(log *params.AuditLogResponse) error {
+		calls++
+		return errors.New("broken pipe")
+	})
+
+	assert.NotNil(t, errResponse)
+	assert.Equal(t, 1, calls)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
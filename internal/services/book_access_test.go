@@ -0,0 +1,303 @@
+package services
+
+import (
+	"context"
+	"library-api-book/internal/logger"
+	"library-api-book/internal/models"
+	"library-api-book/internal/params"
+	"library-api-book/internal/permission"
+	"library-api-book/internal/repositories"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestPolicy(t *testing.T) *permission.Policy {
+	policy, err := permission.NewPolicy(map[string][]permission.Permission{
+		"admin":  {permission.Wildcard},
+		"author": {permission.BooksRead, permission.BooksWrite},
+		"editor": {permission.BooksRead, permission.BooksWrite, permission.BooksWriteAny},
+	})
+	require.NoError(t, err)
+	return policy
+}
+
+func newAccessContext(authID int, role string) *gin.Context {
+	ctx := &gin.Context{}
+	ctx.Set("authId", authID)
+	ctx.Set("role", role)
+	return ctx
+}
+
+func setupAccessTest(t *testing.T) (sqlmock.Sqlmock, *repositories.MockBookRepository, *repositories.MockAuthorRepository, *BookServiceImpl) {
+	db, mockDB, mockRepo, service := setupTest(t)
+	t.Cleanup(func() { db.Close() })
+	service.Logger = logger.NopLogger{}
+
+	authorRepo := new(repositories.MockAuthorRepository)
+	authorRepo.On("FindAuthorByUserID", mock.Anything, mock.Anything, uint64(7)).Return(&models.Author{ID: 2}, nil).Maybe()
+	service.AuthorRepository = authorRepo
+
+	return mockDB, mockRepo, authorRepo, service
+}
+
+func TestUpdateBook_AuthorOwnBook(t *testing.T) {
+	mockDB, mockRepo, _, service := setupAccessTest(t)
+
+	mockDB.ExpectBegin()
+	mockRepo.On("FindBookByID", mock.Anything, mock.Anything, uint64(1)).Return(&models.Book{ID: 1, AuthorID: 2, Stock: 5, Version: 3}, nil)
+	mockRepo.On("UpdateBook", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	mockDB.ExpectCommit()
+
+	req := &params.BookRequest{AuthorID: 2, Title: "Updated Book", Stock: 5}
+	_, errResponse := service.UpdateBook(newAccessContext(7, "author"), 1, []int32{3}, req)
+
+	assert.Nil(t, errResponse)
+	mockRepo.AssertExpectations(t)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestUpdateBook_AuthorOtherBook(t *testing.T) {
+	mockDB, mockRepo, _, service := setupAccessTest(t)
+
+	mockDB.ExpectBegin()
+	mockRepo.On("FindBookByID", mock.Anything, mock.Anything, uint64(1)).Return(&models.Book{ID: 1, AuthorID: 3, Stock: 5, Version: 3}, nil)
+	mockDB.ExpectRollback()
+
+	req := &params.BookRequest{AuthorID: 3, Title: "Updated Book", Stock: 5}
+	_, errResponse := service.UpdateBook(newAccessContext(7, "author"), 1, []int32{3}, req)
+
+	assert.NotNil(t, errResponse)
+	assert.Equal(t, 403, errResponse.StatusCode)
+	mockRepo.AssertNotCalled(t, "UpdateBook", mock.Anything, mock.Anything, mock.Anything)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestUpdateBook_AuthorReassignsBook(t *testing.T) {
+	mockDB, mockRepo, _, service := setupAccessTest(t)
+
+	mockDB.ExpectBegin()
+	mockRepo.On("FindBookByID", mock.Anything, mock.Anything, uint64(1)).Return(&models.Book{ID: 1, AuthorID: 2, Stock: 5, Version: 3}, nil)
+	mockDB.ExpectRollback()
+
+	req := &params.BookRequest{AuthorID: 3, Title: "Updated Book", Stock: 5}
+	_, errResponse := service.UpdateBook(newAccessContext(7, "author"), 1, []int32{3}, req)
+
+	assert.NotNil(t, errResponse)
+	assert.Equal(t, 403, errResponse.StatusCode)
+	mockRepo.AssertNotCalled(t, "UpdateBook", mock.Anything, mock.Anything, mock.Anything)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestUpdateBook_AuthorChangesStock(t *testing.T) {
+	mockDB, mockRepo, _, service := setupAccessTest(t)
+
+	mockDB.ExpectBegin()
+	mockRepo.On("FindBookByID", mock.Anything, mock.Anything, uint64(1)).Return(&models.Book{ID: 1, AuthorID: 2, Stock: 5, Version: 3}, nil)
+	mockDB.ExpectRollback()
+
+	req := &params.BookRequest{AuthorID: 2, Title: "Updated Book", Stock: 50}
+	_, errResponse := service.UpdateBook(newAccessContext(7, "author"), 1, []int32{3}, req)
+
+	assert.NotNil(t, errResponse)
+	assert.Equal(t, 403, errResponse.StatusCode)
+	mockRepo.AssertNotCalled(t, "UpdateBook", mock.Anything, mock.Anything, mock.Anything)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestUpdateBook_AdminAnyBook(t *testing.T) {
+	mockDB, mockRepo, authorRepo, service := setupAccessTest(t)
+
+	mockDB.ExpectBegin()
+	mockRepo.On("FindBookByID", mock.Anything, mock.Anything, uint64(1)).Return(&models.Book{ID: 1, AuthorID: 3, Stock: 5, Version: 3}, nil)
+	mockRepo.On("UpdateBook", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	mockDB.ExpectCommit()
+
+	req := &params.BookRequest{AuthorID: 4, Title: "Updated Book", Stock: 50}
+	_, errResponse := service.UpdateBook(newAccessContext(1, "admin"), 1, []int32{3}, req)
+
+	assert.Nil(t, errResponse)
+	authorRepo.AssertNotCalled(t, "FindAuthorByUserID", mock.Anything, mock.Anything, mock.Anything)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestPatchBook_AuthorChangesStock(t *testing.T) {
+	mockDB, mockRepo, _, service := setupAccessTest(t)
+
+	mockDB.ExpectBegin()
+	mockRepo.On("FindBookByID", mock.Anything, mock.Anything, uint64(1)).Return(&models.Book{ID: 1, AuthorID: 2, Title: "Title", Stock: 5, Version: 3}, nil)
+	mockDB.ExpectRollback()
+
+	_, errResponse := service.PatchBook(newAccessContext(7, "author"), 1, nil, []byte(`{"stock":50}`))
+
+	assert.NotNil(t, errResponse)
+	assert.Equal(t, 403, errResponse.StatusCode)
+	mockRepo.AssertNotCalled(t, "PatchBook", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestCreateBook_AuthorWithoutRecord(t *testing.T) {
+	mockDB, mockRepo, authorRepo, service := setupAccessTest(t)
+
+	mockDB.ExpectBegin()
+	authorRepo.ExpectedCalls = nil
+	authorRepo.On("FindAuthorByUserID", mock.Anything, mock.Anything, uint64(7)).Return(nil, repositories.ErrAuthorNotFound)
+	mockDB.ExpectRollback()
+
+	_, errResponse := service.CreateBook(newAccessContext(7, "author"), &params.BookRequest{AuthorID: 2, Title: "New Book"})
+
+	assert.NotNil(t, errResponse)
+	assert.Equal(t, 403, errResponse.StatusCode)
+	mockRepo.AssertNotCalled(t, "CreateBook", mock.Anything, mock.Anything, mock.Anything)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestDeleteBook_AuthorOtherBook(t *testing.T) {
+	mockDB, mockRepo, _, service := setupAccessTest(t)
+
+	mockDB.ExpectBegin()
+	mockRepo.On("FindBookByID", mock.Anything, mock.Anything, uint64(1)).Return(&models.Book{ID: 1, AuthorID: 3}, nil)
+	mockDB.ExpectRollback()
+
+	errResponse := service.DeleteBook(newAccessContext(7, "author"), 1)
+
+	assert.NotNil(t, errResponse)
+	assert.Equal(t, 403, errResponse.StatusCode)
+	mockRepo.AssertNotCalled(t, "DeleteBook", mock.Anything, mock.Anything, mock.Anything)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func newAPIKeyContext(scopes ...string) context.Context {
+	return WithAPIKeyScopes(context.Background(), scopes)
+}
+
+func TestUpdateBook_NoRoleIsRestricted(t *testing.T) {
+	mockDB, mockRepo, authorRepo, service := setupAccessTest(t)
+
+	mockDB.ExpectBegin()
+	mockRepo.On("FindBookByID", mock.Anything, mock.Anything, uint64(1)).Return(&models.Book{ID: 1, AuthorID: 3, Stock: 5, Version: 3}, nil)
+	mockDB.ExpectRollback()
+
+	req := &params.BookRequest{AuthorID: 3, Title: "Updated Book", Stock: 5}
+	_, errResponse := service.UpdateBook(context.Background(), 1, []int32{3}, req)
+
+	assert.NotNil(t, errResponse)
+	assert.Equal(t, 403, errResponse.StatusCode)
+	authorRepo.AssertNotCalled(t, "FindAuthorByUserID", mock.Anything, mock.Anything, mock.Anything)
+	mockRepo.AssertNotCalled(t, "UpdateBook", mock.Anything, mock.Anything, mock.Anything)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestUpdateBook_TrustedCallerAnyBook(t *testing.T) {
+	mockDB, mockRepo, _, service := setupAccessTest(t)
+
+	mockDB.ExpectBegin()
+	mockRepo.On("FindBookByID", mock.Anything, mock.Anything, uint64(1)).Return(&models.Book{ID: 1, AuthorID: 3, Stock: 5, Version: 3}, nil)
+	mockRepo.On("UpdateBook", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	mockDB.ExpectCommit()
+
+	req := &params.BookRequest{AuthorID: 3, Title: "Updated Book", Stock: 50}
+	_, errResponse := service.UpdateBook(WithTrustedCaller(context.Background()), 1, []int32{3}, req)
+
+	assert.Nil(t, errResponse)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestUpdateBook_APIKeyWithoutWriteScope(t *testing.T) {
+	mockDB, mockRepo, _, service := setupAccessTest(t)
+
+	mockDB.ExpectBegin()
+	mockRepo.On("FindBookByID", mock.Anything, mock.Anything, uint64(1)).Return(&models.Book{ID: 1, AuthorID: 3, Stock: 5, Version: 3}, nil)
+	mockDB.ExpectRollback()
+
+	req := &params.BookRequest{AuthorID: 3, Title: "Updated Book", Stock: 5}
+	_, errResponse := service.UpdateBook(newAPIKeyContext("books:read"), 1, []int32{3}, req)
+
+	assert.NotNil(t, errResponse)
+	assert.Equal(t, 403, errResponse.StatusCode)
+	mockRepo.AssertNotCalled(t, "UpdateBook", mock.Anything, mock.Anything, mock.Anything)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestUpdateBook_APIKeyWritesAnyBook(t *testing.T) {
+	mockDB, mockRepo, authorRepo, service := setupAccessTest(t)
+
+	mockDB.ExpectBegin()
+	mockRepo.On("FindBookByID", mock.Anything, mock.Anything, uint64(1)).Return(&models.Book{ID: 1, AuthorID: 3, Stock: 5, Version: 3}, nil)
+	mockRepo.On("UpdateBook", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	mockDB.ExpectCommit()
+
+	req := &params.BookRequest{AuthorID: 3, Title: "Updated Book", Stock: 5}
+	_, errResponse := service.UpdateBook(newAPIKeyContext("books:write"), 1, []int32{3}, req)
+
+	assert.Nil(t, errResponse)
+	authorRepo.AssertNotCalled(t, "FindAuthorByUserID", mock.Anything, mock.Anything, mock.Anything)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestUpdateBook_APIKeyChangesStockWithoutScope(t *testing.T) {
+	mockDB, mockRepo, _, service := setupAccessTest(t)
+
+	mockDB.ExpectBegin()
+	mockRepo.On("FindBookByID", mock.Anything, mock.Anything, uint64(1)).Return(&models.Book{ID: 1, AuthorID: 3, Stock: 5, Version: 3}, nil)
+	mockDB.ExpectRollback()
+
+	req := &params.BookRequest{AuthorID: 3, Title: "Updated Book", Stock: 50}
+	_, errResponse := service.UpdateBook(newAPIKeyContext("books:write"), 1, []int32{3}, req)
+
+	assert.NotNil(t, errResponse)
+	assert.Equal(t, 403, errResponse.StatusCode)
+	mockRepo.AssertNotCalled(t, "UpdateBook", mock.Anything, mock.Anything, mock.Anything)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestUpdateBook_APIKeyChangesStockWithScope(t *testing.T) {
+	mockDB, mockRepo, _, service := setupAccessTest(t)
+
+	mockDB.ExpectBegin()
+	mockRepo.On("FindBookByID", mock.Anything, mock.Anything, uint64(1)).Return(&models.Book{ID: 1, AuthorID: 3, Stock: 5, Version: 3}, nil)
+	mockRepo.On("UpdateBook", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	mockDB.ExpectCommit()
+
+	req := &params.BookRequest{AuthorID: 3, Title: "Updated Book", Stock: 50}
+	_, errResponse := service.UpdateBook(newAPIKeyContext("books:write", "stock:adjust"), 1, []int32{3}, req)
+
+	assert.Nil(t, errResponse)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestUpdateBook_PolicyGrantsWriteAny(t *testing.T) {
+	mockDB, mockRepo, authorRepo, service := setupAccessTest(t)
+
+	mockDB.ExpectBegin()
+	mockRepo.On("FindBookByID", mock.Anything, mock.Anything, uint64(1)).Return(&models.Book{ID: 1, AuthorID: 3, Stock: 5, Version: 3}, nil)
+	mockRepo.On("UpdateBook", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	mockDB.ExpectCommit()
+
+	req := &params.BookRequest{AuthorID: 3, Title: "Updated Book", Stock: 5}
+	_, errResponse := service.UpdateBook(newAccessContext(7, "editor"), 1, []int32{3}, req)
+
+	assert.Nil(t, errResponse)
+	authorRepo.AssertNotCalled(t, "FindAuthorByUserID", mock.Anything, mock.Anything, mock.Anything)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestUpdateBook_PolicyWithoutStockAdjust(t *testing.T) {
+	mockDB, mockRepo, _, service := setupAccessTest(t)
+
+	mockDB.ExpectBegin()
+	mockRepo.On("FindBookByID", mock.Anything, mock.Anything, uint64(1)).Return(&models.Book{ID: 1, AuthorID: 3, Stock: 5, Version: 3}, nil)
+	mockDB.ExpectRollback()
+
+	req := &params.BookRequest{AuthorID: 3, Title: "Updated Book", Stock: 50}
+	_, errResponse := service.UpdateBook(newAccessContext(7, "editor"), 1, []int32{3}, req)
+
+	assert.NotNil(t, errResponse)
+	assert.Equal(t, 403, errResponse.StatusCode)
+	mockRepo.AssertNotCalled(t, "UpdateBook", mock.Anything, mock.Anything, mock.Anything)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
@@ -0,0 +1,200 @@
+package services
+
+import (
+	"context"
+	"library-api-book/internal/cache"
+	"library-api-book/internal/commons/response"
+	"library-api-book/internal/logger"
+	"library-api-book/internal/models"
+	"library-api-book/internal/params"
+	"math/rand"
+	"strconv"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	// bookListTTL bounds how long a list survives if an invalidation is lost.
+	bookListTTL = 5 * time.Minute
+	// bookDetailTTL and bookMissingTTL are how long a book, respectively the
+	// fact that it does not exist, stay cached, before jitter.
+	bookDetailTTL  = 10 * time.Minute
+	bookMissingTTL = 30 * time.Second
+	// bookVersionTTL expires the version of a book's namespace once nothing
+	// cached under it can be left.
+	bookVersionTTL = 2 * bookDetailTTL
+)
+
+// BookCache holds cached book lists and book details. Every service that
+// writes books reports the write once its transaction has committed, which
+// invalidates the lists and the details of the written books. A nil
+// BookCache caches nothing.
+type BookCache struct {
+	cache *cache.Cache
+	lists *cache.Namespace
+	loads singleflight.Group
+	log   logger.Logger
+}
+
+// cachedBook is a cached book detail. Book is nil when the book does not
+// exist.
+type cachedBook struct {
+	Book *params.BookResponse `json:"book"`
+}
+
+// bookLoad is the result of a book detail load shared by concurrent misses.
+type bookLoad struct {
+	book    *params.BookResponse
+	custErr *response.CustomError
+}
+
+func NewBookCache(c *cache.Cache, log logger.Logger) *BookCache {
+	return &BookCache{
+		cache: c,
+		lists: c.Namespace("books:list", 0),
+		log:   log,
+	}
+}
+
+// listKey returns the cache key of a GetAllBooks query, or "" when the cache
+// cannot be used.
+func (bookCache *BookCache) listKey(ctx context.Context, pagination *models.Pagination, filter *models.BookFilter) string {
+	if bookCache == nil {
+		return ""
+	}
+	key, err := bookCache.lists.Key(ctx, pagination.Page, pagination.PageSize, filter.Search, filter.AuthorID, filter.InStock)
+	if err != nil {
+		bookCache.log.Error("[BookCache] Failed to resolve cache key - listKey", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return ""
+	}
+	return key
+}
+
+func (bookCache *BookCache) getList(ctx context.Context, key string) ([]*params.BookResponse, bool) {
+	if bookCache == nil || key == "" {
+		return nil, false
+	}
+	var books []*params.BookResponse
+	found, err := bookCache.cache.Get(ctx, key, &books)
+	if err != nil {
+		bookCache.log.Error("[BookCache] Failed to read cached books - getList", map[string]interface{}{
+			"cache_key": key,
+			"error":     err.Error(),
+		})
+		return nil, false
+	}
+	return books, found
+}
+
+func (bookCache *BookCache) setList(ctx context.Context, key string, books []*params.BookResponse) {
+	if bookCache == nil || key == "" {
+		return
+	}
+	if err := bookCache.cache.Set(ctx, key, books, bookListTTL); err != nil {
+		bookCache.log.Error("[BookCache] Failed to cache books - setList", map[string]interface{}{
+			"cache_key": key,
+			"error":     err.Error(),
+		})
+	}
+}
+
+// detail returns the book id from the cache, or loads it with load and
+// caches the result. load reports a book that does not exist as a nil book
+// and no error; that is cached briefly and errors are not cached at all.
+// Concurrent misses for the same book share a single load.
+func (bookCache *BookCache) detail(ctx context.Context, id uint64, load func(ctx context.Context) (*params.BookResponse, *response.CustomError)) (*params.BookResponse, *response.CustomError) {
+	if bookCache == nil {
+		return load(ctx)
+	}
+
+	key, err := bookCache.detailNamespace(id).Key(ctx)
+	if err != nil {
+		bookCache.log.Error("[BookCache] Failed to resolve cache key - detail", map[string]interface{}{
+			"book_id": id,
+			"error":   err.Error(),
+		})
+		key = ""
+	}
+	if key != "" {
+		var cached cachedBook
+		found, err := bookCache.cache.Get(ctx, key, &cached)
+		if err != nil {
+			bookCache.log.Error("[BookCache] Failed to read cached book - detail", map[string]interface{}{
+				"cache_key": key,
+				"error":     err.Error(),
+			})
+		} else if found {
+			return cached.Book, nil
+		}
+	}
+
+	// Loads are shared per key, so a caller that resolved the key after a
+	// write never joins a load that may have started before it.
+	flight := key
+	if flight == "" {
+		flight = "books:detail:" + strconv.FormatUint(id, 10)
+	}
+	result, _, _ := bookCache.loads.Do(flight, func() (interface{}, error) {
+		// The load is shared; the caller that started it hanging up must not
+		// fail the others.
+		ctx := context.WithoutCancel(ctx)
+		book, custErr := load(ctx)
+		if custErr == nil && key != "" {
+			ttl := bookDetailTTL
+			if book == nil {
+				ttl = bookMissingTTL
+			}
+			if err := bookCache.cache.Set(ctx, key, cachedBook{Book: book}, jitter(ttl)); err != nil {
+				bookCache.log.Error("[BookCache] Failed to cache book - detail", map[string]interface{}{
+					"cache_key": key,
+					"error":     err.Error(),
+				})
+			}
+		}
+		return bookLoad{book: book, custErr: custErr}, nil
+	})
+	loaded := result.(bookLoad)
+	return loaded.book, loaded.custErr
+}
+
+// detailNamespace holds the cached detail of the book id. Each book has its
+// own namespace so a write invalidates only the books it touched, and a load
+// racing the write caches into the old version, like the lists.
+func (bookCache *BookCache) detailNamespace(id uint64) *cache.Namespace {
+	return bookCache.cache.Namespace("books:detail:"+strconv.FormatUint(id, 10), bookVersionTTL)
+}
+
+// booksChanged invalidates the cached lists and the details of the books ids
+// after a committed write to them. It does nothing when ids is empty, i.e.
+// nothing was written.
+func (bookCache *BookCache) booksChanged(ctx context.Context, ids ...uint64) {
+	if bookCache == nil || len(ids) == 0 {
+		return
+	}
+	// The write is committed; a client hanging up must not keep the cache
+	// stale.
+	ctx = context.WithoutCancel(ctx)
+	if err := bookCache.lists.Invalidate(ctx); err != nil {
+		bookCache.log.Error("[BookCache] Failed to invalidate book lists - booksChanged", map[string]interface{}{
+			"book_ids": ids,
+			"error":    err.Error(),
+		})
+	}
+	for _, id := range ids {
+		if err := bookCache.detailNamespace(id).Invalidate(ctx); err != nil {
+			bookCache.log.Error("[BookCache] Failed to invalidate book - booksChanged", map[string]interface{}{
+				"book_id": id,
+				"error":   err.Error(),
+			})
+		}
+	}
+}
+
+// jitter spreads ttl by up to a tenth either way so entries cached together
+// do not expire together.
+func jitter(ttl time.Duration) time.Duration {
+	return ttl - ttl/10 + time.Duration(rand.Int63n(int64(ttl/5)+1))
+}
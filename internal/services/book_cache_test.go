@@ -0,0 +1,265 @@
+package services
+
+import (
+	"context"
+	"library-api-book/internal/cache"
+	"library-api-book/internal/commons/response"
+	"library-api-book/internal/logger"
+	"library-api-book/internal/models"
+	"library-api-book/internal/params"
+	"library-api-book/internal/repositories"
+	"library-api-book/pkg/breaker"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestBookCache(t *testing.T) (*BookCache, *miniredis.Miniredis) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr(), MaxRetries: -1})
+	t.Cleanup(func() { client.Close() })
+	return NewBookCache(cache.New(client, time.Second, breaker.New(5, time.Second)), logger.NopLogger{}), server
+}
+
+func TestGetAllBooks_CachedUntilWrite(t *testing.T) {
+	db, mockDB, mockRepo, service := setupTest(t)
+	defer db.Close()
+	service.Logger = logger.NopLogger{}
+	service.BookCache, _ = newTestBookCache(t)
+
+	list := func() []uint64 {
+		books, errResponse := service.GetAllBooks(context.Background(), &models.Pagination{Page: 1, PageSize: 10}, &models.BookFilter{})
+		require.Nil(t, errResponse)
+		ids := make([]uint64, len(books))
+		for i, book := range books {
+			ids[i] = book.ID
+			assert.Equal(t, int32(2), book.Stock)
+		}
+		return ids
+	}
+
+	mockDB.ExpectBegin()
+	mockRepo.On("GetAllBooks", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return([]*models.Book{{ID: 1, Stock: 2}}, nil).Once()
+	mockDB.ExpectCommit()
+	assert.Equal(t, []uint64{1}, list())
+
+	// Served from the cache.
+	assert.Equal(t, []uint64{1}, list())
+
+	// A failed write leaves the cache alone.
+	mockDB.ExpectBegin()
+	mockRepo.On("AdjustStock", mock.Anything, mock.Anything, uint64(1), int32(-1)).Return(nil, repositories.ErrBookOutOfStock).Once()
+	mockDB.ExpectCommit()
+	require.NotNil(t, service.DecreaseStock(context.Background(), 1))
+	assert.Equal(t, []uint64{1}, list())
+
+	// A committed write invalidates it.
+	mockDB.ExpectBegin()
+	mockRepo.On("AdjustStock", mock.Anything, mock.Anything, uint64(1), int32(-1)).Return(&models.Book{ID: 1, Stock: 0}, nil).Once()
+	mockDB.ExpectCommit()
+	require.Nil(t, service.DecreaseStock(context.Background(), 1))
+
+	mockDB.ExpectBegin()
+	mockRepo.On("GetAllBooks", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return([]*models.Book{{ID: 1, Stock: 2}, {ID: 2, Stock: 2}}, nil).Once()
+	mockDB.ExpectCommit()
+	assert.Equal(t, []uint64{1, 2}, list())
+
+	mockRepo.AssertExpectations(t)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestGetAllBooks_RedisDown(t *testing.T) {
+	db, mockDB, mockRepo, service := setupTest(t)
+	defer db.Close()
+	service.Logger = logger.NopLogger{}
+	var server *miniredis.Miniredis
+	service.BookCache, server = newTestBookCache(t)
+	server.Close()
+
+	mockDB.ExpectBegin()
+	mockRepo.On("GetAllBooks", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return([]*models.Book{{ID: 1}}, nil)
+	mockDB.ExpectCommit()
+
+	books, errResponse := service.GetAllBooks(context.Background(), &models.Pagination{Page: 1, PageSize: 10}, &models.BookFilter{})
+
+	require.Nil(t, errResponse)
+	assert.Len(t, books, 1)
+}
+
+func TestBookCache_InvalidatesOnlyAfterWrites(t *testing.T) {
+	bookCache, _ := newTestBookCache(t)
+	ctx := context.Background()
+
+	before := bookCache.listKey(ctx, &models.Pagination{Page: 1, PageSize: 10}, &models.BookFilter{})
+	bookCache.booksChanged(ctx)
+	assert.Equal(t, before, bookCache.listKey(ctx, &models.Pagination{Page: 1, PageSize: 10}, &models.BookFilter{}))
+
+	bookCache.booksChanged(ctx, 1)
+	assert.NotEqual(t, before, bookCache.listKey(ctx, &models.Pagination{Page: 1, PageSize: 10}, &models.BookFilter{}))
+}
+
+func TestGetDetailBook_CachedUntilWrite(t *testing.T) {
+	db, mockDB, mockRepo, service := setupTest(t)
+	defer db.Close()
+	service.Logger = logger.NopLogger{}
+	service.BookCache, _ = newTestBookCache(t)
+
+	stock := func() int32 {
+		book, errResponse := service.GetDetailBook(context.Background(), 1)
+		require.Nil(t, errResponse)
+		return book.Stock
+	}
+
+	mockDB.ExpectBegin()
+	mockRepo.On("FindBookByID", mock.Anything, mock.Anything, uint64(1)).Return(&models.Book{ID: 1, Stock: 2}, nil).Once()
+	mockDB.ExpectCommit()
+	assert.Equal(t, int32(2), stock())
+
+	// Served from the cache.
+	assert.Equal(t, int32(2), stock())
+
+	mockDB.ExpectBegin()
+	mockRepo.On("AdjustStock", mock.Anything, mock.Anything, uint64(1), int32(-1)).Return(&models.Book{ID: 1, Stock: 1}, nil).Once()
+	mockDB.ExpectCommit()
+	require.Nil(t, service.DecreaseStock(context.Background(), 1))
+
+	mockDB.ExpectBegin()
+	mockRepo.On("FindBookByID", mock.Anything, mock.Anything, uint64(1)).Return(&models.Book{ID: 1, Stock: 1}, nil).Once()
+	mockDB.ExpectCommit()
+	assert.Equal(t, int32(1), stock())
+
+	mockRepo.AssertExpectations(t)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestGetDetailBook_NotFoundCachedBriefly(t *testing.T) {
+	db, mockDB, mockRepo, service := setupTest(t)
+	defer db.Close()
+	service.Logger = logger.NopLogger{}
+	var server *miniredis.Miniredis
+	service.BookCache, server = newTestBookCache(t)
+
+	mockDB.ExpectBegin()
+	mockRepo.On("FindBookByID", mock.Anything, mock.Anything, uint64(1)).Return(nil, repositories.ErrBookNotFound).Once()
+	mockDB.ExpectCommit()
+
+	for i := 0; i < 2; i++ {
+		book, errResponse := service.GetDetailBook(context.Background(), 1)
+		assert.Nil(t, book)
+		require.NotNil(t, errResponse)
+		assert.Equal(t, "Book not found", errResponse.Message)
+	}
+
+	var ttl time.Duration
+	for _, key := range server.Keys() {
+		if key != "books:detail:1:version" && key != "books:list:version" {
+			ttl = server.TTL(key)
+		}
+	}
+	assert.Greater(t, ttl, time.Duration(0))
+	assert.LessOrEqual(t, ttl, bookMissingTTL+bookMissingTTL/10)
+
+	mockRepo.AssertExpectations(t)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestGetDetailBook_ErrorsNotCached(t *testing.T) {
+	db, mockDB, mockRepo, service := setupTest(t)
+	defer db.Close()
+	service.Logger = logger.NopLogger{}
+	service.BookCache, _ = newTestBookCache(t)
+
+	mockDB.ExpectBegin().WillReturnError(assert.AnError)
+	_, errResponse := service.GetDetailBook(context.Background(), 1)
+	require.NotNil(t, errResponse)
+
+	mockDB.ExpectBegin()
+	mockRepo.On("FindBookByID", mock.Anything, mock.Anything, uint64(1)).Return(&models.Book{ID: 1}, nil).Once()
+	mockDB.ExpectCommit()
+	book, errResponse := service.GetDetailBook(context.Background(), 1)
+	require.Nil(t, errResponse)
+	assert.Equal(t, uint64(1), book.ID)
+
+	mockRepo.AssertExpectations(t)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestGetDetailBook_RedisDown(t *testing.T) {
+	db, mockDB, mockRepo, service := setupTest(t)
+	defer db.Close()
+	service.Logger = logger.NopLogger{}
+	var server *miniredis.Miniredis
+	service.BookCache, server = newTestBookCache(t)
+	server.Close()
+
+	mockDB.ExpectBegin()
+	mockRepo.On("FindBookByID", mock.Anything, mock.Anything, uint64(1)).Return(&models.Book{ID: 1}, nil)
+	mockDB.ExpectCommit()
+
+	book, errResponse := service.GetDetailBook(context.Background(), 1)
+
+	require.Nil(t, errResponse)
+	assert.Equal(t, uint64(1), book.ID)
+}
+
+func TestBookCache_ConcurrentMissesLoadOnce(t *testing.T) {
+	bookCache, _ := newTestBookCache(t)
+
+	var loads atomic.Int32
+	release := make(chan struct{})
+	load := func(ctx context.Context) (*params.BookResponse, *response.CustomError) {
+		loads.Add(1)
+		<-release
+		return &params.BookResponse{ID: 1}, nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			book, custErr := bookCache.detail(context.Background(), 1, load)
+			assert.Nil(t, custErr)
+			assert.Equal(t, uint64(1), book.ID)
+		}()
+	}
+	// Let every caller miss and join the load before it returns.
+	time.Sleep(100 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), loads.Load())
+}
+
+func TestBookCache_WriteHidesRacingLoad(t *testing.T) {
+	bookCache, _ := newTestBookCache(t)
+	ctx := context.Background()
+
+	// The write commits and invalidates while the load is still reading the
+	// old row.
+	_, _ = bookCache.detail(ctx, 1, func(ctx context.Context) (*params.BookResponse, *response.CustomError) {
+		bookCache.booksChanged(ctx, 1)
+		return &params.BookResponse{ID: 1, Stock: 2}, nil
+	})
+
+	book, custErr := bookCache.detail(ctx, 1, func(ctx context.Context) (*params.BookResponse, *response.CustomError) {
+		return &params.BookResponse{ID: 1, Stock: 1}, nil
+	})
+	require.Nil(t, custErr)
+	assert.Equal(t, int32(1), book.Stock)
+}
+
+func TestJitter(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		ttl := jitter(bookDetailTTL)
+		assert.GreaterOrEqual(t, ttl, bookDetailTTL-bookDetailTTL/10)
+		assert.LessOrEqual(t, ttl, bookDetailTTL+bookDetailTTL/10)
+	}
+}
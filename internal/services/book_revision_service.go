@@ -0,0 +1,295 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"library-api-book/internal/commons/response"
+	"library-api-book/internal/logger"
+	"library-api-book/internal/models"
+	"library-api-book/internal/params"
+	"library-api-book/internal/repositories"
+	"reflect"
+	"sort"
+	"strconv"
+	"time"
+)
+
+type BookRevisionService interface {
+	GetRevisions(ctx context.Context, bookID uint64, pagination *models.Pagination) ([]*params.BookRevisionResponse, *response.CustomError)
+	DiffRevisions(ctx context.Context, bookID uint64, from int32, to int32) (*params.BookRevisionDiffResponse, *response.CustomError)
+	RevertBook(ctx context.Context, bookID uint64, revision int32) (*params.BookResponse, *response.CustomError)
+}
+
+type BookRevisionServiceImpl struct {
+	DB                 *sql.DB
+	BookCache          *BookCache
+	BookRepository     repositories.BookRepository
+	RevisionRepository repositories.BookRevisionRepository
+	Logger             logger.Logger
+}
+
+func NewBookRevisionService(db *sql.DB, bookCache *BookCache, bookRepository repositories.BookRepository, revisionRepository repositories.BookRevisionRepository, log logger.Logger) BookRevisionService {
+	return &BookRevisionServiceImpl{
+		DB:                 db,
+		BookCache:          bookCache,
+		BookRepository:     bookRepository,
+		RevisionRepository: revisionRepository,
+		Logger:             log,
+	}
+}
+
+func (service *BookRevisionServiceImpl) GetRevisions(ctx context.Context, bookID uint64, pagination *models.Pagination) ([]*params.BookRevisionResponse, *response.CustomError) {
+	tx, err := service.DB.Begin()
+	if err != nil {
+		service.Logger.Error("[BookRevisionService] Failed to begin transaction - GetRevisions", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return nil, response.GeneralError("Failed to connect to the database: " + err.Error())
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			service.Logger.Error("[BookRevisionService] Transaction rolled back due to panic - GetRevisions", map[string]interface{}{
+				"error": p,
+			})
+		} else if err != nil {
+			tx.Rollback()
+			service.Logger.Error("[BookRevisionService] Transaction rolled back due to error - GetRevisions", map[string]interface{}{
+				"error": err.Error(),
+			})
+		} else {
+			tx.Commit()
+		}
+	}()
+
+	pagination.Offset = (pagination.Page - 1) * pagination.PageSize
+
+	revisions, err := service.RevisionRepository.GetRevisionsByBookID(ctx, tx, bookID, pagination)
+	if err != nil {
+		service.Logger.Error("[BookRevisionService] Failed to fetch revisions - GetRevisions", map[string]interface{}{
+			"book_id": bookID,
+			"error":   err.Error(),
+		})
+		return nil, response.GeneralError("Failed to fetch book revisions: " + err.Error())
+	}
+
+	revisionResponses := make([]*params.BookRevisionResponse, len(revisions))
+	for i, revision := range revisions {
+		revisionResponses[i] = newBookRevisionResponse(revision)
+	}
+
+	return revisionResponses, nil
+}
+
+func (service *BookRevisionServiceImpl) DiffRevisions(ctx context.Context, bookID uint64, from int32, to int32) (*params.BookRevisionDiffResponse, *response.CustomError) {
+	tx, err := service.DB.Begin()
+	if err != nil {
+		service.Logger.Error("[BookRevisionService] Failed to begin transaction - DiffRevisions", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return nil, response.GeneralError("Failed to connect to the database: " + err.Error())
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			service.Logger.Error("[BookRevisionService] Transaction rolled back due to panic - DiffRevisions", map[string]interface{}{
+				"error": p,
+			})
+		} else if err != nil {
+			tx.Rollback()
+			service.Logger.Error("[BookRevisionService] Transaction rolled back due to error - DiffRevisions", map[string]interface{}{
+				"error": err.Error(),
+			})
+		} else {
+			tx.Commit()
+		}
+	}()
+
+	fromRevision, err := service.RevisionRepository.FindRevision(ctx, tx, bookID, from)
+	if err != nil {
+		return nil, revisionLookupError(err, from)
+	}
+	toRevision, err := service.RevisionRepository.FindRevision(ctx, tx, bookID, to)
+	if err != nil {
+		return nil, revisionLookupError(err, to)
+	}
+
+	changes, err := diffSnapshots(fromRevision.Snapshot, toRevision.Snapshot)
+	if err != nil {
+		return nil, response.GeneralError("Failed to compare revisions: " + err.Error())
+	}
+
+	return &params.BookRevisionDiffResponse{
+		BookID:  bookID,
+		From:    from,
+		To:      to,
+		Changes: changes,
+	}, nil
+}
+
+// RevertBook restores the catalog fields of a book from an earlier revision
+// and records the result as a new revision. Stock and cover are left alone:
+// stock is owned by circulation and old cover files may no longer exist.
+func (service *BookRevisionServiceImpl) RevertBook(ctx context.Context, bookID uint64, revision int32) (*params.BookResponse, *response.CustomError) {
+	tx, err := service.DB.Begin()
+	if err != nil {
+		service.Logger.Error("[BookRevisionService] Failed to begin transaction - RevertBook", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return nil, response.GeneralError("Failed to connect to the database: " + err.Error())
+	}
+	var changed []uint64
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			service.Logger.Error("[BookRevisionService] Transaction rolled back due to panic - RevertBook", map[string]interface{}{
+				"error": p,
+			})
+		} else if err != nil {
+			tx.Rollback()
+			service.Logger.Error("[BookRevisionService] Transaction rolled back due to error - RevertBook", map[string]interface{}{
+				"error": err.Error(),
+			})
+		} else if tx.Commit() == nil {
+			service.BookCache.booksChanged(ctx, changed...)
+		}
+	}()
+
+	book, err := service.BookRepository.FindBookByID(ctx, tx, bookID)
+	if err != nil {
+		service.Logger.Error("[BookRevisionService] Failed to find book by ID - RevertBook", map[string]interface{}{
+			"book_id": bookID,
+			"error":   err.Error(),
+		})
+		return nil, response.NotFoundError("Book not found")
+	}
+
+	target, err := service.RevisionRepository.FindRevision(ctx, tx, bookID, revision)
+	if err != nil {
+		return nil, revisionLookupError(err, revision)
+	}
+
+	var columns []string
+	if book.AuthorID != target.Snapshot.AuthorID {
+		book.AuthorID = target.Snapshot.AuthorID
+		columns = append(columns, "author_id")
+	}
+	if book.Title != target.Snapshot.Title {
+		book.Title = target.Snapshot.Title
+		columns = append(columns, "title")
+	}
+	if book.ISBN != target.Snapshot.ISBN {
+		book.ISBN = target.Snapshot.ISBN
+		columns = append(columns, "isbn")
+	}
+	if book.Publisher != target.Snapshot.Publisher {
+		book.Publisher = target.Snapshot.Publisher
+		columns = append(columns, "publisher")
+	}
+	if len(columns) == 0 {
+		err = errors.New("book already matches the revision")
+		return nil, response.BadRequestError("Book already matches revision " + strconv.Itoa(int(revision)))
+	}
+
+	book.UpdatedAt = time.Now()
+	err = service.BookRepository.PatchBook(ctx, tx, book, columns)
+	if err != nil {
+		service.Logger.Error("[BookRevisionService] Failed to revert book - RevertBook", map[string]interface{}{
+			"book_id":  bookID,
+			"revision": revision,
+			"error":    err.Error(),
+		})
+		if errors.Is(err, repositories.ErrBookVersionConflict) {
+			return nil, response.PreconditionFailedError("Book has been modified by someone else, reload it and try again")
+		}
+		return nil, response.GeneralError("Failed to revert book: " + err.Error())
+	}
+
+	err = recordRevision(ctx, tx, service.RevisionRepository, book, models.RevisionActionRevert, &revision)
+	if err != nil {
+		service.Logger.Error("[BookRevisionService] Failed to record revision - RevertBook", map[string]interface{}{
+			"book_id": bookID,
+			"error":   err.Error(),
+		})
+		return nil, response.GeneralError("Failed to record book revision: " + err.Error())
+	}
+
+	changed = []uint64{book.ID}
+	return newBookResponse(book), nil
+}
+
+// recordRevision stores a snapshot of book as it is after a change. It must
+// run in the transaction that made the change so both commit together.
+func recordRevision(ctx context.Context, tx *sql.Tx, repository repositories.BookRevisionRepository, book *models.Book, action string, sourceRevision *int32) error {
+	return repository.CreateRevision(ctx, tx, &models.BookRevision{
+		BookID:         book.ID,
+		Revision:       book.Version,
+		Action:         action,
+		Snapshot:       models.NewBookSnapshot(book),
+		ActorID:        actorFromContext(ctx),
+		SourceRevision: sourceRevision,
+		CreatedAt:      time.Now(),
+	})
+}
+
+// diffSnapshots lists the snapshot fields that differ, ordered by field name.
+func diffSnapshots(from, to models.BookSnapshot) ([]params.BookFieldChange, error) {
+	fromFields, err := snapshotFields(from)
+	if err != nil {
+		return nil, err
+	}
+	toFields, err := snapshotFields(to)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for name := range fromFields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	changes := []params.BookFieldChange{}
+	for _, name := range names {
+		if !reflect.DeepEqual(fromFields[name], toFields[name]) {
+			changes = append(changes, params.BookFieldChange{
+				Field: name,
+				From:  fromFields[name],
+				To:    toFields[name],
+			})
+		}
+	}
+	return changes, nil
+}
+
+func snapshotFields(snapshot models.BookSnapshot) (map[string]interface{}, error) {
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+func revisionLookupError(err error, revision int32) *response.CustomError {
+	if errors.Is(err, repositories.ErrRevisionNotFound) {
+		return response.NotFoundError("Revision " + strconv.Itoa(int(revision)) + " not found")
+	}
+	return response.GeneralError("Failed to find book revision: " + err.Error())
+}
+
+func newBookRevisionResponse(revision *models.BookRevision) *params.BookRevisionResponse {
+	return &params.BookRevisionResponse{
+		Revision:       revision.Revision,
+		Action:         revision.Action,
+		ActorID:        revision.ActorID,
+		SourceRevision: revision.SourceRevision,
+		Snapshot:       revision.Snapshot,
+		CreatedAt:      revision.CreatedAt,
+	}
+}
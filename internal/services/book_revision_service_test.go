@@ -0,0 +1,148 @@
+package services
+
+import (
+	"context"
+	"library-api-book/internal/logger"
+	"library-api-book/internal/models"
+	"library-api-book/internal/params"
+	"library-api-book/internal/repositories"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func setupRevisionTest(t *testing.T) (sqlmock.Sqlmock, *repositories.MockBookRepository, *repositories.MockBookRevisionRepository, *BookRevisionServiceImpl) {
+	db, mockDB, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	bookRepo := new(repositories.MockBookRepository)
+	revisionRepo := new(repositories.MockBookRevisionRepository)
+	service := &BookRevisionServiceImpl{
+		DB:                 db,
+		BookRepository:     bookRepo,
+		RevisionRepository: revisionRepo,
+		Logger:             logger.NopLogger{},
+	}
+
+	return mockDB, bookRepo, revisionRepo, service
+}
+
+func TestRevertBook_Success(t *testing.T) {
+	mockDB, bookRepo, revisionRepo, service := setupRevisionTest(t)
+
+	current := &models.Book{ID: 1, AuthorID: 2, Title: "Renamed", ISBN: "9780306406157", Stock: 4, Version: 5}
+	target := &models.BookRevision{BookID: 1, Revision: 2, Snapshot: models.BookSnapshot{AuthorID: 2, Title: "Original", ISBN: "9780306406157", Stock: 9}}
+
+	mockDB.ExpectBegin()
+	bookRepo.On("FindBookByID", mock.Anything, mock.Anything, uint64(1)).Return(current, nil)
+	revisionRepo.On("FindRevision", mock.Anything, mock.Anything, uint64(1), int32(2)).Return(target, nil)
+	bookRepo.On("PatchBook", mock.Anything, mock.Anything, mock.Anything, []string{"title"}).Run(func(args mock.Arguments) {
+		args.Get(2).(*models.Book).Version++
+	}).Return(nil)
+	revisionRepo.On("CreateRevision", mock.Anything, mock.Anything, mock.MatchedBy(func(revision *models.BookRevision) bool {
+		return revision.Revision == 6 &&
+			revision.Action == models.RevisionActionRevert &&
+			*revision.SourceRevision == 2 &&
+			revision.Snapshot.Title == "Original" &&
+			revision.Snapshot.Stock == 4
+	})).Return(nil)
+	mockDB.ExpectCommit()
+
+	result, errResponse := service.RevertBook(context.Background(), 1, 2)
+
+	assert.Nil(t, errResponse)
+	assert.Equal(t, "Original", result.Title)
+	assert.Equal(t, int32(4), result.Stock)
+	bookRepo.AssertExpectations(t)
+	revisionRepo.AssertExpectations(t)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestRevertBook_AlreadyMatches(t *testing.T) {
+	mockDB, bookRepo, revisionRepo, service := setupRevisionTest(t)
+
+	mockDB.ExpectBegin()
+	bookRepo.On("FindBookByID", mock.Anything, mock.Anything, uint64(1)).Return(&models.Book{ID: 1, AuthorID: 2, Title: "Same", Version: 3}, nil)
+	revisionRepo.On("FindRevision", mock.Anything, mock.Anything, uint64(1), int32(3)).Return(&models.BookRevision{Revision: 3, Snapshot: models.BookSnapshot{AuthorID: 2, Title: "Same"}}, nil)
+	mockDB.ExpectRollback()
+
+	_, errResponse := service.RevertBook(context.Background(), 1, 3)
+
+	assert.NotNil(t, errResponse)
+	assert.Equal(t, "Book already matches revision 3", errResponse.Message)
+	bookRepo.AssertNotCalled(t, "PatchBook", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestRevertBook_RevisionNotFound(t *testing.T) {
+	mockDB, bookRepo, revisionRepo, service := setupRevisionTest(t)
+
+	mockDB.ExpectBegin()
+	bookRepo.On("FindBookByID", mock.Anything, mock.Anything, uint64(1)).Return(&models.Book{ID: 1}, nil)
+	revisionRepo.On("FindRevision", mock.Anything, mock.Anything, uint64(1), int32(9)).Return(nil, repositories.ErrRevisionNotFound)
+	mockDB.ExpectRollback()
+
+	_, errResponse := service.RevertBook(context.Background(), 1, 9)
+
+	assert.NotNil(t, errResponse)
+	assert.Equal(t, "Revision 9 not found", errResponse.Message)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestDiffRevisions_Success(t *testing.T) {
+	mockDB, _, revisionRepo, service := setupRevisionTest(t)
+
+	mockDB.ExpectBegin()
+	revisionRepo.On("FindRevision", mock.Anything, mock.Anything, uint64(1), int32(1)).Return(&models.BookRevision{Revision: 1, Snapshot: models.BookSnapshot{AuthorID: 2, Title: "Old", Stock: 3}}, nil)
+	revisionRepo.On("FindRevision", mock.Anything, mock.Anything, uint64(1), int32(4)).Return(&models.BookRevision{Revision: 4, Snapshot: models.BookSnapshot{AuthorID: 2, Title: "New", Publisher: "Acme", Stock: 3}}, nil)
+	mockDB.ExpectCommit()
+
+	result, errResponse := service.DiffRevisions(context.Background(), 1, 1, 4)
+
+	assert.Nil(t, errResponse)
+	assert.Len(t, result.Changes, 2)
+	assert.Equal(t, "publisher", result.Changes[0].Field)
+	assert.Equal(t, "", result.Changes[0].From)
+	assert.Equal(t, "Acme", result.Changes[0].To)
+	assert.Equal(t, "title", result.Changes[1].Field)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestCreateBook_RecordsRevisionWithActor(t *testing.T) {
+	db, mockDB, mockRepo, service := setupTest(t)
+	defer db.Close()
+	service.Logger = logger.NopLogger{}
+
+	revisionRepo := new(repositories.MockBookRevisionRepository)
+	service.RevisionRepository = revisionRepo
+
+	ginCtx := &gin.Context{}
+	ginCtx.Set("authId", 7)
+	ginCtx.Set("role", "admin")
+
+	mockDB.ExpectBegin()
+	mockRepo.On("CreateBook", mock.Anything, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		book := args.Get(2).(*models.Book)
+		book.ID = 10
+		book.Version = 1
+	}).Return(nil)
+	revisionRepo.On("CreateRevision", mock.Anything, mock.Anything, mock.MatchedBy(func(revision *models.BookRevision) bool {
+		return revision.BookID == 10 &&
+			revision.Revision == 1 &&
+			revision.Action == models.RevisionActionCreate &&
+			revision.ActorID != nil && *revision.ActorID == 7
+	})).Return(nil)
+	mockDB.ExpectCommit()
+
+	_, errResponse := service.CreateBook(ginCtx, &params.BookRequest{AuthorID: 1, Title: "New Book"})
+
+	assert.Nil(t, errResponse)
+	revisionRepo.AssertExpectations(t)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
@@ -1,55 +1,90 @@
 package services
 
 import (
+	"bytes"
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"library-api-book/internal/commons/response"
+	"library-api-book/internal/events"
 	"library-api-book/internal/logger"
 	"library-api-book/internal/models"
 	"library-api-book/internal/params"
+	"library-api-book/internal/permission"
 	"library-api-book/internal/repositories"
+	"library-api-book/pkg/mergepatch"
+	"library-api-book/pkg/storage"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
-	"github.com/redis/go-redis/v9"
+	"github.com/go-playground/validator/v10"
 )
 
 type BookService interface {
-	CreateBook(ctx context.Context, req *params.BookRequest) *response.CustomError
+	CreateBook(ctx context.Context, req *params.BookRequest) (*params.BookResponse, *response.CustomError)
 	GetDetailBook(ctx context.Context, id uint64) (*params.BookResponse, *response.CustomError)
-	UpdateBook(ctx context.Context, id uint64, req *params.BookRequest) *response.CustomError
+	GetBooksByIDs(ctx context.Context, ids []uint64) ([]*params.BookResponse, *response.CustomError)
+	UpdateBook(ctx context.Context, id uint64, versions []int32, req *params.BookRequest) (*params.BookResponse, *response.CustomError)
+	PatchBook(ctx context.Context, id uint64, versions []int32, patch []byte) (*params.BookResponse, *response.CustomError)
 	DeleteBook(ctx context.Context, id uint64) *response.CustomError
-	GetAllBooks(ctx context.Context, pagination *models.Pagination, search string) ([]*params.BookResponse, *response.CustomError)
+	GetAllBooks(ctx context.Context, pagination *models.Pagination, filter *models.BookFilter) ([]*params.BookResponse, *response.CustomError)
 	GetRecommendationBook(ctx context.Context, id uint64) ([]*params.BookResponse, *response.CustomError)
 	DecreaseStock(ctx context.Context, bookID uint64) *response.CustomError
 	IncreaseStock(ctx context.Context, bookID uint64) *response.CustomError
+	RestoreBook(ctx context.Context, id uint64) *response.CustomError
+	GetDeletedBooks(ctx context.Context, pagination *models.Pagination) ([]*params.BookResponse, *response.CustomError)
+	PurgeBook(ctx context.Context, id uint64) *response.CustomError
 }
 
+var validate = validator.New()
+
+const stockForbiddenMessage = "Only callers granted stock:adjust can change the stock of a book"
+
+// MaxBatchBooks is the most books GetBooksByIDs returns in one call.
+const MaxBatchBooks = 100
+
+// Fields of a book that a merge patch may not remove.
+var requiredBookFields = []string{"author_id", "title", "stock"}
+
 type BookServiceImpl struct {
-	DB             *sql.DB
-	BookRepository repositories.BookRepository
-	RedisClient    *redis.Client
-	Logger         logger.Logger
+	DB                 *sql.DB
+	BookRepository     repositories.BookRepository
+	AuthorRepository   repositories.AuthorRepository
+	RevisionRepository repositories.BookRevisionRepository
+	BookCache          *BookCache
+	StockEvents        events.StockPublisher
+	Storage            storage.Storage
+	Policy             *permission.Policy
+	Logger             logger.Logger
 }
 
-func NewBookService(db *sql.DB, redisClient *redis.Client, bookRepository repositories.BookRepository, log logger.Logger) BookService {
+func NewBookService(db *sql.DB, bookCache *BookCache, bookRepository repositories.BookRepository, authorRepository repositories.AuthorRepository, revisionRepository repositories.BookRevisionRepository, stockEvents events.StockPublisher, store storage.Storage, policy *permission.Policy, log logger.Logger) BookService {
 	return &BookServiceImpl{
-		DB:             db,
-		BookRepository: bookRepository,
-		RedisClient:    redisClient,
-		Logger:         log,
+		DB:                 db,
+		BookRepository:     bookRepository,
+		AuthorRepository:   authorRepository,
+		RevisionRepository: revisionRepository,
+		BookCache:          bookCache,
+		StockEvents:        stockEvents,
+		Storage:            store,
+		Policy:             policy,
+		Logger:             log,
 	}
 }
 
-func (service *BookServiceImpl) CreateBook(ctx context.Context, req *params.BookRequest) *response.CustomError {
+func (service *BookServiceImpl) CreateBook(ctx context.Context, req *params.BookRequest) (*params.BookResponse, *response.CustomError) {
 	tx, err := service.DB.Begin()
 	if err != nil {
 		service.Logger.Error("[BookService] Failed to begin transaction - CreateBook", map[string]interface{}{
 			"error": err.Error(),
 		})
-		return response.GeneralError("Failed to connect to the database: " + err.Error())
+		return nil, response.GeneralError("Failed to connect to the database: " + err.Error())
 	}
+	var changed []uint64
 	defer func() {
 		if p := recover(); p != nil {
 			tx.Rollback()
@@ -61,14 +96,26 @@ func (service *BookServiceImpl) CreateBook(ctx context.Context, req *params.Book
 			service.Logger.Error("[BookService] Transaction rolled back due to error - CreateBook", map[string]interface{}{
 				"error": err.Error(),
 			})
-		} else {
-			tx.Commit()
+		} else if tx.Commit() == nil {
+			service.BookCache.booksChanged(ctx, changed...)
 		}
 	}()
 
+	access := bookAccessFromContext(ctx, service.Policy)
+	if custErr, accessErr := access.checkBookWrite(ctx, tx, service.AuthorRepository, req.AuthorID); custErr != nil {
+		err = accessErr
+		return nil, custErr
+	}
+	if !access.mayChangeStock() && req.Stock != 0 {
+		err = errBookAccessDenied
+		return nil, response.ForbiddenError(stockForbiddenMessage)
+	}
+
 	book := models.Book{
 		AuthorID:  req.AuthorID,
 		Title:     req.Title,
+		ISBN:      req.ISBN,
+		Publisher: req.Publisher,
 		Stock:     req.Stock,
 		PublishAt: time.Now(),
 		UpdatedAt: time.Now(),
@@ -79,13 +126,39 @@ func (service *BookServiceImpl) CreateBook(ctx context.Context, req *params.Book
 		service.Logger.Error("[BookService] Failed to create book - CreateBook", map[string]interface{}{
 			"error": err.Error(),
 		})
-		return response.GeneralError("Failed to create book: " + err.Error())
+		return nil, response.GeneralError("Failed to create book: " + err.Error())
 	}
 
-	return nil
+	err = recordRevision(ctx, tx, service.RevisionRepository, &book, models.RevisionActionCreate, nil)
+	if err != nil {
+		service.Logger.Error("[BookService] Failed to record revision - CreateBook", map[string]interface{}{
+			"book_id": book.ID,
+			"error":   err.Error(),
+		})
+		return nil, response.GeneralError("Failed to record book revision: " + err.Error())
+	}
+
+	changed = []uint64{book.ID}
+	return newBookResponse(&book), nil
 }
 
+// GetDetailBook reads through the book cache.
 func (service *BookServiceImpl) GetDetailBook(ctx context.Context, id uint64) (*params.BookResponse, *response.CustomError) {
+	book, custErr := service.BookCache.detail(ctx, id, func(ctx context.Context) (*params.BookResponse, *response.CustomError) {
+		return service.findDetailBook(ctx, id)
+	})
+	if custErr != nil {
+		return nil, custErr
+	}
+	if book == nil {
+		return nil, response.NotFoundError("Book not found")
+	}
+	return book, nil
+}
+
+// findDetailBook loads the book id from the database. A book that does not
+// exist is returned as nil without an error, so it can be cached.
+func (service *BookServiceImpl) findDetailBook(ctx context.Context, id uint64) (*params.BookResponse, *response.CustomError) {
 	tx, err := service.DB.Begin()
 	if err != nil {
 		service.Logger.Error("[BookService] Failed to begin transaction - GetDetailBook", map[string]interface{}{
@@ -110,6 +183,11 @@ func (service *BookServiceImpl) GetDetailBook(ctx context.Context, id uint64) (*
 	}()
 
 	book, err := service.BookRepository.FindBookByID(ctx, tx, id)
+	if errors.Is(err, repositories.ErrBookNotFound) {
+		// A missing book is a result, not a failure to roll back for.
+		err = nil
+		return nil, nil
+	}
 	if err != nil {
 		service.Logger.Error("[BookService] Failed to find book by ID - GetDetailBook", map[string]interface{}{
 			"book_id": id,
@@ -118,26 +196,84 @@ func (service *BookServiceImpl) GetDetailBook(ctx context.Context, id uint64) (*
 		return nil, response.NotFoundError("Book not found")
 	}
 
-	bookResponse := &params.BookResponse{
-		ID:        book.ID,
-		AuthorID:  book.AuthorID,
-		Title:     book.Title,
-		Stock:     book.Stock,
-		PublishAt: book.PublishAt,
-		UpdatedAt: book.UpdatedAt,
+	return newBookResponse(book), nil
+}
+
+// GetBooksByIDs returns the books in the order of ids. It fails with a not
+// found error naming the missing IDs if any book does not exist.
+func (service *BookServiceImpl) GetBooksByIDs(ctx context.Context, ids []uint64) ([]*params.BookResponse, *response.CustomError) {
+	if len(ids) == 0 {
+		return nil, response.BadRequestError("At least one book ID is required")
+	}
+	if len(ids) > MaxBatchBooks {
+		return nil, response.BadRequestError(fmt.Sprintf("At most %d books can be requested at once", MaxBatchBooks))
 	}
 
-	return bookResponse, nil
+	tx, err := service.DB.Begin()
+	if err != nil {
+		service.Logger.Error("[BookService] Failed to begin transaction - GetBooksByIDs", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return nil, response.ServiceUnavailableError("Failed to connect to the database: " + err.Error())
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			service.Logger.Error("[BookService] Transaction rolled back due to panic - GetBooksByIDs", map[string]interface{}{
+				"error": p,
+			})
+		} else if err != nil {
+			tx.Rollback()
+			service.Logger.Error("[BookService] Transaction rolled back due to error - GetBooksByIDs", map[string]interface{}{
+				"error": err.Error(),
+			})
+		} else {
+			tx.Commit()
+		}
+	}()
+
+	books, err := service.BookRepository.FindBooksByIDs(ctx, tx, ids)
+	if err != nil {
+		service.Logger.Error("[BookService] Failed to fetch books - GetBooksByIDs", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return nil, response.GeneralError("Failed to fetch books: " + err.Error())
+	}
+
+	byID := make(map[uint64]*models.Book, len(books))
+	for _, book := range books {
+		byID[book.ID] = book
+	}
+
+	bookResponses := make([]*params.BookResponse, 0, len(ids))
+	var missing []string
+	for _, id := range ids {
+		book, ok := byID[id]
+		if !ok {
+			missing = append(missing, strconv.FormatUint(id, 10))
+			continue
+		}
+		bookResponses = append(bookResponses, newBookResponse(book))
+	}
+	if len(missing) > 0 {
+		return nil, response.NotFoundError("Books not found: " + strings.Join(missing, ", "))
+	}
+
+	return bookResponses, nil
 }
 
-func (service *BookServiceImpl) UpdateBook(ctx context.Context, id uint64, req *params.BookRequest) *response.CustomError {
+// UpdateBook replaces the book only if it is still at one of the given
+// versions. No versions match any version, which is what "If-Match: *" asks for.
+func (service *BookServiceImpl) UpdateBook(ctx context.Context, id uint64, versions []int32, req *params.BookRequest) (*params.BookResponse, *response.CustomError) {
 	tx, err := service.DB.Begin()
 	if err != nil {
 		service.Logger.Error("[BookService] Failed to begin transaction - UpdateBook", map[string]interface{}{
 			"error": err.Error(),
 		})
-		return response.GeneralError("Failed to connect to the database: " + err.Error())
+		return nil, response.GeneralError("Failed to connect to the database: " + err.Error())
 	}
+	var stockChanged *models.Book
+	var changed []uint64
 	defer func() {
 		if p := recover(); p != nil {
 			tx.Rollback()
@@ -149,29 +285,189 @@ func (service *BookServiceImpl) UpdateBook(ctx context.Context, id uint64, req *
 			service.Logger.Error("[BookService] Transaction rolled back due to error - UpdateBook", map[string]interface{}{
 				"error": err.Error(),
 			})
-		} else {
-			tx.Commit()
+		} else if tx.Commit() == nil {
+			service.publishStock(ctx, stockChanged)
+			service.BookCache.booksChanged(ctx, changed...)
 		}
 	}()
 
-	book := models.Book{
-		ID:        id,
-		AuthorID:  req.AuthorID,
-		Title:     req.Title,
-		Stock:     req.Stock,
-		UpdatedAt: time.Now(),
+	book, err := service.BookRepository.FindBookByID(ctx, tx, id)
+	if err != nil {
+		service.Logger.Error("[BookService] Failed to find book by ID - UpdateBook", map[string]interface{}{
+			"book_id": id,
+			"error":   err.Error(),
+		})
+		return nil, response.NotFoundError("Book not found")
+	}
+	if len(versions) > 0 && !slices.Contains(versions, book.Version) {
+		err = repositories.ErrBookVersionConflict
+		return nil, response.PreconditionFailedError("Book has been modified by someone else, reload it and try again")
+	}
+
+	access := bookAccessFromContext(ctx, service.Policy)
+	for _, authorID := range []uint64{book.AuthorID, req.AuthorID} {
+		if custErr, accessErr := access.checkBookWrite(ctx, tx, service.AuthorRepository, authorID); custErr != nil {
+			err = accessErr
+			return nil, custErr
+		}
+	}
+	if !access.mayChangeStock() && req.Stock != book.Stock {
+		err = errBookAccessDenied
+		return nil, response.ForbiddenError(stockForbiddenMessage)
 	}
 
-	err = service.BookRepository.UpdateBook(ctx, tx, &book)
+	book.AuthorID = req.AuthorID
+	book.Title = req.Title
+	book.ISBN = req.ISBN
+	book.Publisher = req.Publisher
+	restocked := book.Stock != req.Stock
+	book.Stock = req.Stock
+	book.UpdatedAt = time.Now()
+
+	err = service.BookRepository.UpdateBook(ctx, tx, book)
 	if err != nil {
 		service.Logger.Error("[BookService] Failed to update book - UpdateBook", map[string]interface{}{
 			"book_id": id,
 			"error":   err.Error(),
 		})
-		return response.GeneralError("Failed to update book: " + err.Error())
+		if errors.Is(err, repositories.ErrBookVersionConflict) {
+			return nil, response.PreconditionFailedError("Book has been modified by someone else, reload it and try again")
+		}
+		return nil, response.GeneralError("Failed to update book: " + err.Error())
 	}
 
-	return nil
+	err = recordRevision(ctx, tx, service.RevisionRepository, book, models.RevisionActionUpdate, nil)
+	if err != nil {
+		service.Logger.Error("[BookService] Failed to record revision - UpdateBook", map[string]interface{}{
+			"book_id": book.ID,
+			"error":   err.Error(),
+		})
+		return nil, response.GeneralError("Failed to record book revision: " + err.Error())
+	}
+
+	if restocked {
+		stockChanged = book
+	}
+	changed = []uint64{book.ID}
+	return newBookResponse(book), nil
+}
+
+// PatchBook applies a JSON Merge Patch to the book, validates the merged
+// result and writes only the columns that actually changed. Like UpdateBook it
+// is skipped unless the book is at one of the given versions, if any.
+func (service *BookServiceImpl) PatchBook(ctx context.Context, id uint64, versions []int32, patch []byte) (*params.BookResponse, *response.CustomError) {
+	tx, err := service.DB.Begin()
+	if err != nil {
+		service.Logger.Error("[BookService] Failed to begin transaction - PatchBook", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return nil, response.GeneralError("Failed to connect to the database: " + err.Error())
+	}
+	var stockChanged *models.Book
+	var changed []uint64
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			service.Logger.Error("[BookService] Transaction rolled back due to panic - PatchBook", map[string]interface{}{
+				"error": p,
+			})
+		} else if err != nil {
+			tx.Rollback()
+			service.Logger.Error("[BookService] Transaction rolled back due to error - PatchBook", map[string]interface{}{
+				"error": err.Error(),
+			})
+		} else if tx.Commit() == nil {
+			service.publishStock(ctx, stockChanged)
+			service.BookCache.booksChanged(ctx, changed...)
+		}
+	}()
+
+	book, err := service.BookRepository.FindBookByID(ctx, tx, id)
+	if err != nil {
+		service.Logger.Error("[BookService] Failed to find book by ID - PatchBook", map[string]interface{}{
+			"book_id": id,
+			"error":   err.Error(),
+		})
+		return nil, response.NotFoundError("Book not found")
+	}
+	if len(versions) > 0 && !slices.Contains(versions, book.Version) {
+		err = repositories.ErrBookVersionConflict
+		return nil, response.PreconditionFailedError("Book has been modified by someone else, reload it and try again")
+	}
+
+	access := bookAccessFromContext(ctx, service.Policy)
+	if custErr, accessErr := access.checkBookWrite(ctx, tx, service.AuthorRepository, book.AuthorID); custErr != nil {
+		err = accessErr
+		return nil, custErr
+	}
+
+	req, err := mergeBookPatch(book, patch)
+	if err != nil {
+		return nil, response.BadRequestError("Invalid merge patch: " + err.Error())
+	}
+
+	if custErr, accessErr := access.checkBookWrite(ctx, tx, service.AuthorRepository, req.AuthorID); custErr != nil {
+		err = accessErr
+		return nil, custErr
+	}
+	if !access.mayChangeStock() && req.Stock != book.Stock {
+		err = errBookAccessDenied
+		return nil, response.ForbiddenError(stockForbiddenMessage)
+	}
+
+	var columns []string
+	if req.AuthorID != book.AuthorID {
+		book.AuthorID = req.AuthorID
+		columns = append(columns, "author_id")
+	}
+	if req.Title != book.Title {
+		book.Title = req.Title
+		columns = append(columns, "title")
+	}
+	if req.ISBN != book.ISBN {
+		book.ISBN = req.ISBN
+		columns = append(columns, "isbn")
+	}
+	if req.Publisher != book.Publisher {
+		book.Publisher = req.Publisher
+		columns = append(columns, "publisher")
+	}
+	if req.Stock != book.Stock {
+		book.Stock = req.Stock
+		columns = append(columns, "stock")
+	}
+	if len(columns) == 0 {
+		return newBookResponse(book), nil
+	}
+
+	book.UpdatedAt = time.Now()
+	err = service.BookRepository.PatchBook(ctx, tx, book, columns)
+	if err != nil {
+		service.Logger.Error("[BookService] Failed to patch book - PatchBook", map[string]interface{}{
+			"book_id": id,
+			"columns": columns,
+			"error":   err.Error(),
+		})
+		if errors.Is(err, repositories.ErrBookVersionConflict) {
+			return nil, response.PreconditionFailedError("Book has been modified by someone else, reload it and try again")
+		}
+		return nil, response.GeneralError("Failed to patch book: " + err.Error())
+	}
+
+	err = recordRevision(ctx, tx, service.RevisionRepository, book, models.RevisionActionUpdate, nil)
+	if err != nil {
+		service.Logger.Error("[BookService] Failed to record revision - PatchBook", map[string]interface{}{
+			"book_id": book.ID,
+			"error":   err.Error(),
+		})
+		return nil, response.GeneralError("Failed to record book revision: " + err.Error())
+	}
+
+	if slices.Contains(columns, "stock") {
+		stockChanged = book
+	}
+	changed = []uint64{book.ID}
+	return newBookResponse(book), nil
 }
 
 func (service *BookServiceImpl) DeleteBook(ctx context.Context, id uint64) *response.CustomError {
@@ -182,6 +478,7 @@ func (service *BookServiceImpl) DeleteBook(ctx context.Context, id uint64) *resp
 		})
 		return response.GeneralError("Failed to connect to the database: " + err.Error())
 	}
+	var changed []uint64
 	defer func() {
 		if p := recover(); p != nil {
 			tx.Rollback()
@@ -193,35 +490,76 @@ func (service *BookServiceImpl) DeleteBook(ctx context.Context, id uint64) *resp
 			service.Logger.Error("[BookService] Transaction rolled back due to error - DeleteBook", map[string]interface{}{
 				"error": err.Error(),
 			})
-		} else {
-			tx.Commit()
+		} else if tx.Commit() == nil {
+			service.BookCache.booksChanged(ctx, changed...)
 		}
 	}()
 
-	err = service.BookRepository.DeleteBook(ctx, tx, id)
+	// Only restricted callers need the current owner; others skip the lookup.
+	access := bookAccessFromContext(ctx, service.Policy)
+	if access.restricted() {
+		current, findErr := service.BookRepository.FindBookByID(ctx, tx, id)
+		if findErr != nil {
+			err = findErr
+			return response.NotFoundError("Book not found")
+		}
+		if custErr, accessErr := access.checkBookWrite(ctx, tx, service.AuthorRepository, current.AuthorID); custErr != nil {
+			err = accessErr
+			return custErr
+		}
+	}
+
+	// Borrows of a deleted book could not be returned, so it must not be on
+	// loan. Deleted books cannot be borrowed, so none is opened afterwards.
+	open, err := service.BookRepository.HasOpenBorrows(ctx, tx, id)
+	if err != nil {
+		service.Logger.Error("[BookService] Failed to check open borrows - DeleteBook", map[string]interface{}{
+			"book_id": id,
+			"error":   err.Error(),
+		})
+		return response.GeneralError("Failed to check open borrows: " + err.Error())
+	}
+	if open {
+		service.Logger.Warn("[BookService] Book still has open borrows - DeleteBook", map[string]interface{}{
+			"book_id": id,
+		})
+		err = errors.New("book still has open borrows")
+		return response.ConflictError("Book cannot be deleted while it is still borrowed")
+	}
+
+	book, err := service.BookRepository.DeleteBook(ctx, tx, id)
 	if err != nil {
 		service.Logger.Error("[BookService] Failed to delete book - DeleteBook", map[string]interface{}{
 			"book_id": id,
 			"error":   err.Error(),
 		})
+		if errors.Is(err, repositories.ErrBookNotFound) {
+			return response.NotFoundError("Book not found")
+		}
 		return response.GeneralError("Failed to delete book: " + err.Error())
 	}
 
+	err = recordRevision(ctx, tx, service.RevisionRepository, book, models.RevisionActionDelete, nil)
+	if err != nil {
+		service.Logger.Error("[BookService] Failed to record revision - DeleteBook", map[string]interface{}{
+			"book_id": book.ID,
+			"error":   err.Error(),
+		})
+		return response.GeneralError("Failed to record book revision: " + err.Error())
+	}
+
+	changed = []uint64{book.ID}
 	return nil
 }
 
-func (service *BookServiceImpl) GetAllBooks(ctx context.Context, pagination *models.Pagination, search string) ([]*params.BookResponse, *response.CustomError) {
-	cacheKey := fmt.Sprintf("books:%d:%d:%s", pagination.Page, pagination.PageSize, search)
-
-	cachedData, err := service.RedisClient.Get(ctx, cacheKey).Bytes()
-	if err == nil {
-		var bookResponses []*params.BookResponse
-		if err := json.Unmarshal(cachedData, &bookResponses); err == nil {
-			service.Logger.Info("[BookService] Retrieved books from cache", map[string]interface{}{
-				"cache_key": cacheKey,
-			})
-			return bookResponses, nil
-		}
+func (service *BookServiceImpl) GetAllBooks(ctx context.Context, pagination *models.Pagination, filter *models.BookFilter) ([]*params.BookResponse, *response.CustomError) {
+	// The key is resolved before the query, see cache.Namespace.Key.
+	cacheKey := service.BookCache.listKey(ctx, pagination, filter)
+	if cached, ok := service.BookCache.getList(ctx, cacheKey); ok {
+		service.Logger.Info("[BookService] Retrieved books from cache", map[string]interface{}{
+			"cache_key": cacheKey,
+		})
+		return cached, nil
 	}
 
 	tx, err := service.DB.Begin()
@@ -249,7 +587,7 @@ func (service *BookServiceImpl) GetAllBooks(ctx context.Context, pagination *mod
 
 	pagination.Offset = (pagination.Page - 1) * pagination.PageSize
 
-	books, err := service.BookRepository.GetAllBooks(ctx, tx, pagination, search)
+	books, err := service.BookRepository.GetAllBooks(ctx, tx, pagination, filter)
 	if err != nil {
 		service.Logger.Error("[BookService] Failed to fetch books - GetAllBooks", map[string]interface{}{
 			"error": err.Error(),
@@ -259,34 +597,12 @@ func (service *BookServiceImpl) GetAllBooks(ctx context.Context, pagination *mod
 
 	bookResponses := make([]*params.BookResponse, len(books))
 	for i, book := range books {
-		bookResponses[i] = &params.BookResponse{
-			ID:        book.ID,
-			AuthorID:  book.AuthorID,
-			Title:     book.Title,
-			Stock:     book.Stock,
-			PublishAt: book.PublishAt,
-			UpdatedAt: book.UpdatedAt,
-		}
+		bookResponses[i] = newBookResponse(book)
 	}
 
 	pagination.PageCount = (pagination.TotalCount + pagination.PageSize - 1) / pagination.PageSize
 
-	serializedData, err := json.Marshal(bookResponses)
-	if err != nil {
-		service.Logger.Error("[BookService] Failed to serialize books for caching - GetAllBooks", map[string]interface{}{
-			"error": err.Error(),
-		})
-	} else {
-		if err := service.RedisClient.Set(ctx, cacheKey, serializedData, 5*time.Minute).Err(); err != nil {
-			service.Logger.Error("[BookService] Failed to cache books - GetAllBooks", map[string]interface{}{
-				"error": err.Error(),
-			})
-		} else {
-			service.Logger.Info("[BookService] Cached books successfully", map[string]interface{}{
-				"cache_key": cacheKey,
-			})
-		}
-	}
+	service.BookCache.setList(ctx, cacheKey, bookResponses)
 
 	return bookResponses, nil
 }
@@ -325,14 +641,7 @@ func (service *BookServiceImpl) GetRecommendationBook(ctx context.Context, id ui
 
 	bookResponses := make([]*params.BookResponse, len(books))
 	for i, book := range books {
-		bookResponses[i] = &params.BookResponse{
-			ID:        book.ID,
-			AuthorID:  book.AuthorID,
-			Title:     book.Title,
-			Stock:     book.Stock,
-			PublishAt: book.PublishAt,
-			UpdatedAt: book.UpdatedAt,
-		}
+		bookResponses[i] = newBookResponse(book)
 	}
 
 	return bookResponses, nil
@@ -344,8 +653,10 @@ func (service *BookServiceImpl) DecreaseStock(ctx context.Context, bookID uint64
 		service.Logger.Error("[BookService] Failed to begin transaction - DecreaseStock", map[string]interface{}{
 			"error": err.Error(),
 		})
-		return response.GeneralError("Failed to connect to the database: " + err.Error())
+		return response.ServiceUnavailableError("Failed to connect to the database: " + err.Error())
 	}
+	var stockChanged *models.Book
+	var changed []uint64
 	defer func() {
 		if p := recover(); p != nil {
 			tx.Rollback()
@@ -357,36 +668,43 @@ func (service *BookServiceImpl) DecreaseStock(ctx context.Context, bookID uint64
 			service.Logger.Error("[BookService] Transaction rolled back due to error - DecreaseStock", map[string]interface{}{
 				"error": err.Error(),
 			})
-		} else {
-			tx.Commit()
+		} else if tx.Commit() == nil {
+			service.publishStock(ctx, stockChanged)
+			service.BookCache.booksChanged(ctx, changed...)
 		}
 	}()
 
-	book, err := service.BookRepository.FindBookByID(ctx, tx, bookID)
-	if err != nil {
-		service.Logger.Error("[BookService] Failed to find book - DecreaseStock", map[string]interface{}{
-			"book_id": bookID,
-			"error":   err.Error(),
-		})
-		return response.GeneralError("Failed to find book: " + err.Error())
-	}
-	if book.Stock <= 0 {
+	book, err := service.BookRepository.AdjustStock(ctx, tx, bookID, -1)
+	if errors.Is(err, repositories.ErrBookOutOfStock) {
 		service.Logger.Warn("[BookService] Book is out of stock - DecreaseStock", map[string]interface{}{
 			"book_id": bookID,
 		})
-		return response.BadRequestError("Book is out of stock")
+		// Nothing was written, so this is not a failure of the transaction.
+		err = nil
+		return response.ConflictError("Book is out of stock")
 	}
-
-	book.Stock--
-	err = service.BookRepository.UpdateBook(ctx, tx, book)
 	if err != nil {
 		service.Logger.Error("[BookService] Failed to update book stock - DecreaseStock", map[string]interface{}{
 			"book_id": bookID,
 			"error":   err.Error(),
 		})
+		if errors.Is(err, repositories.ErrBookNotFound) {
+			return response.NotFoundError("Book not found")
+		}
 		return response.GeneralError("Failed to update book stock: " + err.Error())
 	}
 
+	err = recordRevision(ctx, tx, service.RevisionRepository, book, models.RevisionActionUpdate, nil)
+	if err != nil {
+		service.Logger.Error("[BookService] Failed to record revision - DecreaseStock", map[string]interface{}{
+			"book_id": book.ID,
+			"error":   err.Error(),
+		})
+		return response.GeneralError("Failed to record book revision: " + err.Error())
+	}
+
+	stockChanged = book
+	changed = []uint64{book.ID}
 	return nil
 }
 
@@ -396,8 +714,10 @@ func (service *BookServiceImpl) IncreaseStock(ctx context.Context, bookID uint64
 		service.Logger.Error("[BookService] Failed to begin transaction - IncreaseStock", map[string]interface{}{
 			"error": err.Error(),
 		})
-		return response.GeneralError("Failed to connect to the database: " + err.Error())
+		return response.ServiceUnavailableError("Failed to connect to the database: " + err.Error())
 	}
+	var stockChanged *models.Book
+	var changed []uint64
 	defer func() {
 		if p := recover(); p != nil {
 			tx.Rollback()
@@ -409,29 +729,279 @@ func (service *BookServiceImpl) IncreaseStock(ctx context.Context, bookID uint64
 			service.Logger.Error("[BookService] Transaction rolled back due to error - IncreaseStock", map[string]interface{}{
 				"error": err.Error(),
 			})
+		} else if tx.Commit() == nil {
+			service.publishStock(ctx, stockChanged)
+			service.BookCache.booksChanged(ctx, changed...)
+		}
+	}()
+
+	book, err := service.BookRepository.AdjustStock(ctx, tx, bookID, 1)
+	if err != nil {
+		service.Logger.Error("[BookService] Failed to update book stock - IncreaseStock", map[string]interface{}{
+			"book_id": bookID,
+			"error":   err.Error(),
+		})
+		if errors.Is(err, repositories.ErrBookNotFound) {
+			return response.NotFoundError("Book not found")
+		}
+		return response.GeneralError("Failed to update book stock: " + err.Error())
+	}
+
+	err = recordRevision(ctx, tx, service.RevisionRepository, book, models.RevisionActionUpdate, nil)
+	if err != nil {
+		service.Logger.Error("[BookService] Failed to record revision - IncreaseStock", map[string]interface{}{
+			"book_id": book.ID,
+			"error":   err.Error(),
+		})
+		return response.GeneralError("Failed to record book revision: " + err.Error())
+	}
+
+	stockChanged = book
+	changed = []uint64{book.ID}
+	return nil
+}
+
+func (service *BookServiceImpl) RestoreBook(ctx context.Context, id uint64) *response.CustomError {
+	tx, err := service.DB.Begin()
+	if err != nil {
+		service.Logger.Error("[BookService] Failed to begin transaction - RestoreBook", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return response.GeneralError("Failed to connect to the database: " + err.Error())
+	}
+	var changed []uint64
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			service.Logger.Error("[BookService] Transaction rolled back due to panic - RestoreBook", map[string]interface{}{
+				"error": p,
+			})
+		} else if err != nil {
+			tx.Rollback()
+			service.Logger.Error("[BookService] Transaction rolled back due to error - RestoreBook", map[string]interface{}{
+				"error": err.Error(),
+			})
+		} else if tx.Commit() == nil {
+			service.BookCache.booksChanged(ctx, changed...)
+		}
+	}()
+
+	book, err := service.BookRepository.RestoreBook(ctx, tx, id)
+	if err != nil {
+		service.Logger.Error("[BookService] Failed to restore book - RestoreBook", map[string]interface{}{
+			"book_id": id,
+			"error":   err.Error(),
+		})
+		if errors.Is(err, repositories.ErrBookNotFound) {
+			return response.NotFoundError("Deleted book not found")
+		}
+		return response.GeneralError("Failed to restore book: " + err.Error())
+	}
+
+	err = recordRevision(ctx, tx, service.RevisionRepository, book, models.RevisionActionRestore, nil)
+	if err != nil {
+		service.Logger.Error("[BookService] Failed to record revision - RestoreBook", map[string]interface{}{
+			"book_id": book.ID,
+			"error":   err.Error(),
+		})
+		return response.GeneralError("Failed to record book revision: " + err.Error())
+	}
+
+	changed = []uint64{book.ID}
+	return nil
+}
+
+func (service *BookServiceImpl) GetDeletedBooks(ctx context.Context, pagination *models.Pagination) ([]*params.BookResponse, *response.CustomError) {
+	tx, err := service.DB.Begin()
+	if err != nil {
+		service.Logger.Error("[BookService] Failed to begin transaction - GetDeletedBooks", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return nil, response.GeneralError("Failed to connect to the database: " + err.Error())
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			service.Logger.Error("[BookService] Transaction rolled back due to panic - GetDeletedBooks", map[string]interface{}{
+				"error": p,
+			})
+		} else if err != nil {
+			tx.Rollback()
+			service.Logger.Error("[BookService] Transaction rolled back due to error - GetDeletedBooks", map[string]interface{}{
+				"error": err.Error(),
+			})
 		} else {
 			tx.Commit()
 		}
 	}()
 
-	book, err := service.BookRepository.FindBookByID(ctx, tx, bookID)
+	pagination.Offset = (pagination.Page - 1) * pagination.PageSize
+
+	books, err := service.BookRepository.GetDeletedBooks(ctx, tx, pagination)
 	if err != nil {
-		service.Logger.Error("[BookService] Failed to find book - IncreaseStock", map[string]interface{}{
-			"book_id": bookID,
+		service.Logger.Error("[BookService] Failed to fetch deleted books - GetDeletedBooks", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return nil, response.GeneralError("Failed to fetch deleted books: " + err.Error())
+	}
+
+	bookResponses := make([]*params.BookResponse, len(books))
+	for i, book := range books {
+		bookResponses[i] = newBookResponse(book)
+	}
+
+	return bookResponses, nil
+}
+
+// PurgeBook permanently removes a soft deleted book. Deleted books cannot be
+// borrowed any more, so once no loan is open none can be opened before the
+// purge commits.
+func (service *BookServiceImpl) PurgeBook(ctx context.Context, id uint64) *response.CustomError {
+	tx, err := service.DB.Begin()
+	if err != nil {
+		service.Logger.Error("[BookService] Failed to begin transaction - PurgeBook", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return response.GeneralError("Failed to connect to the database: " + err.Error())
+	}
+	var changed []uint64
+	// obsolete holds the cover objects of the book, removed once the row
+	// that points at them is gone.
+	var obsolete []string
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			service.Logger.Error("[BookService] Transaction rolled back due to panic - PurgeBook", map[string]interface{}{
+				"error": p,
+			})
+		} else if err != nil {
+			tx.Rollback()
+			service.Logger.Error("[BookService] Transaction rolled back due to error - PurgeBook", map[string]interface{}{
+				"error": err.Error(),
+			})
+		} else if tx.Commit() == nil {
+			service.BookCache.booksChanged(ctx, changed...)
+			deleteCoverObjects(ctx, service.Storage, service.Logger, obsolete)
+		}
+	}()
+
+	open, err := service.BookRepository.HasOpenBorrows(ctx, tx, id)
+	if err != nil {
+		service.Logger.Error("[BookService] Failed to check open borrows - PurgeBook", map[string]interface{}{
+			"book_id": id,
 			"error":   err.Error(),
 		})
-		return response.GeneralError("Failed to find book: " + err.Error())
+		return response.GeneralError("Failed to check open borrows: " + err.Error())
+	}
+	if open {
+		service.Logger.Warn("[BookService] Book still has open borrows - PurgeBook", map[string]interface{}{
+			"book_id": id,
+		})
+		err = errors.New("book still has open borrows")
+		return response.ConflictError("Book cannot be purged while it is still borrowed")
 	}
 
-	book.Stock++
-	err = service.BookRepository.UpdateBook(ctx, tx, book)
+	book, err := service.BookRepository.PurgeBook(ctx, tx, id)
 	if err != nil {
-		service.Logger.Error("[BookService] Failed to update book stock - IncreaseStock", map[string]interface{}{
-			"book_id": bookID,
+		service.Logger.Error("[BookService] Failed to purge book - PurgeBook", map[string]interface{}{
+			"book_id": id,
 			"error":   err.Error(),
 		})
-		return response.GeneralError("Failed to update book stock: " + err.Error())
+		if errors.Is(err, repositories.ErrBookNotFound) {
+			return response.NotFoundError("Deleted book not found")
+		}
+		return response.GeneralError("Failed to purge book: " + err.Error())
+	}
+
+	// The purge is the last change to the book, so it gets the next version.
+	// book_revisions has no foreign key to books, which keeps the history of
+	// a purged book readable.
+	book.Version++
+	err = recordRevision(ctx, tx, service.RevisionRepository, book, models.RevisionActionPurge, nil)
+	if err != nil {
+		service.Logger.Error("[BookService] Failed to record revision - PurgeBook", map[string]interface{}{
+			"book_id": id,
+			"error":   err.Error(),
+		})
+		return response.GeneralError("Failed to record book revision: " + err.Error())
 	}
 
+	if book.CoverPath != "" {
+		obsolete = coverKeys(book.CoverPath)
+	}
+
+	changed = []uint64{id}
 	return nil
 }
+
+// mergeBookPatch applies patch to the editable fields of book and returns the
+// validated result.
+func mergeBookPatch(book *models.Book, patch []byte) (*params.BookRequest, error) {
+	original, err := json.Marshal(params.BookRequest{
+		AuthorID:  book.AuthorID,
+		Title:     book.Title,
+		ISBN:      book.ISBN,
+		Publisher: book.Publisher,
+		Stock:     book.Stock,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	merged, err := mergepatch.Apply(original, patch)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(merged, &fields); err != nil {
+		return nil, err
+	}
+	for _, field := range requiredBookFields {
+		if _, ok := fields[field]; !ok {
+			return nil, fmt.Errorf("%s cannot be removed", field)
+		}
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(merged))
+	decoder.DisallowUnknownFields()
+
+	var req params.BookRequest
+	if err := decoder.Decode(&req); err != nil {
+		return nil, err
+	}
+	if err := validate.Struct(&req); err != nil {
+		return nil, err
+	}
+	return &req, nil
+}
+
+// publishStock announces the stock of book once its transaction has
+// committed. book is nil when the call did not change the stock.
+func (service *BookServiceImpl) publishStock(ctx context.Context, book *models.Book) {
+	if book == nil || service.StockEvents == nil {
+		return
+	}
+	service.StockEvents.Publish(ctx, events.StockEvent{
+		BookID:    book.ID,
+		Stock:     book.Stock,
+		Version:   book.Version,
+		ChangedAt: book.UpdatedAt,
+	})
+}
+
+func newBookResponse(book *models.Book) *params.BookResponse {
+	return &params.BookResponse{
+		ID:        book.ID,
+		AuthorID:  book.AuthorID,
+		Title:     book.Title,
+		ISBN:      book.ISBN,
+		Publisher: book.Publisher,
+		Stock:     book.Stock,
+		PublishAt: book.PublishAt,
+		UpdatedAt: book.UpdatedAt,
+		DeletedAt: book.DeletedAt,
+		Version:   book.Version,
+		Cover:     newCoverResponse(book.CoverPath),
+	}
+}
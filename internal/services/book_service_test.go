@@ -4,9 +4,12 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"library-api-book/internal/events"
+	"library-api-book/internal/logger"
 	"library-api-book/internal/models"
 	"library-api-book/internal/params"
 	"library-api-book/internal/repositories"
+	"library-api-book/pkg/storage"
 	"testing"
 	"time"
 
@@ -14,6 +17,7 @@ import (
 	_ "github.com/mattn/go-sqlite3"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 func TestCreateBook_Success(t *testing.T) {
@@ -30,8 +34,11 @@ func TestCreateBook_Success(t *testing.T) {
 	mockDB.ExpectCommit()
 
 	service := &BookServiceImpl{
-		DB:             db,
-		BookRepository: mockBookRepo,
+		DB:                 db,
+		BookRepository:     mockBookRepo,
+		RevisionRepository: newMockRevisionRepository(),
+		BookCache:          newFixtureBookCache(t),
+		Logger:             logger.NopLogger{},
 	}
 
 	req := params.BookRequest{
@@ -40,7 +47,7 @@ func TestCreateBook_Success(t *testing.T) {
 		Stock:    100,
 	}
 
-	errCus := service.CreateBook(context.Background(), &req)
+	_, errCus := service.CreateBook(WithTrustedCaller(context.Background()), &req)
 
 	assert.Nil(t, errCus)
 	mockBookRepo.AssertExpectations(t)
@@ -55,8 +62,11 @@ func TestGetDetailBook_Success(t *testing.T) {
 
 	mockRepo := new(repositories.MockBookRepository)
 	service := &BookServiceImpl{
-		DB:             db,
-		BookRepository: mockRepo,
+		DB:                 db,
+		BookRepository:     mockRepo,
+		RevisionRepository: newMockRevisionRepository(),
+		BookCache:          newFixtureBookCache(t),
+		Logger:             logger.NopLogger{},
 	}
 
 	mockDB.ExpectBegin()
@@ -87,12 +97,18 @@ func TestUpdateBook_Success(t *testing.T) {
 
 	mockRepo := new(repositories.MockBookRepository)
 	service := &BookServiceImpl{
-		DB:             db,
-		BookRepository: mockRepo,
+		DB:                 db,
+		BookRepository:     mockRepo,
+		RevisionRepository: newMockRevisionRepository(),
+		BookCache:          newFixtureBookCache(t),
+		Logger:             logger.NopLogger{},
 	}
 
 	mockDB.ExpectBegin()
-	mockRepo.On("UpdateBook", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	mockRepo.On("FindBookByID", mock.Anything, mock.Anything, uint64(1)).Return(&models.Book{ID: 1, Title: "Book", Version: 3}, nil)
+	mockRepo.On("UpdateBook", mock.Anything, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		args.Get(2).(*models.Book).Version++
+	}).Return(nil)
 	mockDB.ExpectCommit()
 
 	req := &params.BookRequest{
@@ -100,9 +116,11 @@ func TestUpdateBook_Success(t *testing.T) {
 		Title:    "Updated Book",
 		Stock:    5,
 	}
-	errResponse := service.UpdateBook(context.Background(), 1, req)
+	bookResponse, errResponse := service.UpdateBook(WithTrustedCaller(context.Background()), 1, []int32{3}, req)
 
 	assert.Nil(t, errResponse)
+	assert.Equal(t, "Updated Book", bookResponse.Title)
+	assert.Equal(t, int32(4), bookResponse.Version)
 	mockRepo.AssertExpectations(t)
 	mockDB.ExpectationsWereMet()
 }
@@ -116,15 +134,19 @@ func TestDeleteBook_Success(t *testing.T) {
 
 	mockRepo := new(repositories.MockBookRepository)
 	service := &BookServiceImpl{
-		DB:             db,
-		BookRepository: mockRepo,
+		DB:                 db,
+		BookRepository:     mockRepo,
+		RevisionRepository: newMockRevisionRepository(),
+		BookCache:          newFixtureBookCache(t),
+		Logger:             logger.NopLogger{},
 	}
 
 	mockDB.ExpectBegin()
-	mockRepo.On("DeleteBook", mock.Anything, mock.Anything, uint64(1)).Return(nil)
+	mockRepo.On("HasOpenBorrows", mock.Anything, mock.Anything, uint64(1)).Return(false, nil)
+	mockRepo.On("DeleteBook", mock.Anything, mock.Anything, uint64(1)).Return(&models.Book{ID: 1, Version: 2}, nil)
 	mockDB.ExpectCommit()
 
-	errResponse := service.DeleteBook(context.Background(), 1)
+	errResponse := service.DeleteBook(WithTrustedCaller(context.Background()), 1)
 
 	assert.Nil(t, errResponse)
 	mockRepo.AssertExpectations(t)
@@ -140,8 +162,11 @@ func TestGetAllBooks_Success(t *testing.T) {
 
 	mockRepo := new(repositories.MockBookRepository)
 	service := &BookServiceImpl{
-		DB:             db,
-		BookRepository: mockRepo,
+		DB:                 db,
+		BookRepository:     mockRepo,
+		RevisionRepository: newMockRevisionRepository(),
+		BookCache:          newFixtureBookCache(t),
+		Logger:             logger.NopLogger{},
 	}
 
 	mockDB.ExpectBegin()
@@ -161,7 +186,7 @@ func TestGetAllBooks_Success(t *testing.T) {
 		Page:     1,
 		PageSize: 10,
 	}
-	books, errResponse := service.GetAllBooks(context.Background(), pagination, "")
+	books, errResponse := service.GetAllBooks(context.Background(), pagination, &models.BookFilter{})
 
 	assert.Nil(t, errResponse)
 	assert.Equal(t, 1, len(books))
@@ -178,8 +203,11 @@ func TestGetRecommendationBook_Success(t *testing.T) {
 
 	mockRepo := new(repositories.MockBookRepository)
 	service := &BookServiceImpl{
-		DB:             db,
-		BookRepository: mockRepo,
+		DB:                 db,
+		BookRepository:     mockRepo,
+		RevisionRepository: newMockRevisionRepository(),
+		BookCache:          newFixtureBookCache(t),
+		Logger:             logger.NopLogger{},
 	}
 
 	mockDB.ExpectBegin()
@@ -212,16 +240,18 @@ func TestDecreaseStock_Success(t *testing.T) {
 
 	mockRepo := new(repositories.MockBookRepository)
 	service := &BookServiceImpl{
-		DB:             db,
-		BookRepository: mockRepo,
+		DB:                 db,
+		BookRepository:     mockRepo,
+		RevisionRepository: newMockRevisionRepository(),
+		BookCache:          newFixtureBookCache(t),
+		Logger:             logger.NopLogger{},
 	}
 
 	mockDB.ExpectBegin()
-	mockRepo.On("FindBookByID", mock.Anything, mock.Anything, uint64(1)).Return(&models.Book{
+	mockRepo.On("AdjustStock", mock.Anything, mock.Anything, uint64(1), int32(-1)).Return(&models.Book{
 		ID:    1,
-		Stock: 10,
+		Stock: 9,
 	}, nil)
-	mockRepo.On("UpdateBook", mock.Anything, mock.Anything, mock.Anything).Return(nil)
 	mockDB.ExpectCommit()
 
 	errResponse := service.DecreaseStock(context.Background(), 1)
@@ -240,16 +270,18 @@ func TestIncreaseStock_Success(t *testing.T) {
 
 	mockRepo := new(repositories.MockBookRepository)
 	service := &BookServiceImpl{
-		DB:             db,
-		BookRepository: mockRepo,
+		DB:                 db,
+		BookRepository:     mockRepo,
+		RevisionRepository: newMockRevisionRepository(),
+		BookCache:          newFixtureBookCache(t),
+		Logger:             logger.NopLogger{},
 	}
 
 	mockDB.ExpectBegin()
-	mockRepo.On("FindBookByID", mock.Anything, mock.Anything, uint64(1)).Return(&models.Book{
+	mockRepo.On("AdjustStock", mock.Anything, mock.Anything, uint64(1), int32(1)).Return(&models.Book{
 		ID:    1,
-		Stock: 10,
+		Stock: 11,
 	}, nil)
-	mockRepo.On("UpdateBook", mock.Anything, mock.Anything, mock.Anything).Return(nil)
 	mockDB.ExpectCommit()
 
 	errResponse := service.IncreaseStock(context.Background(), 1)
@@ -260,6 +292,19 @@ func TestIncreaseStock_Success(t *testing.T) {
 }
 
 // Helper function to create a mock DB and service
+func newMockRevisionRepository() *repositories.MockBookRevisionRepository {
+	revisionRepo := new(repositories.MockBookRevisionRepository)
+	revisionRepo.On("CreateRevision", mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+	return revisionRepo
+}
+
+// newFixtureBookCache backs the fixtures with a real, empty cache so tests go
+// through the same cache calls as production.
+func newFixtureBookCache(t *testing.T) *BookCache {
+	bookCache, _ := newTestBookCache(t)
+	return bookCache
+}
+
 func setupTest(t *testing.T) (*sql.DB, sqlmock.Sqlmock, *repositories.MockBookRepository, *BookServiceImpl) {
 	db, mockDB, err := sqlmock.New()
 	if err != nil {
@@ -268,8 +313,12 @@ func setupTest(t *testing.T) (*sql.DB, sqlmock.Sqlmock, *repositories.MockBookRe
 
 	mockRepo := new(repositories.MockBookRepository)
 	service := &BookServiceImpl{
-		DB:             db,
-		BookRepository: mockRepo,
+		DB:                 db,
+		BookRepository:     mockRepo,
+		RevisionRepository: newMockRevisionRepository(),
+		BookCache:          newFixtureBookCache(t),
+		Policy:             newTestPolicy(t),
+		Logger:             logger.NopLogger{},
 	}
 
 	return db, mockDB, mockRepo, service
@@ -286,10 +335,10 @@ func TestCreateBook_DBFailure(t *testing.T) {
 		Title:    "Test Book",
 		Stock:    10,
 	}
-	errResponse := service.CreateBook(context.Background(), req)
+	_, errResponse := service.CreateBook(WithTrustedCaller(context.Background()), req)
 
 	assert.NotNil(t, errResponse)
-	assert.Equal(t, "Failed Connection to database errors: database error", errResponse.Message)
+	assert.Equal(t, "Failed to connect to the database: database error", errResponse.Message)
 	mockDB.ExpectationsWereMet()
 }
 
@@ -306,10 +355,10 @@ func TestCreateBook_Failed(t *testing.T) {
 		Title:    "Test Book",
 		Stock:    10,
 	}
-	errResponse := service.CreateBook(context.Background(), req)
+	_, errResponse := service.CreateBook(WithTrustedCaller(context.Background()), req)
 
 	assert.NotNil(t, errResponse)
-	assert.Equal(t, "Failed to create a book, transaction rolled back. Reason: repository error", errResponse.Message)
+	assert.Equal(t, "Failed to create book: Failed to create a book, transaction rolled back. Reason: repository error", errResponse.Message)
 	mockRepo.AssertExpectations(t)
 	mockDB.ExpectationsWereMet()
 }
@@ -324,7 +373,7 @@ func TestGetDetailBook_DBFailure(t *testing.T) {
 
 	assert.Nil(t, bookResponse)
 	assert.NotNil(t, errResponse)
-	assert.Equal(t, "Failed Connection to database errors: database error", errResponse.Message)
+	assert.Equal(t, "Failed to connect to the database: database error", errResponse.Message)
 	mockDB.ExpectationsWereMet()
 }
 
@@ -356,7 +405,7 @@ func TestUpdateBook_DBFailure(t *testing.T) {
 		Title:    "Updated Book",
 		Stock:    5,
 	}
-	errResponse := service.UpdateBook(context.Background(), 1, req)
+	_, errResponse := service.UpdateBook(WithTrustedCaller(context.Background()), 1, []int32{1}, req)
 
 	assert.NotNil(t, errResponse)
 	assert.Equal(t, "Failed to connect to the database: database error", errResponse.Message)
@@ -368,6 +417,7 @@ func TestUpdateBook_Failed(t *testing.T) {
 	defer db.Close()
 
 	mockDB.ExpectBegin()
+	mockRepo.On("FindBookByID", mock.Anything, mock.Anything, uint64(1)).Return(&models.Book{ID: 1, Version: 1}, nil)
 	mockRepo.On("UpdateBook", mock.Anything, mock.Anything, mock.Anything).Return(errors.New("repository error"))
 	mockDB.ExpectRollback()
 
@@ -376,7 +426,7 @@ func TestUpdateBook_Failed(t *testing.T) {
 		Title:    "Updated Book",
 		Stock:    5,
 	}
-	errResponse := service.UpdateBook(context.Background(), 1, req)
+	_, errResponse := service.UpdateBook(WithTrustedCaller(context.Background()), 1, []int32{1}, req)
 
 	assert.NotNil(t, errResponse)
 	assert.Equal(t, "Failed to update book: repository error", errResponse.Message)
@@ -390,7 +440,7 @@ func TestDeleteBook_DBFailure(t *testing.T) {
 
 	mockDB.ExpectBegin().WillReturnError(errors.New("database error"))
 
-	errResponse := service.DeleteBook(context.Background(), 1)
+	errResponse := service.DeleteBook(WithTrustedCaller(context.Background()), 1)
 
 	assert.NotNil(t, errResponse)
 	assert.Equal(t, "Failed to connect to the database: database error", errResponse.Message)
@@ -402,10 +452,11 @@ func TestDeleteBook_Failed(t *testing.T) {
 	defer db.Close()
 
 	mockDB.ExpectBegin()
-	mockRepo.On("DeleteBook", mock.Anything, mock.Anything, uint64(1)).Return(errors.New("repository error"))
+	mockRepo.On("HasOpenBorrows", mock.Anything, mock.Anything, uint64(1)).Return(false, nil)
+	mockRepo.On("DeleteBook", mock.Anything, mock.Anything, uint64(1)).Return(nil, errors.New("repository error"))
 	mockDB.ExpectRollback()
 
-	errResponse := service.DeleteBook(context.Background(), 1)
+	errResponse := service.DeleteBook(WithTrustedCaller(context.Background()), 1)
 
 	assert.NotNil(t, errResponse)
 	assert.Equal(t, "Failed to delete book: repository error", errResponse.Message)
@@ -423,7 +474,7 @@ func TestGetAllBooks_DBFailure(t *testing.T) {
 		Page:     1,
 		PageSize: 10,
 	}
-	books, errResponse := service.GetAllBooks(context.Background(), pagination, "")
+	books, errResponse := service.GetAllBooks(context.Background(), pagination, &models.BookFilter{})
 
 	assert.Nil(t, books)
 	assert.NotNil(t, errResponse)
@@ -443,7 +494,7 @@ func TestGetAllBooks_FailedRepository(t *testing.T) {
 		Page:     1,
 		PageSize: 10,
 	}
-	books, errResponse := service.GetAllBooks(context.Background(), pagination, "")
+	books, errResponse := service.GetAllBooks(context.Background(), pagination, &models.BookFilter{})
 
 	assert.Nil(t, books)
 	assert.NotNil(t, errResponse)
@@ -501,13 +552,13 @@ func TestDecreaseStock_NotFoundBook(t *testing.T) {
 	defer db.Close()
 
 	mockDB.ExpectBegin()
-	mockRepo.On("FindBookByID", mock.Anything, mock.Anything, uint64(1)).Return(&models.Book{}, errors.New("repository error"))
+	mockRepo.On("AdjustStock", mock.Anything, mock.Anything, uint64(1), int32(-1)).Return(nil, repositories.ErrBookNotFound)
 	mockDB.ExpectRollback()
 
 	errResponse := service.DecreaseStock(context.Background(), 1)
 
 	assert.NotNil(t, errResponse)
-	assert.Equal(t, "Failed to find book: repository error", errResponse.Message)
+	assert.Equal(t, "Book not found", errResponse.Message)
 	mockRepo.AssertExpectations(t)
 	mockDB.ExpectationsWereMet()
 }
@@ -530,13 +581,13 @@ func TestIncreaseStock_NotFoundBook(t *testing.T) {
 	defer db.Close()
 
 	mockDB.ExpectBegin()
-	mockRepo.On("FindBookByID", mock.Anything, mock.Anything, uint64(1)).Return(&models.Book{}, errors.New("repository error"))
+	mockRepo.On("AdjustStock", mock.Anything, mock.Anything, uint64(1), int32(1)).Return(nil, repositories.ErrBookNotFound)
 	mockDB.ExpectRollback()
 
 	errResponse := service.IncreaseStock(context.Background(), 1)
 
 	assert.NotNil(t, errResponse)
-	assert.Equal(t, "Failed to find book: repository error", errResponse.Message)
+	assert.Equal(t, "Book not found", errResponse.Message)
 	mockRepo.AssertExpectations(t)
 	mockDB.ExpectationsWereMet()
 }
@@ -550,16 +601,15 @@ func TestDecreaseStock_FailedUpdate(t *testing.T) {
 
 	mockRepo := new(repositories.MockBookRepository)
 	service := &BookServiceImpl{
-		DB:             db,
-		BookRepository: mockRepo,
+		DB:                 db,
+		BookRepository:     mockRepo,
+		RevisionRepository: newMockRevisionRepository(),
+		BookCache:          newFixtureBookCache(t),
+		Logger:             logger.NopLogger{},
 	}
 
 	mockDB.ExpectBegin()
-	mockRepo.On("FindBookByID", mock.Anything, mock.Anything, uint64(1)).Return(&models.Book{
-		ID:    1,
-		Stock: 10,
-	}, nil)
-	mockRepo.On("UpdateBook", mock.Anything, mock.Anything, mock.Anything).Return(errors.New("repository error"))
+	mockRepo.On("AdjustStock", mock.Anything, mock.Anything, uint64(1), int32(-1)).Return(nil, errors.New("repository error"))
 	mockDB.ExpectRollback()
 
 	errResponse := service.DecreaseStock(context.Background(), 1)
@@ -579,16 +629,15 @@ func TestIncreaseStock_FailedUpdate(t *testing.T) {
 
 	mockRepo := new(repositories.MockBookRepository)
 	service := &BookServiceImpl{
-		DB:             db,
-		BookRepository: mockRepo,
+		DB:                 db,
+		BookRepository:     mockRepo,
+		RevisionRepository: newMockRevisionRepository(),
+		BookCache:          newFixtureBookCache(t),
+		Logger:             logger.NopLogger{},
 	}
 
 	mockDB.ExpectBegin()
-	mockRepo.On("FindBookByID", mock.Anything, mock.Anything, uint64(1)).Return(&models.Book{
-		ID:    1,
-		Stock: 10,
-	}, nil)
-	mockRepo.On("UpdateBook", mock.Anything, mock.Anything, mock.Anything).Return(errors.New("repository error"))
+	mockRepo.On("AdjustStock", mock.Anything, mock.Anything, uint64(1), int32(1)).Return(nil, errors.New("repository error"))
 	mockDB.ExpectRollback()
 
 	errResponse := service.IncreaseStock(context.Background(), 1)
@@ -608,16 +657,16 @@ func TestDecreaseStock_FailedStock(t *testing.T) {
 
 	mockRepo := new(repositories.MockBookRepository)
 	service := &BookServiceImpl{
-		DB:             db,
-		BookRepository: mockRepo,
+		DB:                 db,
+		BookRepository:     mockRepo,
+		RevisionRepository: newMockRevisionRepository(),
+		BookCache:          newFixtureBookCache(t),
+		Logger:             logger.NopLogger{},
 	}
 
 	mockDB.ExpectBegin()
-	mockRepo.On("FindBookByID", mock.Anything, mock.Anything, uint64(1)).Return(&models.Book{
-		ID:    1,
-		Stock: 0,
-	}, nil)
-	mockDB.ExpectRollback()
+	mockRepo.On("AdjustStock", mock.Anything, mock.Anything, uint64(1), int32(-1)).Return(nil, repositories.ErrBookOutOfStock)
+	mockDB.ExpectCommit()
 
 	errResponse := service.DecreaseStock(context.Background(), 1)
 
@@ -627,7 +676,8 @@ func TestDecreaseStock_FailedStock(t *testing.T) {
 	mockDB.ExpectationsWereMet()
 }
 
-func TestIncreaseStock_FailedStock(t *testing.T) {
+// Restocking a book that is out of stock is the normal case, not a failure.
+func TestIncreaseStock_FromZero(t *testing.T) {
 	db, mockDB, err := sqlmock.New()
 	if err != nil {
 		t.Fatalf("Failed to create mock database: %v", err)
@@ -636,21 +686,477 @@ func TestIncreaseStock_FailedStock(t *testing.T) {
 
 	mockRepo := new(repositories.MockBookRepository)
 	service := &BookServiceImpl{
-		DB:             db,
-		BookRepository: mockRepo,
+		DB:                 db,
+		BookRepository:     mockRepo,
+		RevisionRepository: newMockRevisionRepository(),
+		BookCache:          newFixtureBookCache(t),
+		Logger:             logger.NopLogger{},
 	}
 
 	mockDB.ExpectBegin()
-	mockRepo.On("FindBookByID", mock.Anything, mock.Anything, uint64(1)).Return(&models.Book{
+	mockRepo.On("AdjustStock", mock.Anything, mock.Anything, uint64(1), int32(1)).Return(&models.Book{
 		ID:    1,
-		Stock: 0,
+		Stock: 1,
 	}, nil)
-	mockDB.ExpectRollback()
+	mockDB.ExpectCommit()
 
 	errResponse := service.IncreaseStock(context.Background(), 1)
 
-	assert.NotNil(t, errResponse)
-	assert.Equal(t, "Book is out of stock", errResponse.Message)
+	assert.Nil(t, errResponse)
 	mockRepo.AssertExpectations(t)
 	mockDB.ExpectationsWereMet()
 }
+
+func TestDeleteBook_NotFound(t *testing.T) {
+	db, mockDB, mockRepo, service := setupTest(t)
+	defer db.Close()
+	service.Logger = logger.NopLogger{}
+
+	mockDB.ExpectBegin()
+	mockRepo.On("HasOpenBorrows", mock.Anything, mock.Anything, uint64(1)).Return(false, nil)
+	mockRepo.On("DeleteBook", mock.Anything, mock.Anything, uint64(1)).Return(nil, repositories.ErrBookNotFound)
+	mockDB.ExpectRollback()
+
+	errResponse := service.DeleteBook(WithTrustedCaller(context.Background()), 1)
+
+	assert.NotNil(t, errResponse)
+	assert.Equal(t, "Book not found", errResponse.Message)
+	mockRepo.AssertExpectations(t)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestDeleteBook_OpenBorrows(t *testing.T) {
+	db, mockDB, mockRepo, service := setupTest(t)
+	defer db.Close()
+
+	mockDB.ExpectBegin()
+	mockRepo.On("HasOpenBorrows", mock.Anything, mock.Anything, uint64(1)).Return(true, nil)
+	mockDB.ExpectRollback()
+
+	errResponse := service.DeleteBook(WithTrustedCaller(context.Background()), 1)
+
+	assert.NotNil(t, errResponse)
+	assert.Equal(t, 409, errResponse.StatusCode)
+	mockRepo.AssertNotCalled(t, "DeleteBook", mock.Anything, mock.Anything, uint64(1))
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestRestoreBook_Success(t *testing.T) {
+	db, mockDB, mockRepo, service := setupTest(t)
+	defer db.Close()
+	service.Logger = logger.NopLogger{}
+
+	mockDB.ExpectBegin()
+	mockRepo.On("RestoreBook", mock.Anything, mock.Anything, uint64(1)).Return(&models.Book{ID: 1, Version: 3}, nil)
+	mockDB.ExpectCommit()
+
+	errResponse := service.RestoreBook(context.Background(), 1)
+
+	assert.Nil(t, errResponse)
+	mockRepo.AssertExpectations(t)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestRestoreBook_NotDeleted(t *testing.T) {
+	db, mockDB, mockRepo, service := setupTest(t)
+	defer db.Close()
+	service.Logger = logger.NopLogger{}
+
+	mockDB.ExpectBegin()
+	mockRepo.On("RestoreBook", mock.Anything, mock.Anything, uint64(1)).Return(nil, repositories.ErrBookNotFound)
+	mockDB.ExpectRollback()
+
+	errResponse := service.RestoreBook(context.Background(), 1)
+
+	assert.NotNil(t, errResponse)
+	assert.Equal(t, "Deleted book not found", errResponse.Message)
+	mockRepo.AssertExpectations(t)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestGetDeletedBooks_Success(t *testing.T) {
+	db, mockDB, mockRepo, service := setupTest(t)
+	defer db.Close()
+	service.Logger = logger.NopLogger{}
+
+	deletedAt := time.Now()
+	books := []*models.Book{{ID: 1, Title: "Removed Book", DeletedAt: &deletedAt}}
+
+	mockDB.ExpectBegin()
+	mockRepo.On("GetDeletedBooks", mock.Anything, mock.Anything, mock.Anything).Return(books, nil)
+	mockDB.ExpectCommit()
+
+	pagination := &models.Pagination{Page: 2, PageSize: 10}
+	result, errResponse := service.GetDeletedBooks(context.Background(), pagination)
+
+	assert.Nil(t, errResponse)
+	assert.Len(t, result, 1)
+	assert.Equal(t, &deletedAt, result[0].DeletedAt)
+	assert.Equal(t, 10, pagination.Offset)
+	mockRepo.AssertExpectations(t)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestPurgeBook_Success(t *testing.T) {
+	db, mockDB, mockRepo, service := setupTest(t)
+	defer db.Close()
+	service.Logger = logger.NopLogger{}
+	store, err := storage.NewLocalStorage(t.TempDir())
+	require.NoError(t, err)
+	service.Storage = store
+	putCover(t, store, "books/1/cover.png")
+
+	mockDB.ExpectBegin()
+	mockRepo.On("HasOpenBorrows", mock.Anything, mock.Anything, uint64(1)).Return(false, nil)
+	deletedAt := time.Now()
+	mockRepo.On("PurgeBook", mock.Anything, mock.Anything, uint64(1)).Return(&models.Book{ID: 1, Title: "Purged", CoverPath: "books/1/cover.png", Version: 3, DeletedAt: &deletedAt}, nil)
+	revisionRepo := new(repositories.MockBookRevisionRepository)
+	revisionRepo.On("CreateRevision", mock.Anything, mock.Anything, mock.MatchedBy(func(revision *models.BookRevision) bool {
+		return revision.BookID == 1 && revision.Revision == 4 && revision.Action == models.RevisionActionPurge && revision.Snapshot.Title == "Purged"
+	})).Return(nil)
+	service.RevisionRepository = revisionRepo
+	mockDB.ExpectCommit()
+
+	errResponse := service.PurgeBook(context.Background(), 1)
+
+	assert.Nil(t, errResponse)
+	mockRepo.AssertExpectations(t)
+	revisionRepo.AssertExpectations(t)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+	for _, key := range coverKeys("books/1/cover.png") {
+		_, _, err := store.Get(context.Background(), key)
+		assert.ErrorIs(t, err, storage.ErrNotFound, key)
+	}
+}
+
+func TestPurgeBook_RevisionFailure(t *testing.T) {
+	db, mockDB, mockRepo, service := setupTest(t)
+	defer db.Close()
+	store, err := storage.NewLocalStorage(t.TempDir())
+	require.NoError(t, err)
+	service.Storage = store
+	putCover(t, store, "books/1/cover.png")
+
+	mockDB.ExpectBegin()
+	mockRepo.On("HasOpenBorrows", mock.Anything, mock.Anything, uint64(1)).Return(false, nil)
+	mockRepo.On("PurgeBook", mock.Anything, mock.Anything, uint64(1)).Return(&models.Book{ID: 1, CoverPath: "books/1/cover.png", Version: 3}, nil)
+	revisionRepo := new(repositories.MockBookRevisionRepository)
+	revisionRepo.On("CreateRevision", mock.Anything, mock.Anything, mock.Anything).Return(errors.New("revision error"))
+	service.RevisionRepository = revisionRepo
+	mockDB.ExpectRollback()
+
+	errResponse := service.PurgeBook(context.Background(), 1)
+
+	assert.NotNil(t, errResponse)
+	assert.Equal(t, "Failed to record book revision: revision error", errResponse.Message)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+	assert.True(t, coverExists(store, "books/1/cover.png"))
+}
+
+func TestPurgeBook_OpenBorrows(t *testing.T) {
+	db, mockDB, mockRepo, service := setupTest(t)
+	defer db.Close()
+	service.Logger = logger.NopLogger{}
+
+	mockDB.ExpectBegin()
+	mockRepo.On("HasOpenBorrows", mock.Anything, mock.Anything, uint64(1)).Return(true, nil)
+	mockDB.ExpectRollback()
+
+	errResponse := service.PurgeBook(context.Background(), 1)
+
+	assert.NotNil(t, errResponse)
+	assert.Equal(t, 409, errResponse.StatusCode)
+	mockRepo.AssertNotCalled(t, "PurgeBook", mock.Anything, mock.Anything, uint64(1))
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestUpdateBook_VersionMismatch(t *testing.T) {
+	db, mockDB, mockRepo, service := setupTest(t)
+	defer db.Close()
+	service.Logger = logger.NopLogger{}
+
+	mockDB.ExpectBegin()
+	mockRepo.On("FindBookByID", mock.Anything, mock.Anything, uint64(1)).Return(&models.Book{ID: 1, Version: 4}, nil)
+	mockDB.ExpectRollback()
+
+	req := &params.BookRequest{AuthorID: 1, Title: "Updated Book"}
+	_, errResponse := service.UpdateBook(WithTrustedCaller(context.Background()), 1, []int32{3}, req)
+
+	assert.NotNil(t, errResponse)
+	assert.Equal(t, 412, errResponse.StatusCode)
+	mockRepo.AssertNotCalled(t, "UpdateBook", mock.Anything, mock.Anything, mock.Anything)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestUpdateBook_MatchesAnyListedVersion(t *testing.T) {
+	db, mockDB, mockRepo, service := setupTest(t)
+	defer db.Close()
+	service.Logger = logger.NopLogger{}
+
+	mockDB.ExpectBegin()
+	mockRepo.On("FindBookByID", mock.Anything, mock.Anything, uint64(1)).Return(&models.Book{ID: 1, AuthorID: 1, Version: 4}, nil)
+	mockRepo.On("UpdateBook", mock.Anything, mock.Anything, mock.MatchedBy(func(book *models.Book) bool {
+		return book.Version == 4
+	})).Return(nil)
+	mockDB.ExpectCommit()
+
+	req := &params.BookRequest{AuthorID: 1, Title: "Updated Book"}
+	_, errResponse := service.UpdateBook(WithTrustedCaller(context.Background()), 1, []int32{3, 4}, req)
+
+	assert.Nil(t, errResponse)
+	mockRepo.AssertExpectations(t)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestUpdateBook_ConcurrentWrite(t *testing.T) {
+	db, mockDB, mockRepo, service := setupTest(t)
+	defer db.Close()
+	service.Logger = logger.NopLogger{}
+
+	mockDB.ExpectBegin()
+	mockRepo.On("FindBookByID", mock.Anything, mock.Anything, uint64(1)).Return(&models.Book{ID: 1, Version: 3}, nil)
+	mockRepo.On("UpdateBook", mock.Anything, mock.Anything, mock.Anything).Return(repositories.ErrBookVersionConflict)
+	mockDB.ExpectRollback()
+
+	req := &params.BookRequest{AuthorID: 1, Title: "Updated Book"}
+	_, errResponse := service.UpdateBook(WithTrustedCaller(context.Background()), 1, []int32{3}, req)
+
+	assert.NotNil(t, errResponse)
+	assert.Equal(t, 412, errResponse.StatusCode)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestPatchBook_OnlyChangedColumns(t *testing.T) {
+	db, mockDB, mockRepo, service := setupTest(t)
+	defer db.Close()
+	service.Logger = logger.NopLogger{}
+
+	book := &models.Book{ID: 1, AuthorID: 2, Title: "Old Title", ISBN: "9780306406157", Stock: 7, Version: 3}
+
+	mockDB.ExpectBegin()
+	mockRepo.On("FindBookByID", mock.Anything, mock.Anything, uint64(1)).Return(book, nil)
+	mockRepo.On("PatchBook", mock.Anything, mock.Anything, mock.Anything, []string{"title", "isbn"}).Run(func(args mock.Arguments) {
+		args.Get(2).(*models.Book).Version++
+	}).Return(nil)
+	mockDB.ExpectCommit()
+
+	result, errResponse := service.PatchBook(WithTrustedCaller(context.Background()), 1, []int32{3}, []byte(`{"title":"New Title","isbn":null,"author_id":2}`))
+
+	assert.Nil(t, errResponse)
+	assert.Equal(t, "New Title", result.Title)
+	assert.Equal(t, "", result.ISBN)
+	assert.Equal(t, int32(7), result.Stock)
+	assert.Equal(t, int32(4), result.Version)
+	mockRepo.AssertExpectations(t)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestPatchBook_NoChanges(t *testing.T) {
+	db, mockDB, mockRepo, service := setupTest(t)
+	defer db.Close()
+	service.Logger = logger.NopLogger{}
+
+	mockDB.ExpectBegin()
+	mockRepo.On("FindBookByID", mock.Anything, mock.Anything, uint64(1)).Return(&models.Book{ID: 1, AuthorID: 2, Title: "Title", Version: 3}, nil)
+	mockDB.ExpectCommit()
+
+	result, errResponse := service.PatchBook(WithTrustedCaller(context.Background()), 1, nil, []byte(`{"title":"Title"}`))
+
+	assert.Nil(t, errResponse)
+	assert.Equal(t, int32(3), result.Version)
+	mockRepo.AssertNotCalled(t, "PatchBook", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestPatchBook_InvalidMergedResult(t *testing.T) {
+	patches := []string{
+		`{"title":null}`,
+		`{"stock":null}`,
+		`{"stock":-1}`,
+		`{"title":""}`,
+		`{"id":5}`,
+		`{"stock":"ten"}`,
+		`["title"]`,
+	}
+
+	for _, patch := range patches {
+		db, mockDB, mockRepo, service := setupTest(t)
+		service.Logger = logger.NopLogger{}
+
+		mockDB.ExpectBegin()
+		mockRepo.On("FindBookByID", mock.Anything, mock.Anything, uint64(1)).Return(&models.Book{ID: 1, AuthorID: 2, Title: "Title", Stock: 1, Version: 1}, nil)
+		mockDB.ExpectRollback()
+
+		_, errResponse := service.PatchBook(WithTrustedCaller(context.Background()), 1, nil, []byte(patch))
+
+		assert.NotNil(t, errResponse, patch)
+		assert.Equal(t, "ERR0005", errResponse.Code, patch)
+		mockRepo.AssertNotCalled(t, "PatchBook", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		assert.NoError(t, mockDB.ExpectationsWereMet(), patch)
+		db.Close()
+	}
+}
+
+func TestDecreaseStock_MissingBook(t *testing.T) {
+	db, mockDB, mockRepo, service := setupTest(t)
+	defer db.Close()
+	service.Logger = logger.NopLogger{}
+
+	mockDB.ExpectBegin()
+	mockRepo.On("AdjustStock", mock.Anything, mock.Anything, uint64(1), int32(-1)).Return(nil, repositories.ErrBookNotFound)
+	mockDB.ExpectRollback()
+
+	errResponse := service.DecreaseStock(context.Background(), 1)
+
+	assert.NotNil(t, errResponse)
+	assert.Equal(t, "ERR0003", errResponse.Code)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestDecreaseStock_OutOfStockConflict(t *testing.T) {
+	db, mockDB, mockRepo, service := setupTest(t)
+	defer db.Close()
+	service.Logger = logger.NopLogger{}
+
+	mockDB.ExpectBegin()
+	mockRepo.On("AdjustStock", mock.Anything, mock.Anything, uint64(1), int32(-1)).Return(nil, repositories.ErrBookOutOfStock)
+	mockDB.ExpectCommit()
+
+	errResponse := service.DecreaseStock(context.Background(), 1)
+
+	assert.NotNil(t, errResponse)
+	assert.Equal(t, 409, errResponse.StatusCode)
+	assert.Equal(t, "Book is out of stock", errResponse.Message)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestGetBooksByIDs_KeepsRequestOrder(t *testing.T) {
+	db, mockDB, mockRepo, service := setupTest(t)
+	defer db.Close()
+	service.Logger = logger.NopLogger{}
+
+	mockDB.ExpectBegin()
+	mockRepo.On("FindBooksByIDs", mock.Anything, mock.Anything, []uint64{3, 1}).Return([]*models.Book{{ID: 1}, {ID: 3}}, nil)
+	mockDB.ExpectCommit()
+
+	books, errResponse := service.GetBooksByIDs(context.Background(), []uint64{3, 1})
+
+	assert.Nil(t, errResponse)
+	assert.Equal(t, uint64(3), books[0].ID)
+	assert.Equal(t, uint64(1), books[1].ID)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestGetBooksByIDs_Missing(t *testing.T) {
+	db, mockDB, mockRepo, service := setupTest(t)
+	defer db.Close()
+	service.Logger = logger.NopLogger{}
+
+	mockDB.ExpectBegin()
+	mockRepo.On("FindBooksByIDs", mock.Anything, mock.Anything, []uint64{1, 2, 5}).Return([]*models.Book{{ID: 1}}, nil)
+	mockDB.ExpectCommit()
+
+	books, errResponse := service.GetBooksByIDs(context.Background(), []uint64{1, 2, 5})
+
+	assert.Nil(t, books)
+	assert.NotNil(t, errResponse)
+	assert.Equal(t, "ERR0003", errResponse.Code)
+	assert.Equal(t, "Books not found: 2, 5", errResponse.Message)
+}
+
+func TestGetBooksByIDs_TooMany(t *testing.T) {
+	db, _, mockRepo, service := setupTest(t)
+	defer db.Close()
+
+	_, errResponse := service.GetBooksByIDs(context.Background(), make([]uint64, MaxBatchBooks+1))
+
+	assert.NotNil(t, errResponse)
+	assert.Equal(t, 400, errResponse.StatusCode)
+	mockRepo.AssertNotCalled(t, "FindBooksByIDs", mock.Anything, mock.Anything, mock.Anything)
+}
+
+type recordingStockPublisher struct {
+	events []events.StockEvent
+}
+
+func (p *recordingStockPublisher) Publish(ctx context.Context, event events.StockEvent) {
+	p.events = append(p.events, event)
+}
+
+func TestDecreaseStock_PublishesAfterCommit(t *testing.T) {
+	db, mockDB, mockRepo, service := setupTest(t)
+	defer db.Close()
+	service.Logger = logger.NopLogger{}
+	publisher := &recordingStockPublisher{}
+	service.StockEvents = publisher
+
+	mockDB.ExpectBegin()
+	mockRepo.On("AdjustStock", mock.Anything, mock.Anything, uint64(1), int32(-1)).Return(&models.Book{ID: 1, Stock: 1, Version: 4}, nil)
+	mockDB.ExpectCommit()
+
+	errResponse := service.DecreaseStock(context.Background(), 1)
+
+	assert.Nil(t, errResponse)
+	require.Len(t, publisher.events, 1)
+	assert.Equal(t, uint64(1), publisher.events[0].BookID)
+	assert.Equal(t, int32(1), publisher.events[0].Stock)
+	assert.Equal(t, int32(4), publisher.events[0].Version)
+}
+
+func TestDecreaseStock_OutOfStockDoesNotPublish(t *testing.T) {
+	db, mockDB, mockRepo, service := setupTest(t)
+	defer db.Close()
+	service.Logger = logger.NopLogger{}
+	publisher := &recordingStockPublisher{}
+	service.StockEvents = publisher
+
+	mockDB.ExpectBegin()
+	mockRepo.On("AdjustStock", mock.Anything, mock.Anything, uint64(1), int32(-1)).Return(nil, repositories.ErrBookOutOfStock)
+	mockDB.ExpectCommit()
+
+	service.DecreaseStock(context.Background(), 1)
+
+	assert.Empty(t, publisher.events)
+}
+
+func TestIncreaseStock_FailedCommitDoesNotPublish(t *testing.T) {
+	db, mockDB, mockRepo, service := setupTest(t)
+	defer db.Close()
+	service.Logger = logger.NopLogger{}
+	publisher := &recordingStockPublisher{}
+	service.StockEvents = publisher
+
+	mockDB.ExpectBegin()
+	mockRepo.On("AdjustStock", mock.Anything, mock.Anything, uint64(1), int32(1)).Return(&models.Book{ID: 1, Stock: 1}, nil)
+	mockDB.ExpectCommit().WillReturnError(errors.New("connection reset"))
+
+	service.IncreaseStock(context.Background(), 1)
+
+	assert.Empty(t, publisher.events)
+}
+
+func TestUpdateBook_PublishesOnlyStockChanges(t *testing.T) {
+	db, mockDB, mockRepo, service := setupTest(t)
+	defer db.Close()
+	service.Logger = logger.NopLogger{}
+	publisher := &recordingStockPublisher{}
+	service.StockEvents = publisher
+
+	mockDB.ExpectBegin()
+	mockRepo.On("FindBookByID", mock.Anything, mock.Anything, uint64(1)).Return(&models.Book{ID: 1, AuthorID: 1, Stock: 5, Version: 3}, nil).Once()
+	mockRepo.On("UpdateBook", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	mockDB.ExpectCommit()
+
+	_, errResponse := service.UpdateBook(WithTrustedCaller(context.Background()), 1, []int32{3}, &params.BookRequest{AuthorID: 1, Title: "Renamed", Stock: 5})
+	require.Nil(t, errResponse)
+	assert.Empty(t, publisher.events)
+
+	mockDB.ExpectBegin()
+	mockRepo.On("FindBookByID", mock.Anything, mock.Anything, uint64(1)).Return(&models.Book{ID: 1, AuthorID: 1, Stock: 5, Version: 3}, nil).Once()
+	mockDB.ExpectCommit()
+
+	_, errResponse = service.UpdateBook(WithTrustedCaller(context.Background()), 1, []int32{3}, &params.BookRequest{AuthorID: 1, Title: "Renamed", Stock: 6})
+	require.Nil(t, errResponse)
+	require.Len(t, publisher.events, 1)
+	assert.Equal(t, int32(6), publisher.events[0].Stock)
+}
@@ -0,0 +1,273 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"library-api-book/internal/commons/response"
+	"library-api-book/internal/logger"
+	"library-api-book/internal/models"
+	"library-api-book/internal/params"
+	"library-api-book/internal/permission"
+	"library-api-book/internal/repositories"
+	"library-api-book/pkg/imaging"
+	"library-api-book/pkg/storage"
+	"path"
+	"strings"
+)
+
+const (
+	CoverURLPrefix = "/api/v1/covers/"
+
+	maxCoverPixels = 40_000_000
+)
+
+var coverSizes = []struct {
+	Name  string
+	Width int
+}{
+	{Name: "small", Width: 96},
+	{Name: "medium", Width: 240},
+	{Name: "large", Width: 480},
+}
+
+type CoverService interface {
+	UploadCover(ctx context.Context, bookID uint64, data []byte) (*params.CoverResponse, *response.CustomError)
+	GetCover(ctx context.Context, key string) (io.ReadCloser, *storage.ObjectInfo, *response.CustomError)
+}
+
+type CoverServiceImpl struct {
+	DB                 *sql.DB
+	BookCache          *BookCache
+	BookRepository     repositories.BookRepository
+	AuthorRepository   repositories.AuthorRepository
+	RevisionRepository repositories.BookRevisionRepository
+	Storage            storage.Storage
+	Policy             *permission.Policy
+	Logger             logger.Logger
+	MaxSize            int64
+}
+
+func NewCoverService(db *sql.DB, bookCache *BookCache, bookRepository repositories.BookRepository, authorRepository repositories.AuthorRepository, revisionRepository repositories.BookRevisionRepository, store storage.Storage, policy *permission.Policy, log logger.Logger, maxSize int64) CoverService {
+	return &CoverServiceImpl{
+		DB:                 db,
+		BookCache:          bookCache,
+		BookRepository:     bookRepository,
+		AuthorRepository:   authorRepository,
+		RevisionRepository: revisionRepository,
+		Storage:            store,
+		Policy:             policy,
+		Logger:             log,
+		MaxSize:            maxSize,
+	}
+}
+
+func (service *CoverServiceImpl) UploadCover(ctx context.Context, bookID uint64, data []byte) (cover *params.CoverResponse, custErr *response.CustomError) {
+	if int64(len(data)) > service.MaxSize {
+		return nil, response.BadRequestError(fmt.Sprintf("Cover image must not exceed %d bytes", service.MaxSize))
+	}
+
+	img, format, err := imaging.Decode(data, maxCoverPixels)
+	if err != nil {
+		service.Logger.Warn("[CoverService] Rejected cover image - UploadCover", map[string]interface{}{
+			"book_id": bookID,
+			"error":   err.Error(),
+		})
+		if errors.Is(err, imaging.ErrUnsupportedFormat) {
+			return nil, response.BadRequestError("Cover image must be a JPEG or PNG")
+		}
+		return nil, response.BadRequestError("Invalid cover image: " + err.Error())
+	}
+
+	tx, err := service.DB.Begin()
+	if err != nil {
+		service.Logger.Error("[CoverService] Failed to begin transaction - UploadCover", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return nil, response.GeneralError("Failed to connect to the database: " + err.Error())
+	}
+	var changed []uint64
+	// written holds the new objects until the row points at them; obsolete
+	// holds the previous cover's objects, removed only once it no longer does.
+	var written, obsolete []string
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			service.deleteKeys(ctx, written)
+			service.Logger.Error("[CoverService] Transaction rolled back due to panic - UploadCover", map[string]interface{}{
+				"error": p,
+			})
+		} else if err != nil {
+			tx.Rollback()
+			service.deleteKeys(ctx, written)
+			service.Logger.Error("[CoverService] Transaction rolled back due to error - UploadCover", map[string]interface{}{
+				"error": err.Error(),
+			})
+		} else if commitErr := tx.Commit(); commitErr != nil {
+			service.deleteKeys(ctx, written)
+			service.Logger.Error("[CoverService] Failed to commit transaction - UploadCover", map[string]interface{}{
+				"book_id": bookID,
+				"error":   commitErr.Error(),
+			})
+			cover, custErr = nil, response.GeneralError("Failed to update book cover: "+commitErr.Error())
+		} else {
+			service.BookCache.booksChanged(ctx, changed...)
+			service.deleteKeys(ctx, obsolete)
+		}
+	}()
+
+	book, err := service.BookRepository.FindBookByID(ctx, tx, bookID)
+	if err != nil {
+		service.Logger.Error("[CoverService] Failed to find book by ID - UploadCover", map[string]interface{}{
+			"book_id": bookID,
+			"error":   err.Error(),
+		})
+		return nil, response.NotFoundError("Book not found")
+	}
+
+	access := bookAccessFromContext(ctx, service.Policy)
+	if custErr, accessErr := access.checkBookWrite(ctx, tx, service.AuthorRepository, book.AuthorID); custErr != nil {
+		err = accessErr
+		return nil, custErr
+	}
+
+	sum := sha256.Sum256(data)
+	coverPath := fmt.Sprintf("books/%d/%s%s", bookID, hex.EncodeToString(sum[:8]), format.Extension)
+
+	// Re-uploading the current cover rewrites the same objects, which must
+	// survive a failure.
+	keys, err := service.storeCover(ctx, coverPath, data, img, format)
+	if coverPath != book.CoverPath {
+		written = keys
+	}
+	if err != nil {
+		service.Logger.Error("[CoverService] Failed to store cover - UploadCover", map[string]interface{}{
+			"book_id": bookID,
+			"error":   err.Error(),
+		})
+		return nil, response.GeneralError("Failed to store cover: " + err.Error())
+	}
+
+	version, err := service.BookRepository.UpdateBookCover(ctx, tx, bookID, coverPath)
+	if err != nil {
+		service.Logger.Error("[CoverService] Failed to update book cover - UploadCover", map[string]interface{}{
+			"book_id": bookID,
+			"error":   err.Error(),
+		})
+		if errors.Is(err, repositories.ErrBookNotFound) {
+			return nil, response.NotFoundError("Book not found")
+		}
+		return nil, response.GeneralError("Failed to update book cover: " + err.Error())
+	}
+
+	previousCoverPath := book.CoverPath
+	book.CoverPath = coverPath
+	book.Version = version
+
+	err = recordRevision(ctx, tx, service.RevisionRepository, book, models.RevisionActionUpdate, nil)
+	if err != nil {
+		service.Logger.Error("[CoverService] Failed to record revision - UploadCover", map[string]interface{}{
+			"book_id": bookID,
+			"error":   err.Error(),
+		})
+		return nil, response.GeneralError("Failed to record book revision: " + err.Error())
+	}
+
+	if previousCoverPath != "" && previousCoverPath != coverPath {
+		obsolete = coverKeys(previousCoverPath)
+	}
+
+	changed = []uint64{bookID}
+	return newCoverResponse(coverPath), nil
+}
+
+func (service *CoverServiceImpl) GetCover(ctx context.Context, key string) (io.ReadCloser, *storage.ObjectInfo, *response.CustomError) {
+	body, info, err := service.Storage.Get(ctx, key)
+	if err != nil {
+		if !errors.Is(err, storage.ErrNotFound) {
+			service.Logger.Error("[CoverService] Failed to read cover - GetCover", map[string]interface{}{
+				"key":   key,
+				"error": err.Error(),
+			})
+		}
+		return nil, nil, response.NotFoundError("Cover not found")
+	}
+	return body, info, nil
+}
+
+// storeCover writes the original upload and every thumbnail size. It returns
+// the keys written so far so the caller can clean up after a failure.
+func (service *CoverServiceImpl) storeCover(ctx context.Context, coverPath string, data []byte, img image.Image, format imaging.Format) ([]string, error) {
+	var written []string
+
+	originalKey := coverKey(coverPath, "original")
+	if err := service.Storage.Put(ctx, originalKey, bytes.NewReader(data), format.ContentType); err != nil {
+		return written, err
+	}
+	written = append(written, originalKey)
+
+	for _, size := range coverSizes {
+		var buf bytes.Buffer
+		if err := imaging.Encode(&buf, imaging.Thumbnail(img, size.Width), format); err != nil {
+			return written, err
+		}
+
+		key := coverKey(coverPath, size.Name)
+		if err := service.Storage.Put(ctx, key, &buf, format.ContentType); err != nil {
+			return written, err
+		}
+		written = append(written, key)
+	}
+
+	return written, nil
+}
+
+func (service *CoverServiceImpl) deleteKeys(ctx context.Context, keys []string) {
+	deleteCoverObjects(ctx, service.Storage, service.Logger, keys)
+}
+
+// deleteCoverObjects removes cover objects no book points at any more. A
+// failure only leaves an orphaned object behind, so it is logged and skipped.
+func deleteCoverObjects(ctx context.Context, store storage.Storage, log logger.Logger, keys []string) {
+	for _, key := range keys {
+		if err := store.Delete(ctx, key); err != nil {
+			log.Warn("[Storage] Failed to delete cover object", map[string]interface{}{
+				"key":   key,
+				"error": err.Error(),
+			})
+		}
+	}
+}
+
+// coverKey maps a stored cover path such as "books/1/ab12.png" to the object
+// key of one rendition, e.g. "books/1/ab12/small.png".
+func coverKey(coverPath string, size string) string {
+	ext := path.Ext(coverPath)
+	return strings.TrimSuffix(coverPath, ext) + "/" + size + ext
+}
+
+func coverKeys(coverPath string) []string {
+	keys := []string{coverKey(coverPath, "original")}
+	for _, size := range coverSizes {
+		keys = append(keys, coverKey(coverPath, size.Name))
+	}
+	return keys
+}
+
+func newCoverResponse(coverPath string) *params.CoverResponse {
+	if coverPath == "" {
+		return nil
+	}
+	return &params.CoverResponse{
+		Original: CoverURLPrefix + coverKey(coverPath, "original"),
+		Small:    CoverURLPrefix + coverKey(coverPath, "small"),
+		Medium:   CoverURLPrefix + coverKey(coverPath, "medium"),
+		Large:    CoverURLPrefix + coverKey(coverPath, "large"),
+	}
+}
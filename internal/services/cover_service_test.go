@@ -0,0 +1,204 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"library-api-book/internal/logger"
+	"library-api-book/internal/models"
+	"library-api-book/internal/repositories"
+	"library-api-book/pkg/storage"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func setupCoverTest(t *testing.T) (sqlmock.Sqlmock, *repositories.MockBookRepository, *storage.LocalStorage, *CoverServiceImpl) {
+	db, mockDB, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	store, err := storage.NewLocalStorage(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+
+	mockRepo := new(repositories.MockBookRepository)
+	service := &CoverServiceImpl{
+		DB:                 db,
+		BookRepository:     mockRepo,
+		RevisionRepository: newMockRevisionRepository(),
+		Storage:            store,
+		Logger:             logger.NopLogger{},
+		MaxSize:            1 << 20,
+	}
+
+	return mockDB, mockRepo, store, service
+}
+
+func samplePNG(t *testing.T, width, height int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		img.Set(x, x%height, color.RGBA{R: 200, A: 255})
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("Failed to encode sample image: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestUploadCover_Success(t *testing.T) {
+	mockDB, mockRepo, store, service := setupCoverTest(t)
+
+	mockDB.ExpectBegin()
+	mockRepo.On("FindBookByID", mock.Anything, mock.Anything, uint64(1)).Return(&models.Book{ID: 1}, nil)
+	mockRepo.On("UpdateBookCover", mock.Anything, mock.Anything, uint64(1), mock.Anything).Return(int32(2), nil)
+	mockDB.ExpectCommit()
+
+	cover, errResponse := service.UploadCover(WithTrustedCaller(context.Background()), 1, samplePNG(t, 600, 900))
+
+	assert.Nil(t, errResponse)
+	assert.True(t, strings.HasPrefix(cover.Small, CoverURLPrefix+"books/1/"))
+	assert.True(t, strings.HasSuffix(cover.Small, "/small.png"))
+
+	expectedWidths := map[string]int{cover.Original: 600, cover.Small: 96, cover.Medium: 240, cover.Large: 480}
+	for url, width := range expectedWidths {
+		body, info, err := store.Get(context.Background(), strings.TrimPrefix(url, CoverURLPrefix))
+		assert.Nil(t, err, url)
+		assert.Equal(t, "image/png", info.ContentType)
+
+		data, _ := io.ReadAll(body)
+		body.Close()
+		config, err := png.DecodeConfig(bytes.NewReader(data))
+		assert.Nil(t, err, url)
+		assert.Equal(t, width, config.Width, url)
+		assert.Equal(t, width*3/2, config.Height, url)
+	}
+
+	mockRepo.AssertExpectations(t)
+	assert.Nil(t, mockDB.ExpectationsWereMet())
+}
+
+func TestUploadCover_RejectsUnsupportedType(t *testing.T) {
+	_, mockRepo, _, service := setupCoverTest(t)
+
+	cover, errResponse := service.UploadCover(WithTrustedCaller(context.Background()), 1, []byte("GIF89a not really an image"))
+
+	assert.Nil(t, cover)
+	assert.NotNil(t, errResponse)
+	assert.Equal(t, "Cover image must be a JPEG or PNG", errResponse.Message)
+	mockRepo.AssertNotCalled(t, "UpdateBookCover", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestUploadCover_RejectsOversizedFile(t *testing.T) {
+	_, _, _, service := setupCoverTest(t)
+	service.MaxSize = 10
+
+	cover, errResponse := service.UploadCover(WithTrustedCaller(context.Background()), 1, samplePNG(t, 20, 20))
+
+	assert.Nil(t, cover)
+	assert.NotNil(t, errResponse)
+	assert.Equal(t, 400, errResponse.StatusCode)
+}
+
+func TestUploadCover_BookNotFound(t *testing.T) {
+	mockDB, mockRepo, _, service := setupCoverTest(t)
+
+	mockDB.ExpectBegin()
+	mockRepo.On("FindBookByID", mock.Anything, mock.Anything, uint64(9)).Return(nil, assert.AnError)
+	mockDB.ExpectRollback()
+
+	cover, errResponse := service.UploadCover(WithTrustedCaller(context.Background()), 9, samplePNG(t, 20, 20))
+
+	assert.Nil(t, cover)
+	assert.Equal(t, "Book not found", errResponse.Message)
+	assert.Nil(t, mockDB.ExpectationsWereMet())
+}
+
+func putCover(t *testing.T, store *storage.LocalStorage, coverPath string) {
+	for _, key := range coverKeys(coverPath) {
+		if err := store.Put(context.Background(), key, strings.NewReader("old"), "image/png"); err != nil {
+			t.Fatalf("Failed to store cover: %v", err)
+		}
+	}
+}
+
+// coverExists reports whether every object of coverPath is stored.
+func coverExists(store *storage.LocalStorage, coverPath string) bool {
+	for _, key := range coverKeys(coverPath) {
+		body, _, err := store.Get(context.Background(), key)
+		if err != nil {
+			return false
+		}
+		body.Close()
+	}
+	return true
+}
+
+func TestUploadCover_ReplacesPreviousCoverAfterCommit(t *testing.T) {
+	mockDB, mockRepo, store, service := setupCoverTest(t)
+	putCover(t, store, "books/1/old.png")
+
+	mockDB.ExpectBegin()
+	mockRepo.On("FindBookByID", mock.Anything, mock.Anything, uint64(1)).Return(&models.Book{ID: 1, CoverPath: "books/1/old.png"}, nil)
+	mockRepo.On("UpdateBookCover", mock.Anything, mock.Anything, uint64(1), mock.Anything).Return(int32(2), nil)
+	mockDB.ExpectCommit()
+
+	cover, errResponse := service.UploadCover(WithTrustedCaller(context.Background()), 1, samplePNG(t, 20, 20))
+
+	assert.Nil(t, errResponse)
+	assert.False(t, coverExists(store, "books/1/old.png"))
+	_, _, err := store.Get(context.Background(), strings.TrimPrefix(cover.Original, CoverURLPrefix))
+	assert.Nil(t, err)
+}
+
+func TestUploadCover_CommitFailureKeepsPreviousCover(t *testing.T) {
+	mockDB, mockRepo, store, service := setupCoverTest(t)
+	putCover(t, store, "books/1/old.png")
+
+	mockDB.ExpectBegin()
+	mockRepo.On("FindBookByID", mock.Anything, mock.Anything, uint64(1)).Return(&models.Book{ID: 1, CoverPath: "books/1/old.png"}, nil)
+	var newCoverPath string
+	mockRepo.On("UpdateBookCover", mock.Anything, mock.Anything, uint64(1), mock.Anything).Run(func(args mock.Arguments) {
+		newCoverPath = args.String(3)
+	}).Return(int32(2), nil)
+	mockDB.ExpectCommit().WillReturnError(assert.AnError)
+
+	cover, errResponse := service.UploadCover(WithTrustedCaller(context.Background()), 1, samplePNG(t, 20, 20))
+
+	assert.Nil(t, cover)
+	assert.NotNil(t, errResponse)
+	assert.True(t, coverExists(store, "books/1/old.png"))
+	for _, key := range coverKeys(newCoverPath) {
+		_, _, err := store.Get(context.Background(), key)
+		assert.ErrorIs(t, err, storage.ErrNotFound, key)
+	}
+}
+
+func TestUploadCover_DeletedBook(t *testing.T) {
+	mockDB, mockRepo, store, service := setupCoverTest(t)
+
+	mockDB.ExpectBegin()
+	mockRepo.On("FindBookByID", mock.Anything, mock.Anything, uint64(1)).Return(&models.Book{ID: 1}, nil)
+	var newCoverPath string
+	mockRepo.On("UpdateBookCover", mock.Anything, mock.Anything, uint64(1), mock.Anything).Run(func(args mock.Arguments) {
+		newCoverPath = args.String(3)
+	}).Return(int32(0), repositories.ErrBookNotFound)
+	mockDB.ExpectRollback()
+
+	cover, errResponse := service.UploadCover(WithTrustedCaller(context.Background()), 1, samplePNG(t, 20, 20))
+
+	assert.Nil(t, cover)
+	assert.Equal(t, "Book not found", errResponse.Message)
+	assert.False(t, coverExists(store, newCoverPath))
+	assert.Nil(t, mockDB.ExpectationsWereMet())
+}
@@ -0,0 +1,298 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"library-api-book/internal/commons/response"
+	"library-api-book/internal/logger"
+	"library-api-book/internal/models"
+	"library-api-book/internal/params"
+	"library-api-book/internal/permission"
+	"library-api-book/internal/repositories"
+	"library-api-book/pkg/marc"
+	"time"
+)
+
+type MarcService interface {
+	ImportRecords(ctx context.Context, records []*marc.Record) (*params.MarcImportResponse, *response.CustomError)
+	ExportBook(ctx context.Context, id uint64) (*marc.Record, *response.CustomError)
+	ExportBooks(ctx context.Context, pagination *models.Pagination, search string) ([]*marc.Record, *response.CustomError)
+}
+
+type MarcServiceImpl struct {
+	DB                 *sql.DB
+	BookCache          *BookCache
+	BookRepository     repositories.BookRepository
+	AuthorRepository   repositories.AuthorRepository
+	CategoryRepository repositories.CategoryRepository
+	RevisionRepository repositories.BookRevisionRepository
+	Policy             *permission.Policy
+	Logger             logger.Logger
+}
+
+func NewMarcService(db *sql.DB, bookCache *BookCache, bookRepository repositories.BookRepository, authorRepository repositories.AuthorRepository, categoryRepository repositories.CategoryRepository, revisionRepository repositories.BookRevisionRepository, policy *permission.Policy, log logger.Logger) MarcService {
+	return &MarcServiceImpl{
+		DB:                 db,
+		BookCache:          bookCache,
+		BookRepository:     bookRepository,
+		AuthorRepository:   authorRepository,
+		CategoryRepository: categoryRepository,
+		RevisionRepository: revisionRepository,
+		Policy:             policy,
+		Logger:             log,
+	}
+}
+
+func (service *MarcServiceImpl) ImportRecords(ctx context.Context, records []*marc.Record) (*params.MarcImportResponse, *response.CustomError) {
+	result := &params.MarcImportResponse{
+		Imported: []uint64{},
+		Failed:   []params.MarcImportFailure{},
+	}
+
+	access := bookAccessFromContext(ctx, service.Policy)
+	for i, record := range records {
+		bookRecord := marc.ToBook(record)
+
+		id, err := service.importRecord(ctx, access, bookRecord)
+		if err != nil {
+			service.Logger.Warn("[MarcService] Failed to import record - ImportRecords", map[string]interface{}{
+				"record": i + 1,
+				"title":  bookRecord.Book.Title,
+				"error":  err.Error(),
+			})
+			result.Failed = append(result.Failed, params.MarcImportFailure{
+				Record: i + 1,
+				Title:  bookRecord.Book.Title,
+				Reason: err.Error(),
+			})
+			continue
+		}
+		result.Imported = append(result.Imported, id)
+	}
+
+	return result, nil
+}
+
+// importRecord stores a single record in its own transaction so one bad record
+// does not discard the rest of the batch.
+func (service *MarcServiceImpl) importRecord(ctx context.Context, access *bookAccess, bookRecord *marc.BookRecord) (uint64, error) {
+	if bookRecord.Book.Title == "" {
+		return 0, errors.New("record has no title (245 $a)")
+	}
+	if bookRecord.Author == "" {
+		return 0, errors.New("record has no main entry author (100 $a)")
+	}
+
+	tx, err := service.DB.Begin()
+	if err != nil {
+		return 0, errors.New("Failed to connect to the database: " + err.Error())
+	}
+	var changed []uint64
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			service.Logger.Error("[MarcService] Transaction rolled back due to panic - ImportRecords", map[string]interface{}{
+				"error": p,
+			})
+		} else if err != nil {
+			tx.Rollback()
+		} else if tx.Commit() == nil {
+			service.BookCache.booksChanged(ctx, changed...)
+		}
+	}()
+
+	if bookRecord.Book.ISBN != "" {
+		if existing, findErr := service.BookRepository.FindBookByISBN(ctx, tx, bookRecord.Book.ISBN); findErr == nil {
+			err = errors.New("book with this ISBN already exists")
+			service.Logger.Info("[MarcService] Skipped duplicate ISBN - ImportRecords", map[string]interface{}{
+				"book_id": existing.ID,
+				"isbn":    existing.ISBN,
+			})
+			return 0, err
+		}
+	}
+
+	author, err := service.findAuthor(ctx, tx, bookRecord)
+	if err != nil {
+		return 0, err
+	}
+
+	allowed, err := access.canWrite(ctx, tx, service.AuthorRepository, author.ID)
+	if err != nil {
+		return 0, err
+	}
+	if !allowed {
+		err = errors.New("authors can only import their own books")
+		return 0, err
+	}
+	if !access.mayChangeStock() && bookRecord.Book.Stock != 0 {
+		err = errors.New("caller may not set the stock of a book")
+		return 0, err
+	}
+
+	book := bookRecord.Book
+	book.AuthorID = author.ID
+	if book.PublishAt.IsZero() {
+		book.PublishAt = time.Now()
+	}
+	book.UpdatedAt = time.Now()
+
+	err = service.BookRepository.CreateBook(ctx, tx, &book)
+	if err != nil {
+		return 0, err
+	}
+
+	err = recordRevision(ctx, tx, service.RevisionRepository, &book, models.RevisionActionCreate, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, name := range bookRecord.Categories {
+		var category *models.Category
+		category, err = service.CategoryRepository.FindOrCreateCategory(ctx, tx, name)
+		if err != nil {
+			return 0, err
+		}
+		err = service.CategoryRepository.AttachCategoryToBook(ctx, tx, book.ID, category.ID)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	changed = []uint64{book.ID}
+	return book.ID, nil
+}
+
+// findAuthor looks the record's author up under every form of the name, so
+// inverted headings from other catalogs match authors stored in natural
+// order.
+func (service *MarcServiceImpl) findAuthor(ctx context.Context, tx *sql.Tx, bookRecord *marc.BookRecord) (*models.Author, error) {
+	for _, name := range bookRecord.AuthorNames() {
+		author, err := service.AuthorRepository.FindAuthorByName(ctx, tx, name)
+		if err == nil {
+			return author, nil
+		}
+	}
+	return nil, errors.New("author not found: " + bookRecord.Author)
+}
+
+func (service *MarcServiceImpl) ExportBook(ctx context.Context, id uint64) (*marc.Record, *response.CustomError) {
+	tx, err := service.DB.Begin()
+	if err != nil {
+		service.Logger.Error("[MarcService] Failed to begin transaction - ExportBook", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return nil, response.GeneralError("Failed to connect to the database: " + err.Error())
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			service.Logger.Error("[MarcService] Transaction rolled back due to panic - ExportBook", map[string]interface{}{
+				"error": p,
+			})
+		} else if err != nil {
+			tx.Rollback()
+			service.Logger.Error("[MarcService] Transaction rolled back due to error - ExportBook", map[string]interface{}{
+				"error": err.Error(),
+			})
+		} else {
+			tx.Commit()
+		}
+	}()
+
+	book, err := service.BookRepository.FindBookByID(ctx, tx, id)
+	if err != nil {
+		service.Logger.Error("[MarcService] Failed to find book by ID - ExportBook", map[string]interface{}{
+			"book_id": id,
+			"error":   err.Error(),
+		})
+		return nil, response.NotFoundError("Book not found")
+	}
+
+	bookRecord, err := service.buildBookRecord(ctx, tx, book)
+	if err != nil {
+		service.Logger.Error("[MarcService] Failed to build record - ExportBook", map[string]interface{}{
+			"book_id": id,
+			"error":   err.Error(),
+		})
+		return nil, response.GeneralError("Failed to export book: " + err.Error())
+	}
+
+	return marc.FromBook(bookRecord), nil
+}
+
+func (service *MarcServiceImpl) ExportBooks(ctx context.Context, pagination *models.Pagination, search string) ([]*marc.Record, *response.CustomError) {
+	tx, err := service.DB.Begin()
+	if err != nil {
+		service.Logger.Error("[MarcService] Failed to begin transaction - ExportBooks", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return nil, response.GeneralError("Failed to connect to the database: " + err.Error())
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			service.Logger.Error("[MarcService] Transaction rolled back due to panic - ExportBooks", map[string]interface{}{
+				"error": p,
+			})
+		} else if err != nil {
+			tx.Rollback()
+			service.Logger.Error("[MarcService] Transaction rolled back due to error - ExportBooks", map[string]interface{}{
+				"error": err.Error(),
+			})
+		} else {
+			tx.Commit()
+		}
+	}()
+
+	pagination.Offset = (pagination.Page - 1) * pagination.PageSize
+
+	books, err := service.BookRepository.GetAllBooks(ctx, tx, pagination, &models.BookFilter{Search: search})
+	if err != nil {
+		service.Logger.Error("[MarcService] Failed to fetch books - ExportBooks", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return nil, response.GeneralError("Failed to fetch books: " + err.Error())
+	}
+
+	records := make([]*marc.Record, len(books))
+	for i, book := range books {
+		bookRecord, buildErr := service.buildBookRecord(ctx, tx, book)
+		if buildErr != nil {
+			err = buildErr
+			service.Logger.Error("[MarcService] Failed to build record - ExportBooks", map[string]interface{}{
+				"book_id": book.ID,
+				"error":   err.Error(),
+			})
+			return nil, response.GeneralError("Failed to export books: " + err.Error())
+		}
+		records[i] = marc.FromBook(bookRecord)
+	}
+
+	return records, nil
+}
+
+func (service *MarcServiceImpl) buildBookRecord(ctx context.Context, tx *sql.Tx, book *models.Book) (*marc.BookRecord, error) {
+	bookRecord := &marc.BookRecord{Book: *book}
+
+	author, err := service.AuthorRepository.FindAuthorByID(ctx, tx, book.AuthorID)
+	if err == nil {
+		bookRecord.Author = author.Name
+	} else {
+		service.Logger.Warn("[MarcService] Author not found, exporting without main entry", map[string]interface{}{
+			"book_id":   book.ID,
+			"author_id": book.AuthorID,
+		})
+	}
+
+	categories, err := service.CategoryRepository.FindCategoriesByBookID(ctx, tx, book.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, category := range categories {
+		bookRecord.Categories = append(bookRecord.Categories, category.Name)
+	}
+
+	return bookRecord, nil
+}
@@ -0,0 +1,149 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"library-api-book/internal/logger"
+	"library-api-book/internal/models"
+	"library-api-book/internal/repositories"
+	"library-api-book/pkg/marc"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func setupMarcTest(t *testing.T) (sqlmock.Sqlmock, *repositories.MockBookRepository, *repositories.MockAuthorRepository, *repositories.MockCategoryRepository, *MarcServiceImpl) {
+	db, mockDB, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	bookRepo := new(repositories.MockBookRepository)
+	authorRepo := new(repositories.MockAuthorRepository)
+	categoryRepo := new(repositories.MockCategoryRepository)
+	service := &MarcServiceImpl{
+		DB:                 db,
+		BookRepository:     bookRepo,
+		AuthorRepository:   authorRepo,
+		CategoryRepository: categoryRepo,
+		RevisionRepository: newMockRevisionRepository(),
+		Logger:             logger.NopLogger{},
+	}
+
+	return mockDB, bookRepo, authorRepo, categoryRepo, service
+}
+
+func sampleMarcRecord(author string) *marc.Record {
+	return marc.FromBook(&marc.BookRecord{
+		Book: models.Book{
+			Title:     "Where the wild things are",
+			ISBN:      "0060254920",
+			Publisher: "Harper & Row",
+			PublishAt: time.Date(1963, time.January, 1, 0, 0, 0, 0, time.UTC),
+		},
+		Author:     author,
+		Categories: []string{"Monsters"},
+	})
+}
+
+func TestImportRecords_Success(t *testing.T) {
+	mockDB, bookRepo, authorRepo, categoryRepo, service := setupMarcTest(t)
+
+	mockDB.ExpectBegin()
+	bookRepo.On("FindBookByISBN", mock.Anything, mock.Anything, "0060254920").Return(nil, errors.New("book is not found"))
+	authorRepo.On("FindAuthorByName", mock.Anything, mock.Anything, "Sendak, Maurice").Return(&models.Author{ID: 3, Name: "Sendak, Maurice"}, nil)
+	bookRepo.On("CreateBook", mock.Anything, mock.Anything, mock.MatchedBy(func(book *models.Book) bool {
+		return book.AuthorID == 3 && book.ISBN == "0060254920" && book.PublishAt.Year() == 1963
+	})).Run(func(args mock.Arguments) {
+		args.Get(2).(*models.Book).ID = 10
+	}).Return(nil)
+	categoryRepo.On("FindOrCreateCategory", mock.Anything, mock.Anything, "Monsters").Return(&models.Category{ID: 4, Name: "Monsters"}, nil)
+	categoryRepo.On("AttachCategoryToBook", mock.Anything, mock.Anything, uint64(10), uint64(4)).Return(nil)
+	mockDB.ExpectCommit()
+
+	result, errResponse := service.ImportRecords(WithTrustedCaller(context.Background()), []*marc.Record{sampleMarcRecord("Sendak, Maurice")})
+
+	assert.Nil(t, errResponse)
+	assert.Equal(t, []uint64{10}, result.Imported)
+	assert.Empty(t, result.Failed)
+	bookRepo.AssertExpectations(t)
+	authorRepo.AssertExpectations(t)
+	categoryRepo.AssertExpectations(t)
+	assert.Nil(t, mockDB.ExpectationsWereMet())
+}
+
+// A record from another catalog gives the author surname first, as MARC
+// headings usually do, while authors are stored in natural order.
+func TestImportRecords_InvertedAuthorHeading(t *testing.T) {
+	mockDB, bookRepo, authorRepo, _, service := setupMarcTest(t)
+
+	record := marc.NewRecord()
+	record.AddControlField("001", "ocm00012345")
+	record.AddDataField("100", "1", " ", marc.Subfield{Code: "a", Value: "Orwell, George,"}, marc.Subfield{Code: "d", Value: "1903-1950."})
+	record.AddDataField("245", "1", "0", marc.Subfield{Code: "a", Value: "Animal farm /"}, marc.Subfield{Code: "c", Value: "George Orwell."})
+
+	mockDB.ExpectBegin()
+	authorRepo.On("FindAuthorByName", mock.Anything, mock.Anything, "Orwell, George").Return(nil, repositories.ErrAuthorNotFound)
+	authorRepo.On("FindAuthorByName", mock.Anything, mock.Anything, "George Orwell").Return(&models.Author{ID: 5, Name: "George Orwell"}, nil)
+	bookRepo.On("CreateBook", mock.Anything, mock.Anything, mock.MatchedBy(func(book *models.Book) bool {
+		return book.AuthorID == 5 && book.Title == "Animal farm"
+	})).Run(func(args mock.Arguments) {
+		args.Get(2).(*models.Book).ID = 11
+	}).Return(nil)
+	mockDB.ExpectCommit()
+
+	result, errResponse := service.ImportRecords(WithTrustedCaller(context.Background()), []*marc.Record{record})
+
+	assert.Nil(t, errResponse)
+	assert.Equal(t, []uint64{11}, result.Imported)
+	assert.Empty(t, result.Failed)
+	authorRepo.AssertExpectations(t)
+	assert.Nil(t, mockDB.ExpectationsWereMet())
+}
+
+func TestImportRecords_UnknownAuthor(t *testing.T) {
+	mockDB, bookRepo, authorRepo, _, service := setupMarcTest(t)
+
+	mockDB.ExpectBegin()
+	bookRepo.On("FindBookByISBN", mock.Anything, mock.Anything, "0060254920").Return(nil, errors.New("book is not found"))
+	authorRepo.On("FindAuthorByName", mock.Anything, mock.Anything, "Nobody").Return(nil, errors.New("author is not found"))
+	mockDB.ExpectRollback()
+
+	result, errResponse := service.ImportRecords(WithTrustedCaller(context.Background()), []*marc.Record{sampleMarcRecord("Nobody")})
+
+	assert.Nil(t, errResponse)
+	assert.Empty(t, result.Imported)
+	assert.Equal(t, 1, len(result.Failed))
+	assert.Equal(t, "author not found: Nobody", result.Failed[0].Reason)
+	bookRepo.AssertNotCalled(t, "CreateBook", mock.Anything, mock.Anything, mock.Anything)
+	assert.Nil(t, mockDB.ExpectationsWereMet())
+}
+
+func TestExportBook_Success(t *testing.T) {
+	mockDB, bookRepo, authorRepo, categoryRepo, service := setupMarcTest(t)
+
+	mockDB.ExpectBegin()
+	bookRepo.On("FindBookByID", mock.Anything, mock.Anything, uint64(1)).Return(&models.Book{
+		ID:       1,
+		AuthorID: 2,
+		Title:    "Test Book",
+		ISBN:     "9780000000002",
+	}, nil)
+	authorRepo.On("FindAuthorByID", mock.Anything, mock.Anything, uint64(2)).Return(&models.Author{ID: 2, Name: "Doe, Jane"}, nil)
+	categoryRepo.On("FindCategoriesByBookID", mock.Anything, mock.Anything, uint64(1)).Return([]*models.Category{{ID: 1, Name: "Fiction"}}, nil)
+	mockDB.ExpectCommit()
+
+	record, errResponse := service.ExportBook(context.Background(), 1)
+
+	assert.Nil(t, errResponse)
+	bookRecord := marc.ToBook(record)
+	assert.Equal(t, "Test Book", bookRecord.Book.Title)
+	assert.Equal(t, "9780000000002", bookRecord.Book.ISBN)
+	assert.Equal(t, "Doe, Jane", bookRecord.Author)
+	assert.Equal(t, []string{"Fiction"}, bookRecord.Categories)
+	assert.Nil(t, mockDB.ExpectationsWereMet())
+}
@@ -0,0 +1,208 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"library-api-book/internal/commons/response"
+	"library-api-book/internal/logger"
+	"library-api-book/internal/models"
+	"library-api-book/internal/repositories"
+	"library-api-book/pkg/oaipmh"
+	"time"
+)
+
+type OAIService interface {
+	GetEarliestDatestamp(ctx context.Context) (time.Time, *response.CustomError)
+	GetItem(ctx context.Context, id uint64) (*oaipmh.Item, *response.CustomError)
+	ListItems(ctx context.Context, filter *models.HarvestFilter) ([]*oaipmh.Item, int, *response.CustomError)
+}
+
+type OAIServiceImpl struct {
+	DB                 *sql.DB
+	BookRepository     repositories.BookRepository
+	AuthorRepository   repositories.AuthorRepository
+	CategoryRepository repositories.CategoryRepository
+	Logger             logger.Logger
+}
+
+func NewOAIService(db *sql.DB, bookRepository repositories.BookRepository, authorRepository repositories.AuthorRepository, categoryRepository repositories.CategoryRepository, log logger.Logger) OAIService {
+	return &OAIServiceImpl{
+		DB:                 db,
+		BookRepository:     bookRepository,
+		AuthorRepository:   authorRepository,
+		CategoryRepository: categoryRepository,
+		Logger:             log,
+	}
+}
+
+func (service *OAIServiceImpl) GetEarliestDatestamp(ctx context.Context) (time.Time, *response.CustomError) {
+	tx, err := service.DB.Begin()
+	if err != nil {
+		service.Logger.Error("[OAIService] Failed to begin transaction - GetEarliestDatestamp", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return time.Time{}, response.GeneralError("Failed to connect to the database: " + err.Error())
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			service.Logger.Error("[OAIService] Transaction rolled back due to panic - GetEarliestDatestamp", map[string]interface{}{
+				"error": p,
+			})
+		} else if err != nil {
+			tx.Rollback()
+			service.Logger.Error("[OAIService] Transaction rolled back due to error - GetEarliestDatestamp", map[string]interface{}{
+				"error": err.Error(),
+			})
+		} else {
+			tx.Commit()
+		}
+	}()
+
+	earliest, err := service.BookRepository.FindEarliestUpdatedAt(ctx, tx)
+	if err != nil {
+		service.Logger.Error("[OAIService] Failed to find earliest datestamp - GetEarliestDatestamp", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return time.Time{}, response.GeneralError("Failed to find earliest datestamp: " + err.Error())
+	}
+
+	return earliest, nil
+}
+
+func (service *OAIServiceImpl) GetItem(ctx context.Context, id uint64) (*oaipmh.Item, *response.CustomError) {
+	tx, err := service.DB.Begin()
+	if err != nil {
+		service.Logger.Error("[OAIService] Failed to begin transaction - GetItem", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return nil, response.GeneralError("Failed to connect to the database: " + err.Error())
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			service.Logger.Error("[OAIService] Transaction rolled back due to panic - GetItem", map[string]interface{}{
+				"error": p,
+			})
+		} else if err != nil {
+			tx.Rollback()
+			service.Logger.Error("[OAIService] Transaction rolled back due to error - GetItem", map[string]interface{}{
+				"error": err.Error(),
+			})
+		} else {
+			tx.Commit()
+		}
+	}()
+
+	book, err := service.BookRepository.FindBookForHarvest(ctx, tx, id)
+	if err != nil {
+		service.Logger.Error("[OAIService] Failed to find book by ID - GetItem", map[string]interface{}{
+			"book_id": id,
+			"error":   err.Error(),
+		})
+		if errors.Is(err, repositories.ErrBookNotFound) {
+			return nil, response.NotFoundError("Book not found")
+		}
+		return nil, response.GeneralError("Failed to find book: " + err.Error())
+	}
+
+	item, err := service.buildItem(ctx, tx, book)
+	if err != nil {
+		service.Logger.Error("[OAIService] Failed to build item - GetItem", map[string]interface{}{
+			"book_id": id,
+			"error":   err.Error(),
+		})
+		return nil, response.GeneralError("Failed to build record: " + err.Error())
+	}
+
+	return item, nil
+}
+
+// ListItems returns up to filter.Limit items together with the size of the
+// complete list matching the from/until range.
+func (service *OAIServiceImpl) ListItems(ctx context.Context, filter *models.HarvestFilter) ([]*oaipmh.Item, int, *response.CustomError) {
+	tx, err := service.DB.Begin()
+	if err != nil {
+		service.Logger.Error("[OAIService] Failed to begin transaction - ListItems", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return nil, 0, response.GeneralError("Failed to connect to the database: " + err.Error())
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			service.Logger.Error("[OAIService] Transaction rolled back due to panic - ListItems", map[string]interface{}{
+				"error": p,
+			})
+		} else if err != nil {
+			tx.Rollback()
+			service.Logger.Error("[OAIService] Transaction rolled back due to error - ListItems", map[string]interface{}{
+				"error": err.Error(),
+			})
+		} else {
+			tx.Commit()
+		}
+	}()
+
+	total, err := service.BookRepository.CountBooksForHarvest(ctx, tx, filter)
+	if err != nil {
+		service.Logger.Error("[OAIService] Failed to count books - ListItems", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return nil, 0, response.GeneralError("Failed to count books: " + err.Error())
+	}
+
+	books, err := service.BookRepository.GetBooksForHarvest(ctx, tx, filter)
+	if err != nil {
+		service.Logger.Error("[OAIService] Failed to fetch books - ListItems", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return nil, 0, response.GeneralError("Failed to fetch books: " + err.Error())
+	}
+
+	items := make([]*oaipmh.Item, len(books))
+	for i, book := range books {
+		items[i], err = service.buildItem(ctx, tx, book)
+		if err != nil {
+			service.Logger.Error("[OAIService] Failed to build item - ListItems", map[string]interface{}{
+				"book_id": book.ID,
+				"error":   err.Error(),
+			})
+			return nil, 0, response.GeneralError("Failed to build record: " + err.Error())
+		}
+	}
+
+	return items, total, nil
+}
+
+// buildItem describes book as an OAI-PMH item. Deleted books keep only their
+// identifier and the datestamp of the deletion.
+func (service *OAIServiceImpl) buildItem(ctx context.Context, tx *sql.Tx, book *models.Book) (*oaipmh.Item, error) {
+	if book.DeletedAt != nil {
+		return &oaipmh.Item{ID: book.ID, Datestamp: book.UpdatedAt, Deleted: true}, nil
+	}
+
+	item := &oaipmh.Item{
+		ID:        book.ID,
+		Datestamp: book.UpdatedAt,
+		Title:     book.Title,
+		Publisher: book.Publisher,
+		Date:      book.PublishAt,
+		ISBN:      book.ISBN,
+	}
+
+	if author, err := service.AuthorRepository.FindAuthorByID(ctx, tx, book.AuthorID); err == nil {
+		item.Creator = author.Name
+	}
+
+	categories, err := service.CategoryRepository.FindCategoriesByBookID(ctx, tx, book.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, category := range categories {
+		item.Subjects = append(item.Subjects, category.Name)
+	}
+
+	return item, nil
+}
@@ -0,0 +1,99 @@
+package services
+
+import (
+	"context"
+	"library-api-book/internal/logger"
+	"library-api-book/internal/models"
+	"library-api-book/internal/repositories"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func setupOAITest(t *testing.T) (sqlmock.Sqlmock, *repositories.MockBookRepository, *repositories.MockAuthorRepository, *repositories.MockCategoryRepository, *OAIServiceImpl) {
+	db, mockDB, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	bookRepo := new(repositories.MockBookRepository)
+	authorRepo := new(repositories.MockAuthorRepository)
+	categoryRepo := new(repositories.MockCategoryRepository)
+	service := &OAIServiceImpl{
+		DB:                 db,
+		BookRepository:     bookRepo,
+		AuthorRepository:   authorRepo,
+		CategoryRepository: categoryRepo,
+		Logger:             logger.NopLogger{},
+	}
+
+	return mockDB, bookRepo, authorRepo, categoryRepo, service
+}
+
+func TestListItems_DeletedBooks(t *testing.T) {
+	mockDB, bookRepo, authorRepo, categoryRepo, service := setupOAITest(t)
+	updatedAt := time.Date(2024, time.March, 1, 10, 0, 0, 0, time.UTC)
+	deletedAt := updatedAt.Add(time.Hour)
+	filter := &models.HarvestFilter{Limit: 100}
+
+	mockDB.ExpectBegin()
+	bookRepo.On("CountBooksForHarvest", mock.Anything, mock.Anything, filter).Return(2, nil)
+	bookRepo.On("GetBooksForHarvest", mock.Anything, mock.Anything, filter).Return([]*models.Book{
+		{ID: 1, AuthorID: 3, Title: "Dune", UpdatedAt: updatedAt},
+		{ID: 2, AuthorID: 3, Title: "Gone", UpdatedAt: deletedAt, DeletedAt: &deletedAt},
+	}, nil)
+	authorRepo.On("FindAuthorByID", mock.Anything, mock.Anything, uint64(3)).Return(&models.Author{ID: 3, Name: "Herbert, Frank"}, nil).Once()
+	categoryRepo.On("FindCategoriesByBookID", mock.Anything, mock.Anything, uint64(1)).Return([]*models.Category{}, nil).Once()
+	mockDB.ExpectCommit()
+
+	items, total, custErr := service.ListItems(context.Background(), filter)
+
+	assert.Nil(t, custErr)
+	assert.Equal(t, 2, total)
+	assert.Len(t, items, 2)
+	assert.False(t, items[0].Deleted)
+	assert.Equal(t, "Herbert, Frank", items[0].Creator)
+	assert.True(t, items[1].Deleted)
+	assert.Equal(t, uint64(2), items[1].ID)
+	assert.Equal(t, deletedAt, items[1].Datestamp)
+	assert.Empty(t, items[1].Title)
+	authorRepo.AssertExpectations(t)
+	categoryRepo.AssertExpectations(t)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestGetItem_DeletedBook(t *testing.T) {
+	mockDB, bookRepo, _, categoryRepo, service := setupOAITest(t)
+	deletedAt := time.Date(2024, time.March, 1, 11, 0, 0, 0, time.UTC)
+
+	mockDB.ExpectBegin()
+	bookRepo.On("FindBookForHarvest", mock.Anything, mock.Anything, uint64(2)).Return(&models.Book{ID: 2, UpdatedAt: deletedAt, DeletedAt: &deletedAt}, nil)
+	mockDB.ExpectCommit()
+
+	item, custErr := service.GetItem(context.Background(), 2)
+
+	assert.Nil(t, custErr)
+	assert.True(t, item.Deleted)
+	assert.Equal(t, deletedAt, item.Datestamp)
+	categoryRepo.AssertNotCalled(t, "FindCategoriesByBookID", mock.Anything, mock.Anything, uint64(2))
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestGetItem_UnknownBook(t *testing.T) {
+	mockDB, bookRepo, _, _, service := setupOAITest(t)
+
+	mockDB.ExpectBegin()
+	bookRepo.On("FindBookForHarvest", mock.Anything, mock.Anything, uint64(9)).Return(nil, repositories.ErrBookNotFound)
+	mockDB.ExpectRollback()
+
+	item, custErr := service.GetItem(context.Background(), 9)
+
+	assert.Nil(t, item)
+	assert.NotNil(t, custErr)
+	assert.Equal(t, "Book not found", custErr.Message)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
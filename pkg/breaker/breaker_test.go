@@ -0,0 +1,97 @@
+package breaker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBreaker_OpensAfterThreshold(t *testing.T) {
+	b := New(3, time.Second)
+
+	for i := 0; i < 2; i++ {
+		assert.NoError(t, b.Allow())
+		b.Failure()
+	}
+	assert.Equal(t, StateClosed, b.State())
+
+	assert.NoError(t, b.Allow())
+	b.Failure()
+	assert.Equal(t, StateOpen, b.State())
+	assert.ErrorIs(t, b.Allow(), ErrOpen)
+}
+
+func TestBreaker_SuccessResetsFailures(t *testing.T) {
+	b := New(2, time.Second)
+
+	b.Failure()
+	b.Success()
+	b.Failure()
+
+	assert.Equal(t, StateClosed, b.State())
+}
+
+func TestBreaker_HalfOpenSingleProbe(t *testing.T) {
+	now := time.Now()
+	b := New(1, time.Second)
+	b.now = func() time.Time { return now }
+
+	b.Failure()
+	assert.ErrorIs(t, b.Allow(), ErrOpen)
+
+	now = now.Add(time.Second)
+	assert.Equal(t, StateHalfOpen, b.State())
+	assert.NoError(t, b.Allow())
+	assert.ErrorIs(t, b.Allow(), ErrOpen)
+
+	b.Success()
+	assert.Equal(t, StateClosed, b.State())
+	assert.NoError(t, b.Allow())
+}
+
+func TestBreaker_FailedProbeReopens(t *testing.T) {
+	now := time.Now()
+	b := New(5, time.Second)
+	b.now = func() time.Time { return now }
+
+	for i := 0; i < 5; i++ {
+		b.Failure()
+	}
+	now = now.Add(time.Second)
+	assert.NoError(t, b.Allow())
+
+	b.Failure()
+	assert.Equal(t, StateOpen, b.State())
+
+	now = now.Add(500 * time.Millisecond)
+	assert.ErrorIs(t, b.Allow(), ErrOpen)
+}
+
+func TestBreaker_ReleasedProbe(t *testing.T) {
+	now := time.Now()
+	b := New(1, time.Second)
+	b.now = func() time.Time { return now }
+
+	b.Failure()
+	now = now.Add(time.Second)
+	assert.NoError(t, b.Allow())
+
+	b.Release()
+	assert.Equal(t, StateHalfOpen, b.State())
+	assert.NoError(t, b.Allow())
+}
+
+func TestBreaker_ReleaseKeepsFailures(t *testing.T) {
+	b := New(2, time.Second)
+
+	assert.NoError(t, b.Allow())
+	b.Failure()
+	assert.NoError(t, b.Allow())
+	b.Release()
+	assert.Equal(t, StateClosed, b.State())
+
+	assert.NoError(t, b.Allow())
+	b.Failure()
+	assert.Equal(t, StateOpen, b.State())
+}
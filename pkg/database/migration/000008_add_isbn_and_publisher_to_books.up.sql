@@ -0,0 +1,4 @@
+ALTER TABLE books ADD COLUMN isbn VARCHAR(20);
+ALTER TABLE books ADD COLUMN publisher VARCHAR(255);
+
+CREATE INDEX idx_books_isbn ON books (isbn);
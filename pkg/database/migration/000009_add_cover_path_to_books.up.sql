@@ -0,0 +1 @@
+ALTER TABLE books ADD COLUMN cover_path VARCHAR(255);
@@ -0,0 +1,3 @@
+ALTER TABLE books ADD COLUMN deleted_at TIMESTAMP;
+
+CREATE INDEX idx_books_deleted_at ON books (deleted_at);
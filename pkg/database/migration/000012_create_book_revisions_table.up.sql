@@ -0,0 +1,11 @@
+CREATE TABLE book_revisions (
+    id SERIAL PRIMARY KEY NOT NULL,
+    book_id INT NOT NULL,
+    revision INT NOT NULL,
+    action VARCHAR(20) CHECK (action IN ('create', 'update', 'delete', 'restore', 'revert')) NOT NULL,
+    snapshot JSONB NOT NULL,
+    actor_id INT,
+    source_revision INT,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (book_id, revision)
+);
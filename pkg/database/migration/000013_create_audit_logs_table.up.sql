@@ -0,0 +1,26 @@
+CREATE TABLE audit_logs (
+    id BIGSERIAL PRIMARY KEY NOT NULL,
+    occurred_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    actor_id INT,
+    role VARCHAR(20),
+    action VARCHAR(255) NOT NULL,
+    target VARCHAR(2048) NOT NULL,
+    ip VARCHAR(64) NOT NULL,
+    request_id VARCHAR(64) NOT NULL,
+    outcome VARCHAR(20) CHECK (outcome IN ('success', 'failure', 'denied')) NOT NULL,
+    status_code INT NOT NULL,
+    detail TEXT
+);
+
+CREATE INDEX idx_audit_logs_occurred_at ON audit_logs (occurred_at);
+CREATE INDEX idx_audit_logs_actor_id ON audit_logs (actor_id);
+
+CREATE FUNCTION audit_logs_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_logs is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_logs_append_only
+    BEFORE UPDATE OR DELETE ON audit_logs
+    FOR EACH ROW EXECUTE FUNCTION audit_logs_append_only();
@@ -0,0 +1,12 @@
+CREATE TABLE api_keys (
+    id BIGSERIAL PRIMARY KEY NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    prefix VARCHAR(16) NOT NULL UNIQUE,
+    key_hash CHAR(64) NOT NULL,
+    permissions TEXT[] NOT NULL,
+    created_by INT,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP,
+    last_used_at TIMESTAMP,
+    revoked_at TIMESTAMP
+);
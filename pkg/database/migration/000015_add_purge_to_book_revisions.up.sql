@@ -0,0 +1,2 @@
+ALTER TABLE book_revisions DROP CONSTRAINT IF EXISTS book_revisions_action_check;
+ALTER TABLE book_revisions ADD CONSTRAINT book_revisions_action_check CHECK (action IN ('create', 'update', 'delete', 'restore', 'revert', 'purge'));
@@ -1,12 +1,46 @@
 package database
 
-import "github.com/redis/go-redis/v9"
+import (
+	"context"
+	"library-api-book/internal/config"
+	"library-api-book/pkg/tlsutil"
+	"log"
 
-func NewRedisClient() *redis.Client {
-	client := redis.NewClient(&redis.Options{
-		Addr: "34.142.158.122:6379",
-		DB:   0,
-	})
+	"github.com/redis/go-redis/v9"
+)
 
-	return client
+// NewRedisClient configures a client from the REDIS_* settings without
+// connecting. Redis only backs caches, rate limits and stock events, so the
+// service starts and serves from Postgres when it is unreachable. TLS
+// certificates are reloaded every GRPC_TLS_RELOAD_INTERVAL until ctx ends.
+func NewRedisClient(ctx context.Context) (*redis.Client, error) {
+	options := &redis.Options{
+		Addr:         config.ENV.RedisAddr,
+		Username:     config.ENV.RedisUsername,
+		Password:     config.ENV.RedisPassword,
+		DB:           config.ENV.RedisDB,
+		DialTimeout:  config.ENV.RedisDialTimeout,
+		ReadTimeout:  config.ENV.RedisReadTimeout,
+		WriteTimeout: config.ENV.RedisWriteTimeout,
+		// Lets the cache and rate limiter cut calls shorter than the read and
+		// write timeouts through their contexts.
+		ContextTimeoutEnabled: true,
+	}
+
+	if config.ENV.RedisTLS {
+		reloader, err := tlsutil.NewReloader(tlsutil.Files{
+			CertFile: config.ENV.RedisTLSCertFile,
+			KeyFile:  config.ENV.RedisTLSKeyFile,
+			CAFile:   config.ENV.RedisTLSCAFile,
+		})
+		if err != nil {
+			return nil, err
+		}
+		go reloader.Run(ctx, config.ENV.GRPCTLSReloadInterval, func(err error) {
+			log.Printf("Failed to reload Redis certificates: %v", err)
+		})
+		options.TLSConfig = reloader.ClientConfig(config.ENV.RedisTLSServerName)
+	}
+
+	return redis.NewClient(options), nil
 }
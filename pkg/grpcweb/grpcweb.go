@@ -0,0 +1,246 @@
+// Package grpcweb lets browsers call a gRPC server through the gRPC-Web
+// protocol, and serves gRPC, gRPC-Web and plain HTTP from a single handler.
+//
+// gRPC-Web requests are rewritten into HTTP/2 gRPC requests for
+// grpc.Server.ServeHTTP; the response trailers, which browsers cannot read,
+// are sent as a final length-prefixed frame of the body instead. Both the
+// binary (application/grpc-web) and the base64 (application/grpc-web-text)
+// encodings are supported. Client streaming is not part of gRPC-Web.
+package grpcweb
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/http2"
+)
+
+const (
+	contentTypeWeb     = "application/grpc-web"
+	contentTypeWebText = "application/grpc-web-text"
+
+	// trailerFlag marks the frame carrying the trailers.
+	trailerFlag = 0x80
+
+	allowHeaders  = "Authorization, Content-Type, Grpc-Timeout, X-Grpc-Web, X-User-Agent"
+	exposeHeaders = "Grpc-Status, Grpc-Message, Grpc-Status-Details-Bin"
+)
+
+// NewMux sends gRPC requests to grpcHandler, normally a *grpc.Server,
+// gRPC-Web requests and their CORS preflights to grpcHandler through the
+// gRPC-Web translation, and everything else to httpHandler. Native gRPC
+// needs HTTP/2, over TLS or h2c.
+func NewMux(grpcHandler http.Handler, httpHandler http.Handler) http.Handler {
+	web := Wrap(grpcHandler)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case IsGRPCWebRequest(r) || IsGRPCWebPreflight(r):
+			web.ServeHTTP(w, r)
+		case r.ProtoMajor == 2 && strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc"):
+			grpcHandler.ServeHTTP(w, r)
+		default:
+			httpHandler.ServeHTTP(w, r)
+		}
+	})
+}
+
+func IsGRPCWebRequest(r *http.Request) bool {
+	return r.Method == http.MethodPost && strings.HasPrefix(r.Header.Get("Content-Type"), contentTypeWeb)
+}
+
+// IsGRPCWebPreflight reports whether r is the CORS preflight a browser sends
+// before a cross-origin gRPC-Web call.
+func IsGRPCWebPreflight(r *http.Request) bool {
+	if r.Method != http.MethodOptions || r.Header.Get("Access-Control-Request-Method") == "" {
+		return false
+	}
+	for _, header := range strings.Split(r.Header.Get("Access-Control-Request-Headers"), ",") {
+		if strings.EqualFold(strings.TrimSpace(header), "x-grpc-web") {
+			return true
+		}
+	}
+	return false
+}
+
+// Wrap translates gRPC-Web requests for grpcHandler. Like the REST API, any
+// origin may call it; credentials travel in the Authorization header.
+func Wrap(grpcHandler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Access-Control-Expose-Headers", exposeHeaders)
+
+		if IsGRPCWebPreflight(r) {
+			w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", allowHeaders)
+			w.Header().Set("Access-Control-Max-Age", "600")
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		if !IsGRPCWebRequest(r) {
+			http.Error(w, "not a gRPC-Web request", http.StatusUnsupportedMediaType)
+			return
+		}
+
+		contentType := r.Header.Get("Content-Type")
+		text := strings.HasPrefix(contentType, contentTypeWebText)
+
+		req := r.Clone(r.Context())
+		req.ProtoMajor, req.ProtoMinor, req.Proto = 2, 0, "HTTP/2.0"
+		req.Header.Set("Content-Type", "application/grpc"+strings.TrimPrefix(strings.TrimPrefix(contentType, contentTypeWebText), contentTypeWeb))
+		req.Header.Set("Te", "trailers")
+		req.Header.Del("Content-Length")
+		req.ContentLength = -1
+		if text {
+			body, err := decodeText(r.Body)
+			if err != nil {
+				http.Error(w, "invalid base64 request body", http.StatusBadRequest)
+				return
+			}
+			req.Body = io.NopCloser(bytes.NewReader(body))
+		}
+
+		writer := newResponseWriter(w, contentType, text)
+		grpcHandler.ServeHTTP(writer, req)
+		writer.finish()
+	})
+}
+
+// decodeText decodes a grpc-web-text body, which may be several base64
+// chunks each with its own padding.
+func decodeText(body io.Reader) ([]byte, error) {
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.Map(func(r rune) rune {
+		if r == '\r' || r == '\n' {
+			return -1
+		}
+		return r
+	}, data)
+
+	var decoded []byte
+	for len(data) > 0 {
+		end := bytes.IndexByte(data, '=')
+		if end < 0 {
+			end = len(data)
+		}
+		for end < len(data) && data[end] == '=' {
+			end++
+		}
+		chunk, err := base64.StdEncoding.DecodeString(string(data[:end]))
+		if err != nil {
+			return nil, err
+		}
+		decoded = append(decoded, chunk...)
+		data = data[end:]
+	}
+	return decoded, nil
+}
+
+// responseWriter turns the HTTP/2 gRPC response written by grpc-go into a
+// gRPC-Web one.
+type responseWriter struct {
+	w           http.ResponseWriter
+	header      http.Header
+	contentType string
+	text        bool
+
+	wroteHeader bool
+	trailers    []string
+	// pending holds the body written since the last flush in text mode, so
+	// that each flush is encoded as one padded base64 chunk.
+	pending bytes.Buffer
+}
+
+func newResponseWriter(w http.ResponseWriter, contentType string, text bool) *responseWriter {
+	return &responseWriter{w: w, header: make(http.Header), contentType: contentType, text: text}
+}
+
+func (writer *responseWriter) Header() http.Header {
+	return writer.header
+}
+
+func (writer *responseWriter) WriteHeader(code int) {
+	if writer.wroteHeader {
+		return
+	}
+	writer.wroteHeader = true
+
+	header := writer.w.Header()
+	for key, values := range writer.header {
+		switch {
+		case key == "Trailer":
+			for _, value := range values {
+				writer.trailers = append(writer.trailers, http.CanonicalHeaderKey(value))
+			}
+		case key == "Content-Type" || strings.HasPrefix(key, http2.TrailerPrefix):
+		default:
+			header[key] = values
+		}
+	}
+	header.Set("Content-Type", writer.contentType)
+	header.Del("Content-Length")
+	writer.w.WriteHeader(code)
+}
+
+func (writer *responseWriter) Write(data []byte) (int, error) {
+	writer.WriteHeader(http.StatusOK)
+	if writer.text {
+		return writer.pending.Write(data)
+	}
+	return writer.w.Write(data)
+}
+
+func (writer *responseWriter) Flush() {
+	writer.WriteHeader(http.StatusOK)
+	if writer.text && writer.pending.Len() > 0 {
+		writer.w.Write([]byte(base64.StdEncoding.EncodeToString(writer.pending.Bytes())))
+		writer.pending.Reset()
+	}
+	if flusher, ok := writer.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// finish writes the trailers grpc-go left in the header map as the last
+// frame of the body.
+func (writer *responseWriter) finish() {
+	trailers := make(http.Header)
+	for _, key := range writer.trailers {
+		if values, ok := writer.header[key]; ok {
+			trailers[key] = values
+		}
+	}
+	for key, values := range writer.header {
+		if name, ok := strings.CutPrefix(key, http2.TrailerPrefix); ok {
+			trailers[http.CanonicalHeaderKey(name)] = values
+		}
+	}
+
+	keys := make([]string, 0, len(trailers))
+	for key := range trailers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var block bytes.Buffer
+	for _, key := range keys {
+		for _, value := range trailers[key] {
+			block.WriteString(strings.ToLower(key) + ": " + value + "\r\n")
+		}
+	}
+
+	frame := make([]byte, 5, 5+block.Len())
+	frame[0] = trailerFlag
+	binary.BigEndian.PutUint32(frame[1:], uint32(block.Len()))
+	frame = append(frame, block.Bytes()...)
+
+	writer.Write(frame)
+	writer.Flush()
+}
@@ -0,0 +1,207 @@
+package grpcweb
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/protobuf/proto"
+)
+
+const checkPath = "/grpc.health.v1.Health/Check"
+
+func startServer(t *testing.T) *httptest.Server {
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus("book.BookService", healthpb.HealthCheckResponse_SERVING)
+
+	grpcServer := grpc.NewServer()
+	healthpb.RegisterHealthServer(grpcServer, healthServer)
+
+	rest := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "rest")
+	})
+
+	server := httptest.NewServer(h2c.NewHandler(NewMux(grpcServer, rest), &http2.Server{}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func frame(flag byte, payload []byte) []byte {
+	data := make([]byte, 5, 5+len(payload))
+	data[0] = flag
+	binary.BigEndian.PutUint32(data[1:], uint32(len(payload)))
+	return append(data, payload...)
+}
+
+// readFrames splits a gRPC-Web body into its messages and its trailers.
+func readFrames(t *testing.T, body []byte) ([][]byte, map[string]string) {
+	var messages [][]byte
+	trailers := make(map[string]string)
+	for len(body) > 0 {
+		require.GreaterOrEqual(t, len(body), 5)
+		size := binary.BigEndian.Uint32(body[1:5])
+		payload := body[5 : 5+size]
+		if body[0]&trailerFlag != 0 {
+			for _, line := range strings.Split(strings.TrimSpace(string(payload)), "\r\n") {
+				key, value, _ := strings.Cut(line, ": ")
+				trailers[key] = value
+			}
+		} else {
+			messages = append(messages, payload)
+		}
+		body = body[5+size:]
+	}
+	return messages, trailers
+}
+
+func callWeb(t *testing.T, server *httptest.Server, contentType string, body []byte) (*http.Response, []byte) {
+	req, err := http.NewRequest(http.MethodPost, server.URL+checkPath, bytes.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("X-Grpc-Web", "1")
+
+	resp, err := server.Client().Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp, data
+}
+
+func checkRequest(t *testing.T, service string) []byte {
+	data, err := proto.Marshal(&healthpb.HealthCheckRequest{Service: service})
+	require.NoError(t, err)
+	return frame(0, data)
+}
+
+func TestGRPCWeb_Binary(t *testing.T) {
+	server := startServer(t)
+
+	resp, body := callWeb(t, server, "application/grpc-web+proto", checkRequest(t, "book.BookService"))
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "application/grpc-web+proto", resp.Header.Get("Content-Type"))
+	assert.Equal(t, "*", resp.Header.Get("Access-Control-Allow-Origin"))
+
+	messages, trailers := readFrames(t, body)
+	require.Len(t, messages, 1)
+	var check healthpb.HealthCheckResponse
+	require.NoError(t, proto.Unmarshal(messages[0], &check))
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, check.Status)
+	assert.Equal(t, "0", trailers["grpc-status"])
+}
+
+func TestGRPCWeb_Text(t *testing.T) {
+	server := startServer(t)
+
+	request := base64.StdEncoding.EncodeToString(checkRequest(t, "book.BookService"))
+	resp, body := callWeb(t, server, "application/grpc-web-text", []byte(request))
+
+	assert.Equal(t, "application/grpc-web-text", resp.Header.Get("Content-Type"))
+	decoded, err := decodeText(bytes.NewReader(body))
+	require.NoError(t, err)
+	messages, trailers := readFrames(t, decoded)
+	assert.Len(t, messages, 1)
+	assert.Equal(t, "0", trailers["grpc-status"])
+}
+
+func TestGRPCWeb_ErrorStatus(t *testing.T) {
+	server := startServer(t)
+
+	_, body := callWeb(t, server, "application/grpc-web", checkRequest(t, "missing.Service"))
+
+	messages, trailers := readFrames(t, body)
+	assert.Empty(t, messages)
+	assert.Equal(t, "5", trailers["grpc-status"])
+	assert.NotEmpty(t, trailers["grpc-message"])
+}
+
+func TestGRPCWeb_ServerStreamingIsFlushed(t *testing.T) {
+	server := startServer(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, server.URL+"/grpc.health.v1.Health/Watch", bytes.NewReader(checkRequest(t, "book.BookService")))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/grpc-web+proto")
+
+	resp, err := server.Client().Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	// The stream stays open; the first update must arrive without it ending.
+	header := make([]byte, 5)
+	_, err = io.ReadFull(resp.Body, header)
+	require.NoError(t, err)
+	assert.Equal(t, byte(0), header[0])
+	payload := make([]byte, binary.BigEndian.Uint32(header[1:]))
+	_, err = io.ReadFull(resp.Body, payload)
+	require.NoError(t, err)
+
+	var update healthpb.HealthCheckResponse
+	require.NoError(t, proto.Unmarshal(payload, &update))
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, update.Status)
+}
+
+func TestGRPCWeb_Preflight(t *testing.T) {
+	server := startServer(t)
+
+	req, err := http.NewRequest(http.MethodOptions, server.URL+checkPath, nil)
+	require.NoError(t, err)
+	req.Header.Set("Origin", "https://kiosk.example")
+	req.Header.Set("Access-Control-Request-Method", "POST")
+	req.Header.Set("Access-Control-Request-Headers", "content-type,x-grpc-web,x-user-agent")
+
+	resp, err := server.Client().Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	assert.Contains(t, resp.Header.Get("Access-Control-Allow-Headers"), "X-Grpc-Web")
+}
+
+func TestMux_NativeGRPCOverH2C(t *testing.T) {
+	server := startServer(t)
+
+	conn, err := grpc.NewClient(strings.TrimPrefix(server.URL, "http://"), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	resp, err := healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{Service: "book.BookService"})
+	require.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.Status)
+}
+
+func TestMux_PlainHTTP(t *testing.T) {
+	server := startServer(t)
+
+	resp, err := server.Client().Get(server.URL + "/api/v1/books")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+
+	assert.Equal(t, "rest", string(body))
+}
+
+func TestDecodeText_PaddedChunks(t *testing.T) {
+	body := base64.StdEncoding.EncodeToString([]byte("ab")) + base64.StdEncoding.EncodeToString([]byte("cde"))
+
+	decoded, err := decodeText(strings.NewReader(body))
+
+	require.NoError(t, err)
+	assert.Equal(t, "abcde", string(decoded))
+}
@@ -0,0 +1,77 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+
+	"golang.org/x/image/draw"
+)
+
+var ErrUnsupportedFormat = errors.New("unsupported image format")
+
+type Format struct {
+	Name        string
+	ContentType string
+	Extension   string
+}
+
+var formats = map[string]Format{
+	"image/jpeg": {Name: "jpeg", ContentType: "image/jpeg", Extension: ".jpg"},
+	"image/png":  {Name: "png", ContentType: "image/png", Extension: ".png"},
+}
+
+// Decode sniffs the content type, rejects anything but JPEG and PNG, checks
+// the declared dimensions before allocating pixels and then decodes the image.
+func Decode(data []byte, maxPixels int) (image.Image, Format, error) {
+	format, ok := formats[http.DetectContentType(data)]
+	if !ok {
+		return nil, Format{}, ErrUnsupportedFormat
+	}
+
+	config, name, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || name != format.Name {
+		return nil, Format{}, ErrUnsupportedFormat
+	}
+	if config.Width <= 0 || config.Height <= 0 || config.Width*config.Height > maxPixels {
+		return nil, Format{}, fmt.Errorf("image dimensions %dx%d are not allowed", config.Width, config.Height)
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, Format{}, err
+	}
+	return img, format, nil
+}
+
+// Thumbnail scales src down to fit within width, keeping the aspect ratio.
+// Images that are already small enough are returned unchanged.
+func Thumbnail(src image.Image, width int) image.Image {
+	bounds := src.Bounds()
+	if bounds.Dx() <= width {
+		return src
+	}
+
+	height := bounds.Dy() * width / bounds.Dx()
+	if height < 1 {
+		height = 1
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, bounds, draw.Over, nil)
+	return dst
+}
+
+func Encode(w io.Writer, img image.Image, format Format) error {
+	switch format.Name {
+	case "jpeg":
+		return jpeg.Encode(w, img, &jpeg.Options{Quality: 85})
+	case "png":
+		return png.Encode(w, img)
+	}
+	return ErrUnsupportedFormat
+}
@@ -0,0 +1,192 @@
+package marc
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// MarshalBinary encodes the record as an ISO 2709 (MARC21 transmission format)
+// record. Record length and base address in the leader are recomputed.
+func (r *Record) MarshalBinary() ([]byte, error) {
+	var directory, data bytes.Buffer
+
+	writeField := func(tag string, body []byte) error {
+		if len(tag) != 3 {
+			return fmt.Errorf("invalid tag %q", tag)
+		}
+		entry := fmt.Sprintf("%s%04d%05d", tag, len(body), data.Len())
+		if len(entry) != directoryEntrySize {
+			return fmt.Errorf("field %s is too long", tag)
+		}
+		directory.WriteString(entry)
+		data.Write(body)
+		return nil
+	}
+
+	for _, field := range r.ControlFields {
+		if err := checkValue(field.Value); err != nil {
+			return nil, fmt.Errorf("field %s: %w", field.Tag, err)
+		}
+		body := append([]byte(field.Value), fieldTerminator)
+		if err := writeField(field.Tag, body); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, field := range r.DataFields {
+		var body bytes.Buffer
+		body.WriteString(indicator(field.Ind1))
+		body.WriteString(indicator(field.Ind2))
+		for _, subfield := range field.Subfields {
+			if len(subfield.Code) != 1 {
+				return nil, fmt.Errorf("field %s: invalid subfield code %q", field.Tag, subfield.Code)
+			}
+			if err := checkValue(subfield.Value); err != nil {
+				return nil, fmt.Errorf("field %s$%s: %w", field.Tag, subfield.Code, err)
+			}
+			body.WriteByte(subfieldDelimiter)
+			body.WriteString(subfield.Code)
+			body.WriteString(subfield.Value)
+		}
+		body.WriteByte(fieldTerminator)
+		if err := writeField(field.Tag, body.Bytes()); err != nil {
+			return nil, err
+		}
+	}
+	directory.WriteByte(fieldTerminator)
+	data.WriteByte(recordTerminator)
+
+	baseAddress := leaderLength + directory.Len()
+	recordLength := baseAddress + data.Len()
+	if recordLength > 99999 {
+		return nil, errors.New("record exceeds the maximum MARC21 record length")
+	}
+
+	leader := []byte(r.Leader)
+	if len(leader) != leaderLength {
+		leader = []byte(DefaultLeader)
+	}
+	copy(leader[0:5], fmt.Sprintf("%05d", recordLength))
+	copy(leader[10:12], "22")
+	copy(leader[12:17], fmt.Sprintf("%05d", baseAddress))
+	copy(leader[20:24], "4500")
+
+	out := make([]byte, 0, recordLength)
+	out = append(out, leader...)
+	out = append(out, directory.Bytes()...)
+	out = append(out, data.Bytes()...)
+	return out, nil
+}
+
+// UnmarshalBinary decodes a single ISO 2709 record.
+func (r *Record) UnmarshalBinary(raw []byte) error {
+	if len(raw) < leaderLength+1 {
+		return errors.New("record is shorter than the leader")
+	}
+
+	leader := string(raw[:leaderLength])
+	baseAddress, err := strconv.Atoi(leader[12:17])
+	if err != nil || baseAddress <= leaderLength || baseAddress > len(raw) {
+		return fmt.Errorf("invalid base address %q", leader[12:17])
+	}
+
+	directory := raw[leaderLength : baseAddress-1]
+	if len(directory)%directoryEntrySize != 0 {
+		return errors.New("malformed directory")
+	}
+
+	record := Record{Leader: leader}
+	data := raw[baseAddress:]
+	for i := 0; i < len(directory); i += directoryEntrySize {
+		entry := string(directory[i : i+directoryEntrySize])
+		tag := entry[0:3]
+		length, err := strconv.Atoi(entry[3:7])
+		if err != nil {
+			return fmt.Errorf("field %s: invalid length", tag)
+		}
+		start, err := strconv.Atoi(entry[7:12])
+		if err != nil {
+			return fmt.Errorf("field %s: invalid start position", tag)
+		}
+		if length == 0 || start+length > len(data) {
+			return fmt.Errorf("field %s: out of bounds", tag)
+		}
+		body := bytes.TrimSuffix(data[start:start+length], []byte{fieldTerminator})
+
+		if isControlTag(tag) {
+			record.AddControlField(tag, string(body))
+			continue
+		}
+
+		if len(body) < 2 {
+			return fmt.Errorf("field %s: missing indicators", tag)
+		}
+		field := DataField{
+			Tag:  tag,
+			Ind1: string(body[0]),
+			Ind2: string(body[1]),
+		}
+		for _, chunk := range bytes.Split(body[2:], []byte{subfieldDelimiter}) {
+			if len(chunk) == 0 {
+				continue
+			}
+			field.Subfields = append(field.Subfields, Subfield{
+				Code:  string(chunk[0]),
+				Value: string(chunk[1:]),
+			})
+		}
+		record.DataFields = append(record.DataFields, field)
+	}
+
+	*r = record
+	return nil
+}
+
+// ParseBinary decodes a stream of concatenated ISO 2709 records.
+func ParseBinary(raw []byte) ([]*Record, error) {
+	var records []*Record
+	for {
+		raw = bytes.TrimLeft(raw, " \r\n")
+		if len(raw) == 0 {
+			break
+		}
+		if len(raw) < 5 {
+			return nil, errors.New("truncated record")
+		}
+		length, err := strconv.Atoi(string(raw[:5]))
+		if err != nil || length < leaderLength || length > len(raw) {
+			return nil, fmt.Errorf("record %d: invalid record length", len(records)+1)
+		}
+
+		record := new(Record)
+		if err := record.UnmarshalBinary(raw[:length]); err != nil {
+			return nil, fmt.Errorf("record %d: %w", len(records)+1, err)
+		}
+		records = append(records, record)
+		raw = raw[length:]
+	}
+	return records, nil
+}
+
+// EncodeBinary encodes records as a single ISO 2709 stream.
+func EncodeBinary(records []*Record) ([]byte, error) {
+	var out bytes.Buffer
+	for _, record := range records {
+		raw, err := record.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		out.Write(raw)
+	}
+	return out.Bytes(), nil
+}
+
+func checkValue(value string) error {
+	if strings.ContainsAny(value, "\x1d\x1e\x1f") {
+		return errors.New("value contains a MARC delimiter")
+	}
+	return nil
+}
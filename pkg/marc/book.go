@@ -0,0 +1,174 @@
+package marc
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"library-api-book/internal/models"
+)
+
+const transactionTimeLayout = "20060102150405.0"
+
+var yearPattern = regexp.MustCompile(`\d{4}`)
+
+// BookRecord is the bibliographic view of a book: the catalog row plus the
+// author name and category names that MARC carries inline. Author is the
+// name as written, either "Forename Surname" or "Surname, Forename".
+type BookRecord struct {
+	Book       models.Book
+	Author     string
+	Categories []string
+}
+
+// AuthorNames returns the forms Author may be stored under: the name as
+// written and, for an inverted "Surname, Forename" heading as most catalogs
+// write it, the name in natural order.
+func (bookRecord *BookRecord) AuthorNames() []string {
+	if bookRecord.Author == "" {
+		return nil
+	}
+	names := []string{bookRecord.Author}
+	if surname, forename, ok := strings.Cut(bookRecord.Author, ","); ok {
+		surname, forename = strings.TrimSpace(surname), strings.TrimSpace(forename)
+		if surname != "" && forename != "" {
+			names = append(names, forename+" "+surname)
+		}
+	}
+	return names
+}
+
+// FromBook builds a MARC21 bibliographic record from a book.
+func FromBook(bookRecord *BookRecord) *Record {
+	book := bookRecord.Book
+	record := NewRecord()
+
+	if book.ID != 0 {
+		record.AddControlField("001", strconv.FormatUint(book.ID, 10))
+	}
+	if !book.UpdatedAt.IsZero() {
+		record.AddControlField("005", book.UpdatedAt.UTC().Format(transactionTimeLayout))
+	}
+
+	if book.ISBN != "" {
+		record.AddDataField("020", " ", " ", Subfield{Code: "a", Value: book.ISBN})
+	}
+	if bookRecord.Author != "" {
+		// First indicator 1 claims a surname-first heading; names stored in
+		// natural order are given as forename first.
+		nameInd1 := "0"
+		if strings.Contains(bookRecord.Author, ",") {
+			nameInd1 = "1"
+		}
+		record.AddDataField("100", nameInd1, " ", Subfield{Code: "a", Value: bookRecord.Author})
+	}
+
+	titleInd1 := "0"
+	if bookRecord.Author != "" {
+		titleInd1 = "1"
+	}
+	record.AddDataField("245", titleInd1, "0", Subfield{Code: "a", Value: book.Title})
+
+	var publication []Subfield
+	if book.Publisher != "" {
+		publication = append(publication, Subfield{Code: "b", Value: book.Publisher})
+	}
+	if !book.PublishAt.IsZero() {
+		publication = append(publication, Subfield{Code: "c", Value: strconv.Itoa(book.PublishAt.Year())})
+	}
+	if len(publication) > 0 {
+		record.AddDataField("264", " ", "1", publication...)
+	}
+
+	for _, category := range bookRecord.Categories {
+		record.AddDataField("650", " ", "4", Subfield{Code: "a", Value: category})
+	}
+
+	return record
+}
+
+// ToBook extracts the fields the catalog stores from a MARC21 bibliographic
+// record. Fields that are missing are left at their zero value.
+func ToBook(record *Record) *BookRecord {
+	bookRecord := new(BookRecord)
+
+	if value, ok := record.ControlField("005"); ok {
+		if updatedAt, err := time.Parse(transactionTimeLayout, value); err == nil {
+			bookRecord.Book.UpdatedAt = updatedAt
+		}
+	}
+
+	for _, field := range record.Fields("020") {
+		if isbn := normalizeISBN(field.Subfield("a")); isbn != "" {
+			bookRecord.Book.ISBN = isbn
+			break
+		}
+	}
+
+	if fields := record.Fields("100"); len(fields) > 0 {
+		bookRecord.Author = trimPunctuation(fields[0].Subfield("a"))
+	}
+
+	if fields := record.Fields("245"); len(fields) > 0 {
+		title := trimPunctuation(fields[0].Subfield("a"))
+		if subtitle := trimPunctuation(fields[0].Subfield("b")); subtitle != "" {
+			title += " : " + subtitle
+		}
+		bookRecord.Book.Title = title
+	}
+
+	if publication, ok := publicationField(record); ok {
+		bookRecord.Book.Publisher = trimPunctuation(publication.Subfield("b"))
+		bookRecord.Book.PublishAt = parseYear(publication.Subfield("c"))
+	}
+	if bookRecord.Book.PublishAt.IsZero() {
+		if value, ok := record.ControlField("008"); ok && len(value) >= 11 {
+			bookRecord.Book.PublishAt = parseYear(value[7:11])
+		}
+	}
+
+	for _, field := range record.Fields("650") {
+		if subject := trimPunctuation(field.Subfield("a")); subject != "" {
+			bookRecord.Categories = append(bookRecord.Categories, subject)
+		}
+	}
+
+	return bookRecord
+}
+
+// publicationField prefers a 264 publication statement (second indicator 1)
+// and falls back to the older 260 field.
+func publicationField(record *Record) (DataField, bool) {
+	for _, field := range record.Fields("264") {
+		if field.Ind2 == "1" {
+			return field, true
+		}
+	}
+	if fields := record.Fields("260"); len(fields) > 0 {
+		return fields[0], true
+	}
+	return DataField{}, false
+}
+
+func normalizeISBN(value string) string {
+	fields := strings.Fields(value)
+	if len(fields) == 0 {
+		return ""
+	}
+	return strings.ToUpper(strings.ReplaceAll(fields[0], "-", ""))
+}
+
+func parseYear(value string) time.Time {
+	year, err := strconv.Atoi(yearPattern.FindString(value))
+	if err != nil || year == 0 {
+		return time.Time{}
+	}
+	return time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+}
+
+// trimPunctuation strips the ISBD punctuation catalogers leave at the end of
+// subfields, e.g. "Orwell, George," or "Nineteen eighty-four /".
+func trimPunctuation(value string) string {
+	return strings.TrimSpace(strings.TrimRight(strings.TrimSpace(value), " /:;,."))
+}
@@ -0,0 +1,242 @@
+package marc
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"library-api-book/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const sampleMarcXML = `<?xml version="1.0" encoding="UTF-8"?>
+<collection xmlns="http://www.loc.gov/MARC21/slim">
+  <record>
+    <leader>01142cam  2200301 a 4500</leader>
+    <controlfield tag="001">   92005291 </controlfield>
+    <controlfield tag="008">920219s1993    caua   j      000 0 eng  </controlfield>
+    <datafield tag="020" ind1=" " ind2=" ">
+      <subfield code="a">0-15-200017-3 (pbk.)</subfield>
+    </datafield>
+    <datafield tag="100" ind1="1" ind2=" ">
+      <subfield code="a">Sendak, Maurice,</subfield>
+    </datafield>
+    <datafield tag="245" ind1="1" ind2="0">
+      <subfield code="a">Where the wild things are /</subfield>
+      <subfield code="c">story and pictures by Maurice Sendak.</subfield>
+    </datafield>
+    <datafield tag="260" ind1=" " ind2=" ">
+      <subfield code="a">New York :</subfield>
+      <subfield code="b">HarperCollins,</subfield>
+      <subfield code="c">c1963.</subfield>
+    </datafield>
+    <datafield tag="650" ind1=" " ind2="1">
+      <subfield code="a">Monsters</subfield>
+      <subfield code="v">Fiction.</subfield>
+    </datafield>
+    <datafield tag="650" ind1=" " ind2="1">
+      <subfield code="a">Picture books.</subfield>
+    </datafield>
+  </record>
+  <record>
+    <leader>00714cam a2200205 i 4500</leader>
+    <controlfield tag="001">2</controlfield>
+    <datafield tag="020" ind1=" " ind2=" ">
+      <subfield code="a">9780451524935</subfield>
+    </datafield>
+    <datafield tag="100" ind1="1" ind2=" ">
+      <subfield code="a">Orwell, George,</subfield>
+      <subfield code="d">1903-1950.</subfield>
+    </datafield>
+    <datafield tag="245" ind1="1" ind2="0">
+      <subfield code="a">Nineteen eighty-four :</subfield>
+      <subfield code="b">a novel.</subfield>
+    </datafield>
+    <datafield tag="264" ind1=" " ind2="1">
+      <subfield code="a">New York :</subfield>
+      <subfield code="b">Signet Classics,</subfield>
+      <subfield code="c">[1950]</subfield>
+    </datafield>
+    <datafield tag="650" ind1=" " ind2="0">
+      <subfield code="a">Dystopias</subfield>
+    </datafield>
+  </record>
+</collection>`
+
+func TestParseXML_SampleRecords(t *testing.T) {
+	records, err := ParseXML([]byte(sampleMarcXML))
+
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(records))
+
+	first := ToBook(records[0])
+	assert.Equal(t, "0152000173", first.Book.ISBN)
+	assert.Equal(t, "Sendak, Maurice", first.Author)
+	assert.Equal(t, "Where the wild things are", first.Book.Title)
+	assert.Equal(t, "HarperCollins", first.Book.Publisher)
+	assert.Equal(t, 1963, first.Book.PublishAt.Year())
+	assert.Equal(t, []string{"Monsters", "Picture books"}, first.Categories)
+
+	second := ToBook(records[1])
+	assert.Equal(t, "9780451524935", second.Book.ISBN)
+	assert.Equal(t, "Orwell, George", second.Author)
+	assert.Equal(t, "Nineteen eighty-four : a novel", second.Book.Title)
+	assert.Equal(t, "Signet Classics", second.Book.Publisher)
+	assert.Equal(t, 1950, second.Book.PublishAt.Year())
+	assert.Equal(t, []string{"Dystopias"}, second.Categories)
+	assert.Equal(t, []string{"Orwell, George", "George Orwell"}, second.AuthorNames())
+}
+
+func TestFromBook_NameIndicator(t *testing.T) {
+	natural := FromBook(&BookRecord{Book: models.Book{Title: "Animal Farm"}, Author: "George Orwell"})
+	assert.Equal(t, "0", natural.Fields("100")[0].Ind1)
+	assert.Equal(t, []string{"George Orwell"}, ToBook(natural).AuthorNames())
+
+	inverted := FromBook(&BookRecord{Book: models.Book{Title: "Animal Farm"}, Author: "Orwell, George"})
+	assert.Equal(t, "1", inverted.Fields("100")[0].Ind1)
+}
+
+func TestBinary_RoundTrip(t *testing.T) {
+	records, err := ParseXML([]byte(sampleMarcXML))
+	assert.Nil(t, err)
+
+	raw, err := EncodeBinary(records)
+	assert.Nil(t, err)
+
+	decoded, err := ParseBinary(raw)
+	assert.Nil(t, err)
+	assert.Equal(t, len(records), len(decoded))
+
+	for i := range records {
+		assert.Equal(t, records[i].ControlFields, decoded[i].ControlFields)
+		assert.Equal(t, records[i].DataFields, decoded[i].DataFields)
+		assert.Equal(t, records[i].Leader[5:10], decoded[i].Leader[5:10])
+	}
+}
+
+func TestBinary_LeaderAndDirectory(t *testing.T) {
+	record := NewRecord()
+	record.AddControlField("001", "42")
+	record.AddDataField("245", "0", "0", Subfield{Code: "a", Value: "Über Bücher"})
+
+	raw, err := record.MarshalBinary()
+	assert.Nil(t, err)
+
+	assert.Equal(t, byte(recordTerminator), raw[len(raw)-1])
+	assert.Equal(t, len(raw), atoi(t, string(raw[0:5])))
+	assert.Equal(t, leaderLength+2*directoryEntrySize+1, atoi(t, string(raw[12:17])))
+
+	decoded := new(Record)
+	assert.Nil(t, decoded.UnmarshalBinary(raw))
+	assert.Equal(t, "Über Bücher", decoded.Fields("245")[0].Subfield("a"))
+}
+
+func TestBinary_RejectsDelimiterInValue(t *testing.T) {
+	record := NewRecord()
+	record.AddDataField("245", "0", "0", Subfield{Code: "a", Value: "bad\x1evalue"})
+
+	_, err := record.MarshalBinary()
+
+	assert.NotNil(t, err)
+}
+
+func TestParseBinary_Truncated(t *testing.T) {
+	record := NewRecord()
+	record.AddDataField("245", "0", "0", Subfield{Code: "a", Value: "Title"})
+	raw, err := record.MarshalBinary()
+	assert.Nil(t, err)
+
+	_, err = ParseBinary(raw[:len(raw)-5])
+
+	assert.NotNil(t, err)
+}
+
+func TestXML_RoundTrip(t *testing.T) {
+	records, err := ParseXML([]byte(sampleMarcXML))
+	assert.Nil(t, err)
+
+	raw, err := EncodeXML(records)
+	assert.Nil(t, err)
+	assert.True(t, strings.Contains(string(raw), `xmlns="http://www.loc.gov/MARC21/slim"`))
+
+	decoded, err := ParseXML(raw)
+	assert.Nil(t, err)
+	assert.Equal(t, records, decoded)
+}
+
+func TestParseXML_SingleRecordRoot(t *testing.T) {
+	raw := `<record xmlns="http://www.loc.gov/MARC21/slim">
+  <leader>00000nam a2200000 i 4500</leader>
+  <datafield tag="245" ind1="0" ind2="0"><subfield code="a">Standalone</subfield></datafield>
+</record>`
+
+	records, err := ParseXML([]byte(raw))
+
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(records))
+	assert.Equal(t, "Standalone", ToBook(records[0]).Book.Title)
+}
+
+func TestParseXML_Empty(t *testing.T) {
+	_, err := ParseXML([]byte(`<collection xmlns="http://www.loc.gov/MARC21/slim"></collection>`))
+
+	assert.NotNil(t, err)
+}
+
+func TestBookRecord_RoundTrip(t *testing.T) {
+	bookRecord := &BookRecord{
+		Book: models.Book{
+			ID:        7,
+			Title:     "The Left Hand of Darkness",
+			ISBN:      "9780441478125",
+			Publisher: "Ace Books",
+			PublishAt: time.Date(1969, time.January, 1, 0, 0, 0, 0, time.UTC),
+			UpdatedAt: time.Date(2024, time.March, 5, 10, 30, 0, 0, time.UTC),
+		},
+		Author:     "Le Guin, Ursula K",
+		Categories: []string{"Science fiction", "Gender identity"},
+	}
+
+	for _, format := range []string{"binary", "xml"} {
+		var (
+			raw     []byte
+			records []*Record
+			err     error
+		)
+		if format == "binary" {
+			raw, err = EncodeBinary([]*Record{FromBook(bookRecord)})
+			assert.Nil(t, err)
+			records, err = ParseBinary(raw)
+		} else {
+			raw, err = EncodeXML([]*Record{FromBook(bookRecord)})
+			assert.Nil(t, err)
+			records, err = ParseXML(raw)
+		}
+		assert.Nil(t, err, format)
+		assert.Equal(t, 1, len(records), format)
+
+		decoded := ToBook(records[0])
+		assert.Equal(t, bookRecord.Book.Title, decoded.Book.Title, format)
+		assert.Equal(t, bookRecord.Book.ISBN, decoded.Book.ISBN, format)
+		assert.Equal(t, bookRecord.Book.Publisher, decoded.Book.Publisher, format)
+		assert.Equal(t, bookRecord.Book.PublishAt, decoded.Book.PublishAt, format)
+		assert.Equal(t, bookRecord.Book.UpdatedAt, decoded.Book.UpdatedAt, format)
+		assert.Equal(t, bookRecord.Author, decoded.Author, format)
+		assert.Equal(t, bookRecord.Categories, decoded.Categories, format)
+
+		controlNumber, ok := records[0].ControlField("001")
+		assert.True(t, ok, format)
+		assert.Equal(t, "7", controlNumber, format)
+	}
+}
+
+func atoi(t *testing.T, value string) int {
+	t.Helper()
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		t.Fatalf("not a number: %q", value)
+	}
+	return n
+}
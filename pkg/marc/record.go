@@ -0,0 +1,94 @@
+package marc
+
+import "strings"
+
+const (
+	fieldTerminator    = 0x1E
+	recordTerminator   = 0x1D
+	subfieldDelimiter  = 0x1F
+	leaderLength       = 24
+	directoryEntrySize = 12
+
+	// DefaultLeader is used for records built from scratch: a new (n) record for
+	// language material (a), monograph (m), Unicode encoded (a).
+	DefaultLeader = "00000nam a2200000 i 4500"
+)
+
+type Record struct {
+	Leader        string
+	ControlFields []ControlField
+	DataFields    []DataField
+}
+
+type ControlField struct {
+	Tag   string
+	Value string
+}
+
+type DataField struct {
+	Tag       string
+	Ind1      string
+	Ind2      string
+	Subfields []Subfield
+}
+
+type Subfield struct {
+	Code  string
+	Value string
+}
+
+func NewRecord() *Record {
+	return &Record{Leader: DefaultLeader}
+}
+
+func (r *Record) AddControlField(tag, value string) {
+	r.ControlFields = append(r.ControlFields, ControlField{Tag: tag, Value: value})
+}
+
+func (r *Record) AddDataField(tag, ind1, ind2 string, subfields ...Subfield) {
+	r.DataFields = append(r.DataFields, DataField{
+		Tag:       tag,
+		Ind1:      ind1,
+		Ind2:      ind2,
+		Subfields: subfields,
+	})
+}
+
+func (r *Record) ControlField(tag string) (string, bool) {
+	for _, field := range r.ControlFields {
+		if field.Tag == tag {
+			return field.Value, true
+		}
+	}
+	return "", false
+}
+
+func (r *Record) Fields(tag string) []DataField {
+	var fields []DataField
+	for _, field := range r.DataFields {
+		if field.Tag == tag {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+func (f DataField) Subfield(code string) string {
+	for _, subfield := range f.Subfields {
+		if subfield.Code == code {
+			return subfield.Value
+		}
+	}
+	return ""
+}
+
+func isControlTag(tag string) bool {
+	return strings.HasPrefix(tag, "00")
+}
+
+func indicator(value string) string {
+	if value == "" {
+		return " "
+	}
+	return value[:1]
+}
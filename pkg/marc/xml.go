@@ -0,0 +1,138 @@
+package marc
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+)
+
+const Namespace = "http://www.loc.gov/MARC21/slim"
+
+type xmlCollection struct {
+	XMLName xml.Name    `xml:"collection"`
+	Xmlns   string      `xml:"xmlns,attr"`
+	Records []xmlRecord `xml:"record"`
+}
+
+type xmlRecord struct {
+	XMLName       xml.Name          `xml:"record"`
+	Leader        string            `xml:"leader"`
+	ControlFields []xmlControlField `xml:"controlfield"`
+	DataFields    []xmlDataField    `xml:"datafield"`
+}
+
+type xmlControlField struct {
+	Tag   string `xml:"tag,attr"`
+	Value string `xml:",chardata"`
+}
+
+type xmlDataField struct {
+	Tag       string        `xml:"tag,attr"`
+	Ind1      string        `xml:"ind1,attr"`
+	Ind2      string        `xml:"ind2,attr"`
+	Subfields []xmlSubfield `xml:"subfield"`
+}
+
+type xmlSubfield struct {
+	Code  string `xml:"code,attr"`
+	Value string `xml:",chardata"`
+}
+
+// ParseXML decodes MARCXML. Both a <collection> root and a bare <record> root
+// are accepted.
+func ParseXML(raw []byte) ([]*Record, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(raw))
+
+	var records []*Record
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != "record" {
+			continue
+		}
+
+		var element xmlRecord
+		if err := decoder.DecodeElement(&element, &start); err != nil {
+			return nil, err
+		}
+		records = append(records, element.toRecord())
+	}
+
+	if len(records) == 0 {
+		return nil, errors.New("no MARCXML record found")
+	}
+	return records, nil
+}
+
+// EncodeXML encodes records as a MARCXML <collection>.
+func EncodeXML(records []*Record) ([]byte, error) {
+	collection := xmlCollection{Xmlns: Namespace}
+	for _, record := range records {
+		collection.Records = append(collection.Records, newXMLRecord(record))
+	}
+
+	out, err := xml.MarshalIndent(collection, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), out...), nil
+}
+
+func newXMLRecord(record *Record) xmlRecord {
+	element := xmlRecord{Leader: record.Leader}
+	if element.Leader == "" {
+		element.Leader = DefaultLeader
+	}
+
+	for _, field := range record.ControlFields {
+		element.ControlFields = append(element.ControlFields, xmlControlField{
+			Tag:   field.Tag,
+			Value: field.Value,
+		})
+	}
+	for _, field := range record.DataFields {
+		dataField := xmlDataField{
+			Tag:  field.Tag,
+			Ind1: indicator(field.Ind1),
+			Ind2: indicator(field.Ind2),
+		}
+		for _, subfield := range field.Subfields {
+			dataField.Subfields = append(dataField.Subfields, xmlSubfield{
+				Code:  subfield.Code,
+				Value: subfield.Value,
+			})
+		}
+		element.DataFields = append(element.DataFields, dataField)
+	}
+	return element
+}
+
+func (element xmlRecord) toRecord() *Record {
+	record := &Record{Leader: element.Leader}
+	for _, field := range element.ControlFields {
+		record.AddControlField(field.Tag, field.Value)
+	}
+	for _, field := range element.DataFields {
+		dataField := DataField{
+			Tag:  field.Tag,
+			Ind1: indicator(field.Ind1),
+			Ind2: indicator(field.Ind2),
+		}
+		for _, subfield := range field.Subfields {
+			dataField.Subfields = append(dataField.Subfields, Subfield{
+				Code:  subfield.Code,
+				Value: subfield.Value,
+			})
+		}
+		record.DataFields = append(record.DataFields, dataField)
+	}
+	return record
+}
@@ -0,0 +1,54 @@
+// Package mergepatch implements JSON Merge Patch as described in RFC 7396.
+package mergepatch
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+const ContentType = "application/merge-patch+json"
+
+var ErrInvalidPatch = errors.New("merge patch must be a JSON object")
+
+// Apply merges patch into the JSON document original and returns the result.
+// Object members set to null are removed, nested objects are merged
+// recursively and every other value replaces the original one.
+func Apply(original, patch []byte) ([]byte, error) {
+	var patchValue interface{}
+	if err := json.Unmarshal(patch, &patchValue); err != nil {
+		return nil, err
+	}
+	if _, ok := patchValue.(map[string]interface{}); !ok {
+		return nil, ErrInvalidPatch
+	}
+
+	var originalValue interface{}
+	if len(original) != 0 {
+		if err := json.Unmarshal(original, &originalValue); err != nil {
+			return nil, err
+		}
+	}
+
+	return json.Marshal(merge(originalValue, patchValue))
+}
+
+func merge(target, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = map[string]interface{}{}
+	}
+
+	for key, value := range patchObject {
+		if value == nil {
+			delete(targetObject, key)
+			continue
+		}
+		targetObject[key] = merge(targetObject[key], value)
+	}
+	return targetObject
+}
@@ -0,0 +1,43 @@
+package mergepatch
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Examples from RFC 7396, appendix A, that apply to object patches.
+func TestApply_RFCExamples(t *testing.T) {
+	cases := []struct {
+		original string
+		patch    string
+		result   string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`{"e":null}`, `{"a":1}`, `{"a":1,"e":null}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	}
+
+	for _, c := range cases {
+		result, err := Apply([]byte(c.original), []byte(c.patch))
+
+		assert.Nil(t, err, c.patch)
+		assert.JSONEq(t, c.result, string(result), c.patch)
+	}
+}
+
+func TestApply_RejectsNonObjectPatch(t *testing.T) {
+	for _, patch := range []string{`["a"]`, `"a"`, `null`, `{`} {
+		_, err := Apply([]byte(`{"a":"b"}`), []byte(patch))
+
+		assert.NotNil(t, err, patch)
+	}
+}
@@ -0,0 +1,76 @@
+package oaipmh
+
+import (
+	"encoding/xml"
+	"strconv"
+	"time"
+)
+
+const (
+	MetadataPrefixDC = "oai_dc"
+
+	dcSchema         = "http://www.openarchives.org/OAI/2.0/oai_dc.xsd"
+	dcNamespace      = "http://www.openarchives.org/OAI/2.0/oai_dc/"
+	dcElements       = "http://purl.org/dc/elements/1.1/"
+	dcSchemaLocation = "http://www.openarchives.org/OAI/2.0/oai_dc/ http://www.openarchives.org/OAI/2.0/oai_dc.xsd"
+)
+
+var DublinCoreFormat = MetadataFormat{
+	MetadataPrefix:    MetadataPrefixDC,
+	Schema:            dcSchema,
+	MetadataNamespace: dcNamespace,
+}
+
+// Item is the repository-neutral description of a catalog entry that gets
+// disseminated as a record. A deleted item only has ID and Datestamp.
+type Item struct {
+	ID        uint64
+	Datestamp time.Time
+	Deleted   bool
+	Title     string
+	Creator   string
+	Publisher string
+	Date      time.Time
+	ISBN      string
+	Subjects  []string
+}
+
+type DublinCore struct {
+	XMLName        xml.Name `xml:"oai_dc:dc"`
+	XmlnsOaiDC     string   `xml:"xmlns:oai_dc,attr"`
+	XmlnsDC        string   `xml:"xmlns:dc,attr"`
+	XmlnsXsi       string   `xml:"xmlns:xsi,attr"`
+	SchemaLocation string   `xml:"xsi:schemaLocation,attr"`
+	Title          []string `xml:"dc:title"`
+	Creator        []string `xml:"dc:creator"`
+	Subject        []string `xml:"dc:subject"`
+	Publisher      []string `xml:"dc:publisher"`
+	Date           []string `xml:"dc:date"`
+	Type           []string `xml:"dc:type"`
+	Identifier     []string `xml:"dc:identifier"`
+}
+
+func NewDublinCore(item *Item) *DublinCore {
+	dc := &DublinCore{
+		XmlnsOaiDC:     dcNamespace,
+		XmlnsDC:        dcElements,
+		XmlnsXsi:       schemaInstance,
+		SchemaLocation: dcSchemaLocation,
+		Title:          []string{item.Title},
+		Subject:        item.Subjects,
+		Type:           []string{"Text"},
+	}
+	if item.Creator != "" {
+		dc.Creator = []string{item.Creator}
+	}
+	if item.Publisher != "" {
+		dc.Publisher = []string{item.Publisher}
+	}
+	if !item.Date.IsZero() {
+		dc.Date = []string{strconv.Itoa(item.Date.Year())}
+	}
+	if item.ISBN != "" {
+		dc.Identifier = []string{"urn:isbn:" + item.ISBN}
+	}
+	return dc
+}
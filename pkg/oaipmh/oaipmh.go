@@ -0,0 +1,160 @@
+package oaipmh
+
+import (
+	"encoding/xml"
+	"time"
+)
+
+const (
+	ProtocolVersion = "2.0"
+	Granularity     = "YYYY-MM-DDThh:mm:ssZ"
+	DatestampLayout = "2006-01-02T15:04:05Z"
+	DayLayout       = "2006-01-02"
+
+	namespace      = "http://www.openarchives.org/OAI/2.0/"
+	schemaInstance = "http://www.w3.org/2001/XMLSchema-instance"
+	schemaLocation = "http://www.openarchives.org/OAI/2.0/ http://www.openarchives.org/OAI/2.0/OAI-PMH.xsd"
+)
+
+// DeletedRecordPersistent tells harvesters that deletions are kept and
+// reported with StatusDeleted headers.
+const (
+	DeletedRecordPersistent = "persistent"
+	StatusDeleted           = "deleted"
+)
+
+// Error codes defined in section 3.6 of the OAI-PMH specification.
+const (
+	ErrBadArgument             = "badArgument"
+	ErrBadResumptionToken      = "badResumptionToken"
+	ErrBadVerb                 = "badVerb"
+	ErrCannotDisseminateFormat = "cannotDisseminateFormat"
+	ErrIDDoesNotExist          = "idDoesNotExist"
+	ErrNoRecordsMatch          = "noRecordsMatch"
+	ErrNoSetHierarchy          = "noSetHierarchy"
+)
+
+type Response struct {
+	XMLName             xml.Name             `xml:"OAI-PMH"`
+	Xmlns               string               `xml:"xmlns,attr"`
+	XmlnsXsi            string               `xml:"xmlns:xsi,attr"`
+	SchemaLocation      string               `xml:"xsi:schemaLocation,attr"`
+	ResponseDate        string               `xml:"responseDate"`
+	Request             Request              `xml:"request"`
+	Errors              []Error              `xml:"error,omitempty"`
+	Identify            *Identify            `xml:"Identify,omitempty"`
+	ListMetadataFormats *ListMetadataFormats `xml:"ListMetadataFormats,omitempty"`
+	ListIdentifiers     *ListIdentifiers     `xml:"ListIdentifiers,omitempty"`
+	ListRecords         *ListRecords         `xml:"ListRecords,omitempty"`
+	GetRecord           *GetRecord           `xml:"GetRecord,omitempty"`
+}
+
+type Request struct {
+	Verb            string `xml:"verb,attr,omitempty"`
+	Identifier      string `xml:"identifier,attr,omitempty"`
+	MetadataPrefix  string `xml:"metadataPrefix,attr,omitempty"`
+	From            string `xml:"from,attr,omitempty"`
+	Until           string `xml:"until,attr,omitempty"`
+	Set             string `xml:"set,attr,omitempty"`
+	ResumptionToken string `xml:"resumptionToken,attr,omitempty"`
+	URL             string `xml:",chardata"`
+}
+
+type Error struct {
+	Code    string `xml:"code,attr"`
+	Message string `xml:",chardata"`
+}
+
+type Identify struct {
+	RepositoryName    string `xml:"repositoryName"`
+	BaseURL           string `xml:"baseURL"`
+	ProtocolVersion   string `xml:"protocolVersion"`
+	AdminEmail        string `xml:"adminEmail"`
+	EarliestDatestamp string `xml:"earliestDatestamp"`
+	DeletedRecord     string `xml:"deletedRecord"`
+	Granularity       string `xml:"granularity"`
+}
+
+type ListMetadataFormats struct {
+	MetadataFormats []MetadataFormat `xml:"metadataFormat"`
+}
+
+type MetadataFormat struct {
+	MetadataPrefix    string `xml:"metadataPrefix"`
+	Schema            string `xml:"schema"`
+	MetadataNamespace string `xml:"metadataNamespace"`
+}
+
+type ListIdentifiers struct {
+	Headers         []Header         `xml:"header"`
+	ResumptionToken *ResumptionToken `xml:"resumptionToken,omitempty"`
+}
+
+type ListRecords struct {
+	Records         []Record         `xml:"record"`
+	ResumptionToken *ResumptionToken `xml:"resumptionToken,omitempty"`
+}
+
+type GetRecord struct {
+	Record Record `xml:"record"`
+}
+
+type Header struct {
+	Status     string `xml:"status,attr,omitempty"`
+	Identifier string `xml:"identifier"`
+	Datestamp  string `xml:"datestamp"`
+}
+
+type Record struct {
+	Header   Header    `xml:"header"`
+	Metadata *Metadata `xml:"metadata,omitempty"`
+}
+
+type Metadata struct {
+	DublinCore *DublinCore
+}
+
+// ResumptionToken is the element returned with incomplete lists. An empty
+// Value marks the last page of a list that needed one.
+type ResumptionToken struct {
+	Value            string `xml:",chardata"`
+	ExpirationDate   string `xml:"expirationDate,attr,omitempty"`
+	CompleteListSize int    `xml:"completeListSize,attr"`
+	Cursor           int    `xml:"cursor,attr"`
+}
+
+func NewResponse(request Request, now time.Time) *Response {
+	return &Response{
+		Xmlns:          namespace,
+		XmlnsXsi:       schemaInstance,
+		SchemaLocation: schemaLocation,
+		ResponseDate:   FormatDatestamp(now),
+		Request:        request,
+	}
+}
+
+func (response *Response) AddError(code string, message string) {
+	response.Errors = append(response.Errors, Error{Code: code, Message: message})
+}
+
+func (response *Response) Marshal() ([]byte, error) {
+	out, err := xml.MarshalIndent(response, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), out...), nil
+}
+
+func FormatDatestamp(t time.Time) string {
+	return t.UTC().Format(DatestampLayout)
+}
+
+// ParseDatestamp parses a from/until argument in either day or seconds
+// granularity. The returned bool reports whether the value had day granularity.
+func ParseDatestamp(value string) (time.Time, bool, error) {
+	if t, err := time.Parse(DayLayout, value); err == nil {
+		return t, true, nil
+	}
+	t, err := time.Parse(DatestampLayout, value)
+	return t, false, err
+}
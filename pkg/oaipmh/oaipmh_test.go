@@ -0,0 +1,102 @@
+package oaipmh
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCursor_RoundTrip(t *testing.T) {
+	from := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	cursor := &Cursor{
+		MetadataPrefix: MetadataPrefixDC,
+		From:           &from,
+		AfterDatestamp: time.Date(2024, time.February, 3, 4, 5, 6, 789000, time.UTC),
+		AfterID:        42,
+		Position:       100,
+	}
+
+	decoded, err := DecodeCursor(EncodeCursor(cursor))
+
+	assert.Nil(t, err)
+	assert.Equal(t, cursor.MetadataPrefix, decoded.MetadataPrefix)
+	assert.True(t, cursor.From.Equal(*decoded.From))
+	assert.Nil(t, decoded.Until)
+	assert.True(t, cursor.AfterDatestamp.Equal(decoded.AfterDatestamp))
+	assert.Equal(t, cursor.AfterID, decoded.AfterID)
+	assert.Equal(t, cursor.Position, decoded.Position)
+}
+
+func TestDecodeCursor_Invalid(t *testing.T) {
+	for _, token := range []string{"not base64!", "e30", EncodeCursor(&Cursor{MetadataPrefix: MetadataPrefixDC})} {
+		_, err := DecodeCursor(token)
+		assert.NotNil(t, err, token)
+	}
+}
+
+func TestParseDatestamp(t *testing.T) {
+	day, isDay, err := ParseDatestamp("2024-05-06")
+	assert.Nil(t, err)
+	assert.True(t, isDay)
+	assert.Equal(t, time.Date(2024, time.May, 6, 0, 0, 0, 0, time.UTC), day)
+
+	seconds, isDay, err := ParseDatestamp("2024-05-06T07:08:09Z")
+	assert.Nil(t, err)
+	assert.False(t, isDay)
+	assert.Equal(t, time.Date(2024, time.May, 6, 7, 8, 9, 0, time.UTC), seconds)
+
+	_, _, err = ParseDatestamp("2024-05-06T07:08Z")
+	assert.NotNil(t, err)
+}
+
+func TestResponse_MarshalDublinCoreRecord(t *testing.T) {
+	response := NewResponse(Request{Verb: "GetRecord", URL: "http://localhost/oai"}, time.Now())
+	response.GetRecord = &GetRecord{Record: Record{
+		Header: Header{Identifier: "oai:library-api-book:book/1", Datestamp: "2024-01-01T00:00:00Z"},
+		Metadata: &Metadata{DublinCore: NewDublinCore(&Item{
+			ID:        1,
+			Title:     "Dune",
+			Creator:   "Herbert, Frank",
+			Publisher: "Chilton Books",
+			Date:      time.Date(1965, time.January, 1, 0, 0, 0, 0, time.UTC),
+			ISBN:      "9780441172719",
+			Subjects:  []string{"Science fiction"},
+		})},
+	}}
+
+	raw, err := response.Marshal()
+	assert.Nil(t, err)
+
+	out := string(raw)
+	assert.True(t, strings.Contains(out, `<OAI-PMH xmlns="http://www.openarchives.org/OAI/2.0/"`))
+	assert.True(t, strings.Contains(out, `<oai_dc:dc xmlns:oai_dc="http://www.openarchives.org/OAI/2.0/oai_dc/" xmlns:dc="http://purl.org/dc/elements/1.1/"`))
+	assert.True(t, strings.Contains(out, `<dc:title>Dune</dc:title>`))
+	assert.True(t, strings.Contains(out, `<dc:creator>Herbert, Frank</dc:creator>`))
+	assert.True(t, strings.Contains(out, `<dc:date>1965</dc:date>`))
+	assert.True(t, strings.Contains(out, `<dc:identifier>urn:isbn:9780441172719</dc:identifier>`))
+	assert.False(t, strings.Contains(out, `<error`))
+}
+
+func TestResponse_MarshalError(t *testing.T) {
+	response := NewResponse(Request{URL: "http://localhost/oai"}, time.Now())
+	response.AddError(ErrBadVerb, "Illegal OAI verb")
+
+	raw, err := response.Marshal()
+	assert.Nil(t, err)
+	assert.True(t, strings.Contains(string(raw), `<request>http://localhost/oai</request>`))
+	assert.True(t, strings.Contains(string(raw), `<error code="badVerb">Illegal OAI verb</error>`))
+}
+
+func TestResponse_MarshalDeletedRecord(t *testing.T) {
+	response := NewResponse(Request{Verb: "GetRecord", URL: "http://localhost/oai"}, time.Now())
+	response.GetRecord = &GetRecord{Record: Record{
+		Header: Header{Status: StatusDeleted, Identifier: "oai:library-api-book:book/1", Datestamp: "2024-01-01T00:00:00Z"},
+	}}
+
+	raw, err := response.Marshal()
+	assert.Nil(t, err)
+	assert.True(t, strings.Contains(string(raw), `<header status="deleted">`))
+	assert.False(t, strings.Contains(string(raw), `<metadata>`))
+}
@@ -0,0 +1,48 @@
+package oaipmh
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// Cursor is the harvesting state carried in a resumption token. It pins the
+// original arguments and the (datestamp, id) of the last item returned so that
+// the next page continues after it even when earlier rows change.
+type Cursor struct {
+	MetadataPrefix string     `json:"p"`
+	From           *time.Time `json:"f,omitempty"`
+	Until          *time.Time `json:"u,omitempty"`
+	AfterDatestamp time.Time  `json:"t"`
+	AfterID        uint64     `json:"i"`
+	Position       int        `json:"c"`
+	Expires        time.Time  `json:"e"`
+}
+
+// Expired reports whether the token the cursor came from is no longer valid
+// at now. Tokens issued without an expiry never expire.
+func (cursor *Cursor) Expired(now time.Time) bool {
+	return !cursor.Expires.IsZero() && now.After(cursor.Expires)
+}
+
+func EncodeCursor(cursor *Cursor) string {
+	raw, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func DecodeCursor(token string) (*Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, errors.New("malformed resumption token")
+	}
+
+	var cursor Cursor
+	if err := json.Unmarshal(raw, &cursor); err != nil {
+		return nil, errors.New("malformed resumption token")
+	}
+	if cursor.MetadataPrefix == "" || cursor.AfterID == 0 {
+		return nil, errors.New("incomplete resumption token")
+	}
+	return &cursor, nil
+}
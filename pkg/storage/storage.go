@@ -0,0 +1,25 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+var ErrNotFound = errors.New("object not found")
+
+// Storage is a flat key/value object store. Keys use forward slashes and are
+// relative to the store's root, e.g. "books/1/3f2a/original.png".
+type Storage interface {
+	Put(ctx context.Context, key string, body io.Reader, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, *ObjectInfo, error)
+	Delete(ctx context.Context, key string) error
+}
+
+type ObjectInfo struct {
+	Key          string
+	Size         int64
+	ContentType  string
+	LastModified time.Time
+}
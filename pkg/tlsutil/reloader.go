@@ -0,0 +1,228 @@
+// Package tlsutil builds TLS configurations whose certificates and CA bundle
+// can be replaced on disk without restarting the process.
+package tlsutil
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+type Files struct {
+	CertFile string
+	KeyFile  string
+	// CAFile verifies the peer. Empty means the system roots for clients
+	// and no client certificate verification for servers.
+	CAFile string
+}
+
+// Reloader holds the current certificate and CA pool loaded from Files.
+// Configurations built from it read them on every handshake, so a reload
+// applies to new connections immediately.
+type Reloader struct {
+	files Files
+
+	mu     sync.RWMutex
+	cert   *tls.Certificate
+	pool   *x509.CertPool
+	stamps map[string]fileStamp
+}
+
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+func NewReloader(files Files) (*Reloader, error) {
+	if (files.CertFile == "") != (files.KeyFile == "") {
+		return nil, errors.New("certificate and key files must be set together")
+	}
+
+	reloader := &Reloader{files: files}
+	if err := reloader.Reload(); err != nil {
+		return nil, err
+	}
+	return reloader, nil
+}
+
+// Reload reads all files again. On error the previous certificate and pool
+// stay in use.
+func (reloader *Reloader) Reload() error {
+	stamps, err := reloader.stat()
+	if err != nil {
+		return err
+	}
+
+	var cert *tls.Certificate
+	if reloader.files.CertFile != "" {
+		pair, err := tls.LoadX509KeyPair(reloader.files.CertFile, reloader.files.KeyFile)
+		if err != nil {
+			return fmt.Errorf("load certificate: %w", err)
+		}
+		cert = &pair
+	}
+
+	var pool *x509.CertPool
+	if reloader.files.CAFile != "" {
+		data, err := os.ReadFile(reloader.files.CAFile)
+		if err != nil {
+			return fmt.Errorf("read CA bundle: %w", err)
+		}
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return fmt.Errorf("CA bundle %s contains no certificates", reloader.files.CAFile)
+		}
+	}
+
+	reloader.mu.Lock()
+	reloader.cert = cert
+	reloader.pool = pool
+	reloader.stamps = stamps
+	reloader.mu.Unlock()
+	return nil
+}
+
+// Run reloads the files whenever their size or modification time changes,
+// checking every interval until ctx is done. Failed reloads are reported to
+// onError and retried on the next check.
+func (reloader *Reloader) Run(ctx context.Context, interval time.Duration, onError func(error)) {
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if !reloader.changed() {
+			continue
+		}
+		if err := reloader.Reload(); err != nil && onError != nil {
+			onError(err)
+		}
+	}
+}
+
+// ServerConfig returns a server configuration. With a CA file, client
+// certificates are verified against it, and required when requireClientCert
+// is set. protocols are offered through ALPN and default to "h2".
+func (reloader *Reloader) ServerConfig(requireClientCert bool, protocols ...string) *tls.Config {
+	if len(protocols) == 0 {
+		protocols = []string{"h2"}
+	}
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			cert, pool := reloader.current()
+			if cert == nil {
+				return nil, errors.New("no server certificate configured")
+			}
+
+			config := &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				Certificates: []tls.Certificate{*cert},
+				NextProtos:   protocols,
+			}
+			if pool != nil {
+				config.ClientCAs = pool
+				config.ClientAuth = tls.VerifyClientCertIfGiven
+				if requireClientCert {
+					config.ClientAuth = tls.RequireAndVerifyClientCert
+				}
+			}
+			return config, nil
+		},
+	}
+}
+
+// ClientConfig returns a client configuration that presents the certificate,
+// if any, and verifies the server against the current CA pool. serverName
+// overrides the host name checked in the server certificate.
+func (reloader *Reloader) ClientConfig(serverName string) *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: serverName,
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			cert, _ := reloader.current()
+			if cert == nil {
+				return &tls.Certificate{}, nil
+			}
+			return cert, nil
+		},
+		// RootCAs is fixed once a handshake starts, so the default verification
+		// is replaced by the same check against the pool current at handshake
+		// time. VerifyConnection runs for every connection, including resumed
+		// ones.
+		InsecureSkipVerify: true,
+		VerifyConnection: func(state tls.ConnectionState) error {
+			_, pool := reloader.current()
+			return verifyServer(state, pool)
+		},
+	}
+}
+
+func verifyServer(state tls.ConnectionState, pool *x509.CertPool) error {
+	if len(state.PeerCertificates) == 0 {
+		return errors.New("server presented no certificate")
+	}
+
+	options := x509.VerifyOptions{
+		Roots:         pool,
+		DNSName:       state.ServerName,
+		Intermediates: x509.NewCertPool(),
+	}
+	for _, cert := range state.PeerCertificates[1:] {
+		options.Intermediates.AddCert(cert)
+	}
+	_, err := state.PeerCertificates[0].Verify(options)
+	return err
+}
+
+func (reloader *Reloader) current() (*tls.Certificate, *x509.CertPool) {
+	reloader.mu.RLock()
+	defer reloader.mu.RUnlock()
+	return reloader.cert, reloader.pool
+}
+
+func (reloader *Reloader) changed() bool {
+	stamps, err := reloader.stat()
+	if err != nil {
+		// Files are often replaced in several steps; try again later.
+		return false
+	}
+
+	reloader.mu.RLock()
+	defer reloader.mu.RUnlock()
+	for path, stamp := range stamps {
+		if reloader.stamps[path] != stamp {
+			return true
+		}
+	}
+	return false
+}
+
+func (reloader *Reloader) stat() (map[string]fileStamp, error) {
+	stamps := make(map[string]fileStamp, 3)
+	for _, path := range []string{reloader.files.CertFile, reloader.files.KeyFile, reloader.files.CAFile} {
+		if path == "" {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		stamps[path] = fileStamp{modTime: info.ModTime(), size: info.Size()}
+	}
+	return stamps, nil
+}
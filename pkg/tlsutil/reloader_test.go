@@ -0,0 +1,239 @@
+package tlsutil
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+var serial int64
+
+func newTestCA(t *testing.T) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	serial++
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(serial),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue writes a leaf certificate for localhost and its key into dir.
+func (ca *testCA) issue(t *testing.T, dir, name string, usage x509.ExtKeyUsage) (certFile, keyFile string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	serial++
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certFile = filepath.Join(dir, name+".crt")
+	keyFile = filepath.Join(dir, name+".key")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+	return certFile, keyFile
+}
+
+func (ca *testCA) write(t *testing.T, dir, name string) string {
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(path, ca.pem, 0o600))
+	return path
+}
+
+// serve starts a health-only gRPC server with the given TLS configuration.
+func serve(t *testing.T, config *tls.Config) *bufconn.Listener {
+	listener := bufconn.Listen(1 << 20)
+	server := grpc.NewServer(grpc.Creds(credentials.NewTLS(config)))
+	healthpb.RegisterHealthServer(server, health.NewServer())
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+	return listener
+}
+
+func check(t *testing.T, listener *bufconn.Listener, config *tls.Config) error {
+	conn, err := grpc.NewClient("passthrough:///localhost",
+		grpc.WithTransportCredentials(credentials.NewTLS(config)),
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+	)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	_, err = healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+	return err
+}
+
+func TestMutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	caFile := ca.write(t, dir, "ca.pem")
+	serverCert, serverKey := ca.issue(t, dir, "server", x509.ExtKeyUsageServerAuth)
+	clientCert, clientKey := ca.issue(t, dir, "client", x509.ExtKeyUsageClientAuth)
+
+	server, err := NewReloader(Files{CertFile: serverCert, KeyFile: serverKey, CAFile: caFile})
+	require.NoError(t, err)
+	listener := serve(t, server.ServerConfig(true))
+
+	client, err := NewReloader(Files{CertFile: clientCert, KeyFile: clientKey, CAFile: caFile})
+	require.NoError(t, err)
+	assert.NoError(t, check(t, listener, client.ClientConfig("")))
+
+	// No client certificate.
+	anonymous, err := NewReloader(Files{CAFile: caFile})
+	require.NoError(t, err)
+	assert.Error(t, check(t, listener, anonymous.ClientConfig("")))
+
+	// Client certificate from a CA the server does not trust.
+	otherDir := t.TempDir()
+	otherCert, otherKey := newTestCA(t).issue(t, otherDir, "client", x509.ExtKeyUsageClientAuth)
+	untrusted, err := NewReloader(Files{CertFile: otherCert, KeyFile: otherKey, CAFile: caFile})
+	require.NoError(t, err)
+	assert.Error(t, check(t, listener, untrusted.ClientConfig("")))
+}
+
+func TestClientRejectsUnknownServer(t *testing.T) {
+	dir := t.TempDir()
+	serverCert, serverKey := newTestCA(t).issue(t, dir, "server", x509.ExtKeyUsageServerAuth)
+	caFile := newTestCA(t).write(t, dir, "ca.pem")
+
+	server, err := NewReloader(Files{CertFile: serverCert, KeyFile: serverKey})
+	require.NoError(t, err)
+	listener := serve(t, server.ServerConfig(false))
+
+	client, err := NewReloader(Files{CAFile: caFile})
+	require.NoError(t, err)
+	assert.Error(t, check(t, listener, client.ClientConfig("")))
+}
+
+func TestClientChecksServerName(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	caFile := ca.write(t, dir, "ca.pem")
+	serverCert, serverKey := ca.issue(t, dir, "server", x509.ExtKeyUsageServerAuth)
+
+	server, err := NewReloader(Files{CertFile: serverCert, KeyFile: serverKey})
+	require.NoError(t, err)
+	listener := serve(t, server.ServerConfig(false))
+
+	client, err := NewReloader(Files{CAFile: caFile})
+	require.NoError(t, err)
+	assert.Error(t, check(t, listener, client.ClientConfig("books.internal")))
+}
+
+func TestReloadRotatesCertificates(t *testing.T) {
+	dir := t.TempDir()
+	oldCA := newTestCA(t)
+	caFile := oldCA.write(t, dir, "ca.pem")
+	serverCert, serverKey := oldCA.issue(t, dir, "server", x509.ExtKeyUsageServerAuth)
+
+	server, err := NewReloader(Files{CertFile: serverCert, KeyFile: serverKey})
+	require.NoError(t, err)
+	listener := serve(t, server.ServerConfig(false))
+
+	client, err := NewReloader(Files{CAFile: caFile})
+	require.NoError(t, err)
+	require.NoError(t, check(t, listener, client.ClientConfig("")))
+
+	// The server moves to a new CA; the client only trusts it after reloading.
+	newCA := newTestCA(t)
+	newCA.issue(t, dir, "server", x509.ExtKeyUsageServerAuth)
+	require.NoError(t, server.Reload())
+	assert.Error(t, check(t, listener, client.ClientConfig("")))
+
+	newCA.write(t, dir, "ca.pem")
+	require.True(t, client.changed())
+	require.NoError(t, client.Reload())
+	assert.NoError(t, check(t, listener, client.ClientConfig("")))
+}
+
+func TestReloadKeepsCertificateOnError(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := newTestCA(t).issue(t, dir, "server", x509.ExtKeyUsageServerAuth)
+
+	reloader, err := NewReloader(Files{CertFile: certFile, KeyFile: keyFile})
+	require.NoError(t, err)
+	before, _ := reloader.current()
+
+	require.NoError(t, os.WriteFile(keyFile, []byte("not a key"), 0o600))
+	assert.Error(t, reloader.Reload())
+
+	after, _ := reloader.current()
+	assert.Same(t, before, after)
+}
+
+func TestNewReloader_RequiresKeyWithCert(t *testing.T) {
+	_, err := NewReloader(Files{CertFile: "server.crt"})
+	assert.Error(t, err)
+}
+
+func TestServerConfigProtocols(t *testing.T) {
+	dir := t.TempDir()
+	serverCert, serverKey := newTestCA(t).issue(t, dir, "server", x509.ExtKeyUsageServerAuth)
+	server, err := NewReloader(Files{CertFile: serverCert, KeyFile: serverKey})
+	require.NoError(t, err)
+
+	negotiate := func(config *tls.Config, offered ...string) string {
+		clientConn, serverConn := net.Pipe()
+		defer clientConn.Close()
+		defer serverConn.Close()
+		go tls.Server(serverConn, config).Handshake()
+
+		conn := tls.Client(clientConn, &tls.Config{InsecureSkipVerify: true, NextProtos: offered})
+		require.NoError(t, conn.Handshake())
+		return conn.ConnectionState().NegotiatedProtocol
+	}
+
+	assert.Equal(t, "h2", negotiate(server.ServerConfig(false), "h2", "http/1.1"))
+	assert.Equal(t, "http/1.1", negotiate(server.ServerConfig(false, "h2", "http/1.1"), "http/1.1"))
+}
@@ -0,0 +1,198 @@
+package token
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	// ErrUnknownKey means the token names a kid this key set does not hold,
+	// or carries no kid and the set has no default key.
+	ErrUnknownKey = errors.New("token signing key is unknown")
+	ErrNoExpiry   = errors.New("token has no expiry")
+	ErrExpired    = errors.New("Token Expired")
+)
+
+// Key is a single verification key. HMAC keys hold the shared secret, RSA and
+// ECDSA keys hold only the public key.
+type Key struct {
+	ID        string
+	Algorithm string
+	verify    interface{}
+}
+
+// KeySet verifies tokens locally. Keys are selected by the token's "kid"
+// header so a new key can be added before the issuer switches to it and the
+// old one removed once its tokens have expired.
+type KeySet struct {
+	keys       map[string]*Key
+	defaultKID string
+	now        func() time.Time
+}
+
+type keyFile struct {
+	DefaultKID string         `json:"default_kid"`
+	Keys       []keyFileEntry `json:"keys"`
+}
+
+type keyFileEntry struct {
+	KID       string `json:"kid"`
+	Algorithm string `json:"alg"`
+	Secret    string `json:"secret"`
+	PublicKey string `json:"public_key"`
+}
+
+func NewKeySet(defaultKID string, keys ...*Key) (*KeySet, error) {
+	set := &KeySet{
+		keys:       make(map[string]*Key, len(keys)),
+		defaultKID: defaultKID,
+		now:        time.Now,
+	}
+	for _, key := range keys {
+		if _, ok := set.keys[key.ID]; ok {
+			return nil, fmt.Errorf("duplicate key id %q", key.ID)
+		}
+		set.keys[key.ID] = key
+	}
+	if len(set.keys) == 0 {
+		return nil, errors.New("key set is empty")
+	}
+	if _, ok := set.keys[defaultKID]; defaultKID != "" && !ok {
+		return nil, fmt.Errorf("default key id %q is not in the key set", defaultKID)
+	}
+	return set, nil
+}
+
+// NewHMACKey returns an HS256 key for secret.
+func NewHMACKey(kid string, secret []byte) (*Key, error) {
+	if len(secret) < 32 {
+		return nil, fmt.Errorf("key %q: HMAC secret must be at least 32 bytes", kid)
+	}
+	return &Key{ID: kid, Algorithm: jwt.SigningMethodHS256.Alg(), verify: secret}, nil
+}
+
+// NewPublicKey parses a PEM encoded RSA or ECDSA public key for alg, e.g.
+// RS256 or ES256.
+func NewPublicKey(kid string, alg string, pemData []byte) (*Key, error) {
+	var (
+		verify interface{}
+		err    error
+	)
+	switch {
+	case strings.HasPrefix(alg, "RS"), strings.HasPrefix(alg, "PS"):
+		verify, err = jwt.ParseRSAPublicKeyFromPEM(pemData)
+	case strings.HasPrefix(alg, "ES"):
+		verify, err = jwt.ParseECPublicKeyFromPEM(pemData)
+	default:
+		return nil, fmt.Errorf("key %q: unsupported algorithm %q", kid, alg)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("key %q: %w", kid, err)
+	}
+	if jwt.GetSigningMethod(alg) == nil {
+		return nil, fmt.Errorf("key %q: unsupported algorithm %q", kid, alg)
+	}
+	return &Key{ID: kid, Algorithm: alg, verify: verify}, nil
+}
+
+// LoadKeys reads the keys of a JSON key file:
+//
+//	{
+//	  "default_kid": "2024-01",
+//	  "keys": [
+//	    {"kid": "2024-01", "alg": "HS256", "secret": "..."},
+//	    {"kid": "2024-07", "alg": "RS256", "public_key": "-----BEGIN PUBLIC KEY-----..."}
+//	  ]
+//	}
+func LoadKeys(path string) (string, []*Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", nil, fmt.Errorf("read key file: %w", err)
+	}
+
+	var file keyFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return "", nil, fmt.Errorf("parse key file: %w", err)
+	}
+
+	keys := make([]*Key, 0, len(file.Keys))
+	for _, entry := range file.Keys {
+		if entry.KID == "" {
+			return "", nil, errors.New("key file entry without kid")
+		}
+
+		var key *Key
+		if entry.Algorithm == "" || entry.Algorithm == jwt.SigningMethodHS256.Alg() {
+			key, err = NewHMACKey(entry.KID, []byte(entry.Secret))
+		} else {
+			key, err = NewPublicKey(entry.KID, entry.Algorithm, []byte(entry.PublicKey))
+		}
+		if err != nil {
+			return "", nil, err
+		}
+		keys = append(keys, key)
+	}
+	return file.DefaultKID, keys, nil
+}
+
+// Validate verifies the signature and expiry of tokenString and returns its
+// payload. The algorithm must match the one configured for the key, so a
+// token cannot pick a weaker algorithm than its key.
+func (set *KeySet) Validate(tokenString string) (*Token, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, set.keyFunc, jwt.WithTimeFunc(set.now))
+	if err != nil {
+		return nil, err
+	}
+
+	var payload Token
+	data, err := json.Marshal(claims["payload"])
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return nil, err
+	}
+	if payload.AuthId <= 0 {
+		return nil, errors.New("token has no auth id")
+	}
+
+	// jwt has already checked a standard "exp" claim; tokens issued by the
+	// user service carry their expiry in the payload instead.
+	exp, err := claims.GetExpirationTime()
+	if err != nil {
+		return nil, err
+	}
+	if exp != nil && (payload.Expired.IsZero() || exp.Time.Before(payload.Expired)) {
+		payload.Expired = exp.Time
+	}
+	if payload.Expired.IsZero() {
+		return nil, ErrNoExpiry
+	}
+	if set.now().After(payload.Expired) {
+		return nil, ErrExpired
+	}
+	return &payload, nil
+}
+
+func (set *KeySet) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		kid = set.defaultKID
+	}
+
+	key, ok := set.keys[kid]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	if token.Method.Alg() != key.Algorithm {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Method.Alg())
+	}
+	return key.verify, nil
+}
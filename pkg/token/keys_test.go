@@ -0,0 +1,156 @@
+package token
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	oldSecret = []byte("0123456789abcdef0123456789abcdef")
+	newSecret = []byte("fedcba9876543210fedcba9876543210")
+)
+
+func newTestKeySet(t *testing.T) *KeySet {
+	oldKey, err := NewHMACKey("2024-01", oldSecret)
+	require.NoError(t, err)
+	newKey, err := NewHMACKey("2024-07", newSecret)
+	require.NoError(t, err)
+
+	set, err := NewKeySet("2024-01", oldKey, newKey)
+	require.NoError(t, err)
+	return set
+}
+
+func TestKeySet_ValidateRotatedKeys(t *testing.T) {
+	set := newTestKeySet(t)
+
+	for _, tc := range []struct {
+		kid    string
+		secret []byte
+	}{
+		{kid: "2024-07", secret: newSecret},
+		{kid: "2024-01", secret: oldSecret},
+		{kid: "", secret: oldSecret},
+	} {
+		tokenString, err := GenerateToken(tc.kid, tc.secret, 7, "author")
+		require.NoError(t, err)
+
+		payload, err := set.Validate(tokenString)
+		require.NoError(t, err, tc.kid)
+		assert.Equal(t, 7, payload.AuthId)
+		assert.Equal(t, "author", payload.Role)
+	}
+}
+
+func TestKeySet_WrongKeyForKID(t *testing.T) {
+	tokenString, err := GenerateToken("2024-07", oldSecret, 7, "author")
+	require.NoError(t, err)
+
+	_, err = newTestKeySet(t).Validate(tokenString)
+	assert.ErrorIs(t, err, jwt.ErrSignatureInvalid)
+}
+
+func TestKeySet_UnknownKID(t *testing.T) {
+	tokenString, err := GenerateToken("2025-01", newSecret, 7, "author")
+	require.NoError(t, err)
+
+	_, err = newTestKeySet(t).Validate(tokenString)
+	assert.ErrorIs(t, err, ErrUnknownKey)
+}
+
+func TestKeySet_Expired(t *testing.T) {
+	set := newTestKeySet(t)
+	tokenString, err := GenerateToken("2024-07", newSecret, 7, "author")
+	require.NoError(t, err)
+
+	set.now = func() time.Time { return time.Now().Add(TOKEN_Expiry + time.Minute) }
+	_, err = set.Validate(tokenString)
+	assert.ErrorIs(t, err, ErrExpired)
+}
+
+func TestKeySet_StandardExpClaim(t *testing.T) {
+	tokenString, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"exp":     time.Now().Add(-time.Minute).Unix(),
+		"payload": map[string]interface{}{"AuthId": 7},
+	}).SignedString(oldSecret)
+	require.NoError(t, err)
+
+	_, err = newTestKeySet(t).Validate(tokenString)
+	assert.ErrorIs(t, err, jwt.ErrTokenExpired)
+}
+
+func TestKeySet_NoExpiry(t *testing.T) {
+	tokenString, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"payload": map[string]interface{}{"AuthId": 7},
+	}).SignedString(oldSecret)
+	require.NoError(t, err)
+
+	_, err = newTestKeySet(t).Validate(tokenString)
+	assert.ErrorIs(t, err, ErrNoExpiry)
+}
+
+func TestKeySet_RejectsAlgorithmSwitch(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+	require.NoError(t, err)
+	publicPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+
+	key, err := NewPublicKey("rsa", "RS256", publicPEM)
+	require.NoError(t, err)
+	set, err := NewKeySet("", key)
+	require.NoError(t, err)
+
+	claims := jwt.MapClaims{"payload": Token{AuthId: 7, Expired: time.Now().Add(time.Hour)}}
+	signed := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	signed.Header["kid"] = "rsa"
+	tokenString, err := signed.SignedString(privateKey)
+	require.NoError(t, err)
+
+	payload, err := set.Validate(tokenString)
+	require.NoError(t, err)
+	assert.Equal(t, 7, payload.AuthId)
+
+	// An HS256 token signed with the public key bytes must not verify.
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	forged.Header["kid"] = "rsa"
+	forgedString, err := forged.SignedString(publicPEM)
+	require.NoError(t, err)
+
+	_, err = set.Validate(forgedString)
+	assert.Error(t, err)
+}
+
+func TestLoadKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{
+		"default_kid": "2024-01",
+		"keys": [
+			{"kid": "2024-01", "alg": "HS256", "secret": "0123456789abcdef0123456789abcdef"},
+			{"kid": "2024-07", "secret": "fedcba9876543210fedcba9876543210"}
+		]
+	}`), 0o600))
+
+	defaultKID, keys, err := LoadKeys(path)
+	require.NoError(t, err)
+	assert.Equal(t, "2024-01", defaultKID)
+	assert.Len(t, keys, 2)
+
+	_, err = NewKeySet("missing", keys...)
+	assert.Error(t, err)
+}
+
+func TestNewHMACKey_ShortSecret(t *testing.T) {
+	_, err := NewHMACKey("short", []byte("secret"))
+	assert.Error(t, err)
+}